@@ -93,6 +93,22 @@ func addAssetBalance(
 	}
 }
 
+func getAssetBalance(
+	ctx context.Context,
+	im state.Immutable,
+	actor codec.Address,
+	asset uint8,
+) (uint64, error) {
+	switch asset {
+	case AssetVEIL:
+		return storage.GetBalance(ctx, im, actor)
+	case AssetVAI:
+		return storage.GetVAIBalance(ctx, im, actor)
+	default:
+		return 0, storage.ErrUnsupportedAsset
+	}
+}
+
 func mulDiv(a uint64, b uint64, den uint64) (uint64, error) {
 	if den == 0 {
 		return 0, errors.New("division by zero")
@@ -0,0 +1,221 @@
+package actions
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+
+	smath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+const MaxBatchOrders = MaxBatchFills
+
+var (
+	ErrTooManyOrders        = errors.New("too many orders")
+	ErrOrderQuantityZero    = errors.New("order quantity is zero")
+	ErrOrderLimitOutOfRange = errors.New("order limit price is outside the outcome price scale")
+	ErrOrdersNotCanonical   = errors.New("orders must be sorted by trader with no duplicates")
+	ErrClearingMismatch     = errors.New("batch does not match deterministic clearing")
+)
+
+// BatchOrder is a revealed order entering a window's uniform-price auction.
+// Buys take YES shares at or below LimitPrice; sells take NO shares, i.e.
+// they sell YES at or above LimitPrice.
+type BatchOrder struct {
+	Trader     codec.Address `serialize:"true" json:"trader"`
+	Side       uint8         `serialize:"true" json:"side"`
+	LimitPrice uint64        `serialize:"true" json:"limit_price"`
+	Quantity   uint64        `serialize:"true" json:"quantity"`
+}
+
+// maxOrderCost is the collateral an order may draw if it fully fills at its
+// own limit, which bounds any uniform clearing price it can accept.
+func maxOrderCost(order BatchOrder) (uint64, error) {
+	return fillCost(order.Side, order.LimitPrice, order.Quantity)
+}
+
+type ClearingResult struct {
+	ClearPrice  uint64
+	TotalVolume uint64
+	Fills       []BatchFill
+	FillsHash   [32]byte
+}
+
+// ComputeUniformClearing runs the window's single-price call auction. The
+// rules are deterministic and replayable from the order set alone:
+//
+//  1. Candidate prices are the distinct order limit prices.
+//  2. The clearing price maximizes matched volume min(demand, supply), where
+//     demand counts buys with limit >= price and supply counts sells with
+//     limit <= price.
+//  3. Ties go to the smallest |demand - supply|, then to the lowest price.
+//  4. The short side fills completely. The long side fills by price priority
+//     (highest buy limit or lowest sell limit first), then by trader address
+//     ascending; the last order reached may fill partially.
+//
+// If no candidate matches any volume the result has zero price, volume and
+// fills. Orders must be sorted by trader with at most one order per trader.
+func ComputeUniformClearing(marketID ids.ID, windowID uint64, orders []BatchOrder) (ClearingResult, error) {
+	if err := validateBatchOrders(orders); err != nil {
+		return ClearingResult{}, err
+	}
+
+	var (
+		bestPrice     uint64
+		bestMatched   uint64
+		bestImbalance uint64
+	)
+	for _, price := range candidateClearingPrices(orders) {
+		demand, supply, err := crossedVolume(orders, price)
+		if err != nil {
+			return ClearingResult{}, err
+		}
+		matched := minU64(demand, supply)
+		if matched == 0 {
+			continue
+		}
+		imbalance := smath.AbsDiff(demand, supply)
+		if matched > bestMatched || (matched == bestMatched && imbalance < bestImbalance) {
+			bestPrice, bestMatched, bestImbalance = price, matched, imbalance
+		}
+	}
+
+	result := ClearingResult{
+		ClearPrice:  bestPrice,
+		TotalVolume: bestMatched,
+	}
+	if bestMatched > 0 {
+		result.Fills = allocateFills(orders, bestPrice, bestMatched)
+	}
+	result.FillsHash = ComputeBatchFillsHash(marketID, windowID, result.ClearPrice, result.Fills)
+	return result, nil
+}
+
+func validateBatchOrders(orders []BatchOrder) error {
+	if len(orders) > MaxBatchOrders {
+		return ErrTooManyOrders
+	}
+	for i, order := range orders {
+		if err := validateBatchOrder(order); err != nil {
+			return err
+		}
+		if i > 0 && bytes.Compare(orders[i-1].Trader[:], order.Trader[:]) >= 0 {
+			return ErrOrdersNotCanonical
+		}
+	}
+	return nil
+}
+
+func validateBatchOrder(order BatchOrder) error {
+	if order.Side != OrderSideBuy && order.Side != OrderSideSell {
+		return ErrInvalidOrderSide
+	}
+	if order.Quantity == 0 {
+		return ErrOrderQuantityZero
+	}
+	if order.LimitPrice == 0 || order.LimitPrice >= OutcomePriceScale {
+		return ErrOrderLimitOutOfRange
+	}
+	return nil
+}
+
+// validateBatchTraders checks a clear's trader list is sorted with no
+// duplicates, matching the canonical order of the resulting orders.
+func validateBatchTraders(traders []codec.Address) error {
+	if len(traders) > MaxBatchOrders {
+		return ErrTooManyOrders
+	}
+	for i := 1; i < len(traders); i++ {
+		if bytes.Compare(traders[i-1][:], traders[i][:]) >= 0 {
+			return ErrOrdersNotCanonical
+		}
+	}
+	return nil
+}
+
+// candidateClearingPrices returns the distinct limit prices in ascending
+// order, so the first candidate reaching a given score is the lowest price.
+func candidateClearingPrices(orders []BatchOrder) []uint64 {
+	prices := make([]uint64, 0, len(orders))
+	for _, order := range orders {
+		prices = append(prices, order.LimitPrice)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+	out := prices[:0]
+	for i, price := range prices {
+		if i == 0 || price != prices[i-1] {
+			out = append(out, price)
+		}
+	}
+	return out
+}
+
+func crossedVolume(orders []BatchOrder, price uint64) (uint64, uint64, error) {
+	var demand, supply uint64
+	for _, order := range orders {
+		var err error
+		switch {
+		case order.Side == OrderSideBuy && order.LimitPrice >= price:
+			demand, err = smath.Add(demand, order.Quantity)
+		case order.Side == OrderSideSell && order.LimitPrice <= price:
+			supply, err = smath.Add(supply, order.Quantity)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return demand, supply, nil
+}
+
+// allocateFills distributes matched volume on each side by price priority
+// and returns fills in the orders' canonical trader order.
+func allocateFills(orders []BatchOrder, price uint64, matched uint64) []BatchFill {
+	buys := make([]int, 0, len(orders))
+	sells := make([]int, 0, len(orders))
+	for i, order := range orders {
+		switch {
+		case order.Side == OrderSideBuy && order.LimitPrice >= price:
+			buys = append(buys, i)
+		case order.Side == OrderSideSell && order.LimitPrice <= price:
+			sells = append(sells, i)
+		}
+	}
+	// Orders are already sorted by trader, so a stable sort on price keeps
+	// the trader tie-break.
+	sort.SliceStable(buys, func(i, j int) bool {
+		return orders[buys[i]].LimitPrice > orders[buys[j]].LimitPrice
+	})
+	sort.SliceStable(sells, func(i, j int) bool {
+		return orders[sells[i]].LimitPrice < orders[sells[j]].LimitPrice
+	})
+
+	filled := make([]uint64, len(orders))
+	for _, side := range [][]int{buys, sells} {
+		remaining := matched
+		for _, idx := range side {
+			if remaining == 0 {
+				break
+			}
+			qty := minU64(orders[idx].Quantity, remaining)
+			filled[idx] = qty
+			remaining -= qty
+		}
+	}
+
+	fills := make([]BatchFill, 0, len(buys)+len(sells))
+	for i, order := range orders {
+		if filled[i] == 0 {
+			continue
+		}
+		fills = append(fills, BatchFill{
+			Trader:   order.Trader,
+			Side:     order.Side,
+			Quantity: filled[i],
+		})
+	}
+	return fills
+}
@@ -0,0 +1,97 @@
+package actions
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+func TestComputeUniformClearingMaximizesVolume(t *testing.T) {
+	marketID := ids.ID{1}
+	orders := []BatchOrder{
+		{Trader: testTrader(1), Side: OrderSideBuy, LimitPrice: 6000, Quantity: 100},
+		{Trader: testTrader(2), Side: OrderSideBuy, LimitPrice: 5000, Quantity: 50},
+		{Trader: testTrader(3), Side: OrderSideSell, LimitPrice: 4000, Quantity: 80},
+		{Trader: testTrader(4), Side: OrderSideSell, LimitPrice: 5500, Quantity: 100},
+	}
+
+	result, err := ComputeUniformClearing(marketID, 7, orders)
+	if err != nil {
+		t.Fatalf("clearing failed: %v", err)
+	}
+	// 5500 and 6000 both match 100 with the same imbalance; the lower wins.
+	if result.ClearPrice != 5500 {
+		t.Fatalf("unexpected clear price: got=%d want=5500", result.ClearPrice)
+	}
+	if result.TotalVolume != 100 {
+		t.Fatalf("unexpected volume: got=%d want=100", result.TotalVolume)
+	}
+	wantFills := []BatchFill{
+		{Trader: testTrader(1), Side: OrderSideBuy, Quantity: 100},
+		{Trader: testTrader(3), Side: OrderSideSell, Quantity: 80},
+		{Trader: testTrader(4), Side: OrderSideSell, Quantity: 20},
+	}
+	if !reflect.DeepEqual(result.Fills, wantFills) {
+		t.Fatalf("unexpected fills: got=%+v want=%+v", result.Fills, wantFills)
+	}
+	if result.FillsHash != ComputeBatchFillsHash(marketID, 7, 5500, wantFills) {
+		t.Fatalf("fills hash does not match canonical fills")
+	}
+}
+
+func TestComputeUniformClearingTieBreaksToLowestPrice(t *testing.T) {
+	orders := []BatchOrder{
+		{Trader: testTrader(1), Side: OrderSideBuy, LimitPrice: 7000, Quantity: 10},
+		{Trader: testTrader(2), Side: OrderSideSell, LimitPrice: 3000, Quantity: 10},
+	}
+	result, err := ComputeUniformClearing(ids.Empty, 1, orders)
+	if err != nil {
+		t.Fatalf("clearing failed: %v", err)
+	}
+	if result.ClearPrice != 3000 || result.TotalVolume != 10 {
+		t.Fatalf("unexpected clearing: price=%d volume=%d", result.ClearPrice, result.TotalVolume)
+	}
+}
+
+func TestComputeUniformClearingNoCross(t *testing.T) {
+	orders := []BatchOrder{
+		{Trader: testTrader(1), Side: OrderSideBuy, LimitPrice: 3000, Quantity: 10},
+		{Trader: testTrader(2), Side: OrderSideSell, LimitPrice: 7000, Quantity: 10},
+	}
+	result, err := ComputeUniformClearing(ids.Empty, 1, orders)
+	if err != nil {
+		t.Fatalf("clearing failed: %v", err)
+	}
+	if result.ClearPrice != 0 || result.TotalVolume != 0 || len(result.Fills) != 0 {
+		t.Fatalf("expected empty clearing, got %+v", result)
+	}
+	empty, err := ComputeUniformClearing(ids.Empty, 1, nil)
+	if err != nil {
+		t.Fatalf("empty clearing failed: %v", err)
+	}
+	if result.FillsHash != empty.FillsHash {
+		t.Fatalf("no-cross and empty windows should share a fills hash")
+	}
+}
+
+func TestComputeUniformClearingRejectsNonCanonicalOrders(t *testing.T) {
+	orders := []BatchOrder{
+		{Trader: testTrader(2), Side: OrderSideBuy, LimitPrice: 5000, Quantity: 10},
+		{Trader: testTrader(1), Side: OrderSideSell, LimitPrice: 5000, Quantity: 10},
+	}
+	if _, err := ComputeUniformClearing(ids.Empty, 1, orders); !errors.Is(err, ErrOrdersNotCanonical) {
+		t.Fatalf("expected ErrOrdersNotCanonical, got %v", err)
+	}
+
+	orders[0].Trader = testTrader(1)
+	if _, err := ComputeUniformClearing(ids.Empty, 1, orders); !errors.Is(err, ErrOrdersNotCanonical) {
+		t.Fatalf("expected duplicate trader rejection, got %v", err)
+	}
+
+	orders = []BatchOrder{{Trader: testTrader(1), Side: OrderSideBuy, LimitPrice: OutcomePriceScale, Quantity: 10}}
+	if _, err := ComputeUniformClearing(ids.Empty, 1, orders); !errors.Is(err, ErrOrderLimitOutOfRange) {
+		t.Fatalf("expected ErrOrderLimitOutOfRange, got %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package actions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+
+	smath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+const (
+	FillsDomainTag = "VEIL_FILLS_V1"
+
+	// OutcomePriceScale is the payout of one winning outcome share, in base
+	// units of the market's collateral asset. Batch prices are quoted on the
+	// same scale: a fill at price p costs p per YES share and
+	// OutcomePriceScale-p per NO share, so every matched pair adds exactly
+	// one payout to the market pool.
+	OutcomePriceScale uint64 = 10_000
+
+	// Batch auctions trade binary markets. A buy takes YES shares at the
+	// clearing price; a sell is the mirror order and takes NO shares.
+	OrderSideBuy  uint8 = 0
+	OrderSideSell uint8 = 1
+
+	BinaryOutcomeYes uint8 = 0
+	BinaryOutcomeNo  uint8 = 1
+
+	MaxBatchFills = 256
+)
+
+var (
+	ErrInvalidOrderSide         = errors.New("invalid order side")
+	ErrClearPriceOutOfRange     = errors.New("clear price is outside the outcome price scale")
+	ErrFillsRequireBinaryMarket = errors.New("batch fills require a binary market")
+)
+
+// BatchFill is one trader's executed quantity in a cleared window. All fills
+// of a window execute at the window's uniform clearing price.
+type BatchFill struct {
+	Trader   codec.Address `serialize:"true" json:"trader"`
+	Side     uint8         `serialize:"true" json:"side"`
+	Quantity uint64        `serialize:"true" json:"quantity"`
+}
+
+func fillOutcome(side uint8) (uint8, error) {
+	switch side {
+	case OrderSideBuy:
+		return BinaryOutcomeYes, nil
+	case OrderSideSell:
+		return BinaryOutcomeNo, nil
+	default:
+		return 0, ErrInvalidOrderSide
+	}
+}
+
+// fillCost returns the collateral a fill draws from the trader's escrow.
+func fillCost(side uint8, clearPrice uint64, quantity uint64) (uint64, error) {
+	if clearPrice == 0 || clearPrice >= OutcomePriceScale {
+		return 0, ErrClearPriceOutOfRange
+	}
+	switch side {
+	case OrderSideBuy:
+		return smath.Mul(clearPrice, quantity)
+	case OrderSideSell:
+		return smath.Mul(OutcomePriceScale-clearPrice, quantity)
+	default:
+		return 0, ErrInvalidOrderSide
+	}
+}
+
+// ComputeBatchFillsHash canonicalizes a window's fills into the digest stored
+// as the batch fills hash.
+func ComputeBatchFillsHash(
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	fills []BatchFill,
+) [32]byte {
+	preimage := make([]byte, 0, len(FillsDomainTag)+ids.IDLen+8+8+4+len(fills)*(codec.AddressLen+1+8))
+	preimage = append(preimage, FillsDomainTag...)
+	preimage = append(preimage, marketID[:]...)
+	preimage = binary.BigEndian.AppendUint64(preimage, windowID)
+	preimage = binary.BigEndian.AppendUint64(preimage, clearPrice)
+	preimage = binary.BigEndian.AppendUint32(preimage, uint32(len(fills)))
+	for _, fill := range fills {
+		preimage = append(preimage, fill.Trader[:]...)
+		preimage = append(preimage, fill.Side)
+		preimage = binary.BigEndian.AppendUint64(preimage, fill.Quantity)
+	}
+	return sha256.Sum256(preimage)
+}
+
+// batchTradersStateKeys declares every key decryption and settlement may
+// touch for the window's traders. Order sides are sealed until the window
+// key opens them, so both outcome positions are declared.
+func batchTradersStateKeys(keys state.Keys, marketID ids.ID, windowID uint64, traders []codec.Address) state.Keys {
+	if len(traders) == 0 {
+		return keys
+	}
+	keys[string(storage.WindowDecryptionKey(marketID, windowID))] = state.Read
+	keys[string(storage.MarketPoolKey(marketID))] = state.Read | state.Write
+	for _, trader := range traders {
+		keys[string(storage.CommitmentKey(marketID, windowID, trader))] = state.Read
+		keys[string(storage.OrderFaultKey(marketID, windowID, trader))] = state.All
+		keys[string(storage.EscrowKey(marketID, windowID, trader))] = state.Read | state.Write
+		keys[string(storage.PositionKey(marketID, BinaryOutcomeYes, trader))] = state.All
+		keys[string(storage.PositionKey(marketID, BinaryOutcomeNo, trader))] = state.All
+		keys[string(storage.SettlementKey(marketID, windowID, trader))] = state.All
+		keys[string(storage.BalanceKey(trader))] = state.Read | state.Write
+		keys[string(storage.VAIBalanceKey(trader))] = state.Read | state.Write
+	}
+	return keys
+}
+
+// settleBatch applies a cleared window to every listed trader. A fill's
+// cost moves from the trader's escrow into the market pool and credits the
+// matching outcome position; whatever escrow is left, including all of it
+// for unfilled or faulted orders, is refunded to the trader's balance. Each
+// trader gets a settlement record so fills reconcile on-chain.
+func settleBatch(
+	ctx context.Context,
+	mu state.Mutable,
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	traders []codec.Address,
+	fills []BatchFill,
+	timestamp int64,
+) error {
+	if len(traders) == 0 {
+		return nil
+	}
+	pool, err := storage.GetMarketPool(ctx, mu, marketID)
+	if err != nil {
+		return err
+	}
+	filled := make(map[codec.Address]BatchFill, len(fills))
+	for _, fill := range fills {
+		filled[fill.Trader] = fill
+	}
+	for _, trader := range traders {
+		escrow, err := storage.GetEscrow(ctx, mu, marketID, windowID, trader)
+		hasEscrow := err == nil
+		if err != nil && !errors.Is(err, storage.ErrEscrowNotFound) {
+			return err
+		}
+		settlement := storage.Settlement{
+			Asset:       pool.Asset,
+			SettledAtMs: timestamp,
+		}
+		if fill, ok := filled[trader]; ok {
+			outcome, err := fillOutcome(fill.Side)
+			if err != nil {
+				return err
+			}
+			cost, err := fillCost(fill.Side, clearPrice, fill.Quantity)
+			if err != nil {
+				return err
+			}
+			if !hasEscrow || escrow.Amount < cost {
+				return storage.ErrInsufficientEscrow
+			}
+			if escrow.Asset != pool.Asset {
+				return storage.ErrCollateralAssetMismatch
+			}
+			escrow.Amount -= cost
+			if _, err := storage.AddPosition(ctx, mu, marketID, outcome, trader, fill.Quantity); err != nil {
+				return err
+			}
+			pool.Collateral, err = smath.Add(pool.Collateral, cost)
+			if err != nil {
+				return err
+			}
+			settlement.Side = fill.Side
+			settlement.Quantity = fill.Quantity
+			settlement.Cost = cost
+		}
+		if hasEscrow {
+			if err := storage.DeleteEscrow(ctx, mu, marketID, windowID, trader); err != nil {
+				return err
+			}
+			if escrow.Amount > 0 {
+				if _, err := addAssetBalance(ctx, mu, trader, escrow.Asset, escrow.Amount); err != nil {
+					return err
+				}
+			}
+			settlement.Asset = escrow.Asset
+			settlement.Refunded = escrow.Amount
+		}
+		if err := storage.PutSettlement(ctx, mu, marketID, windowID, trader, settlement); err != nil {
+			return err
+		}
+	}
+	return storage.PutMarketPool(ctx, mu, marketID, pool)
+}
@@ -0,0 +1,92 @@
+package actions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func TestSettleBatchRefundsUnfilledEscrow(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	const (
+		windowID = uint64(4)
+		now      = int64(40_000)
+	)
+	orders := []BatchOrder{
+		{Trader: testTrader(1), Side: OrderSideBuy, LimitPrice: 6000, Quantity: 100},
+		{Trader: testTrader(2), Side: OrderSideBuy, LimitPrice: 5000, Quantity: 50},
+		{Trader: testTrader(3), Side: OrderSideSell, LimitPrice: 4000, Quantity: 80},
+		{Trader: testTrader(4), Side: OrderSideSell, LimitPrice: 5500, Quantity: 100},
+	}
+	traders := make([]codec.Address, len(orders))
+	for i, order := range orders {
+		traders[i] = order.Trader
+		escrow, err := maxOrderCost(order)
+		if err != nil {
+			t.Fatalf("max order cost: %v", err)
+		}
+		if err := storage.PutEscrow(ctx, mu, marketID, windowID, order.Trader, storage.Escrow{Asset: AssetVEIL, Amount: escrow}); err != nil {
+			t.Fatalf("put escrow: %v", err)
+		}
+	}
+	clearing, err := ComputeUniformClearing(marketID, windowID, orders)
+	if err != nil {
+		t.Fatalf("clearing: %v", err)
+	}
+	if err := settleBatch(ctx, mu, marketID, windowID, clearing.ClearPrice, traders, clearing.Fills, now); err != nil {
+		t.Fatalf("settle batch: %v", err)
+	}
+
+	// The batch clears 100 at 5500. Trader 4's sell only fills 20 of 100.
+	tests := []struct {
+		trader   codec.Address
+		outcome  uint8
+		quantity uint64
+		cost     uint64
+		refunded uint64
+	}{
+		{trader: testTrader(1), outcome: BinaryOutcomeYes, quantity: 100, cost: 550_000, refunded: 50_000},
+		{trader: testTrader(2), refunded: 250_000},
+		{trader: testTrader(3), outcome: BinaryOutcomeNo, quantity: 80, cost: 360_000, refunded: 120_000},
+		{trader: testTrader(4), outcome: BinaryOutcomeNo, quantity: 20, cost: 90_000, refunded: 360_000},
+	}
+	for _, tt := range tests {
+		settlement, err := storage.GetSettlement(ctx, mu, marketID, windowID, tt.trader)
+		if err != nil {
+			t.Fatalf("get settlement: %v", err)
+		}
+		if settlement.Quantity != tt.quantity || settlement.Cost != tt.cost || settlement.Refunded != tt.refunded || settlement.SettledAtMs != now {
+			t.Fatalf("trader %x: unexpected settlement: %+v", tt.trader[0], settlement)
+		}
+		balance, err := storage.GetBalance(ctx, mu, tt.trader)
+		if err != nil {
+			t.Fatalf("get balance: %v", err)
+		}
+		if balance != tt.refunded {
+			t.Fatalf("trader %x: unexpected refund: got=%d want=%d", tt.trader[0], balance, tt.refunded)
+		}
+		if tt.quantity == 0 {
+			continue
+		}
+		position, err := storage.GetPosition(ctx, mu, marketID, tt.outcome, tt.trader)
+		if err != nil {
+			t.Fatalf("get position: %v", err)
+		}
+		if position != tt.quantity {
+			t.Fatalf("trader %x: unexpected position: got=%d want=%d", tt.trader[0], position, tt.quantity)
+		}
+	}
+
+	// Each matched YES and NO share adds one payout to the pool.
+	pool, err := storage.GetMarketPool(ctx, mu, marketID)
+	if err != nil {
+		t.Fatalf("get market pool: %v", err)
+	}
+	if pool.Collateral != OutcomePriceScale*clearing.TotalVolume {
+		t.Fatalf("unexpected pool collateral: got=%d want=%d", pool.Collateral, OutcomePriceScale*clearing.TotalVolume)
+	}
+}
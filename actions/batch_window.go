@@ -0,0 +1,129 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/state"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+
+	smath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+var ErrWindowOutOfRange = errors.New("window id is out of range")
+
+// WindowIDAt returns the batch window whose commit phase contains
+// timestamp. Window w accepts commits during [w*windowMs, (w+1)*windowMs).
+func WindowIDAt(timestamp int64, windowMs int64) uint64 {
+	if timestamp <= 0 || windowMs <= 0 {
+		return 0
+	}
+	return uint64(timestamp / windowMs)
+}
+
+// newWindowState derives a window's timeline from the current ProofConfig.
+// It is written to state on first use and never re-derived afterwards.
+func newWindowState(windowID uint64, cfg storage.ProofConfig, timestamp int64) (storage.WindowState, error) {
+	openAt, err := smath.Mul(windowID, uint64(cfg.BatchWindowMs))
+	if err != nil {
+		return storage.WindowState{}, ErrWindowOutOfRange
+	}
+	closeAt, err := smath.Add(openAt, uint64(cfg.BatchWindowMs))
+	if err != nil {
+		return storage.WindowState{}, ErrWindowOutOfRange
+	}
+	deadlineAt, err := smath.Add(closeAt, uint64(cfg.ProofDeadlineMs))
+	if err != nil || deadlineAt > math.MaxInt64 {
+		return storage.WindowState{}, ErrWindowOutOfRange
+	}
+	return storage.WindowState{
+		Phase:        storage.WindowPhaseOpen,
+		OpenAtMs:     int64(openAt),
+		CloseAtMs:    int64(closeAt),
+		DeadlineAtMs: int64(deadlineAt),
+		UpdatedAtMs:  timestamp,
+	}, nil
+}
+
+// loadWindowState returns the stored window state, or a freshly derived one
+// when the window has not been used yet. The bool reports whether it was
+// already stored.
+func loadWindowState(
+	ctx context.Context,
+	im state.Immutable,
+	marketID ids.ID,
+	windowID uint64,
+	cfg storage.ProofConfig,
+	timestamp int64,
+) (storage.WindowState, bool, error) {
+	ws, err := storage.GetWindowState(ctx, im, marketID, windowID)
+	if err == nil {
+		return ws, true, nil
+	}
+	if !errors.Is(err, storage.ErrWindowStateNotFound) {
+		return storage.WindowState{}, false, err
+	}
+	ws, err = newWindowState(windowID, cfg, timestamp)
+	return ws, false, err
+}
+
+// bindCommitteeEpoch pins a window to the decrypt committee epoch it is
+// first used under, and fails with ErrDecryptCommitteeRotated once that
+// committee has been replaced: its envelopes are sealed to the old key.
+func bindCommitteeEpoch(ws *storage.WindowState, stored bool, epoch uint64) error {
+	if !stored {
+		ws.CommitteeEpoch = epoch
+		return nil
+	}
+	if ws.CommitteeEpoch != epoch {
+		return storage.ErrDecryptCommitteeRotated
+	}
+	return nil
+}
+
+// windowPhaseAt applies the time-driven transitions to a stored phase. An
+// open window freezes at CloseAtMs, and any window not yet proven when
+// DeadlineAtMs passes is abandoned. A proven window is abandoned too if it
+// is not cleared by its clear deadline, so a proof nobody clears cannot hold
+// escrow forever. Revealed, proven and cleared are only reached through
+// actions and are stored as they happen.
+func windowPhaseAt(ws storage.WindowState, timestamp int64) uint8 {
+	switch {
+	case ws.Phase == storage.WindowPhaseProven && clearDeadlinePassed(ws, timestamp):
+		return storage.WindowPhaseAbandoned
+	case ws.Phase >= storage.WindowPhaseProven:
+		return ws.Phase
+	case timestamp > ws.DeadlineAtMs:
+		return storage.WindowPhaseAbandoned
+	case ws.Phase == storage.WindowPhaseOpen && timestamp >= ws.CloseAtMs:
+		return storage.WindowPhaseFrozen
+	default:
+		return ws.Phase
+	}
+}
+
+// clearDeadlinePassed reports whether timestamp is past the window's clear
+// deadline, one more proof period after DeadlineAtMs. It is compared as an
+// offset so the deadline never has to be stored or overflow.
+func clearDeadlinePassed(ws storage.WindowState, timestamp int64) bool {
+	return timestamp-ws.DeadlineAtMs > ws.DeadlineAtMs-ws.CloseAtMs
+}
+
+// putWindowPhase records a phase transition at timestamp.
+func putWindowPhase(
+	ctx context.Context,
+	mu state.Mutable,
+	marketID ids.ID,
+	windowID uint64,
+	ws storage.WindowState,
+	phase uint8,
+	timestamp int64,
+) error {
+	ws.Phase = phase
+	ws.UpdatedAtMs = timestamp
+	return storage.PutWindowState(ctx, mu, marketID, windowID, ws)
+}
@@ -0,0 +1,66 @@
+package actions
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func TestWindowTimelineFromProofConfig(t *testing.T) {
+	cfg := storage.ProofConfig{BatchWindowMs: 5_000, ProofDeadlineMs: 10_000}
+	const now = int64(1_700_000_002_500)
+
+	windowID := WindowIDAt(now, cfg.BatchWindowMs)
+	if windowID != 340_000_000 {
+		t.Fatalf("unexpected window id: %d", windowID)
+	}
+	ws, err := newWindowState(windowID, cfg, now)
+	if err != nil {
+		t.Fatalf("new window state failed: %v", err)
+	}
+	if ws.OpenAtMs != 1_700_000_000_000 || ws.CloseAtMs != 1_700_000_005_000 || ws.DeadlineAtMs != 1_700_000_015_000 {
+		t.Fatalf("unexpected timeline: %+v", ws)
+	}
+
+	if _, err := newWindowState(math.MaxUint64, cfg, now); !errors.Is(err, ErrWindowOutOfRange) {
+		t.Fatalf("expected ErrWindowOutOfRange, got %v", err)
+	}
+}
+
+func TestWindowPhaseAt(t *testing.T) {
+	ws := storage.WindowState{
+		Phase:        storage.WindowPhaseOpen,
+		OpenAtMs:     0,
+		CloseAtMs:    100,
+		DeadlineAtMs: 300,
+	}
+	tests := []struct {
+		stored    uint8
+		timestamp int64
+		want      uint8
+	}{
+		{storage.WindowPhaseOpen, 99, storage.WindowPhaseOpen},
+		{storage.WindowPhaseOpen, 100, storage.WindowPhaseFrozen},
+		{storage.WindowPhaseFrozen, 300, storage.WindowPhaseFrozen},
+		{storage.WindowPhaseFrozen, 301, storage.WindowPhaseAbandoned},
+		{storage.WindowPhaseRevealed, 301, storage.WindowPhaseAbandoned},
+		{storage.WindowPhaseProven, 500, storage.WindowPhaseProven},
+		{storage.WindowPhaseProven, 501, storage.WindowPhaseAbandoned},
+		{storage.WindowPhaseCleared, 10_000, storage.WindowPhaseCleared},
+	}
+	for _, tt := range tests {
+		ws.Phase = tt.stored
+		if got := windowPhaseAt(ws, tt.timestamp); got != tt.want {
+			t.Fatalf("phase %d at %d: got=%d want=%d", tt.stored, tt.timestamp, got, tt.want)
+		}
+	}
+
+	if err := checkWindowClearable(storage.WindowPhaseRevealed, true); !errors.Is(err, storage.ErrWindowNotClearable) {
+		t.Fatalf("expected proof-gated clear to require a proven window, got %v", err)
+	}
+	if err := checkWindowClearable(storage.WindowPhaseCleared, false); !errors.Is(err, storage.ErrBatchAlreadyCleared) {
+		t.Fatalf("expected ErrBatchAlreadyCleared, got %v", err)
+	}
+}
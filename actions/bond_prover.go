@@ -0,0 +1,147 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+
+	smath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+const (
+	BondProverComputeUnits = 1
+	MaxBondProverSize      = 256
+)
+
+var (
+	ErrUnmarshalEmptyBondProver              = errors.New("cannot unmarshal empty bytes as bond_prover")
+	_                           chain.Action = (*BondProver)(nil)
+)
+
+// BondProver moves VEIL from the actor's balance into its slashable prover
+// bond.
+type BondProver struct {
+	Amount uint64 `serialize:"true" json:"amount"`
+}
+
+func (*BondProver) GetTypeID() uint8 {
+	return mconsts.BondProverID
+}
+
+func (*BondProver) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
+	return proverBondStateKeys(state.Keys{
+		string(storage.BalanceKey(actor)): state.Read | state.Write,
+	}, actor)
+}
+
+func (t *BondProver) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxBondProverSize),
+		MaxSize: MaxBondProverSize,
+	}
+	p.PackByte(mconsts.BondProverID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalBondProver(bytes []byte) (chain.Action, error) {
+	t := &BondProver{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyBondProver
+	}
+	if bytes[0] != mconsts.BondProverID {
+		return nil, fmt.Errorf("unexpected bond_prover typeID: %d != %d", bytes[0], mconsts.BondProverID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *BondProver) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	_ int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	if t.Amount == 0 {
+		return nil, storage.ErrInvalidBondAmount
+	}
+	bond, err := storage.GetProverBond(ctx, mu, actor)
+	if err != nil {
+		return nil, err
+	}
+	bond.Bonded, err = smath.Add(bond.Bonded, t.Amount)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := storage.SubBalance(ctx, mu, actor, t.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if err := putProverBond(ctx, mu, actor, bond); err != nil {
+		return nil, err
+	}
+
+	result := &BondProverResult{
+		Bonded:        bond.Bonded,
+		SenderBalance: balance,
+	}
+	return result.Bytes(), nil
+}
+
+func (*BondProver) ComputeUnits(chain.Rules) uint64 {
+	return BondProverComputeUnits
+}
+
+func (*BondProver) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*BondProverResult)(nil)
+
+type BondProverResult struct {
+	Bonded        uint64 `serialize:"true" json:"bonded"`
+	SenderBalance uint64 `serialize:"true" json:"sender_balance"`
+}
+
+func (*BondProverResult) GetTypeID() uint8 {
+	return mconsts.BondProverID
+}
+
+func (t *BondProverResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxBondProverSize,
+	}
+	p.PackByte(mconsts.BondProverID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalBondProverResult(b []byte) (codec.Typed, error) {
+	t := &BondProverResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
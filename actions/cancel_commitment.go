@@ -0,0 +1,185 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/state"
+
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+const (
+	CancelCommitmentComputeUnits = 2
+	MaxCancelCommitmentSize      = 256
+)
+
+var (
+	ErrUnmarshalEmptyCancelCommitment              = errors.New("cannot unmarshal empty bytes as cancel_commitment")
+	_                                 chain.Action = (*CancelCommitment)(nil)
+)
+
+// CancelCommitment withdraws the actor's commitment while its window is
+// still open and refunds the collateral it locked.
+type CancelCommitment struct {
+	MarketID ids.ID `serialize:"true" json:"market_id"`
+	WindowID uint64 `serialize:"true" json:"window_id"`
+}
+
+func (*CancelCommitment) GetTypeID() uint8 {
+	return mconsts.CancelCommitmentID
+}
+
+func (t *CancelCommitment) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
+	return state.Keys{
+		string(storage.ProofConfigKey()):                             state.Read,
+		string(storage.WindowStateKey(t.MarketID, t.WindowID)):       state.Read | state.Write,
+		string(storage.CommitmentKey(t.MarketID, t.WindowID, actor)): state.Read | state.Write,
+		string(storage.EscrowKey(t.MarketID, t.WindowID, actor)):     state.Read | state.Write,
+		string(storage.BalanceKey(actor)):                            state.Read | state.Write,
+		string(storage.VAIBalanceKey(actor)):                         state.Read | state.Write,
+	}
+}
+
+func (t *CancelCommitment) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxCancelCommitmentSize),
+		MaxSize: MaxCancelCommitmentSize,
+	}
+	p.PackByte(mconsts.CancelCommitmentID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalCancelCommitment(bytes []byte) (chain.Action, error) {
+	t := &CancelCommitment{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyCancelCommitment
+	}
+	if bytes[0] != mconsts.CancelCommitmentID {
+		return nil, fmt.Errorf("unexpected cancel_commitment typeID: %d != %d", bytes[0], mconsts.CancelCommitmentID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *CancelCommitment) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	actor codec.Address,
+	_ ids.ID,
+) (_ []byte, err error) {
+	start := time.Now()
+	defer func() {
+		RecordCancelMetric(t.MarketID, t.WindowID, time.Since(start), err)
+	}()
+
+	// Once the window freezes its commitment set is final.
+	proofCfg, err := storage.GetProofConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	window, _, err := loadWindowState(ctx, mu, t.MarketID, t.WindowID, proofCfg, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if windowPhaseAt(window, timestamp) != storage.WindowPhaseOpen {
+		return nil, storage.ErrWindowNotOpen
+	}
+
+	exists, err := storage.HasCommitment(ctx, mu, t.MarketID, t.WindowID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, storage.ErrCommitmentNotFound
+	}
+	if err := storage.DeleteCommitment(ctx, mu, t.MarketID, t.WindowID, actor); err != nil {
+		return nil, err
+	}
+	if window.Commitments > 0 {
+		window.Commitments--
+	}
+	if err := storage.PutWindowState(ctx, mu, t.MarketID, t.WindowID, window); err != nil {
+		return nil, err
+	}
+
+	result := &CancelCommitmentResult{WindowID: t.WindowID}
+	escrow, err := storage.GetEscrow(ctx, mu, t.MarketID, t.WindowID, actor)
+	if errors.Is(err, storage.ErrEscrowNotFound) {
+		return result.Bytes(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.DeleteEscrow(ctx, mu, t.MarketID, t.WindowID, actor); err != nil {
+		return nil, err
+	}
+	balance, err := addAssetBalance(ctx, mu, actor, escrow.Asset, escrow.Amount)
+	if err != nil {
+		return nil, err
+	}
+	result.Asset = escrow.Asset
+	result.Released = escrow.Amount
+	result.SenderBalance = balance
+	return result.Bytes(), nil
+}
+
+func (*CancelCommitment) ComputeUnits(chain.Rules) uint64 {
+	return CancelCommitmentComputeUnits
+}
+
+func (*CancelCommitment) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*CancelCommitmentResult)(nil)
+
+type CancelCommitmentResult struct {
+	WindowID      uint64 `serialize:"true" json:"window_id"`
+	Asset         uint8  `serialize:"true" json:"asset"`
+	Released      uint64 `serialize:"true" json:"released"`
+	SenderBalance uint64 `serialize:"true" json:"sender_balance"`
+}
+
+func (*CancelCommitmentResult) GetTypeID() uint8 {
+	return mconsts.CancelCommitmentID
+}
+
+func (t *CancelCommitmentResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxCancelCommitmentSize,
+	}
+	p.PackByte(mconsts.CancelCommitmentID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalCancelCommitmentResult(b []byte) (codec.Typed, error) {
+	t := &CancelCommitmentResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
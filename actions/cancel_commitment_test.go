@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func TestCancelCommitmentRefundsEscrow(t *testing.T) {
+	const windowID = uint64(4)
+	tests := []struct {
+		name        string
+		commit      bool
+		now         int64
+		err         error
+		wantBalance uint64
+	}{
+		{name: "open window", commit: true, now: 24_999, wantBalance: 1_000},
+		{name: "frozen window", commit: true, now: 25_000, err: storage.ErrWindowNotOpen, wantBalance: 400},
+		{name: "no commitment", now: 24_999, err: storage.ErrCommitmentNotFound, wantBalance: 1_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mu := mapState{}
+			marketID := putTestMarket(t, mu, AssetVEIL)
+			trader := testTrader(1)
+			if err := storage.SetBalance(ctx, mu, trader, 1_000); err != nil {
+				t.Fatalf("set balance: %v", err)
+			}
+			if tt.commit {
+				if _, err := testCommitOrder(marketID, windowID, AssetVEIL, 600).Execute(ctx, nil, mu, 20_001, trader, ids.Empty); err != nil {
+					t.Fatalf("commit: %v", err)
+				}
+			}
+
+			out, err := (&CancelCommitment{MarketID: marketID, WindowID: windowID}).Execute(ctx, nil, mu, tt.now, trader, ids.Empty)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: got=%v want=%v", err, tt.err)
+			}
+			balance, err := storage.GetBalance(ctx, mu, trader)
+			if err != nil {
+				t.Fatalf("get balance: %v", err)
+			}
+			if balance != tt.wantBalance {
+				t.Fatalf("unexpected balance: got=%d want=%d", balance, tt.wantBalance)
+			}
+			if tt.err != nil {
+				return
+			}
+			resultAny, err := UnmarshalCancelCommitmentResult(out)
+			if err != nil {
+				t.Fatalf("unmarshal result: %v", err)
+			}
+			if result := resultAny.(*CancelCommitmentResult); result.Released != 600 || result.SenderBalance != 1_000 {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+			if exists, err := storage.HasCommitment(ctx, mu, marketID, windowID, trader); err != nil || exists {
+				t.Fatalf("commitment not deleted: exists=%v err=%v", exists, err)
+			}
+			if _, err := storage.GetEscrow(ctx, mu, marketID, windowID, trader); !errors.Is(err, storage.ErrEscrowNotFound) {
+				t.Fatalf("escrow not released: %v", err)
+			}
+		})
+	}
+}
+
+func TestRecordCancelMetrics(t *testing.T) {
+	c := newZKMetricsCollector(0)
+	marketID := ids.GenerateTestID()
+	c.recordCommit(marketID, 4, 20_001, time.Millisecond, nil)
+	c.recordCommit(marketID, 4, 20_002, time.Millisecond, nil)
+	c.recordCancel(marketID, 4, 2*time.Millisecond, storage.ErrWindowNotOpen)
+	c.recordCancel(marketID, 4, 3*time.Millisecond, nil)
+
+	w := c.windows[zkWindowKey(marketID, 4)]
+	if w.CommitCount != 1 || w.CancelCount != 1 || c.summary.TotalCancels != 1 {
+		t.Fatalf("unexpected counts: %+v", w)
+	}
+	if w.CommitExecUs != 2_000 || w.CancelExecUs != 5_000 {
+		t.Fatalf("unexpected exec time: commit=%d cancel=%d", w.CommitExecUs, w.CancelExecUs)
+	}
+	if w.Rejected || w.LastError == "" {
+		t.Fatalf("failed cancel should be recorded without rejecting the window: %+v", w)
+	}
+}
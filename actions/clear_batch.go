@@ -21,16 +21,17 @@ import (
 )
 
 const (
-	ClearBatchComputeUnits = 10
-	MaxFillsHashSize       = 64
-	MaxClearBatchSize      = 2048
+	ClearBatchComputeUnits       = 10
+	ClearBatchTraderComputeUnits = 3
+	MaxFillsHashSize             = 64
+	MaxClearBatchSize            = 16_384
 )
 
 var (
 	ErrFillsHashEmpty                        = errors.New("fills hash is empty")
 	ErrFillsHashTooLarge                     = errors.New("fills hash is too large")
 	ErrFillsHashWrongSize                    = errors.New("fills hash has invalid size")
-	ErrClearPriceZero                        = errors.New("clear price is zero")
+	ErrTradersIncomplete                     = errors.New("traders do not cover every commitment in the window")
 	ErrUnmarshalEmptyClearBatch              = errors.New("cannot unmarshal empty bytes as clear_batch")
 	_                           chain.Action = (*ClearBatch)(nil)
 )
@@ -41,6 +42,23 @@ type ClearBatch struct {
 	ClearPrice  uint64 `serialize:"true" json:"clear_price"`
 	TotalVolume uint64 `serialize:"true" json:"total_volume"`
 	FillsHash   []byte `serialize:"true" json:"fills_hash"`
+
+	// Traders lists every trader committed to the window, sorted; its
+	// length must equal the window's commitment count. Their envelopes
+	// are decrypted with the revealed window key, and ClearPrice, TotalVolume
+	// and FillsHash must equal ComputeUniformClearing over the orders that
+	// open cleanly, match their commitment and whose escrow covers their
+	// worst-case cost. The rest are recorded as order faults. Every listed
+	// trader is settled and refunded its unused escrow.
+	Traders []codec.Address `serialize:"true" json:"traders"`
+
+	// Nullifiers and OutputCommitments list the notes spent and created by
+	// the window's proof, in public-input order. Each nullifier is recorded
+	// as spent by this clear, and the clear fails if any was spent before.
+	// Outputs are appended to the note tree. Both are empty unless the proof
+	// circuit moves shielded notes.
+	Nullifiers        []ids.ID `serialize:"true" json:"nullifiers"`
+	OutputCommitments []ids.ID `serialize:"true" json:"output_commitments"`
 }
 
 func (*ClearBatch) GetTypeID() uint8 {
@@ -48,13 +66,22 @@ func (*ClearBatch) GetTypeID() uint8 {
 }
 
 func (t *ClearBatch) StateKeys(_ codec.Address, _ ids.ID) state.Keys {
-	return state.Keys{
+	keys := state.Keys{
 		string(storage.MarketKey(t.MarketID)):                  state.Read,
 		string(storage.BatchKey(t.MarketID, t.WindowID)):       state.All,
+		string(storage.ClearedWindowsKey(t.MarketID)):          state.All,
 		string(storage.ProofConfigKey()):                       state.Read,
+		string(storage.ProofUpgradeKey()):                      state.Read,
 		string(storage.BatchProofKey(t.MarketID, t.WindowID)):  state.Read,
-		string(storage.VellumProofKey(t.MarketID, t.WindowID)): state.Read,
+		string(storage.WindowStateKey(t.MarketID, t.WindowID)): state.All,
 	}
+	if len(t.Nullifiers) > 0 || len(t.OutputCommitments) > 0 {
+		// The stored proof is only read back for its shielded public inputs.
+		keys[string(storage.VellumProofKey(t.MarketID, t.WindowID))] = state.Read
+	}
+	keys = proverAssignmentStateKeys(keys, t.MarketID, t.WindowID)
+	keys = shieldedTransferStateKeys(keys, t.Nullifiers, t.OutputCommitments)
+	return batchTradersStateKeys(keys, t.MarketID, t.WindowID, t.Traders)
 }
 
 func (t *ClearBatch) Bytes() []byte {
@@ -92,7 +119,7 @@ func (t *ClearBatch) Execute(
 	mu state.Mutable,
 	timestamp int64,
 	actor codec.Address,
-	_ ids.ID,
+	txID ids.ID,
 ) (_ []byte, err error) {
 	start := time.Now()
 	var verificationDuration time.Duration
@@ -110,9 +137,6 @@ func (t *ClearBatch) Execute(
 		)
 	}()
 
-	if t.ClearPrice == 0 {
-		return nil, ErrClearPriceZero
-	}
 	if len(t.FillsHash) == 0 {
 		return nil, ErrFillsHashEmpty
 	}
@@ -122,24 +146,62 @@ func (t *ClearBatch) Execute(
 	if len(t.FillsHash) != ExpectedFillsHashSize {
 		return nil, ErrFillsHashWrongSize
 	}
+	if len(t.Nullifiers) > MaxBatchNullifiers {
+		return nil, ErrTooManyNullifiers
+	}
+	if len(t.OutputCommitments) > MaxBatchOutputCommitments {
+		return nil, ErrTooManyOutputCommitments
+	}
 
 	// Verify market exists and is active
-	status, _, _, _, _, err := storage.GetMarket(ctx, mu, t.MarketID)
+	status, outcomes, _, _, _, err := storage.GetMarket(ctx, mu, t.MarketID)
 	if err != nil {
 		return nil, err
 	}
 	if status != storage.MarketStatusActive {
 		return nil, storage.ErrMarketNotActive
 	}
-
 	proofCfg, err := storage.GetProofConfig(ctx, mu)
 	if err != nil {
 		return nil, err
 	}
+	window, _, err := loadWindowState(ctx, mu, t.MarketID, t.WindowID, proofCfg, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWindowClearable(windowPhaseAt(window, timestamp), proofCfg.RequireProof); err != nil {
+		return nil, err
+	}
+
+	if len(t.Traders) > 0 && outcomes != 2 {
+		return nil, ErrFillsRequireBinaryMarket
+	}
+	if err := validateBatchTraders(t.Traders); err != nil {
+		return nil, err
+	}
+	if len(t.Traders) != int(window.Commitments) {
+		return nil, ErrTradersIncomplete
+	}
+	eligible, faults, err := revealedBatchOrders(ctx, mu, t.MarketID, t.WindowID, t.Traders)
+	if err != nil {
+		return nil, err
+	}
+	clearing, err := ComputeUniformClearing(t.MarketID, t.WindowID, eligible)
+	if err != nil {
+		return nil, err
+	}
+	if clearing.ClearPrice != t.ClearPrice ||
+		clearing.TotalVolume != t.TotalVolume ||
+		!bytes.Equal(clearing.FillsHash[:], t.FillsHash) {
+		return nil, ErrClearingMismatch
+	}
+
+	var transfer *shieldedTransfer
 	if proofCfg.RequireProof {
-		// In proof-gated mode, only the configured authority may finalize clears.
-		if actor != proofCfg.ProverAuthority {
-			return nil, storage.ErrUnauthorized
+		// In proof-gated mode, only the window's assigned provers may
+		// finalize clears.
+		if err := authorizeProver(ctx, mu, proofCfg, t.MarketID, t.WindowID, window.CloseAtMs, actor, timestamp); err != nil {
+			return nil, err
 		}
 
 		verifyStart := time.Now()
@@ -153,9 +215,6 @@ func (t *ClearBatch) Execute(
 		if err != nil {
 			return nil, err
 		}
-		if record.ProofType != proofCfg.RequiredProofType {
-			return nil, storage.ErrProofTypeMismatch
-		}
 		if record.SubmittedAtMs > record.WindowCloseAtMs+proofCfg.ProofDeadlineMs {
 			missedDeadline = true
 			return nil, storage.ErrProofDeadlineMissed
@@ -163,25 +222,31 @@ func (t *ClearBatch) Execute(
 		if !bytes.Equal(record.FillsHash, t.FillsHash) {
 			return nil, storage.ErrProofFillsMismatch
 		}
-		proofBytes, err := storage.GetVellumProof(ctx, mu, t.MarketID, t.WindowID)
+		// A batch proof was checked against PublicInputsHash at submission
+		// and an aggregate attests to it; either only has to be bound to
+		// this clear's inputs. Legacy records do not say which rule accepted
+		// them, so their windows cannot be cleared and are abandoned at the
+		// clear deadline.
+		if record.Legacy {
+			return nil, storage.ErrProofNotVerified
+		}
+		// The clear is judged by the rules in effect now, not when the proof
+		// was submitted.
+		rules, err := loadProofRules(ctx, mu, proofCfg, timestamp)
 		if err != nil {
 			return nil, err
 		}
-		commitment := sha256.Sum256(proofBytes)
-		if !bytes.Equal(commitment[:], record.ProofCommitment[:]) {
-			return nil, storage.ErrProofCommitmentMismatch
-		}
-		_, circuitID, _, _, _, err := parseProofEnvelope(proofBytes)
-		if err != nil {
+		if err := checkProofRecordRule(rules, record); err != nil {
 			return nil, err
 		}
 		expectedInputsHash, err := computeExpectedPublicInputsHash(
-			circuitID,
+			record.CircuitID,
 			t.MarketID,
 			t.WindowID,
 			t.ClearPrice,
 			t.TotalVolume,
 			t.FillsHash,
+			eligible,
 		)
 		if err != nil {
 			return nil, err
@@ -189,14 +254,66 @@ func (t *ClearBatch) Execute(
 		if !bytes.Equal(record.PublicInputsHash, expectedInputsHash[:]) {
 			return nil, storage.ErrProofPublicInputsMismatch
 		}
-		if err := verifyBatchProofInConsensus(record.ProofType, proofBytes, expectedInputsHash[:]); err != nil {
-			return nil, err
+		if record.CircuitID == mconsts.ProofCircuitShieldedLedgerV2 {
+			// A v2 proof always reveals nullifiers, so a clear that lists
+			// none cannot match it and the proof is not worth reading.
+			if len(t.Nullifiers) == 0 && len(t.OutputCommitments) == 0 {
+				return nil, ErrNullifierMismatch
+			}
+			proofBytes, err := storage.GetVellumProof(ctx, mu, t.MarketID, t.WindowID)
+			if err != nil {
+				return nil, err
+			}
+			commitment := sha256.Sum256(proofBytes)
+			if !bytes.Equal(commitment[:], record.ProofCommitment[:]) {
+				return nil, storage.ErrProofCommitmentMismatch
+			}
+			_, _, _, witness, _, err := parseProofEnvelope(proofBytes)
+			if err != nil {
+				return nil, err
+			}
+			transfer, err = proofShieldedTransfer(record.CircuitID, witness)
+			if err != nil {
+				return nil, err
+			}
+			// Notes only change hands inside a clear; value may leave the
+			// pool only through Unshield, which names who receives it.
+			if transfer.Fee != 0 {
+				return nil, ErrShieldedFeeOnClear
+			}
 		}
 		verificationDuration = time.Since(verifyStart)
 	}
 
+	if err := applyShieldedTransfer(ctx, mu, t.Nullifiers, t.OutputCommitments, transfer, txID, timestamp); err != nil {
+		return nil, err
+	}
+	for _, fault := range faults {
+		if err := storage.PutOrderFault(ctx, mu, t.MarketID, t.WindowID, fault.Trader, storage.OrderFault{
+			Reason:       fault.Reason,
+			RecordedAtMs: timestamp,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if err := settleBatch(ctx, mu, t.MarketID, t.WindowID, clearing.ClearPrice, t.Traders, clearing.Fills, timestamp); err != nil {
+		return nil, err
+	}
+
 	// Store batch result
-	if err := storage.PutBatchResult(ctx, mu, t.MarketID, t.WindowID, t.ClearPrice, t.TotalVolume, t.FillsHash); err != nil {
+	if err := storage.PutBatchResult(ctx, mu, t.MarketID, t.WindowID, storage.BatchResult{
+		ClearPrice:  t.ClearPrice,
+		TotalVolume: t.TotalVolume,
+		Fills:       uint32(len(clearing.Fills)),
+		ClearedAtMs: timestamp,
+		FillsHash:   t.FillsHash,
+	}); err != nil {
+		return nil, err
+	}
+	if err := storage.AppendClearedWindow(ctx, mu, t.MarketID, t.WindowID); err != nil {
+		return nil, err
+	}
+	if err := putWindowPhase(ctx, mu, t.MarketID, t.WindowID, window, storage.WindowPhaseCleared, timestamp); err != nil {
 		return nil, err
 	}
 	acceptedAtMs = timestamp
@@ -204,12 +321,18 @@ func (t *ClearBatch) Execute(
 	result := &ClearBatchResult{
 		ClearPrice:  t.ClearPrice,
 		TotalVolume: t.TotalVolume,
+		Fills:       uint32(len(clearing.Fills)),
+		Excluded:    uint32(len(faults)),
 	}
 	return result.Bytes(), nil
 }
 
-func (*ClearBatch) ComputeUnits(chain.Rules) uint64 {
-	return ClearBatchComputeUnits
+func (t *ClearBatch) ComputeUnits(chain.Rules) uint64 {
+	units := ClearBatchComputeUnits + uint64(len(t.Traders))*ClearBatchTraderComputeUnits
+	if len(t.OutputCommitments) > 0 {
+		units += NoteAppendComputeUnits
+	}
+	return units
 }
 
 func (*ClearBatch) ValidRange(chain.Rules) (int64, int64) {
@@ -221,6 +344,8 @@ var _ codec.Typed = (*ClearBatchResult)(nil)
 type ClearBatchResult struct {
 	ClearPrice  uint64 `serialize:"true" json:"clear_price"`
 	TotalVolume uint64 `serialize:"true" json:"total_volume"`
+	Fills       uint32 `serialize:"true" json:"fills"`
+	Excluded    uint32 `serialize:"true" json:"excluded"`
 }
 
 func (*ClearBatchResult) GetTypeID() uint8 {
@@ -248,6 +373,100 @@ func UnmarshalClearBatchResult(b []byte) (codec.Typed, error) {
 	return t, nil
 }
 
+// checkWindowClearable requires a revealed window, or a proven one when
+// proofs are mandatory.
+func checkWindowClearable(phase uint8, requireProof bool) error {
+	switch phase {
+	case storage.WindowPhaseCleared:
+		return storage.ErrBatchAlreadyCleared
+	case storage.WindowPhaseAbandoned:
+		return storage.ErrWindowAbandoned
+	case storage.WindowPhaseProven:
+		return nil
+	case storage.WindowPhaseRevealed:
+		if requireProof {
+			return storage.ErrWindowNotClearable
+		}
+		return nil
+	default:
+		return storage.ErrWindowNotClearable
+	}
+}
+
+type orderFault struct {
+	Trader codec.Address
+	Reason uint8
+}
+
+// revealedBatchOrders opens each trader's envelope with the window key and
+// keeps the orders that decode to a valid order matching the trader's
+// commitment, and whose escrow covers a full fill at its own limit. Any
+// other order is returned as a fault rather than failing the clear, so one
+// bad commitment cannot block the window.
+func revealedBatchOrders(
+	ctx context.Context,
+	im state.Immutable,
+	marketID ids.ID,
+	windowID uint64,
+	traders []codec.Address,
+) ([]BatchOrder, []orderFault, error) {
+	if len(traders) == 0 {
+		return nil, nil, nil
+	}
+	tally, err := storage.GetWindowDecryption(ctx, im, marketID, windowID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tally.WindowKey) == 0 {
+		return nil, nil, storage.ErrWindowNotRevealed
+	}
+	pool, err := storage.GetMarketPool(ctx, im, marketID)
+	if err != nil {
+		return nil, nil, err
+	}
+	eligible := make([]BatchOrder, 0, len(traders))
+	faults := make([]orderFault, 0)
+	for _, trader := range traders {
+		commitment, envelope, err := storage.GetCommitment(ctx, im, marketID, windowID, trader)
+		if err != nil {
+			return nil, nil, err
+		}
+		plaintext, err := openOrder(tally.WindowKey, marketID, windowID, envelope)
+		if err != nil {
+			faults = append(faults, orderFault{Trader: trader, Reason: storage.OrderFaultUndecryptable})
+			continue
+		}
+		expected := ComputeOrderCommitment(marketID, windowID, trader, plaintext)
+		if !bytes.Equal(commitment, expected[:]) {
+			faults = append(faults, orderFault{Trader: trader, Reason: storage.OrderFaultCommitmentMismatch})
+			continue
+		}
+		order := plaintext.batchOrder(trader)
+		if validateBatchOrder(order) != nil {
+			faults = append(faults, orderFault{Trader: trader, Reason: storage.OrderFaultInvalidOrder})
+			continue
+		}
+		escrow, err := storage.GetEscrow(ctx, im, marketID, windowID, trader)
+		if errors.Is(err, storage.ErrEscrowNotFound) {
+			faults = append(faults, orderFault{Trader: trader, Reason: storage.OrderFaultUnderCollateralized})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		maxCost, err := maxOrderCost(order)
+		if err != nil {
+			return nil, nil, err
+		}
+		if escrow.Asset != pool.Asset || escrow.Amount < maxCost {
+			faults = append(faults, orderFault{Trader: trader, Reason: storage.OrderFaultUnderCollateralized})
+			continue
+		}
+		eligible = append(eligible, order)
+	}
+	return eligible, faults, nil
+}
+
 func computeExpectedPublicInputsHash(
 	circuitID string,
 	marketID ids.ID,
@@ -255,6 +474,7 @@ func computeExpectedPublicInputsHash(
 	clearPrice uint64,
 	totalVolume uint64,
 	fillsHash []byte,
+	orders []BatchOrder,
 ) ([32]byte, error) {
 	circuitID = strings.TrimSpace(circuitID)
 	switch circuitID {
@@ -274,6 +494,31 @@ func computeExpectedPublicInputsHash(
 			totalVolume,
 			fillsHash,
 		), nil
+	case mconsts.ProofCircuitShieldedLedgerV2:
+		return ComputeShieldedLedgerV2PublicInputsHash(
+			marketID,
+			windowID,
+			clearPrice,
+			totalVolume,
+			fillsHash,
+		), nil
+	case mconsts.ProofCircuitClearingV1:
+		return ComputeClearingV1PublicInputsHash(
+			marketID,
+			windowID,
+			clearPrice,
+			totalVolume,
+			fillsHash,
+			orders,
+		)
+	case mconsts.ProofCircuitClearHashMiMCV1, mconsts.ProofCircuitAggregateV1:
+		return ComputeClearMiMCPublicInputsHash(
+			marketID,
+			windowID,
+			clearPrice,
+			totalVolume,
+			fillsHash,
+		)
 	default:
 		return [32]byte{}, storage.ErrUnsupportedProofCircuit
 	}
@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func TestBatchResultReadsLegacyRecords(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := ids.GenerateTestID()
+	fillsHash := bytes.Repeat([]byte{0xF1}, ExpectedFillsHashSize)
+
+	want := storage.BatchResult{
+		ClearPrice:  5_500,
+		TotalVolume: 100,
+		Fills:       3,
+		ClearedAtMs: 40_000,
+		FillsHash:   fillsHash,
+	}
+	if err := storage.PutBatchResult(ctx, mu, marketID, 1, want); err != nil {
+		t.Fatalf("put batch result: %v", err)
+	}
+	// Results written before the version byte: price ‖ volume ‖ fills hash.
+	legacy := binary.BigEndian.AppendUint64(nil, 4_200)
+	legacy = binary.BigEndian.AppendUint64(legacy, 7)
+	legacy = append(legacy, fillsHash...)
+	if err := mu.Insert(ctx, storage.BatchKey(marketID, 2), legacy); err != nil {
+		t.Fatalf("insert legacy result: %v", err)
+	}
+
+	tests := []struct {
+		windowID uint64
+		want     storage.BatchResult
+	}{
+		{windowID: 1, want: want},
+		{windowID: 2, want: storage.BatchResult{ClearPrice: 4_200, TotalVolume: 7, FillsHash: fillsHash}},
+	}
+	for _, tt := range tests {
+		got, err := storage.GetBatchResult(ctx, mu, marketID, tt.windowID)
+		if err != nil {
+			t.Fatalf("window %d: get batch result: %v", tt.windowID, err)
+		}
+		if got.ClearPrice != tt.want.ClearPrice || got.TotalVolume != tt.want.TotalVolume ||
+			got.Fills != tt.want.Fills || got.ClearedAtMs != tt.want.ClearedAtMs ||
+			!bytes.Equal(got.FillsHash, tt.want.FillsHash) {
+			t.Fatalf("window %d: unexpected result: %+v", tt.windowID, got)
+		}
+	}
+}
@@ -20,7 +20,6 @@ import (
 const (
 	CommitOrderComputeUnits = 2
 	MaxEnvelopeSize         = 4096
-	MaxCommitmentSize       = 64
 	MaxCommitOrderSize      = 8192
 )
 
@@ -28,16 +27,25 @@ var (
 	ErrEnvelopeEmpty                          = errors.New("envelope is empty")
 	ErrEnvelopeTooLarge                       = errors.New("envelope is too large")
 	ErrCommitmentEmpty                        = errors.New("commitment is empty")
-	ErrCommitmentTooLarge                     = errors.New("commitment is too large")
+	ErrCommitmentWrongSize                    = errors.New("commitment has the wrong size")
+	ErrMaxNotionalZero                        = errors.New("max notional is zero")
 	ErrUnmarshalEmptyCommitOrder              = errors.New("cannot unmarshal empty bytes as commit_order")
 	_                            chain.Action = (*CommitOrder)(nil)
 )
 
 type CommitOrder struct {
-	MarketID   ids.ID `serialize:"true" json:"market_id"`
-	WindowID   uint64 `serialize:"true" json:"window_id"`
-	Envelope   []byte `serialize:"true" json:"envelope"`
+	MarketID ids.ID `serialize:"true" json:"market_id"`
+	WindowID uint64 `serialize:"true" json:"window_id"`
+	Envelope []byte `serialize:"true" json:"envelope"`
+
+	// Commitment is ComputeOrderCommitment over the sealed order. ClearBatch
+	// recomputes it from the decrypted envelope and excludes mismatches.
 	Commitment []byte `serialize:"true" json:"commitment"`
+
+	// Asset and MaxNotional declare the collateral locked in escrow for the
+	// lifetime of the commitment.
+	Asset       uint8  `serialize:"true" json:"asset"`
+	MaxNotional uint64 `serialize:"true" json:"max_notional"`
 }
 
 func (*CommitOrder) GetTypeID() uint8 {
@@ -47,7 +55,15 @@ func (*CommitOrder) GetTypeID() uint8 {
 func (t *CommitOrder) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
 	return state.Keys{
 		string(storage.MarketKey(t.MarketID)):                        state.Read,
+		string(storage.MarketPoolKey(t.MarketID)):                    state.Read,
+		string(storage.ProofConfigKey()):                             state.Read,
+		string(storage.DecryptCommitteeKey()):                        state.Read,
+		string(storage.ProofUpgradeKey()):                            state.Read,
+		string(storage.WindowStateKey(t.MarketID, t.WindowID)):       state.All,
 		string(storage.CommitmentKey(t.MarketID, t.WindowID, actor)): state.All,
+		string(storage.EscrowKey(t.MarketID, t.WindowID, actor)):     state.All,
+		string(storage.BalanceKey(actor)):                            state.Read | state.Write,
+		string(storage.VAIBalanceKey(actor)):                         state.Read | state.Write,
 	}
 }
 
@@ -102,8 +118,14 @@ func (t *CommitOrder) Execute(
 	if len(t.Commitment) == 0 {
 		return nil, ErrCommitmentEmpty
 	}
-	if len(t.Commitment) > MaxCommitmentSize {
-		return nil, ErrCommitmentTooLarge
+	if len(t.Commitment) != OrderCommitmentSize {
+		return nil, ErrCommitmentWrongSize
+	}
+	if !isSupportedAsset(t.Asset) {
+		return nil, storage.ErrUnsupportedAsset
+	}
+	if t.MaxNotional == 0 {
+		return nil, ErrMaxNotionalZero
 	}
 
 	// Verify market exists and is active
@@ -114,17 +136,105 @@ func (t *CommitOrder) Execute(
 	if status != storage.MarketStatusActive {
 		return nil, storage.ErrMarketNotActive
 	}
+	pool, err := storage.GetMarketPool(ctx, mu, t.MarketID)
+	if err != nil {
+		return nil, err
+	}
+	if pool.Asset != t.Asset {
+		return nil, storage.ErrCollateralAssetMismatch
+	}
+
+	// Commits only land in the window whose commit phase contains the block
+	// timestamp; the first commit pins that window's timeline.
+	proofCfg, err := storage.GetProofConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	window, stored, err := loadWindowState(ctx, mu, t.MarketID, t.WindowID, proofCfg, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if timestamp < window.OpenAtMs || windowPhaseAt(window, timestamp) != storage.WindowPhaseOpen {
+		return nil, storage.ErrWindowNotOpen
+	}
+	// Envelopes are sealed to the current committee, so a window opened
+	// under a replaced one takes no more orders.
+	var epoch uint64
+	committee, err := storage.GetDecryptCommittee(ctx, mu)
+	switch {
+	case err == nil:
+		epoch = committee.Epoch
+	case !errors.Is(err, storage.ErrDecryptCommitteeNotFound):
+		return nil, err
+	}
+	if err := bindCommitteeEpoch(&window, stored, epoch); err != nil {
+		return nil, err
+	}
+
+	// One commitment per actor per window; overwriting would orphan the escrow.
+	exists, err := storage.HasCommitment(ctx, mu, t.MarketID, t.WindowID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, storage.ErrCommitmentExists
+	}
+
+	// The window counts its commitments so a clear must settle all of them,
+	// and stops accepting once a clear could no longer list or prove them.
+	rules, err := loadProofRules(ctx, mu, proofCfg, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if window.Commitments >= windowOrderLimit(proofCfg, rules) {
+		return nil, ErrTooManyOrders
+	}
+	window.Commitments++
+	if err := storage.PutWindowState(ctx, mu, t.MarketID, t.WindowID, window); err != nil {
+		return nil, err
+	}
+
+	// Lock collateral so the commitment is economically binding.
+	balance, err := subAssetBalance(ctx, mu, actor, t.Asset, t.MaxNotional)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.PutEscrow(ctx, mu, t.MarketID, t.WindowID, actor, storage.Escrow{
+		Asset:      t.Asset,
+		Amount:     t.MaxNotional,
+		LockedAtMs: timestamp,
+	}); err != nil {
+		return nil, err
+	}
 
-	// TODO(M2): lock collateral at commit time to make commitments economically binding.
 	// Store commitment
 	if err := storage.PutCommitment(ctx, mu, t.MarketID, t.WindowID, actor, t.Envelope, t.Commitment); err != nil {
 		return nil, err
 	}
 
-	result := &CommitOrderResult{WindowID: t.WindowID}
+	result := &CommitOrderResult{
+		WindowID:      t.WindowID,
+		Escrowed:      t.MaxNotional,
+		SenderBalance: balance,
+	}
 	return result.Bytes(), nil
 }
 
+// windowOrderLimit returns how many commitments a window accepts. When clears
+// need a proof and every rule in effect is clearing-v1, that is the circuit's
+// order capacity, since a larger window could never be proven.
+func windowOrderLimit(cfg storage.ProofConfig, rules []storage.ProofRule) uint32 {
+	if !cfg.RequireProof {
+		return MaxBatchOrders
+	}
+	for _, rule := range rules {
+		if rule.CircuitID != mconsts.ProofCircuitClearingV1 {
+			return MaxBatchOrders
+		}
+	}
+	return ClearingV1MaxOrders
+}
+
 func (*CommitOrder) ComputeUnits(chain.Rules) uint64 {
 	return CommitOrderComputeUnits
 }
@@ -136,7 +246,9 @@ func (*CommitOrder) ValidRange(chain.Rules) (int64, int64) {
 var _ codec.Typed = (*CommitOrderResult)(nil)
 
 type CommitOrderResult struct {
-	WindowID uint64 `serialize:"true" json:"window_id"`
+	WindowID      uint64 `serialize:"true" json:"window_id"`
+	Escrowed      uint64 `serialize:"true" json:"escrowed"`
+	SenderBalance uint64 `serialize:"true" json:"sender_balance"`
 }
 
 func (*CommitOrderResult) GetTypeID() uint8 {
@@ -0,0 +1,316 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+type mapState map[string][]byte
+
+func (m mapState) GetValue(ctx context.Context, key []byte) ([]byte, error) {
+	return state.ImmutableStorage(m).GetValue(ctx, key)
+}
+
+func (m mapState) Insert(_ context.Context, key []byte, value []byte) error {
+	m[string(key)] = value
+	return nil
+}
+
+func (m mapState) Remove(_ context.Context, key []byte) error {
+	delete(m, string(key))
+	return nil
+}
+
+var testWindowConfig = storage.ProofConfig{
+	RequiredProofType: mconsts.ProofTypeGroth16,
+	BatchWindowMs:     5_000,
+	ProofDeadlineMs:   10_000,
+	ProverAuthority:   codec.Address{0xAA},
+}
+
+func testTrader(b byte) codec.Address {
+	var addr codec.Address
+	addr[0] = b
+	return addr
+}
+
+// putTestMarket stores an active binary market whose pool holds asset, and
+// the proof config its escrow timeout is derived from.
+func putTestMarket(t *testing.T, mu mapState, asset uint8) ids.ID {
+	t.Helper()
+	ctx := context.Background()
+	marketID := ids.GenerateTestID()
+	if err := storage.PutMarket(ctx, mu, marketID, storage.MarketStatusActive, 2, 0, 0, []byte("q")); err != nil {
+		t.Fatalf("put market: %v", err)
+	}
+	if err := storage.PutMarketPool(ctx, mu, marketID, storage.MarketPool{Asset: asset}); err != nil {
+		t.Fatalf("put market pool: %v", err)
+	}
+	if err := storage.PutProofConfig(ctx, mu, testWindowConfig); err != nil {
+		t.Fatalf("put proof config: %v", err)
+	}
+	return marketID
+}
+
+func testCommitOrder(marketID ids.ID, windowID uint64, asset uint8, maxNotional uint64) *CommitOrder {
+	return &CommitOrder{
+		MarketID:    marketID,
+		WindowID:    windowID,
+		Envelope:    []byte("sealed"),
+		Commitment:  make([]byte, OrderCommitmentSize),
+		Asset:       asset,
+		MaxNotional: maxNotional,
+	}
+}
+
+func TestCommitOrderLocksEscrow(t *testing.T) {
+	const (
+		windowID = uint64(4)
+		now      = int64(20_001)
+	)
+	tests := []struct {
+		name        string
+		poolAsset   uint8
+		asset       uint8
+		balance     uint64
+		maxNotional uint64
+		err         error
+		wantBalance uint64
+	}{
+		{name: "veil", poolAsset: AssetVEIL, asset: AssetVEIL, balance: 1_000, maxNotional: 600, wantBalance: 400},
+		{name: "vai", poolAsset: AssetVAI, asset: AssetVAI, balance: 1_000, maxNotional: 1_000, wantBalance: 0},
+		{name: "insufficient balance", poolAsset: AssetVEIL, asset: AssetVEIL, balance: 500, maxNotional: 600, err: storage.ErrInvalidBalance, wantBalance: 500},
+		{name: "asset mismatch", poolAsset: AssetVAI, asset: AssetVEIL, balance: 1_000, maxNotional: 600, err: storage.ErrCollateralAssetMismatch, wantBalance: 1_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mu := mapState{}
+			marketID := putTestMarket(t, mu, tt.poolAsset)
+			trader := testTrader(1)
+			if _, err := addAssetBalance(ctx, mu, trader, tt.asset, tt.balance); err != nil {
+				t.Fatalf("fund trader: %v", err)
+			}
+
+			_, err := testCommitOrder(marketID, windowID, tt.asset, tt.maxNotional).Execute(ctx, nil, mu, now, trader, ids.Empty)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: got=%v want=%v", err, tt.err)
+			}
+			balance, err := getAssetBalance(ctx, mu, trader, tt.asset)
+			if err != nil {
+				t.Fatalf("get balance: %v", err)
+			}
+			if balance != tt.wantBalance {
+				t.Fatalf("unexpected balance: got=%d want=%d", balance, tt.wantBalance)
+			}
+			escrow, err := storage.GetEscrow(ctx, mu, marketID, windowID, trader)
+			if tt.err != nil {
+				if !errors.Is(err, storage.ErrEscrowNotFound) {
+					t.Fatalf("failed commit left escrow: %+v %v", escrow, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get escrow: %v", err)
+			}
+			if escrow.Asset != tt.asset || escrow.Amount != tt.maxNotional || escrow.LockedAtMs != now {
+				t.Fatalf("unexpected escrow: %+v", escrow)
+			}
+		})
+	}
+}
+
+func TestCommitOrderRejectsSecondCommitment(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	trader := testTrader(1)
+	if err := storage.SetBalance(ctx, mu, trader, 1_000); err != nil {
+		t.Fatalf("set balance: %v", err)
+	}
+	action := testCommitOrder(marketID, 4, AssetVEIL, 300)
+	if _, err := action.Execute(ctx, nil, mu, 20_001, trader, ids.Empty); err != nil {
+		t.Fatalf("first commit: %v", err)
+	}
+	if _, err := action.Execute(ctx, nil, mu, 20_002, trader, ids.Empty); !errors.Is(err, storage.ErrCommitmentExists) {
+		t.Fatalf("expected ErrCommitmentExists, got %v", err)
+	}
+	balance, err := storage.GetBalance(ctx, mu, trader)
+	if err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+	if balance != 700 {
+		t.Fatalf("unexpected balance: %d", balance)
+	}
+}
+
+func TestCommitOrderCapsClearingV1Windows(t *testing.T) {
+	tests := []struct {
+		name     string
+		circuit  string
+		accepted int
+	}{
+		{name: "any circuit", accepted: ClearingV1MaxOrders + 1},
+		{name: "clearing-v1", circuit: mconsts.ProofCircuitClearingV1, accepted: ClearingV1MaxOrders},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mu := mapState{}
+			marketID := putTestMarket(t, mu, AssetVEIL)
+			cfg := testWindowConfig
+			cfg.RequireProof = true
+			if err := storage.PutProofConfig(ctx, mu, cfg); err != nil {
+				t.Fatalf("put proof config: %v", err)
+			}
+			if err := storage.PutProofUpgrade(ctx, mu, storage.ProofUpgrade{
+				From:         storage.ProofRule{ProofType: mconsts.ProofTypeGroth16},
+				To:           storage.ProofRule{ProofType: mconsts.ProofTypeGroth16, CircuitID: tt.circuit},
+				ActivateAtMs: 1_000,
+			}); err != nil {
+				t.Fatalf("put proof upgrade: %v", err)
+			}
+
+			for i := 1; i <= ClearingV1MaxOrders+1; i++ {
+				trader := testTrader(byte(i))
+				if err := storage.SetBalance(ctx, mu, trader, 100); err != nil {
+					t.Fatalf("set balance: %v", err)
+				}
+				_, err := testCommitOrder(marketID, 4, AssetVEIL, 100).Execute(ctx, nil, mu, 20_001, trader, ids.Empty)
+				if i <= tt.accepted && err != nil {
+					t.Fatalf("commit %d: %v", i, err)
+				}
+				if i > tt.accepted && !errors.Is(err, ErrTooManyOrders) {
+					t.Fatalf("commit %d: expected ErrTooManyOrders, got %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestReleaseEscrowRefundsAbandonedWindow(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	const windowID = uint64(4)
+	var trader codec.Address
+	trader[0] = 0x07
+	if err := storage.SetBalance(ctx, mu, trader, 1_000); err != nil {
+		t.Fatalf("set balance: %v", err)
+	}
+	if _, err := testCommitOrder(marketID, windowID, AssetVEIL, 600).Execute(ctx, nil, mu, 20_001, trader, ids.Empty); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	release := &ReleaseEscrow{MarketID: marketID, WindowID: windowID}
+	window, err := storage.GetWindowState(ctx, mu, marketID, windowID)
+	if err != nil {
+		t.Fatalf("get window state: %v", err)
+	}
+	if _, err := release.Execute(ctx, nil, mu, window.DeadlineAtMs, trader, ids.Empty); !errors.Is(err, storage.ErrEscrowLocked) {
+		t.Fatalf("expected escrow locked before the deadline, got %v", err)
+	}
+	out, err := release.Execute(ctx, nil, mu, window.DeadlineAtMs+1, trader, ids.Empty)
+	if err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	resultAny, err := UnmarshalReleaseEscrowResult(out)
+	if err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	result := resultAny.(*ReleaseEscrowResult)
+	if !result.Abandoned || result.Released != 600 || result.SenderBalance != 1_000 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if _, err := release.Execute(ctx, nil, mu, window.DeadlineAtMs+2, trader, ids.Empty); !errors.Is(err, storage.ErrEscrowNotFound) {
+		t.Fatalf("expected escrow to be released once, got %v", err)
+	}
+}
+
+func TestReleaseEscrowRefundsUnclearedProvenWindow(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	const windowID = uint64(4)
+	trader := testTrader(1)
+	if err := storage.SetBalance(ctx, mu, trader, 1_000); err != nil {
+		t.Fatalf("set balance: %v", err)
+	}
+	if _, err := testCommitOrder(marketID, windowID, AssetVEIL, 600).Execute(ctx, nil, mu, 20_001, trader, ids.Empty); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	window, err := storage.GetWindowState(ctx, mu, marketID, windowID)
+	if err != nil {
+		t.Fatalf("get window state: %v", err)
+	}
+	window.Phase = storage.WindowPhaseProven
+	if err := storage.PutWindowState(ctx, mu, marketID, windowID, window); err != nil {
+		t.Fatalf("put window state: %v", err)
+	}
+
+	// A proven window may still clear until one more proof period has passed
+	// after its deadline.
+	clearDeadline := window.DeadlineAtMs + testWindowConfig.ProofDeadlineMs
+	release := &ReleaseEscrow{MarketID: marketID, WindowID: windowID}
+	if _, err := release.Execute(ctx, nil, mu, clearDeadline, trader, ids.Empty); !errors.Is(err, storage.ErrEscrowLocked) {
+		t.Fatalf("expected escrow locked before the clear deadline, got %v", err)
+	}
+	out, err := release.Execute(ctx, nil, mu, clearDeadline+1, trader, ids.Empty)
+	if err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	resultAny, err := UnmarshalReleaseEscrowResult(out)
+	if err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if result := resultAny.(*ReleaseEscrowResult); !result.Abandoned || result.SenderBalance != 1_000 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestClearBatchRequiresEveryCommitment(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	const windowID = uint64(4)
+	for i := byte(1); i <= 3; i++ {
+		if err := storage.SetBalance(ctx, mu, testTrader(i), 1_000); err != nil {
+			t.Fatalf("set balance: %v", err)
+		}
+		if _, err := testCommitOrder(marketID, windowID, AssetVEIL, 100).Execute(ctx, nil, mu, 20_001, testTrader(i), ids.Empty); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+	}
+	if _, err := (&CancelCommitment{MarketID: marketID, WindowID: windowID}).Execute(ctx, nil, mu, 20_002, testTrader(2), ids.Empty); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	window, err := storage.GetWindowState(ctx, mu, marketID, windowID)
+	if err != nil {
+		t.Fatalf("get window state: %v", err)
+	}
+	if window.Commitments != 2 {
+		t.Fatalf("unexpected commitment count: %d", window.Commitments)
+	}
+
+	window.Phase = storage.WindowPhaseRevealed
+	if err := storage.PutWindowState(ctx, mu, marketID, windowID, window); err != nil {
+		t.Fatalf("put window state: %v", err)
+	}
+	action := &ClearBatch{
+		MarketID:  marketID,
+		WindowID:  windowID,
+		FillsHash: make([]byte, ExpectedFillsHashSize),
+		Traders:   []codec.Address{testTrader(1)},
+	}
+	if _, err := action.Execute(ctx, nil, mu, window.CloseAtMs, codec.EmptyAddress, ids.Empty); !errors.Is(err, ErrTradersIncomplete) {
+		t.Fatalf("expected ErrTradersIncomplete, got %v", err)
+	}
+}
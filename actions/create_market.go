@@ -37,6 +37,10 @@ type CreateMarket struct {
 	Outcomes       uint8  `serialize:"true" json:"outcomes"`
 	ResolutionTime int64  `serialize:"true" json:"resolution_time"`
 	CreatorBond    uint64 `serialize:"true" json:"creator_bond"`
+
+	// CollateralAsset backs the market's outcome positions; orders must
+	// escrow this asset and winnings are paid in it.
+	CollateralAsset uint8 `serialize:"true" json:"collateral_asset"`
 }
 
 func (*CreateMarket) GetTypeID() uint8 {
@@ -45,8 +49,9 @@ func (*CreateMarket) GetTypeID() uint8 {
 
 func (a *CreateMarket) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
 	return state.Keys{
-		string(storage.BalanceKey(actor)):     state.Read | state.Write,
-		string(storage.MarketKey(a.MarketID)): state.All,
+		string(storage.BalanceKey(actor)):         state.Read | state.Write,
+		string(storage.MarketKey(a.MarketID)):     state.All,
+		string(storage.MarketPoolKey(a.MarketID)): state.All,
 	}
 }
 
@@ -102,6 +107,9 @@ func (a *CreateMarket) Execute(
 	if len(a.Question) > MaxQuestionSize {
 		return nil, ErrQuestionTooLarge
 	}
+	if !isSupportedAsset(a.CollateralAsset) {
+		return nil, storage.ErrUnsupportedAsset
+	}
 
 	// Reject duplicate market IDs to avoid silent overwrites.
 	_, _, _, _, _, err := storage.GetMarket(ctx, mu, a.MarketID)
@@ -122,6 +130,9 @@ func (a *CreateMarket) Execute(
 	if err := storage.PutMarket(ctx, mu, a.MarketID, storage.MarketStatusActive, a.Outcomes, a.ResolutionTime, 0, a.Question); err != nil {
 		return nil, err
 	}
+	if err := storage.PutMarketPool(ctx, mu, a.MarketID, storage.MarketPool{Asset: a.CollateralAsset}); err != nil {
+		return nil, err
+	}
 
 	result := &CreateMarketResult{SenderBalance: senderBalance}
 	return result.Bytes(), nil
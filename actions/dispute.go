@@ -20,6 +20,10 @@ const (
 	DisputeComputeUnits = 3
 	MaxEvidenceSize     = 4096
 	MaxDisputeSize      = 8192
+
+	// MarketDisputeWindowMs is how long after resolution a market may be
+	// disputed. Winnings are redeemable once it has passed.
+	MarketDisputeWindowMs int64 = 24 * 60 * 60 * 1000
 )
 
 var (
@@ -30,6 +34,9 @@ var (
 	_                        chain.Action = (*Dispute)(nil)
 )
 
+// Dispute challenges a market's resolution during its dispute window and
+// locks Bond until governance settles it with SettleDispute. Redemptions stay
+// closed while the market is disputed.
 type Dispute struct {
 	MarketID ids.ID `serialize:"true" json:"market_id"`
 	Bond     uint64 `serialize:"true" json:"bond"`
@@ -42,9 +49,10 @@ func (*Dispute) GetTypeID() uint8 {
 
 func (t *Dispute) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
 	return state.Keys{
-		string(storage.BalanceKey(actor)):      state.Read | state.Write,
-		string(storage.MarketKey(t.MarketID)):  state.Read | state.Write,
-		string(storage.DisputeKey(t.MarketID)): state.All,
+		string(storage.BalanceKey(actor)):               state.Read | state.Write,
+		string(storage.MarketKey(t.MarketID)):           state.Read | state.Write,
+		string(storage.MarketResolutionKey(t.MarketID)): state.Read,
+		string(storage.DisputeKey(t.MarketID)):          state.All,
 	}
 }
 
@@ -81,7 +89,7 @@ func (t *Dispute) Execute(
 	ctx context.Context,
 	_ chain.Rules,
 	mu state.Mutable,
-	_ int64,
+	timestamp int64,
 	actor codec.Address,
 	_ ids.ID,
 ) ([]byte, error) {
@@ -103,6 +111,17 @@ func (t *Dispute) Execute(
 	if status != storage.MarketStatusResolved {
 		return nil, storage.ErrMarketNotResolved
 	}
+	// Markets resolved before resolution records were kept have no window.
+	resolution, err := storage.GetMarketResolution(ctx, mu, t.MarketID)
+	if errors.Is(err, storage.ErrMarketNotResolved) {
+		return nil, storage.ErrDisputeWindow
+	}
+	if err != nil {
+		return nil, err
+	}
+	if timestamp >= resolution.DisputeEndsAtMs {
+		return nil, storage.ErrDisputeWindow
+	}
 
 	// Deduct bond from disputer
 	newBalance, err := storage.SubBalance(ctx, mu, actor, t.Bond)
@@ -116,7 +135,12 @@ func (t *Dispute) Execute(
 	}
 
 	// Store dispute
-	if err := storage.PutDispute(ctx, mu, t.MarketID, t.Bond, t.Evidence); err != nil {
+	if err := storage.PutDispute(ctx, mu, t.MarketID, storage.MarketDispute{
+		Disputer:     actor,
+		Bond:         t.Bond,
+		DisputedAtMs: timestamp,
+		Evidence:     t.Evidence,
+	}); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,105 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/threshold"
+)
+
+const (
+	OrderPlaintextVersion byte = 1
+	OrderSaltSize              = 32
+	OrderPlaintextSize         = 1 + 1 + 8 + 8 + OrderSaltSize
+	OrderCommitmentSize        = sha256.Size
+
+	OrderCommitmentDomainTag = "VEIL_ORDER_COMMIT_V1"
+)
+
+var ErrInvalidOrderPlaintext = errors.New("invalid order plaintext")
+
+// OrderPlaintext is the order sealed inside a CommitOrder envelope. The
+// trader is the committing actor, so it is not repeated here. Salt blinds
+// the order commitment and must be chosen uniformly at random.
+type OrderPlaintext struct {
+	Side       uint8
+	LimitPrice uint64
+	Quantity   uint64
+	Salt       [OrderSaltSize]byte
+}
+
+func (o OrderPlaintext) Bytes() []byte {
+	b := make([]byte, 0, OrderPlaintextSize)
+	b = append(b, OrderPlaintextVersion, o.Side)
+	b = binary.BigEndian.AppendUint64(b, o.LimitPrice)
+	b = binary.BigEndian.AppendUint64(b, o.Quantity)
+	return append(b, o.Salt[:]...)
+}
+
+func ParseOrderPlaintext(b []byte) (OrderPlaintext, error) {
+	if len(b) != OrderPlaintextSize || b[0] != OrderPlaintextVersion {
+		return OrderPlaintext{}, ErrInvalidOrderPlaintext
+	}
+	order := OrderPlaintext{
+		Side:       b[1],
+		LimitPrice: binary.BigEndian.Uint64(b[2:10]),
+		Quantity:   binary.BigEndian.Uint64(b[10:18]),
+	}
+	copy(order.Salt[:], b[18:])
+	return order, nil
+}
+
+// ComputeOrderCommitment is the CommitOrder commitment for an order:
+// SHA-256 over the domain tag, market, window, trader and the order
+// plaintext including its salt. Binding the trader stops a copied
+// envelope from clearing under another account.
+func ComputeOrderCommitment(
+	marketID ids.ID,
+	windowID uint64,
+	trader codec.Address,
+	order OrderPlaintext,
+) [OrderCommitmentSize]byte {
+	preimage := make([]byte, 0, len(OrderCommitmentDomainTag)+ids.IDLen+8+codec.AddressLen+OrderPlaintextSize)
+	preimage = append(preimage, OrderCommitmentDomainTag...)
+	preimage = append(preimage, marketID[:]...)
+	preimage = binary.BigEndian.AppendUint64(preimage, windowID)
+	preimage = append(preimage, trader[:]...)
+	preimage = append(preimage, order.Bytes()...)
+	return sha256.Sum256(preimage)
+}
+
+// SealOrder encrypts an order to the decrypt committee's public key for one
+// (market, window). The result is a valid CommitOrder envelope.
+func SealOrder(
+	committeePublicKey []byte,
+	marketID ids.ID,
+	windowID uint64,
+	order OrderPlaintext,
+	rng io.Reader,
+) ([]byte, error) {
+	return threshold.Encrypt(committeePublicKey, marketID, windowID, order.Bytes(), rng)
+}
+
+// openOrder decrypts a stored envelope with the window key and decodes the
+// order it carries.
+func openOrder(windowKey []byte, marketID ids.ID, windowID uint64, envelope []byte) (OrderPlaintext, error) {
+	plaintext, err := threshold.Decrypt(windowKey, marketID, windowID, envelope)
+	if err != nil {
+		return OrderPlaintext{}, err
+	}
+	return ParseOrderPlaintext(plaintext)
+}
+
+func (o OrderPlaintext) batchOrder(trader codec.Address) BatchOrder {
+	return BatchOrder{
+		Trader:     trader,
+		Side:       o.Side,
+		LimitPrice: o.LimitPrice,
+		Quantity:   o.Quantity,
+	}
+}
@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/threshold"
+)
+
+func TestSealedOrderMatchesCommitment(t *testing.T) {
+	publicKey, _, shares, err := threshold.Deal(1, 1, rand.Reader)
+	if err != nil {
+		t.Fatalf("deal failed: %v", err)
+	}
+	marketID := ids.ID{3}
+	const windowID = uint64(11)
+	order := OrderPlaintext{Side: OrderSideSell, LimitPrice: 4200, Quantity: 9}
+	if _, err := rand.Read(order.Salt[:]); err != nil {
+		t.Fatalf("salt failed: %v", err)
+	}
+	commitment := ComputeOrderCommitment(marketID, windowID, testTrader(1), order)
+
+	envelope, err := SealOrder(publicKey, marketID, windowID, order, rand.Reader)
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	share, err := shares[0].DecryptionShare(marketID, windowID)
+	if err != nil {
+		t.Fatalf("decryption share failed: %v", err)
+	}
+	windowKey, err := threshold.CombineShares([]uint32{shares[0].Index}, [][]byte{share})
+	if err != nil {
+		t.Fatalf("combine failed: %v", err)
+	}
+	opened, err := openOrder(windowKey, marketID, windowID, envelope)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if opened != order {
+		t.Fatalf("opened order mismatch: got=%+v want=%+v", opened, order)
+	}
+	if ComputeOrderCommitment(marketID, windowID, testTrader(1), opened) != commitment {
+		t.Fatalf("opened order does not match its commitment")
+	}
+
+	// A copied envelope does not match under another trader or another salt.
+	if ComputeOrderCommitment(marketID, windowID, testTrader(2), opened) == commitment {
+		t.Fatalf("commitment must bind the trader")
+	}
+	opened.Salt[0] ^= 1
+	if ComputeOrderCommitment(marketID, windowID, testTrader(1), opened) == commitment {
+		t.Fatalf("commitment must bind the salt")
+	}
+}
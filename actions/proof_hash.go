@@ -3,16 +3,71 @@ package actions
 import (
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
+	"math/big"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/shielded"
+)
+
+const (
+	ClearInputsDomainTag            = "VEIL_CLEAR_V1"
+	ShieldedLedgerInputsDomainTag   = "VEIL_SHIELDED_LEDGER_V1"
+	ShieldedLedgerV2InputsDomainTag = "VEIL_SHIELDED_LEDGER_V2"
+	UnshieldInputsDomainTag         = "VEIL_UNSHIELD_V1"
+	ExpectedFillsHashSize           = sha256.Size
 )
 
 const (
-	ClearInputsDomainTag         = "VEIL_CLEAR_V1"
-	ShieldedLedgerInputsDomainTag = "VEIL_SHIELDED_LEDGER_V1"
-	ExpectedFillsHashSize        = sha256.Size
+	ClearingV1InputsDomainTag = "VEIL_CLEARING_V1"
+	ClearingOrdersDomainTag   = "VEIL_CLEARING_ORDERS_V1"
+
+	// ClearingV1MaxOrders is the fixed order capacity of the clearing-v1
+	// circuit. Windows with more eligible orders need another circuit.
+	ClearingV1MaxOrders = 16
+
+	// ClearingTraderHighBytes is how many leading address bytes go into the
+	// first of the two field elements a trader is packed into.
+	ClearingTraderHighBytes = codec.AddressLen - 16
 )
 
+const (
+	ClearMiMCInputsDomainTag = "VEIL_CLEAR_MIMC_V1"
+
+	// ClearMiMCPreimageElements is the number of field elements in the
+	// clearhash-mimc-v1 preimage: the domain tag, market ID and fills hash
+	// split into 16-byte halves, window ID, clear price and total volume.
+	ClearMiMCPreimageElements = 8
+)
+
+const (
+	AggregateInputsDomainTag = "VEIL_AGGREGATE_V1"
+
+	// AggregateV1MaxWindows is the fixed window capacity of the aggregate-v1
+	// circuit.
+	AggregateV1MaxWindows = 8
+)
+
+var (
+	ErrTooManyClearingOrders = errors.New("window has more orders than the clearing-v1 circuit supports")
+	ErrInvalidFillsHashSize  = errors.New("fills hash has the wrong size")
+	ErrInvalidAggregateInput = errors.New("aggregate needs 1 to 8 clearhash-mimc-v1 window hashes")
+
+	clearingOrdersTag = domainTagElement(ClearingOrdersDomainTag)
+	clearMiMCTag      = domainTagElement(ClearMiMCInputsDomainTag)
+	aggregateTag      = domainTagElement(AggregateInputsDomainTag)
+)
+
+func domainTagElement(tag string) fr.Element {
+	sum := sha256.Sum256([]byte(tag))
+	var out fr.Element
+	out.SetBytes(sum[:])
+	return out
+}
+
 // BuildClearPublicInputsPreimage canonicalizes clear-batch inputs into the byte
 // preimage used for public input hashing.
 func BuildClearPublicInputsPreimage(
@@ -22,23 +77,7 @@ func BuildClearPublicInputsPreimage(
 	totalVolume uint64,
 	fillsHash []byte,
 ) []byte {
-	preimage := make([]byte, 0, len(ClearInputsDomainTag)+ids.IDLen+8+8+8+2+len(fillsHash))
-	preimage = append(preimage, ClearInputsDomainTag...)
-	preimage = append(preimage, marketID[:]...)
-
-	var scratch [8]byte
-	binary.BigEndian.PutUint64(scratch[:], windowID)
-	preimage = append(preimage, scratch[:]...)
-	binary.BigEndian.PutUint64(scratch[:], clearPrice)
-	preimage = append(preimage, scratch[:]...)
-	binary.BigEndian.PutUint64(scratch[:], totalVolume)
-	preimage = append(preimage, scratch[:]...)
-
-	var hashLen [2]byte
-	binary.BigEndian.PutUint16(hashLen[:], uint16(len(fillsHash)))
-	preimage = append(preimage, hashLen[:]...)
-	preimage = append(preimage, fillsHash...)
-	return preimage
+	return buildBatchPublicInputsPreimage(ClearInputsDomainTag, marketID, windowID, clearPrice, totalVolume, fillsHash)
 }
 
 // ComputeClearPublicInputsHash canonicalizes clear-batch inputs into a single
@@ -68,23 +107,7 @@ func BuildShieldedLedgerPublicInputsPreimage(
 	totalVolume uint64,
 	fillsHash []byte,
 ) []byte {
-	preimage := make([]byte, 0, len(ShieldedLedgerInputsDomainTag)+ids.IDLen+8+8+8+2+len(fillsHash))
-	preimage = append(preimage, ShieldedLedgerInputsDomainTag...)
-	preimage = append(preimage, marketID[:]...)
-
-	var scratch [8]byte
-	binary.BigEndian.PutUint64(scratch[:], windowID)
-	preimage = append(preimage, scratch[:]...)
-	binary.BigEndian.PutUint64(scratch[:], clearPrice)
-	preimage = append(preimage, scratch[:]...)
-	binary.BigEndian.PutUint64(scratch[:], totalVolume)
-	preimage = append(preimage, scratch[:]...)
-
-	var hashLen [2]byte
-	binary.BigEndian.PutUint16(hashLen[:], uint16(len(fillsHash)))
-	preimage = append(preimage, hashLen[:]...)
-	preimage = append(preimage, fillsHash...)
-	return preimage
+	return buildBatchPublicInputsPreimage(ShieldedLedgerInputsDomainTag, marketID, windowID, clearPrice, totalVolume, fillsHash)
 }
 
 // ComputeShieldedLedgerPublicInputsHash computes the canonical digest for
@@ -104,3 +127,242 @@ func ComputeShieldedLedgerPublicInputsHash(
 		fillsHash,
 	))
 }
+
+// BuildShieldedLedgerV2PublicInputsPreimage canonicalizes the batch fields
+// that a shielded-ledger-v2 proof is bound to. The note root, nullifiers and
+// output commitments are carried as separate public inputs of the circuit.
+func BuildShieldedLedgerV2PublicInputsPreimage(
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	totalVolume uint64,
+	fillsHash []byte,
+) []byte {
+	return buildBatchPublicInputsPreimage(ShieldedLedgerV2InputsDomainTag, marketID, windowID, clearPrice, totalVolume, fillsHash)
+}
+
+// ComputeShieldedLedgerV2PublicInputsHash computes the batch digest exposed as
+// the first public input of shielded-ledger-v2 proofs.
+func ComputeShieldedLedgerV2PublicInputsHash(
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	totalVolume uint64,
+	fillsHash []byte,
+) [32]byte {
+	return sha256.Sum256(BuildShieldedLedgerV2PublicInputsPreimage(
+		marketID,
+		windowID,
+		clearPrice,
+		totalVolume,
+		fillsHash,
+	))
+}
+
+// buildBatchPublicInputsPreimage lays out the batch fields shared by the
+// SHA-256 public-input digests behind a circuit's domain tag.
+func buildBatchPublicInputsPreimage(
+	domainTag string,
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	totalVolume uint64,
+	fillsHash []byte,
+) []byte {
+	preimage := make([]byte, 0, len(domainTag)+ids.IDLen+8+8+8+2+len(fillsHash))
+	preimage = append(preimage, domainTag...)
+	preimage = append(preimage, marketID[:]...)
+	preimage = binary.BigEndian.AppendUint64(preimage, windowID)
+	preimage = binary.BigEndian.AppendUint64(preimage, clearPrice)
+	preimage = binary.BigEndian.AppendUint64(preimage, totalVolume)
+	preimage = binary.BigEndian.AppendUint16(preimage, uint16(len(fillsHash)))
+	preimage = append(preimage, fillsHash...)
+	return preimage
+}
+
+// BuildUnshieldPublicInputsPreimage canonicalizes the withdrawal an Unshield
+// proof authorizes, so the proof cannot be replayed to a different address,
+// asset or amount.
+func BuildUnshieldPublicInputsPreimage(asset uint8, amount uint64, to codec.Address) []byte {
+	preimage := make([]byte, 0, len(UnshieldInputsDomainTag)+1+8+codec.AddressLen)
+	preimage = append(preimage, UnshieldInputsDomainTag...)
+	preimage = append(preimage, asset)
+	preimage = binary.BigEndian.AppendUint64(preimage, amount)
+	preimage = append(preimage, to[:]...)
+	return preimage
+}
+
+// ComputeUnshieldPublicInputsHash computes the digest exposed as the first
+// public input of the shielded-ledger-v2 proof carried by Unshield.
+func ComputeUnshieldPublicInputsHash(asset uint8, amount uint64, to codec.Address) [32]byte {
+	return sha256.Sum256(BuildUnshieldPublicInputsPreimage(asset, amount, to))
+}
+
+// ClearingOrdersTag exposes the domain tag of the clearing-v1 orders digest
+// to circuit code.
+func ClearingOrdersTag() *big.Int {
+	return clearingOrdersTag.BigInt(new(big.Int))
+}
+
+// ComputeClearingOrdersDigest commits to a window's eligible orders in
+// canonical trader order. It is MiMC over the domain tag and
+// ClearingV1MaxOrders slots of (trader high, trader low, side, limit price,
+// quantity); unused slots are zero.
+func ComputeClearingOrdersDigest(orders []BatchOrder) (ids.ID, error) {
+	if len(orders) > ClearingV1MaxOrders {
+		return ids.Empty, ErrTooManyClearingOrders
+	}
+	elems := make([]fr.Element, 1, 1+5*ClearingV1MaxOrders)
+	elems[0] = clearingOrdersTag
+	for i := 0; i < ClearingV1MaxOrders; i++ {
+		var slot [5]fr.Element
+		if i < len(orders) {
+			order := orders[i]
+			slot[0].SetBytes(order.Trader[:ClearingTraderHighBytes])
+			slot[1].SetBytes(order.Trader[ClearingTraderHighBytes:])
+			slot[2].SetUint64(uint64(order.Side))
+			slot[3].SetUint64(order.LimitPrice)
+			slot[4].SetUint64(order.Quantity)
+		}
+		elems = append(elems, slot[:]...)
+	}
+	digest := shielded.Hash(elems...)
+	return ids.ID(digest.Bytes()), nil
+}
+
+// BuildClearingV1PublicInputsPreimage canonicalizes clear-batch inputs and
+// the orders digest into the preimage of the clearing-v1 public input.
+func BuildClearingV1PublicInputsPreimage(
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	totalVolume uint64,
+	fillsHash []byte,
+	ordersDigest ids.ID,
+) []byte {
+	preimage := buildBatchPublicInputsPreimage(ClearingV1InputsDomainTag, marketID, windowID, clearPrice, totalVolume, fillsHash)
+	return append(preimage, ordersDigest[:]...)
+}
+
+// ComputeClearingV1PublicInputsHash computes the digest a clearing-v1 proof
+// exposes. Unlike the other circuits it also binds the orders the clearing
+// was computed from.
+func ComputeClearingV1PublicInputsHash(
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	totalVolume uint64,
+	fillsHash []byte,
+	orders []BatchOrder,
+) ([32]byte, error) {
+	ordersDigest, err := ComputeClearingOrdersDigest(orders)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(BuildClearingV1PublicInputsPreimage(
+		marketID,
+		windowID,
+		clearPrice,
+		totalVolume,
+		fillsHash,
+		ordersDigest,
+	)), nil
+}
+
+// ClearMiMCTag exposes the domain tag of the clearhash-mimc-v1 digest to
+// circuit code.
+func ClearMiMCTag() *big.Int {
+	return clearMiMCTag.BigInt(new(big.Int))
+}
+
+// BuildClearMiMCPublicInputsPreimage encodes clear-batch inputs as
+// ClearMiMCPreimageElements big-endian field elements of 32 bytes each.
+// Every element is canonical, so the layout is injective.
+func BuildClearMiMCPublicInputsPreimage(
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	totalVolume uint64,
+	fillsHash []byte,
+) ([]byte, error) {
+	elems, err := clearMiMCElements(marketID, windowID, clearPrice, totalVolume, fillsHash)
+	if err != nil {
+		return nil, err
+	}
+	preimage := make([]byte, 0, len(elems)*fr.Bytes)
+	for i := range elems {
+		b := elems[i].Bytes()
+		preimage = append(preimage, b[:]...)
+	}
+	return preimage, nil
+}
+
+// ComputeClearMiMCPublicInputsHash is the clearhash-mimc-v1 counterpart of
+// ComputeClearPublicInputsHash: MiMC over BN254 instead of SHA-256, which is
+// far cheaper to prove. The digest is a canonical field element in
+// big-endian form.
+func ComputeClearMiMCPublicInputsHash(
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	totalVolume uint64,
+	fillsHash []byte,
+) ([32]byte, error) {
+	elems, err := clearMiMCElements(marketID, windowID, clearPrice, totalVolume, fillsHash)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	digest := shielded.Hash(elems...)
+	return digest.Bytes(), nil
+}
+
+func clearMiMCElements(
+	marketID ids.ID,
+	windowID uint64,
+	clearPrice uint64,
+	totalVolume uint64,
+	fillsHash []byte,
+) ([]fr.Element, error) {
+	if len(fillsHash) != ExpectedFillsHashSize {
+		return nil, ErrInvalidFillsHashSize
+	}
+	elems := make([]fr.Element, ClearMiMCPreimageElements)
+	elems[0] = clearMiMCTag
+	elems[1].SetBytes(marketID[:ids.IDLen/2])
+	elems[2].SetBytes(marketID[ids.IDLen/2:])
+	elems[3].SetUint64(windowID)
+	elems[4].SetUint64(clearPrice)
+	elems[5].SetUint64(totalVolume)
+	elems[6].SetBytes(fillsHash[:ExpectedFillsHashSize/2])
+	elems[7].SetBytes(fillsHash[ExpectedFillsHashSize/2:])
+	return elems, nil
+}
+
+// AggregateTag exposes the domain tag of the aggregate-v1 digest to circuit
+// code.
+func AggregateTag() *big.Int {
+	return aggregateTag.BigInt(new(big.Int))
+}
+
+// ComputeAggregatePublicInputsHash commits to the clearhash-mimc-v1 public
+// inputs hashes of the windows an aggregate-v1 proof covers, in order. It is
+// MiMC over the domain tag, the window count and AggregateV1MaxWindows hash
+// slots; unused slots are zero.
+func ComputeAggregatePublicInputsHash(windowHashes [][]byte) ([32]byte, error) {
+	if len(windowHashes) == 0 || len(windowHashes) > AggregateV1MaxWindows {
+		return [32]byte{}, ErrInvalidAggregateInput
+	}
+	elems := make([]fr.Element, 2+AggregateV1MaxWindows)
+	elems[0] = aggregateTag
+	elems[1].SetUint64(uint64(len(windowHashes)))
+	for i, hash := range windowHashes {
+		if len(hash) != fr.Bytes {
+			return [32]byte{}, ErrInvalidAggregateInput
+		}
+		if err := elems[2+i].SetBytesCanonical(hash); err != nil {
+			return [32]byte{}, ErrInvalidAggregateInput
+		}
+	}
+	digest := shielded.Hash(elems...)
+	return digest.Bytes(), nil
+}
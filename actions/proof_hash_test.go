@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"testing"
 
 	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
 )
 
 func TestBuildShieldedLedgerPublicInputsPreimageLayout(t *testing.T) {
@@ -99,3 +102,41 @@ func TestComputeShieldedLedgerPublicInputsHashMatchesCanonicalPreimage(t *testin
 		t.Fatalf("hash mismatch")
 	}
 }
+
+func TestComputeClearingV1PublicInputsHashBindsOrders(t *testing.T) {
+	var marketID ids.ID
+	marketID[0] = 0x42
+	fillsHash := make([]byte, ExpectedFillsHashSize)
+
+	var buyer, seller codec.Address
+	buyer[0], seller[0] = 1, 2
+	orders := []BatchOrder{
+		{Trader: buyer, Side: OrderSideBuy, LimitPrice: 6000, Quantity: 10},
+		{Trader: seller, Side: OrderSideSell, LimitPrice: 4000, Quantity: 10},
+	}
+	hash, err := ComputeClearingV1PublicInputsHash(marketID, 3, 4000, 10, fillsHash, orders)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	ordersDigest, err := ComputeClearingOrdersDigest(orders)
+	if err != nil {
+		t.Fatalf("orders digest: %v", err)
+	}
+	if want := sha256.Sum256(BuildClearingV1PublicInputsPreimage(marketID, 3, 4000, 10, fillsHash, ordersDigest)); hash != want {
+		t.Fatalf("hash mismatch")
+	}
+
+	changed := append([]BatchOrder(nil), orders...)
+	changed[0].Quantity++
+	other, err := ComputeClearingV1PublicInputsHash(marketID, 3, 4000, 10, fillsHash, changed)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if other == hash {
+		t.Fatalf("hash does not bind order quantities")
+	}
+
+	if _, err := ComputeClearingOrdersDigest(make([]BatchOrder, ClearingV1MaxOrders+1)); !errors.Is(err, ErrTooManyClearingOrders) {
+		t.Fatalf("expected ErrTooManyClearingOrders, got %v", err)
+	}
+}
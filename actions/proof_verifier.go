@@ -2,12 +2,18 @@ package actions
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"strings"
 	"sync"
 
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/state"
+
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
 	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
 )
 
@@ -20,10 +26,15 @@ const (
 )
 
 // BatchProofVerifier performs consensus-critical proof verification.
+//
+// verifyingKey is the key registered on-chain for the circuit and proof type,
+// or nil when governance has not registered one; only then may the verifier
+// fall back to its node-local key.
 type BatchProofVerifier interface {
 	Verify(
 		proofType uint8,
 		circuitID string,
+		verifyingKey []byte,
 		proof []byte,
 		publicInputsHash []byte,
 		publicWitness []byte,
@@ -38,7 +49,10 @@ var (
 
 // ConfigureBatchProofVerifier installs or clears the process-wide proof verifier.
 //
-// When strict=true and verifier=nil, proof-gated clears fail-closed.
+// A proof for a circuit with a registered key is always verified against it:
+// without a verifier it fails closed whatever strict says. strict only
+// decides circuits with no registered key, whose proofs pass unverified when
+// strict=false and verifier=nil.
 func ConfigureBatchProofVerifier(verifier BatchProofVerifier, strict bool) {
 	proofVerifierMu.Lock()
 	defer proofVerifierMu.Unlock()
@@ -105,35 +119,302 @@ func BuildProofEnvelopeWithCircuit(
 	return out, nil
 }
 
+// verifyBatchProofInConsensus verifies a batch proof of proofType against the
+// proof rules in effect. The proof's circuit must match one of the rules, and
+// a rule that pins a key hash requires that exact key to be registered. It
+// returns the rule the proof was accepted under: its proof type, registry
+// circuit ID and the hash of the registered key, if any. checked is false
+// when a non-strict node accepted the proof without a verifier running.
 func verifyBatchProofInConsensus(
-	requiredProofType uint8,
+	ctx context.Context,
+	im state.Immutable,
+	rules []storage.ProofRule,
+	proofType uint8,
 	proofBlob []byte,
 	publicInputsHash []byte,
-) error {
-	proofType, circuitID, proof, witness, hasEnvelope, err := parseProofEnvelope(proofBlob)
+) (storage.ProofRule, bool, error) {
+	envelopeType, circuitID, proof, witness, hasEnvelope, err := parseProofEnvelope(proofBlob)
 	if err != nil {
-		return err
+		return storage.ProofRule{}, false, err
+	}
+	if hasEnvelope && envelopeType != proofType {
+		return storage.ProofRule{}, false, storage.ErrProofTypeMismatch
+	}
+	if strings.TrimSpace(circuitID) == mconsts.ProofCircuitAggregateV1 {
+		// Aggregates go through SubmitAggregateProof.
+		return storage.ProofRule{}, false, storage.ErrProofCircuitMismatch
+	}
+	rule, err := matchProofRule(rules, proofType, circuitID)
+	if err != nil {
+		return storage.ProofRule{}, false, err
+	}
+	vk, registered, err := registeredVerifyingKey(ctx, im, circuitID, proofType)
+	if err != nil {
+		return storage.ProofRule{}, false, err
+	}
+	if rule.KeyHash != ids.Empty && (!registered || vk.KeyHash != rule.KeyHash) {
+		return storage.ProofRule{}, false, storage.ErrVerifyingKeyMismatch
 	}
-	if hasEnvelope && proofType != requiredProofType {
-		return storage.ErrProofTypeMismatch
+	verified := storage.ProofRule{
+		ProofType: proofType,
+		CircuitID: registryCircuitID(circuitID),
+		KeyHash:   vk.KeyHash,
 	}
 
 	verifier, strict := getBatchProofVerifier()
-	if verifier == nil {
-		if strict {
-			return storage.ErrProofVerifierUnavailable
+	err = storage.ErrProofVerifierUnavailable
+	if verifier != nil {
+		err = verifier.Verify(proofType, circuitID, vk.Key, proof, publicInputsHash, witness)
+	}
+	switch {
+	case err == nil:
+		return verified, true, nil
+	case errors.Is(err, storage.ErrProofVerifierUnavailable):
+		// Only a non-strict node may pass a proof it cannot check, and never
+		// one for a circuit with a registered key.
+		if strict || registered {
+			return storage.ProofRule{}, false, err
 		}
-		return nil
+		return verified, false, nil
+	default:
+		return storage.ProofRule{}, false, invalidProofError(err, registered)
+	}
+}
+
+// verifyAggregateProofInConsensus verifies an aggregate-v1 proof of
+// proofType against the registered aggregate-v1 key, which must exist. The
+// covered windows are bound by clearhash-mimc-v1, so that circuit must be
+// accepted by the rules in effect. A rule that pins a key hash pins a
+// per-window key and accepts no aggregates. It returns the aggregate-v1 rule
+// and key that each covered window's record names.
+func verifyAggregateProofInConsensus(
+	ctx context.Context,
+	im state.Immutable,
+	rules []storage.ProofRule,
+	proofType uint8,
+	proofBlob []byte,
+	aggregateHash []byte,
+) (storage.ProofRule, error) {
+	envelopeType, circuitID, proof, witness, hasEnvelope, err := parseProofEnvelope(proofBlob)
+	if err != nil {
+		return storage.ProofRule{}, err
+	}
+	if !hasEnvelope {
+		return storage.ProofRule{}, storage.ErrInvalidProofEnvelope
+	}
+	if envelopeType != proofType {
+		return storage.ProofRule{}, storage.ErrProofTypeMismatch
+	}
+	if strings.TrimSpace(circuitID) != mconsts.ProofCircuitAggregateV1 {
+		return storage.ProofRule{}, storage.ErrProofCircuitMismatch
+	}
+	rule, err := matchProofRule(rules, proofType, mconsts.ProofCircuitClearHashMiMCV1)
+	if err != nil {
+		return storage.ProofRule{}, err
+	}
+	if rule.KeyHash != ids.Empty {
+		return storage.ProofRule{}, storage.ErrVerifyingKeyMismatch
+	}
+	// The node-local key file holds a per-window key, so aggregates need a
+	// registered one.
+	vk, registered, err := registeredVerifyingKey(ctx, im, circuitID, proofType)
+	if err != nil {
+		return storage.ProofRule{}, err
+	}
+	if !registered {
+		return storage.ProofRule{}, storage.ErrVerifyingKeyNotFound
+	}
+	verified := storage.ProofRule{
+		ProofType: proofType,
+		CircuitID: mconsts.ProofCircuitAggregateV1,
+		KeyHash:   vk.KeyHash,
+	}
+
+	verifier, _ := getBatchProofVerifier()
+	if verifier == nil {
+		return storage.ProofRule{}, storage.ErrProofVerifierUnavailable
+	}
+	if err := verifier.Verify(proofType, circuitID, vk.Key, proof, aggregateHash, witness); err != nil {
+		return storage.ProofRule{}, invalidProofError(err, true)
+	}
+	return verified, nil
+}
+
+// invalidProofError classifies a verifier error for the proof submissions.
+// Only a well-formed proof failing its pairing check against the key
+// registered on chain is objective evidence against the prover; it is
+// reported as ErrProofVerificationFailed, which slashes. Anything else, such
+// as a malformed proof or key or a node-local key, fails the transaction.
+func invalidProofError(err error, registered bool) error {
+	if registered && errors.Is(err, storage.ErrProofPairingFailed) {
+		return fmt.Errorf("%w: %v", storage.ErrProofVerificationFailed, err)
+	}
+	return err
+}
+
+// verifyShieldedProofInConsensus verifies a shielded-ledger-v2 Groth16 proof
+// outside of a batch and returns the transfer it proves. Unlike batch proofs
+// it always fails closed: without a verifier an unchecked proof could spend
+// notes it does not own, and without a registered key nodes would each check
+// it against their own local key.
+func verifyShieldedProofInConsensus(
+	ctx context.Context,
+	im state.Immutable,
+	proofBlob []byte,
+	publicInputsHash []byte,
+) (*shieldedTransfer, error) {
+	proofType, circuitID, proof, witness, hasEnvelope, err := parseProofEnvelope(proofBlob)
+	if err != nil {
+		return nil, err
+	}
+	if !hasEnvelope {
+		return nil, storage.ErrInvalidProofEnvelope
+	}
+	if proofType != mconsts.ProofTypeGroth16 {
+		return nil, storage.ErrProofTypeMismatch
 	}
-	if err := verifier.Verify(requiredProofType, circuitID, proof, publicInputsHash, witness); err != nil {
+	if strings.TrimSpace(circuitID) != mconsts.ProofCircuitShieldedLedgerV2 {
+		return nil, storage.ErrProofCircuitMismatch
+	}
+
+	verifier, _ := getBatchProofVerifier()
+	if verifier == nil {
+		return nil, storage.ErrProofVerifierUnavailable
+	}
+	vk, registered, err := registeredVerifyingKey(ctx, im, circuitID, proofType)
+	if err != nil {
+		return nil, err
+	}
+	if !registered {
+		return nil, storage.ErrVerifyingKeyNotFound
+	}
+	if err := verifier.Verify(proofType, circuitID, vk.Key, proof, publicInputsHash, witness); err != nil {
 		if errors.Is(err, storage.ErrProofVerifierUnavailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", storage.ErrProofVerificationFailed, err)
+	}
+	return proofShieldedTransfer(circuitID, witness)
+}
+
+// registeredVerifyingKey returns the governance-registered key for a proof.
+// A zero key with ok=false means none is registered.
+func registeredVerifyingKey(ctx context.Context, im state.Immutable, circuitID string, proofType uint8) (storage.VerifyingKey, bool, error) {
+	vk, err := storage.GetVerifyingKey(ctx, im, registryCircuitID(circuitID), proofType)
+	if errors.Is(err, storage.ErrVerifyingKeyNotFound) {
+		return storage.VerifyingKey{}, false, nil
+	}
+	if err != nil {
+		return storage.VerifyingKey{}, false, err
+	}
+	return vk, true, nil
+}
+
+// effectiveProofRules returns the proof rules accepted at timestamp. Without
+// a scheduled upgrade the config's RequiredProofType is accepted for any
+// circuit. A scheduled upgrade supersedes it: its From rule applies until
+// ActivateAtMs, both rules during the overlap, and To afterwards.
+func effectiveProofRules(
+	cfg storage.ProofConfig,
+	upgrade storage.ProofUpgrade,
+	scheduled bool,
+	timestamp int64,
+) []storage.ProofRule {
+	if !scheduled {
+		return []storage.ProofRule{{ProofType: cfg.RequiredProofType}}
+	}
+	switch {
+	case timestamp < upgrade.ActivateAtMs:
+		return []storage.ProofRule{upgrade.From}
+	case timestamp < upgrade.ActivateAtMs+upgrade.OverlapMs:
+		return []storage.ProofRule{upgrade.From, upgrade.To}
+	default:
+		return []storage.ProofRule{upgrade.To}
+	}
+}
+
+func loadProofRules(
+	ctx context.Context,
+	im state.Immutable,
+	cfg storage.ProofConfig,
+	timestamp int64,
+) ([]storage.ProofRule, error) {
+	upgrade, scheduled, err := storage.GetProofUpgrade(ctx, im)
+	if err != nil {
+		return nil, err
+	}
+	return effectiveProofRules(cfg, upgrade, scheduled, timestamp), nil
+}
+
+// checkProofRecordRule checks the rule a stored batch proof was accepted
+// under is still accepted by rules, so a proof submitted during an upgrade's
+// overlap cannot clear once its rule is retired.
+func checkProofRecordRule(rules []storage.ProofRule, record storage.BatchProofRecord) error {
+	if record.CircuitID == mconsts.ProofCircuitAggregateV1 {
+		// An aggregate attests to the window's clearhash-mimc-v1 digest but
+		// is no proof of the window. It clears only while that circuit is
+		// accepted without a pinned per-window key.
+		rule, err := matchProofRule(rules, record.ProofType, mconsts.ProofCircuitClearHashMiMCV1)
+		if err != nil {
 			return err
 		}
-		return fmt.Errorf("%w: %v", storage.ErrProofVerificationFailed, err)
+		if rule.KeyHash != ids.Empty {
+			return storage.ErrVerifyingKeyMismatch
+		}
+		return nil
+	}
+	rule, err := matchProofRule(rules, record.ProofType, record.CircuitID)
+	if err != nil {
+		return err
+	}
+	if rule.KeyHash != ids.Empty && rule.KeyHash != record.KeyHash {
+		return storage.ErrVerifyingKeyMismatch
 	}
 	return nil
 }
 
+// matchProofRule returns the first rule accepting a proof of proofType for
+// circuitID.
+func matchProofRule(rules []storage.ProofRule, proofType uint8, circuitID string) (storage.ProofRule, error) {
+	circuitID = registryCircuitID(circuitID)
+	typeMatched := false
+	for _, rule := range rules {
+		if rule.ProofType != proofType {
+			continue
+		}
+		typeMatched = true
+		if rule.CircuitID == "" || rule.CircuitID == circuitID {
+			return rule, nil
+		}
+	}
+	if typeMatched {
+		return storage.ProofRule{}, storage.ErrProofCircuitMismatch
+	}
+	return storage.ProofRule{}, storage.ErrProofTypeMismatch
+}
+
+// registryCircuitID maps a proof's circuit to its verifying-key registry
+// entry. Raw proofs and VZK1 envelopes carry no circuit and are clear-hash
+// v1 proofs.
+func registryCircuitID(circuitID string) string {
+	circuitID = strings.TrimSpace(circuitID)
+	if circuitID == "" {
+		return mconsts.ProofCircuitClearHashV1
+	}
+	return circuitID
+}
+
+// proofVerifyingKeyStateKeys adds the registry key read when verifying
+// proofBlob as proofType.
+func proofVerifyingKeyStateKeys(keys state.Keys, proofType uint8, proofBlob []byte) state.Keys {
+	_, circuitID, _, _, _, err := parseProofEnvelope(proofBlob)
+	if err != nil {
+		return keys
+	}
+	keys[string(storage.VerifyingKeyKey(registryCircuitID(circuitID), proofType))] = state.Read
+	return keys
+}
+
 func getBatchProofVerifier() (BatchProofVerifier, bool) {
 	proofVerifierMu.RLock()
 	defer proofVerifierMu.RUnlock()
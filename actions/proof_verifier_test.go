@@ -2,9 +2,18 @@ package actions
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/state"
+
 	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
 )
 
 type captureVerifier struct {
@@ -14,11 +23,13 @@ type captureVerifier struct {
 	wantHash   []byte
 	wantWit    []byte
 	gotCircuit string
+	gotKey     []byte
 }
 
 func (c *captureVerifier) Verify(
 	proofType uint8,
 	circuitID string,
+	verifyingKey []byte,
 	proof []byte,
 	publicInputsHash []byte,
 	publicWitness []byte,
@@ -36,6 +47,7 @@ func (c *captureVerifier) Verify(
 		c.t.Fatalf("unexpected public witness")
 	}
 	c.gotCircuit = circuitID
+	c.gotKey = verifyingKey
 	return nil
 }
 
@@ -110,6 +122,7 @@ func TestVerifyBatchProofPassesCircuitID(t *testing.T) {
 		t.Fatalf("build envelope: %v", err)
 	}
 
+	groth16Rules := []storage.ProofRule{{ProofType: mconsts.ProofTypeGroth16}}
 	verifier := &captureVerifier{
 		t:         t,
 		wantType:  mconsts.ProofTypeGroth16,
@@ -118,12 +131,48 @@ func TestVerifyBatchProofPassesCircuitID(t *testing.T) {
 		wantWit:   witness,
 	}
 	ConfigureBatchProofVerifier(verifier, true)
-	if err := verifyBatchProofInConsensus(mconsts.ProofTypeGroth16, envelope, hash); err != nil {
+	if _, _, err := verifyBatchProofInConsensus(context.Background(), state.ImmutableStorage{}, groth16Rules, mconsts.ProofTypeGroth16, envelope, hash); err != nil {
 		t.Fatalf("verify batch proof: %v", err)
 	}
 	if verifier.gotCircuit != mconsts.ProofCircuitClearHashV1 {
 		t.Fatalf("unexpected circuit passed to verifier: %s", verifier.gotCircuit)
 	}
+	if verifier.gotKey != nil {
+		t.Fatalf("expected no registered key, got %x", verifier.gotKey)
+	}
+
+	// A governance-registered key is passed through to the verifier.
+	key := []byte("registered-vk")
+	mu := mapState{}
+	if err := storage.PutVerifyingKey(context.Background(), mu, mconsts.ProofCircuitClearHashV1, mconsts.ProofTypeGroth16, storage.VerifyingKey{
+		Version: 1,
+		KeyHash: sha256.Sum256(key),
+		Key:     key,
+	}); err != nil {
+		t.Fatalf("put verifying key: %v", err)
+	}
+	if _, _, err := verifyBatchProofInConsensus(context.Background(), mu, groth16Rules, mconsts.ProofTypeGroth16, envelope, hash); err != nil {
+		t.Fatalf("verify batch proof: %v", err)
+	}
+	if !bytes.Equal(verifier.gotKey, key) {
+		t.Fatalf("registered key not passed to verifier: %x", verifier.gotKey)
+	}
+
+	// A registered key is never skipped, even by a non-strict node.
+	ConfigureBatchProofVerifier(nil, false)
+	if _, _, err := verifyBatchProofInConsensus(context.Background(), mu, groth16Rules, mconsts.ProofTypeGroth16, envelope, hash); !errors.Is(err, storage.ErrProofVerifierUnavailable) {
+		t.Fatalf("expected verifier unavailable with a registered key, got %v", err)
+	}
+
+	// A non-strict node without a verifier accepts proofs for unregistered
+	// circuits, but does not report them as checked.
+	_, checked, err := verifyBatchProofInConsensus(context.Background(), state.ImmutableStorage{}, groth16Rules, mconsts.ProofTypeGroth16, envelope, hash)
+	if err != nil {
+		t.Fatalf("expected an unregistered circuit to pass a non-strict node, got %v", err)
+	}
+	if checked {
+		t.Fatalf("proof reported as checked without a verifier")
+	}
 }
 
 func TestBuildProofEnvelopeWithCircuitRejectsInvalidCircuitID(t *testing.T) {
@@ -137,3 +186,337 @@ func TestBuildProofEnvelopeWithCircuitRejectsInvalidCircuitID(t *testing.T) {
 		t.Fatalf("expected invalid circuit id error")
 	}
 }
+
+func TestVerifyShieldedProofFailsClosed(t *testing.T) {
+	prevVerifier, prevStrict := getBatchProofVerifier()
+	defer ConfigureBatchProofVerifier(prevVerifier, prevStrict)
+
+	hash := []byte{0x05, 0x06}
+	envelope, err := BuildProofEnvelopeWithCircuit(
+		mconsts.ProofTypeGroth16,
+		mconsts.ProofCircuitShieldedLedgerV2,
+		[]byte{0x01},
+		[]byte{0x02},
+	)
+	if err != nil {
+		t.Fatalf("build envelope: %v", err)
+	}
+	// Shielded spends never accept unverified proofs, even in non-strict mode.
+	ConfigureBatchProofVerifier(nil, false)
+	if _, err := verifyShieldedProofInConsensus(context.Background(), state.ImmutableStorage{}, envelope, hash); !errors.Is(err, storage.ErrProofVerifierUnavailable) {
+		t.Fatalf("expected verifier unavailable, got %v", err)
+	}
+
+	clearEnvelope, err := BuildProofEnvelopeWithCircuit(
+		mconsts.ProofTypeGroth16,
+		mconsts.ProofCircuitClearHashV1,
+		[]byte{0x01},
+		[]byte{0x02},
+	)
+	if err != nil {
+		t.Fatalf("build envelope: %v", err)
+	}
+	if _, err := verifyShieldedProofInConsensus(context.Background(), state.ImmutableStorage{}, clearEnvelope, hash); !errors.Is(err, storage.ErrProofCircuitMismatch) {
+		t.Fatalf("expected circuit mismatch, got %v", err)
+	}
+	if _, err := verifyShieldedProofInConsensus(context.Background(), state.ImmutableStorage{}, []byte{0x01, 0x02, 0x03, 0x04}, hash); !errors.Is(err, storage.ErrInvalidProofEnvelope) {
+		t.Fatalf("expected invalid envelope for raw proof, got %v", err)
+	}
+}
+
+func TestEffectiveProofRulesAcrossUpgrade(t *testing.T) {
+	cfg := storage.ProofConfig{RequiredProofType: mconsts.ProofTypeGroth16}
+	upgrade := storage.ProofUpgrade{
+		From:         storage.ProofRule{ProofType: mconsts.ProofTypeGroth16},
+		To:           storage.ProofRule{ProofType: mconsts.ProofTypePlonk, CircuitID: mconsts.ProofCircuitShieldedLedgerV2},
+		ActivateAtMs: 1_000,
+		OverlapMs:    500,
+	}
+	accepts := func(timestamp int64, proofType uint8, circuitID string) bool {
+		_, err := matchProofRule(effectiveProofRules(cfg, upgrade, true, timestamp), proofType, circuitID)
+		return err == nil
+	}
+
+	if !accepts(999, mconsts.ProofTypeGroth16, mconsts.ProofCircuitClearHashV1) || accepts(999, mconsts.ProofTypePlonk, mconsts.ProofCircuitShieldedLedgerV2) {
+		t.Fatalf("only the old rule should apply before activation")
+	}
+	if !accepts(1_000, mconsts.ProofTypeGroth16, "") || !accepts(1_499, mconsts.ProofTypePlonk, mconsts.ProofCircuitShieldedLedgerV2) {
+		t.Fatalf("both rules should apply during the overlap")
+	}
+	if accepts(1_500, mconsts.ProofTypeGroth16, mconsts.ProofCircuitClearHashV1) || !accepts(1_500, mconsts.ProofTypePlonk, mconsts.ProofCircuitShieldedLedgerV2) {
+		t.Fatalf("only the new rule should apply after the overlap")
+	}
+	if _, err := matchProofRule(effectiveProofRules(cfg, upgrade, true, 2_000), mconsts.ProofTypePlonk, mconsts.ProofCircuitClearHashV1); !errors.Is(err, storage.ErrProofCircuitMismatch) {
+		t.Fatalf("expected circuit mismatch, got %v", err)
+	}
+
+	// Without an upgrade the configured proof type is accepted for any circuit.
+	if rules := effectiveProofRules(cfg, storage.ProofUpgrade{}, false, 2_000); len(rules) != 1 || rules[0].ProofType != mconsts.ProofTypeGroth16 || rules[0].CircuitID != "" {
+		t.Fatalf("unexpected rules without upgrade: %+v", rules)
+	}
+}
+
+func TestProofRecordRuleAcrossActivation(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	cfg := storage.ProofConfig{RequiredProofType: mconsts.ProofTypeGroth16}
+	newKey := ids.ID{0x07}
+	if err := storage.PutProofUpgrade(ctx, mu, storage.ProofUpgrade{
+		From:         storage.ProofRule{ProofType: mconsts.ProofTypeGroth16, CircuitID: mconsts.ProofCircuitClearHashV1},
+		To:           storage.ProofRule{ProofType: mconsts.ProofTypePlonk, CircuitID: mconsts.ProofCircuitShieldedLedgerV2, KeyHash: newKey},
+		ActivateAtMs: 1_000,
+		OverlapMs:    500,
+	}); err != nil {
+		t.Fatalf("put proof upgrade: %v", err)
+	}
+	oldProof := storage.BatchProofRecord{ProofType: mconsts.ProofTypeGroth16, CircuitID: mconsts.ProofCircuitClearHashV1}
+	newProof := storage.BatchProofRecord{ProofType: mconsts.ProofTypePlonk, CircuitID: mconsts.ProofCircuitShieldedLedgerV2, KeyHash: newKey}
+	staleKey := newProof
+	staleKey.KeyHash = ids.ID{0x08}
+
+	// A proof stored during the overlap may clear until the old rule retires.
+	tests := []struct {
+		name      string
+		record    storage.BatchProofRecord
+		timestamp int64
+		err       error
+	}{
+		{name: "old rule before activation", record: oldProof, timestamp: 999},
+		{name: "new rule before activation", record: newProof, timestamp: 999, err: storage.ErrProofTypeMismatch},
+		{name: "old rule during overlap", record: oldProof, timestamp: 1_499},
+		{name: "new rule during overlap", record: newProof, timestamp: 1_000},
+		{name: "old rule after overlap", record: oldProof, timestamp: 1_500, err: storage.ErrProofTypeMismatch},
+		{name: "new rule after overlap", record: newProof, timestamp: 1_500},
+		{name: "unpinned key after overlap", record: staleKey, timestamp: 1_500, err: storage.ErrVerifyingKeyMismatch},
+	}
+	for _, tt := range tests {
+		rules, err := loadProofRules(ctx, mu, cfg, tt.timestamp)
+		if err != nil {
+			t.Fatalf("%s: load proof rules: %v", tt.name, err)
+		}
+		if err := checkProofRecordRule(rules, tt.record); !errors.Is(err, tt.err) {
+			t.Fatalf("%s: unexpected error: got=%v want=%v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestAggregateRecordRule(t *testing.T) {
+	record := storage.BatchProofRecord{
+		ProofType: mconsts.ProofTypeGroth16,
+		CircuitID: mconsts.ProofCircuitAggregateV1,
+		KeyHash:   ids.ID{0x09},
+	}
+	tests := []struct {
+		name string
+		rule storage.ProofRule
+		err  error
+	}{
+		{name: "clearhash-mimc-v1", rule: storage.ProofRule{ProofType: mconsts.ProofTypeGroth16, CircuitID: mconsts.ProofCircuitClearHashMiMCV1}},
+		{name: "any circuit", rule: storage.ProofRule{ProofType: mconsts.ProofTypeGroth16}},
+		{name: "clearhash-v1", rule: storage.ProofRule{ProofType: mconsts.ProofTypeGroth16, CircuitID: mconsts.ProofCircuitClearHashV1}, err: storage.ErrProofCircuitMismatch},
+		{name: "pinned key", rule: storage.ProofRule{ProofType: mconsts.ProofTypeGroth16, CircuitID: mconsts.ProofCircuitClearHashMiMCV1, KeyHash: record.KeyHash}, err: storage.ErrVerifyingKeyMismatch},
+	}
+	for _, tt := range tests {
+		if err := checkProofRecordRule([]storage.ProofRule{tt.rule}, record); !errors.Is(err, tt.err) {
+			t.Fatalf("%s: unexpected error: got=%v want=%v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestVerifyBatchProofEnforcesPinnedKey(t *testing.T) {
+	prevVerifier, prevStrict := getBatchProofVerifier()
+	defer ConfigureBatchProofVerifier(prevVerifier, prevStrict)
+
+	envelope, err := BuildProofEnvelopeWithCircuit(mconsts.ProofTypeGroth16, mconsts.ProofCircuitClearHashV1, []byte{0x01}, []byte{0x02})
+	if err != nil {
+		t.Fatalf("build envelope: %v", err)
+	}
+	key := []byte("registered-vk")
+	rules := []storage.ProofRule{{
+		ProofType: mconsts.ProofTypeGroth16,
+		CircuitID: mconsts.ProofCircuitClearHashV1,
+		KeyHash:   sha256.Sum256(key),
+	}}
+	mu := mapState{}
+	ConfigureBatchProofVerifier(nil, false)
+	if _, _, err := verifyBatchProofInConsensus(context.Background(), mu, rules, mconsts.ProofTypeGroth16, envelope, nil); !errors.Is(err, storage.ErrVerifyingKeyMismatch) {
+		t.Fatalf("expected key mismatch before registration, got %v", err)
+	}
+	if err := storage.PutVerifyingKey(context.Background(), mu, mconsts.ProofCircuitClearHashV1, mconsts.ProofTypeGroth16, storage.VerifyingKey{
+		Version: 1,
+		KeyHash: sha256.Sum256(key),
+		Key:     key,
+	}); err != nil {
+		t.Fatalf("put verifying key: %v", err)
+	}
+	// A registered key is never skipped, even by a non-strict node.
+	if _, _, err := verifyBatchProofInConsensus(context.Background(), mu, rules, mconsts.ProofTypeGroth16, envelope, nil); !errors.Is(err, storage.ErrProofVerifierUnavailable) {
+		t.Fatalf("expected verifier unavailable with a registered key, got %v", err)
+	}
+	verifier := &captureVerifier{t: t, wantType: mconsts.ProofTypeGroth16, wantProof: []byte{0x01}, wantWit: []byte{0x02}}
+	ConfigureBatchProofVerifier(verifier, false)
+	verified, checked, err := verifyBatchProofInConsensus(context.Background(), mu, rules, mconsts.ProofTypeGroth16, envelope, nil)
+	if err != nil {
+		t.Fatalf("verify with pinned key: %v", err)
+	}
+	if verified != rules[0] || !checked || !bytes.Equal(verifier.gotKey, key) {
+		t.Fatalf("unexpected verified rule: %+v", verified)
+	}
+}
+
+// failVerifier rejects every proof with err.
+type failVerifier struct{ err error }
+
+func (f failVerifier) Verify(uint8, string, []byte, []byte, []byte, []byte) error {
+	return f.err
+}
+
+func TestVerifyBatchProofSlashesOnlyRegisteredPairingFailures(t *testing.T) {
+	prevVerifier, prevStrict := getBatchProofVerifier()
+	defer ConfigureBatchProofVerifier(prevVerifier, prevStrict)
+
+	envelope, err := BuildProofEnvelopeWithCircuit(mconsts.ProofTypeGroth16, mconsts.ProofCircuitClearHashV1, []byte{0x01}, []byte{0x02})
+	if err != nil {
+		t.Fatalf("build envelope: %v", err)
+	}
+	rules := []storage.ProofRule{{ProofType: mconsts.ProofTypeGroth16}}
+	registered := mapState{}
+	key := []byte("registered-vk")
+	if err := storage.PutVerifyingKey(context.Background(), registered, mconsts.ProofCircuitClearHashV1, mconsts.ProofTypeGroth16, storage.VerifyingKey{
+		Version: 1,
+		KeyHash: sha256.Sum256(key),
+		Key:     key,
+	}); err != nil {
+		t.Fatalf("put verifying key: %v", err)
+	}
+	pairing := fmt.Errorf("%w: pairing doesn't match", storage.ErrProofPairingFailed)
+
+	tests := []struct {
+		name  string
+		state state.Immutable
+		err   error
+		want  error
+	}{
+		{name: "registered key rejects proof", state: registered, err: pairing, want: storage.ErrProofVerificationFailed},
+		{name: "registered key is malformed", state: registered, err: storage.ErrInvalidVerifyingKey, want: storage.ErrInvalidVerifyingKey},
+		{name: "witness does not match", state: registered, err: storage.ErrProofPublicInputsMismatch, want: storage.ErrProofPublicInputsMismatch},
+		{name: "local key rejects proof", state: mapState{}, err: pairing, want: storage.ErrProofPairingFailed},
+	}
+	for _, tt := range tests {
+		ConfigureBatchProofVerifier(failVerifier{err: tt.err}, true)
+		_, _, err := verifyBatchProofInConsensus(context.Background(), tt.state, rules, mconsts.ProofTypeGroth16, envelope, nil)
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%s: unexpected error: got=%v want=%v", tt.name, err, tt.want)
+		}
+		// Only ErrProofVerificationFailed slashes the prover.
+		if tt.want != storage.ErrProofVerificationFailed && errors.Is(err, storage.ErrProofVerificationFailed) {
+			t.Fatalf("%s: error would slash: %v", tt.name, err)
+		}
+	}
+}
+
+func TestBatchProofRecordKeepsVerification(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := ids.GenerateTestID()
+	record := storage.BatchProofRecord{
+		ProofType:        mconsts.ProofTypeGroth16,
+		SubmittedAtMs:    2_000,
+		WindowCloseAtMs:  1_000,
+		PublicInputsHash: bytes.Repeat([]byte{0x01}, 32),
+		FillsHash:        bytes.Repeat([]byte{0x02}, 32),
+	}
+
+	// Records stored without a proof rule read back as legacy.
+	legacy := record
+	legacy.Legacy = true
+	if err := storage.PutBatchProofRecord(ctx, mu, marketID, 1, legacy); err != nil {
+		t.Fatalf("put legacy record: %v", err)
+	}
+	got, err := storage.GetBatchProofRecord(ctx, mu, marketID, 1)
+	if err != nil {
+		t.Fatalf("get legacy record: %v", err)
+	}
+	if !got.Legacy || got.Verified || got.CircuitID != "" {
+		t.Fatalf("unexpected legacy record: %+v", got)
+	}
+
+	record.CircuitID = mconsts.ProofCircuitShieldedLedgerV2
+	for windowID, verified := range map[uint64]bool{2: false, 3: true} {
+		record.Verified = verified
+		record.KeyHash = ids.GenerateTestID()
+		if err := storage.PutBatchProofRecord(ctx, mu, marketID, windowID, record); err != nil {
+			t.Fatalf("put record: %v", err)
+		}
+		got, err = storage.GetBatchProofRecord(ctx, mu, marketID, windowID)
+		if err != nil {
+			t.Fatalf("get record: %v", err)
+		}
+		if got.Legacy || got.Verified != verified || got.CircuitID != record.CircuitID || got.KeyHash != record.KeyHash {
+			t.Fatalf("verification not persisted: %+v", got)
+		}
+	}
+	if !bytes.Equal(got.PublicInputsHash, record.PublicInputsHash) {
+		t.Fatalf("public inputs hash not persisted")
+	}
+}
+
+func TestVerifyAggregateProofRules(t *testing.T) {
+	prevVerifier, prevStrict := getBatchProofVerifier()
+	defer ConfigureBatchProofVerifier(prevVerifier, prevStrict)
+
+	proof := []byte{0x01}
+	witness := []byte{0x02}
+	hash := []byte{0x03}
+	envelope, err := BuildProofEnvelopeWithCircuit(mconsts.ProofTypeGroth16, mconsts.ProofCircuitAggregateV1, proof, witness)
+	if err != nil {
+		t.Fatalf("build envelope: %v", err)
+	}
+	key := []byte("aggregate-vk")
+	mu := mapState{}
+	if err := storage.PutVerifyingKey(context.Background(), mu, mconsts.ProofCircuitAggregateV1, mconsts.ProofTypeGroth16, storage.VerifyingKey{
+		Version: 1,
+		KeyHash: sha256.Sum256(key),
+		Key:     key,
+	}); err != nil {
+		t.Fatalf("put verifying key: %v", err)
+	}
+	verifier := &captureVerifier{
+		t:         t,
+		wantType:  mconsts.ProofTypeGroth16,
+		wantProof: proof,
+		wantHash:  hash,
+		wantWit:   witness,
+	}
+	ConfigureBatchProofVerifier(verifier, true)
+
+	// Covered windows are recorded as aggregate-v1 under the aggregate key.
+	mimcRules := []storage.ProofRule{{ProofType: mconsts.ProofTypeGroth16, CircuitID: mconsts.ProofCircuitClearHashMiMCV1}}
+	verified, err := verifyAggregateProofInConsensus(context.Background(), mu, mimcRules, mconsts.ProofTypeGroth16, envelope, hash)
+	if err != nil {
+		t.Fatalf("verify aggregate: %v", err)
+	}
+	if verifier.gotCircuit != mconsts.ProofCircuitAggregateV1 || !bytes.Equal(verifier.gotKey, key) {
+		t.Fatalf("aggregate not verified against its registered key")
+	}
+	want := storage.ProofRule{ProofType: mconsts.ProofTypeGroth16, CircuitID: mconsts.ProofCircuitAggregateV1, KeyHash: sha256.Sum256(key)}
+	if verified != want {
+		t.Fatalf("unexpected verified rule: %+v", verified)
+	}
+
+	shaRules := []storage.ProofRule{{ProofType: mconsts.ProofTypeGroth16, CircuitID: mconsts.ProofCircuitClearHashV1}}
+	if _, err := verifyAggregateProofInConsensus(context.Background(), mu, shaRules, mconsts.ProofTypeGroth16, envelope, hash); !errors.Is(err, storage.ErrProofCircuitMismatch) {
+		t.Fatalf("expected circuit mismatch under a clearhash-v1 rule, got %v", err)
+	}
+	pinned := []storage.ProofRule{{ProofType: mconsts.ProofTypeGroth16, KeyHash: sha256.Sum256([]byte("per-window-vk"))}}
+	if _, err := verifyAggregateProofInConsensus(context.Background(), mu, pinned, mconsts.ProofTypeGroth16, envelope, hash); !errors.Is(err, storage.ErrVerifyingKeyMismatch) {
+		t.Fatalf("expected key mismatch under a pinned rule, got %v", err)
+	}
+	if _, err := verifyAggregateProofInConsensus(context.Background(), mapState{}, mimcRules, mconsts.ProofTypeGroth16, envelope, hash); !errors.Is(err, storage.ErrVerifyingKeyNotFound) {
+		t.Fatalf("expected a registered aggregate key to be required, got %v", err)
+	}
+	anyRules := []storage.ProofRule{{ProofType: mconsts.ProofTypeGroth16}}
+	if _, _, err := verifyBatchProofInConsensus(context.Background(), mu, anyRules, mconsts.ProofTypeGroth16, envelope, hash); !errors.Is(err, storage.ErrProofCircuitMismatch) {
+		t.Fatalf("expected aggregate rejected as a batch proof, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package actions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const ProverAssignmentDomainTag = "VEIL_PROVER_ASSIGN_V1"
+
+// AssignProvers draws committee.Primaries and then committee.Backups distinct
+// members for a window, skipping excluded ones. The draw is a Fisher-Yates
+// shuffle seeded by the window's revealed key, which no one can predict
+// before the decrypt committee reaches threshold, so provers cannot grind
+// their assignment. Excluding a member only moves later draws up a place.
+func AssignProvers(
+	committee storage.ProverCommittee,
+	marketID ids.ID,
+	windowID uint64,
+	windowKey []byte,
+	excluded map[codec.Address]struct{},
+) (primaries []codec.Address, backups []codec.Address) {
+	seedInput := make([]byte, 0, len(ProverAssignmentDomainTag)+ids.IDLen+8+len(windowKey))
+	seedInput = append(seedInput, ProverAssignmentDomainTag...)
+	seedInput = append(seedInput, marketID[:]...)
+	seedInput = binary.BigEndian.AppendUint64(seedInput, windowID)
+	seedInput = append(seedInput, windowKey...)
+	seed := sha256.Sum256(seedInput)
+
+	members := append([]codec.Address(nil), committee.Members...)
+	draws := int(committee.Primaries) + int(committee.Backups)
+	drawn := make([]codec.Address, 0, draws)
+	var step [sha256.Size + 4]byte
+	copy(step[:], seed[:])
+	for i := 0; i < len(members) && len(drawn) < draws; i++ {
+		binary.BigEndian.PutUint32(step[sha256.Size:], uint32(i))
+		digest := sha256.Sum256(step[:])
+		j := i + int(binary.BigEndian.Uint64(digest[:8])%uint64(len(members)-i))
+		members[i], members[j] = members[j], members[i]
+		if _, ok := excluded[members[i]]; ok {
+			continue
+		}
+		drawn = append(drawn, members[i])
+	}
+	primaryCount := min(int(committee.Primaries), len(drawn))
+	return drawn[:primaryCount], drawn[primaryCount:]
+}
+
+// BackupEligibleAtMs is when a window's backups may start acting: once
+// BackupDelayBips of the proof deadline has passed since the window closed.
+func BackupEligibleAtMs(committee storage.ProverCommittee, proofDeadlineMs int64, closeAtMs int64) int64 {
+	deadline := uint64(proofDeadlineMs)
+	bips := uint64(committee.BackupDelayBips)
+	delay := deadline/10_000*bips + deadline%10_000*bips/10_000
+	return closeAtMs + int64(delay)
+}
+
+// pinProverAssignment draws a window's provers when its key is revealed,
+// leaving out those jailed or underbonded at timestamp, and stores the draw.
+// Nothing is pinned while no prover committee is registered.
+func pinProverAssignment(
+	ctx context.Context,
+	mu state.Mutable,
+	cfg storage.ProofConfig,
+	marketID ids.ID,
+	windowID uint64,
+	closeAtMs int64,
+	windowKey []byte,
+	timestamp int64,
+) error {
+	committee, err := storage.GetProverCommittee(ctx, mu)
+	if errors.Is(err, storage.ErrProverCommitteeNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	excluded, err := ineligibleProvers(ctx, mu, timestamp)
+	if err != nil {
+		return err
+	}
+	primaries, backups := AssignProvers(committee, marketID, windowID, windowKey, excluded)
+	return storage.PutProverAssignment(ctx, mu, marketID, windowID, storage.ProverAssignment{
+		Primaries:          primaries,
+		Backups:            backups,
+		BackupEligibleAtMs: BackupEligibleAtMs(committee, cfg.ProofDeadlineMs, closeAtMs),
+	})
+}
+
+// LoadProverAssignment returns the provers pinned for a window when its key
+// was revealed. Windows revealed before a committee was registered are drawn
+// from the current committee, leaving out provers ineligible at timestamp.
+// It fails with ErrProverCommitteeNotFound when no committee is registered
+// and with ErrWindowNotRevealed until the window key is known.
+func LoadProverAssignment(
+	ctx context.Context,
+	im state.Immutable,
+	cfg storage.ProofConfig,
+	marketID ids.ID,
+	windowID uint64,
+	closeAtMs int64,
+	timestamp int64,
+) (storage.ProverAssignment, error) {
+	assignment, err := storage.GetProverAssignment(ctx, im, marketID, windowID)
+	if !errors.Is(err, storage.ErrProverAssignmentNotFound) {
+		return assignment, err
+	}
+	committee, err := storage.GetProverCommittee(ctx, im)
+	if err != nil {
+		return storage.ProverAssignment{}, err
+	}
+	excluded, err := ineligibleProvers(ctx, im, timestamp)
+	if err != nil {
+		return storage.ProverAssignment{}, err
+	}
+	tally, err := storage.GetWindowDecryption(ctx, im, marketID, windowID)
+	if err != nil {
+		return storage.ProverAssignment{}, err
+	}
+	if len(tally.WindowKey) == 0 {
+		return storage.ProverAssignment{}, storage.ErrWindowNotRevealed
+	}
+	primaries, backups := AssignProvers(committee, marketID, windowID, tally.WindowKey, excluded)
+	return storage.ProverAssignment{
+		Primaries:          primaries,
+		Backups:            backups,
+		BackupEligibleAtMs: BackupEligibleAtMs(committee, cfg.ProofDeadlineMs, closeAtMs),
+	}, nil
+}
+
+// ineligibleProvers returns the members left out of new assignments at
+// timestamp: those jailed and those bonded below MinProverBond.
+func ineligibleProvers(ctx context.Context, im state.Immutable, timestamp int64) (map[codec.Address]struct{}, error) {
+	excluded, err := jailedProvers(ctx, im, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	underbonded, err := storage.GetUnderbondedProvers(ctx, im)
+	if err != nil {
+		return nil, err
+	}
+	for _, prover := range underbonded {
+		excluded[prover] = struct{}{}
+	}
+	return excluded, nil
+}
+
+// authorizeProver checks actor may prove or clear a window at timestamp.
+// Primaries may act for the whole proof window and backups once their delay
+// has passed. Until a prover committee is registered only
+// cfg.ProverAuthority may act. A jailed prover may not act at all.
+func authorizeProver(
+	ctx context.Context,
+	im state.Immutable,
+	cfg storage.ProofConfig,
+	marketID ids.ID,
+	windowID uint64,
+	closeAtMs int64,
+	actor codec.Address,
+	timestamp int64,
+) error {
+	jailed, err := jailedProvers(ctx, im, timestamp)
+	if err != nil {
+		return err
+	}
+	if _, ok := jailed[actor]; ok {
+		return storage.ErrProverJailed
+	}
+	assignment, err := LoadProverAssignment(ctx, im, cfg, marketID, windowID, closeAtMs, timestamp)
+	if errors.Is(err, storage.ErrProverCommitteeNotFound) {
+		if actor != cfg.ProverAuthority {
+			return storage.ErrUnauthorized
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, prover := range assignment.Primaries {
+		if actor == prover {
+			return nil
+		}
+	}
+	for _, prover := range assignment.Backups {
+		if actor == prover {
+			if timestamp < assignment.BackupEligibleAtMs {
+				return storage.ErrBackupProverNotEligible
+			}
+			return nil
+		}
+	}
+	return storage.ErrProverNotAssigned
+}
+
+// proverAssignmentStateKeys adds the keys authorizeProver reads.
+func proverAssignmentStateKeys(keys state.Keys, marketID ids.ID, windowID uint64) state.Keys {
+	keys.Add(string(storage.ProverCommitteeKey()), state.Read)
+	keys.Add(string(storage.ProverJailsKey()), state.Read)
+	keys.Add(string(storage.UnderbondedProversKey()), state.Read)
+	keys.Add(string(storage.ProverAssignmentKey(marketID, windowID)), state.Read)
+	keys.Add(string(storage.WindowDecryptionKey(marketID, windowID)), state.Read)
+	return keys
+}
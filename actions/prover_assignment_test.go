@@ -0,0 +1,223 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func testProverCommittee(n int) storage.ProverCommittee {
+	committee := storage.ProverCommittee{Primaries: 5, Backups: 2, BackupDelayBips: 5_000}
+	for i := 0; i < n; i++ {
+		var addr codec.Address
+		addr[0] = 0x01
+		addr[1] = byte(i + 1)
+		committee.Members = append(committee.Members, addr)
+	}
+	return committee
+}
+
+func TestAssignProversIsDeterministicAndDistinct(t *testing.T) {
+	committee := testProverCommittee(20)
+	marketID := ids.GenerateTestID()
+	key := []byte("window-key")
+
+	primaries, backups := AssignProvers(committee, marketID, 7, key, nil)
+	if len(primaries) != 5 || len(backups) != 2 {
+		t.Fatalf("unexpected assignment size: primaries=%d backups=%d", len(primaries), len(backups))
+	}
+	seen := make(map[codec.Address]struct{})
+	for _, addr := range append(append([]codec.Address(nil), primaries...), backups...) {
+		if _, ok := seen[addr]; ok {
+			t.Fatalf("prover %s assigned twice", addr)
+		}
+		seen[addr] = struct{}{}
+	}
+
+	againPrimaries, againBackups := AssignProvers(committee, marketID, 7, key, nil)
+	for i := range primaries {
+		if againPrimaries[i] != primaries[i] {
+			t.Fatalf("primary %d not deterministic", i)
+		}
+	}
+	for i := range backups {
+		if againBackups[i] != backups[i] {
+			t.Fatalf("backup %d not deterministic", i)
+		}
+	}
+
+	otherPrimaries, _ := AssignProvers(committee, marketID, 7, []byte("other-key"), nil)
+	same := true
+	for i := range primaries {
+		if otherPrimaries[i] != primaries[i] {
+			same = false
+		}
+	}
+	if same {
+		t.Fatalf("assignment ignores the window key")
+	}
+}
+
+func TestAuthorizeProverBackupDelay(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := ids.GenerateTestID()
+	const (
+		windowID = uint64(3)
+		closeAt  = int64(10_000)
+	)
+	var authority codec.Address
+	authority[0] = 0xAA
+	cfg := storage.ProofConfig{ProofDeadlineMs: 8_000, ProverAuthority: authority}
+
+	// Without a committee, the single authority still proves.
+	if err := authorizeProver(ctx, mu, cfg, marketID, windowID, closeAt, authority, closeAt); err != nil {
+		t.Fatalf("authority without committee: %v", err)
+	}
+
+	committee := testProverCommittee(20)
+	if err := storage.PutProverCommittee(ctx, mu, committee); err != nil {
+		t.Fatalf("put committee: %v", err)
+	}
+	if err := authorizeProver(ctx, mu, cfg, marketID, windowID, closeAt, authority, closeAt); !errors.Is(err, storage.ErrWindowNotRevealed) {
+		t.Fatalf("expected unrevealed window, got %v", err)
+	}
+	key := []byte("window-key")
+	if err := storage.PutWindowDecryption(ctx, mu, marketID, windowID, storage.WindowDecryption{WindowKey: key}); err != nil {
+		t.Fatalf("put window decryption: %v", err)
+	}
+
+	primaries, backups := AssignProvers(committee, marketID, windowID, key, nil)
+	if err := authorizeProver(ctx, mu, cfg, marketID, windowID, closeAt, primaries[0], closeAt); err != nil {
+		t.Fatalf("primary at close: %v", err)
+	}
+	// BackupDelayBips of 50% opens backups 4s into the 8s proof window.
+	if err := authorizeProver(ctx, mu, cfg, marketID, windowID, closeAt, backups[0], closeAt+3_999); !errors.Is(err, storage.ErrBackupProverNotEligible) {
+		t.Fatalf("expected backup not yet eligible, got %v", err)
+	}
+	if err := authorizeProver(ctx, mu, cfg, marketID, windowID, closeAt, backups[0], closeAt+4_000); err != nil {
+		t.Fatalf("backup after delay: %v", err)
+	}
+	if err := authorizeProver(ctx, mu, cfg, marketID, windowID, closeAt, authority, closeAt); !errors.Is(err, storage.ErrProverNotAssigned) {
+		t.Fatalf("expected authority to be unassigned, got %v", err)
+	}
+}
+
+func TestRevealPinsProverAssignment(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	_, shares, members := putTestDecryptCommittee(t, mu)
+	governance := testTrader(0xCC)
+	if err := storage.PutTreasuryConfig(ctx, mu, storage.TreasuryConfig{Governance: governance}); err != nil {
+		t.Fatalf("put treasury config: %v", err)
+	}
+	if err := storage.PutTreasuryState(ctx, mu, storage.TreasuryState{}); err != nil {
+		t.Fatalf("put treasury state: %v", err)
+	}
+
+	// Only the last 7 of 20 members are bonded, exactly enough for 5
+	// primaries and 2 backups.
+	committee := testProverCommittee(20)
+	bonded := make(map[codec.Address]struct{})
+	for _, member := range committee.Members[13:] {
+		bonded[member] = struct{}{}
+		if err := storage.PutProverBond(ctx, mu, member, storage.ProverBond{Bonded: MinProverBond}); err != nil {
+			t.Fatalf("put bond: %v", err)
+		}
+	}
+	set := &SetProverCommittee{
+		Primaries:       committee.Primaries,
+		Backups:         committee.Backups,
+		BackupDelayBips: committee.BackupDelayBips,
+		Members:         committee.Members,
+	}
+	if _, err := set.Execute(ctx, nil, mu, 0, governance, ids.Empty); err != nil {
+		t.Fatalf("set prover committee: %v", err)
+	}
+	underbonded, err := storage.GetUnderbondedProvers(ctx, mu)
+	if err != nil {
+		t.Fatalf("get underbonded provers: %v", err)
+	}
+	if len(underbonded) != 13 {
+		t.Fatalf("unexpected underbonded provers: %d", len(underbonded))
+	}
+
+	// Window 4 closes at 25_000 and its deadline is 35_000.
+	const windowID = uint64(4)
+	for index := 1; index <= 2; index++ {
+		action := testRevealBatch(t, shares, marketID, index, windowID)
+		if _, err := action.Execute(ctx, nil, mu, 26_000, members[index-1], ids.Empty); err != nil {
+			t.Fatalf("reveal %d: %v", index, err)
+		}
+	}
+	pinned, err := storage.GetProverAssignment(ctx, mu, marketID, windowID)
+	if err != nil {
+		t.Fatalf("get prover assignment: %v", err)
+	}
+	if len(pinned.Primaries) != 5 || len(pinned.Backups) != 2 {
+		t.Fatalf("unexpected assignment: %+v", pinned)
+	}
+	for _, prover := range append(append([]codec.Address(nil), pinned.Primaries...), pinned.Backups...) {
+		if _, ok := bonded[prover]; !ok {
+			t.Fatalf("underbonded prover %x was assigned", prover[:2])
+		}
+	}
+
+	// Jailing one primary and unbonding another after the reveal does not
+	// reshuffle the window.
+	jailedPrimary, unbondedPrimary := pinned.Primaries[0], pinned.Primaries[1]
+	if _, err := penalizeInvalidProof(ctx, mu, jailedPrimary, 27_000); err != nil {
+		t.Fatalf("penalize: %v", err)
+	}
+	unbond := &UnbondProver{Amount: 1}
+	if _, err := unbond.Execute(ctx, nil, mu, 27_000, unbondedPrimary, ids.Empty); err != nil {
+		t.Fatalf("unbond: %v", err)
+	}
+	underbonded, err = storage.GetUnderbondedProvers(ctx, mu)
+	if err != nil {
+		t.Fatalf("get underbonded provers: %v", err)
+	}
+	if len(underbonded) != 15 {
+		t.Fatalf("slashed and unbonded primaries not marked underbonded: %d", len(underbonded))
+	}
+	assignment, err := LoadProverAssignment(ctx, mu, testWindowConfig, marketID, windowID, 25_000, 28_000)
+	if err != nil {
+		t.Fatalf("load prover assignment: %v", err)
+	}
+	for i := range pinned.Primaries {
+		if assignment.Primaries[i] != pinned.Primaries[i] {
+			t.Fatalf("primary %d reshuffled after reveal", i)
+		}
+	}
+	if err := authorizeProver(ctx, mu, testWindowConfig, marketID, windowID, 25_000, jailedPrimary, 28_000); !errors.Is(err, storage.ErrProverJailed) {
+		t.Fatalf("expected jailed primary to be rejected, got %v", err)
+	}
+	if err := authorizeProver(ctx, mu, testWindowConfig, marketID, windowID, 25_000, unbondedPrimary, 28_000); err != nil {
+		t.Fatalf("unbonded primary: %v", err)
+	}
+
+	// A primary jailed after the deadline still owes the missed proof; one
+	// jailed before it does not.
+	latePrimary := pinned.Primaries[2]
+	if _, err := penalizeInvalidProof(ctx, mu, latePrimary, 36_000); err != nil {
+		t.Fatalf("penalize: %v", err)
+	}
+	owed, err := missedProofProvers(ctx, mu, testWindowConfig, marketID, windowID, 25_000, 35_000, 40_000)
+	if err != nil {
+		t.Fatalf("missed proof provers: %v", err)
+	}
+	if len(owed) != 4 {
+		t.Fatalf("unexpected owed provers: %d", len(owed))
+	}
+	for i, prover := range owed {
+		if prover != pinned.Primaries[i+1] {
+			t.Fatalf("owed prover %d: got=%x want=%x", i, prover[:2], pinned.Primaries[i+1][:2])
+		}
+	}
+}
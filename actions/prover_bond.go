@@ -0,0 +1,206 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+
+	smath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+const (
+	// ProverUnbondCooldownMs is how long unbonding stake stays slashable
+	// before it can be withdrawn.
+	ProverUnbondCooldownMs int64 = 7 * 24 * 60 * 60 * 1000
+	// ProverJailMs is how long a jailed prover is left out of assignments.
+	ProverJailMs int64 = 7 * 24 * 60 * 60 * 1000
+	// MinProverBond is the bonded stake a committee member needs to be
+	// drawn for new windows.
+	MinProverBond uint64 = 10_000
+
+	MissedProofSlashBips  uint64 = 50
+	InvalidProofSlashBips uint64 = 500
+	// ProverMissJailThreshold is how many missed deadlines jail a prover.
+	ProverMissJailThreshold uint32 = 3
+)
+
+// slashProverBond takes bips of everything the prover has at stake, bonded
+// stake first and then unbonding stake. Half the slash is burned and the
+// rest is added to the treasury's locked pool. The updated bond is returned
+// unsaved.
+func slashProverBond(
+	ctx context.Context,
+	mu state.Mutable,
+	prover codec.Address,
+	bips uint64,
+) (storage.ProverBond, uint64, error) {
+	bond, err := storage.GetProverBond(ctx, mu, prover)
+	if err != nil {
+		return storage.ProverBond{}, 0, err
+	}
+	total, err := smath.Add(bond.Bonded, bond.Unbonding)
+	if err != nil {
+		return storage.ProverBond{}, 0, err
+	}
+	slashed := total/10_000*bips + total%10_000*bips/10_000
+	if slashed == 0 {
+		return bond, 0, nil
+	}
+	fromBonded := min(slashed, bond.Bonded)
+	bond.Bonded -= fromBonded
+	bond.Unbonding -= slashed - fromBonded
+
+	burned := slashed / 2
+	treasury, err := storage.GetTreasuryState(ctx, mu)
+	if err != nil {
+		return storage.ProverBond{}, 0, err
+	}
+	treasury.Locked, err = smath.Add(treasury.Locked, slashed-burned)
+	if err != nil {
+		return storage.ProverBond{}, 0, err
+	}
+	if err := storage.PutTreasuryState(ctx, mu, treasury); err != nil {
+		return storage.ProverBond{}, 0, err
+	}
+	return bond, slashed, nil
+}
+
+// penalizeMissedProof slashes a prover that let a window's deadline lapse
+// and jails it on every ProverMissJailThreshold-th miss.
+func penalizeMissedProof(ctx context.Context, mu state.Mutable, prover codec.Address, timestamp int64) (uint64, error) {
+	bond, slashed, err := slashProverBond(ctx, mu, prover, MissedProofSlashBips)
+	if err != nil {
+		return 0, err
+	}
+	bond.Misses++
+	if bond.Misses >= ProverMissJailThreshold {
+		bond.Misses = 0
+		if err := jailProver(ctx, mu, prover, timestamp); err != nil {
+			return 0, err
+		}
+	}
+	return slashed, putProverBond(ctx, mu, prover, bond)
+}
+
+// penalizeInvalidProof slashes and jails a prover whose proof failed
+// verification.
+func penalizeInvalidProof(ctx context.Context, mu state.Mutable, prover codec.Address, timestamp int64) (uint64, error) {
+	bond, slashed, err := slashProverBond(ctx, mu, prover, InvalidProofSlashBips)
+	if err != nil {
+		return 0, err
+	}
+	if err := jailProver(ctx, mu, prover, timestamp); err != nil {
+		return 0, err
+	}
+	return slashed, putProverBond(ctx, mu, prover, bond)
+}
+
+// putProverBond stores a prover's bond and keeps the committee's
+// underbonded list in step with it.
+func putProverBond(ctx context.Context, mu state.Mutable, prover codec.Address, bond storage.ProverBond) error {
+	if err := storage.PutProverBond(ctx, mu, prover, bond); err != nil {
+		return err
+	}
+	committee, err := storage.GetProverCommittee(ctx, mu)
+	if errors.Is(err, storage.ErrProverCommitteeNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(committee.Members, prover) {
+		return nil
+	}
+	underbonded, err := storage.GetUnderbondedProvers(ctx, mu)
+	if err != nil {
+		return err
+	}
+	i := slices.Index(underbonded, prover)
+	switch below := bond.Bonded < MinProverBond; {
+	case below && i < 0:
+		underbonded = append(underbonded, prover)
+	case !below && i >= 0:
+		underbonded = slices.Delete(underbonded, i, i+1)
+	default:
+		return nil
+	}
+	return storage.PutUnderbondedProvers(ctx, mu, underbonded)
+}
+
+// proverBondStateKeys adds the keys putProverBond touches.
+func proverBondStateKeys(keys state.Keys, prover codec.Address) state.Keys {
+	keys.Add(string(storage.ProverBondKey(prover)), state.All)
+	keys.Add(string(storage.ProverCommitteeKey()), state.Read)
+	keys.Add(string(storage.UnderbondedProversKey()), state.All)
+	return keys
+}
+
+// jailProver jails prover for ProverJailMs from timestamp, or keeps a longer
+// jail it is already serving. Expired jails are pruned on the way.
+func jailProver(ctx context.Context, mu state.Mutable, prover codec.Address, timestamp int64) error {
+	jails, err := storage.GetProverJails(ctx, mu)
+	if err != nil {
+		return err
+	}
+	untilMs := timestamp + ProverJailMs
+	kept := jails[:0]
+	for _, jail := range jails {
+		if jail.UntilMs <= timestamp {
+			continue
+		}
+		if jail.Prover == prover {
+			untilMs = max(untilMs, jail.UntilMs)
+			continue
+		}
+		kept = append(kept, jail)
+	}
+	kept = append(kept, storage.ProverJail{Prover: prover, UntilMs: untilMs})
+	return storage.PutProverJails(ctx, mu, kept)
+}
+
+// jailedProvers returns the provers still jailed at timestamp.
+func jailedProvers(ctx context.Context, im state.Immutable, timestamp int64) (map[codec.Address]struct{}, error) {
+	jails, err := storage.GetProverJails(ctx, im)
+	if err != nil {
+		return nil, err
+	}
+	jailed := make(map[codec.Address]struct{}, len(jails))
+	for _, jail := range jails {
+		if jail.UntilMs > timestamp {
+			jailed[jail.Prover] = struct{}{}
+		}
+	}
+	return jailed, nil
+}
+
+// proversJailedAt returns the provers that were serving a jail at
+// timestamp. A jail runs ProverJailMs from the prover's latest jailing, so
+// one that started after timestamp is not counted. Jails pruned since they
+// expired are not counted either.
+func proversJailedAt(ctx context.Context, im state.Immutable, timestamp int64) (map[codec.Address]struct{}, error) {
+	jails, err := storage.GetProverJails(ctx, im)
+	if err != nil {
+		return nil, err
+	}
+	jailed := make(map[codec.Address]struct{}, len(jails))
+	for _, jail := range jails {
+		if jail.UntilMs > timestamp && jail.UntilMs-ProverJailMs <= timestamp {
+			jailed[jail.Prover] = struct{}{}
+		}
+	}
+	return jailed, nil
+}
+
+// proverPenaltyStateKeys adds the keys a penalty against provers may touch.
+func proverPenaltyStateKeys(keys state.Keys, provers ...codec.Address) state.Keys {
+	keys.Add(string(storage.ProverJailsKey()), state.All)
+	keys.Add(string(storage.TreasuryStateKey()), state.Read|state.Write)
+	for _, prover := range provers {
+		keys = proverBondStateKeys(keys, prover)
+	}
+	return keys
+}
@@ -0,0 +1,115 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func TestUnbondProverCooldown(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	var prover codec.Address
+	prover[0] = 0x0C
+	if err := storage.SetBalance(ctx, mu, prover, 1_000); err != nil {
+		t.Fatalf("set balance: %v", err)
+	}
+
+	if _, err := (&BondProver{Amount: 600}).Execute(ctx, nil, mu, 0, prover, ids.Empty); err != nil {
+		t.Fatalf("bond: %v", err)
+	}
+	const now = int64(1_000_000)
+	if _, err := (&UnbondProver{Amount: 700}).Execute(ctx, nil, mu, now, prover, ids.Empty); !errors.Is(err, storage.ErrInsufficientProverBond) {
+		t.Fatalf("expected insufficient bond, got %v", err)
+	}
+	if _, err := (&UnbondProver{Amount: 400}).Execute(ctx, nil, mu, now, prover, ids.Empty); err != nil {
+		t.Fatalf("start unbond: %v", err)
+	}
+	if _, err := (&UnbondProver{}).Execute(ctx, nil, mu, now+ProverUnbondCooldownMs-1, prover, ids.Empty); !errors.Is(err, storage.ErrProverBondUnbonding) {
+		t.Fatalf("expected cooldown, got %v", err)
+	}
+	if _, err := (&UnbondProver{}).Execute(ctx, nil, mu, now+ProverUnbondCooldownMs, prover, ids.Empty); err != nil {
+		t.Fatalf("withdraw: %v", err)
+	}
+	balance, err := storage.GetBalance(ctx, mu, prover)
+	if err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+	bond, err := storage.GetProverBond(ctx, mu, prover)
+	if err != nil {
+		t.Fatalf("get bond: %v", err)
+	}
+	if balance != 800 || bond.Bonded != 200 || bond.Unbonding != 0 {
+		t.Fatalf("unexpected balance=%d bond=%+v", balance, bond)
+	}
+}
+
+func TestPenalizeInvalidProofSlashesAndJails(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	committee := testProverCommittee(20)
+	marketID := ids.GenerateTestID()
+	key := []byte("window-key")
+	primaries, _ := AssignProvers(committee, marketID, 1, key, nil)
+	prover := primaries[0]
+
+	if err := storage.PutTreasuryState(ctx, mu, storage.TreasuryState{Locked: 1_000}); err != nil {
+		t.Fatalf("put treasury state: %v", err)
+	}
+	if err := storage.PutProverBond(ctx, mu, prover, storage.ProverBond{Bonded: 8_000, Unbonding: 2_000, UnbondAtMs: 50}); err != nil {
+		t.Fatalf("put bond: %v", err)
+	}
+	const now = int64(10_000)
+	slashed, err := penalizeInvalidProof(ctx, mu, prover, now)
+	if err != nil {
+		t.Fatalf("penalize: %v", err)
+	}
+	// 5% of everything at stake, taken from the bonded stake first; half
+	// goes to the treasury and half is burned.
+	if slashed != 500 {
+		t.Fatalf("unexpected slash: %d", slashed)
+	}
+	bond, err := storage.GetProverBond(ctx, mu, prover)
+	if err != nil {
+		t.Fatalf("get bond: %v", err)
+	}
+	if bond.Bonded != 7_500 || bond.Unbonding != 2_000 {
+		t.Fatalf("unexpected bond: %+v", bond)
+	}
+	treasury, err := storage.GetTreasuryState(ctx, mu)
+	if err != nil {
+		t.Fatalf("get treasury state: %v", err)
+	}
+	if treasury.Locked != 1_250 {
+		t.Fatalf("unexpected treasury locked: %d", treasury.Locked)
+	}
+
+	jailed, err := jailedProvers(ctx, mu, now+ProverJailMs-1)
+	if err != nil {
+		t.Fatalf("jailed provers: %v", err)
+	}
+	if _, ok := jailed[prover]; !ok {
+		t.Fatalf("prover not jailed")
+	}
+	next, _ := AssignProvers(committee, marketID, 1, key, jailed)
+	for _, addr := range next {
+		if addr == prover {
+			t.Fatalf("jailed prover was assigned")
+		}
+	}
+	if next[0] != primaries[1] {
+		t.Fatalf("jail should move later draws up a place")
+	}
+	released, err := jailedProvers(ctx, mu, now+ProverJailMs)
+	if err != nil {
+		t.Fatalf("jailed provers: %v", err)
+	}
+	if len(released) != 0 {
+		t.Fatalf("jail did not expire")
+	}
+}
@@ -0,0 +1,206 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+
+	smath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+const (
+	RedeemWinningsComputeUnits = 3
+	MaxRedeemWinningsSize      = 256
+)
+
+var (
+	ErrUnmarshalEmptyRedeemWinnings              = errors.New("cannot unmarshal empty bytes as redeem_winnings")
+	_                               chain.Action = (*RedeemWinnings)(nil)
+)
+
+// RedeemWinnings settles the actor's positions in a resolved market once its
+// dispute window has closed: each share of the resolved outcome pays
+// OutcomePriceScale from the market pool and every position the actor holds
+// in the market is burned.
+type RedeemWinnings struct {
+	MarketID ids.ID `serialize:"true" json:"market_id"`
+}
+
+func (*RedeemWinnings) GetTypeID() uint8 {
+	return mconsts.RedeemWinningsID
+}
+
+func (t *RedeemWinnings) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
+	return state.Keys{
+		string(storage.MarketKey(t.MarketID)):                            state.Read,
+		string(storage.MarketResolutionKey(t.MarketID)):                  state.Read,
+		string(storage.MarketPoolKey(t.MarketID)):                        state.Read | state.Write,
+		string(storage.PositionKey(t.MarketID, BinaryOutcomeYes, actor)): state.Read | state.Write,
+		string(storage.PositionKey(t.MarketID, BinaryOutcomeNo, actor)):  state.Read | state.Write,
+		string(storage.BalanceKey(actor)):                                state.Read | state.Write,
+		string(storage.VAIBalanceKey(actor)):                             state.Read | state.Write,
+	}
+}
+
+func (t *RedeemWinnings) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxRedeemWinningsSize),
+		MaxSize: MaxRedeemWinningsSize,
+	}
+	p.PackByte(mconsts.RedeemWinningsID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalRedeemWinnings(bytes []byte) (chain.Action, error) {
+	t := &RedeemWinnings{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyRedeemWinnings
+	}
+	if bytes[0] != mconsts.RedeemWinningsID {
+		return nil, fmt.Errorf("unexpected redeem_winnings typeID: %d != %d", bytes[0], mconsts.RedeemWinningsID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *RedeemWinnings) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	status, _, _, resolvedOutcome, _, err := storage.GetMarket(ctx, mu, t.MarketID)
+	if err != nil {
+		return nil, err
+	}
+	if status != storage.MarketStatusResolved {
+		return nil, storage.ErrMarketNotResolved
+	}
+	// Markets resolved before resolution records were kept are final.
+	resolution, err := storage.GetMarketResolution(ctx, mu, t.MarketID)
+	if err != nil && !errors.Is(err, storage.ErrMarketNotResolved) {
+		return nil, err
+	}
+	if err == nil && timestamp < resolution.DisputeEndsAtMs {
+		return nil, storage.ErrDisputeWindowOpen
+	}
+
+	var winning, burned uint64
+	for _, outcome := range []uint8{BinaryOutcomeYes, BinaryOutcomeNo} {
+		shares, err := storage.GetPosition(ctx, mu, t.MarketID, outcome, actor)
+		if err != nil {
+			return nil, err
+		}
+		if shares == 0 {
+			continue
+		}
+		if outcome == resolvedOutcome {
+			winning = shares
+		} else {
+			burned = shares
+		}
+		if err := storage.DeletePosition(ctx, mu, t.MarketID, outcome, actor); err != nil {
+			return nil, err
+		}
+	}
+	if winning == 0 && burned == 0 {
+		return nil, storage.ErrNoPosition
+	}
+
+	pool, err := storage.GetMarketPool(ctx, mu, t.MarketID)
+	if err != nil {
+		return nil, err
+	}
+	payout, err := smath.Mul(winning, OutcomePriceScale)
+	if err != nil {
+		return nil, err
+	}
+	if pool.Collateral < payout {
+		return nil, storage.ErrInsufficientCollateral
+	}
+	pool.Collateral -= payout
+	if err := storage.PutMarketPool(ctx, mu, t.MarketID, pool); err != nil {
+		return nil, err
+	}
+
+	var balance uint64
+	if payout > 0 {
+		balance, err = addAssetBalance(ctx, mu, actor, pool.Asset, payout)
+	} else {
+		balance, err = getAssetBalance(ctx, mu, actor, pool.Asset)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RedeemWinningsResult{
+		Outcome:       resolvedOutcome,
+		Redeemed:      winning,
+		Burned:        burned,
+		Payout:        payout,
+		SenderBalance: balance,
+	}
+	return result.Bytes(), nil
+}
+
+func (*RedeemWinnings) ComputeUnits(chain.Rules) uint64 {
+	return RedeemWinningsComputeUnits
+}
+
+func (*RedeemWinnings) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*RedeemWinningsResult)(nil)
+
+type RedeemWinningsResult struct {
+	Outcome       uint8  `serialize:"true" json:"outcome"`
+	Redeemed      uint64 `serialize:"true" json:"redeemed"`
+	Burned        uint64 `serialize:"true" json:"burned"`
+	Payout        uint64 `serialize:"true" json:"payout"`
+	SenderBalance uint64 `serialize:"true" json:"sender_balance"`
+}
+
+func (*RedeemWinningsResult) GetTypeID() uint8 {
+	return mconsts.RedeemWinningsID
+}
+
+func (t *RedeemWinningsResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxRedeemWinningsSize,
+	}
+	p.PackByte(mconsts.RedeemWinningsID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalRedeemWinningsResult(b []byte) (codec.Typed, error) {
+	t := &RedeemWinningsResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,183 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func TestFillCostPairFundsOnePayout(t *testing.T) {
+	tests := []struct {
+		price    uint64
+		quantity uint64
+		err      error
+	}{
+		{price: 1, quantity: 7},
+		{price: 5_500, quantity: 100},
+		{price: OutcomePriceScale - 1, quantity: 3},
+		{price: 0, quantity: 1, err: ErrClearPriceOutOfRange},
+		{price: OutcomePriceScale, quantity: 1, err: ErrClearPriceOutOfRange},
+	}
+	for _, tt := range tests {
+		buy, err := fillCost(OrderSideBuy, tt.price, tt.quantity)
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("price %d: unexpected error: got=%v want=%v", tt.price, err, tt.err)
+		}
+		if tt.err != nil {
+			continue
+		}
+		sell, err := fillCost(OrderSideSell, tt.price, tt.quantity)
+		if err != nil {
+			t.Fatalf("price %d: sell cost: %v", tt.price, err)
+		}
+		// A matched YES and NO share pay in exactly what one share pays out.
+		if buy+sell != OutcomePriceScale*tt.quantity {
+			t.Fatalf("price %d: buy=%d sell=%d do not fund %d payouts", tt.price, buy, sell, tt.quantity)
+		}
+	}
+}
+
+// putResolvedMarket resolves a binary market holding collateral through
+// ResolveMarket at resolvedAt.
+func putResolvedMarket(t *testing.T, mu mapState, collateral uint64, outcome uint8, resolvedAt int64) ids.ID {
+	t.Helper()
+	ctx := context.Background()
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	if err := storage.PutMarketPool(ctx, mu, marketID, storage.MarketPool{Asset: AssetVEIL, Collateral: collateral}); err != nil {
+		t.Fatalf("put market pool: %v", err)
+	}
+	resolve := &ResolveMarket{MarketID: marketID, Outcome: outcome, Signature: []byte{0x01}}
+	if _, err := resolve.Execute(ctx, nil, mu, resolvedAt, codec.EmptyAddress, ids.Empty); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	return marketID
+}
+
+func TestRedeemWinningsPayout(t *testing.T) {
+	const resolvedAt = int64(1_000)
+	tests := []struct {
+		name       string
+		yes        uint64
+		no         uint64
+		err        error
+		wantPayout uint64
+		wantPool   uint64
+	}{
+		{name: "winner", yes: 3, wantPayout: 3 * OutcomePriceScale, wantPool: 2 * OutcomePriceScale},
+		{name: "both sides", yes: 2, no: 4, wantPayout: 2 * OutcomePriceScale, wantPool: 3 * OutcomePriceScale},
+		{name: "loser", no: 5, wantPool: 5 * OutcomePriceScale},
+		{name: "no position", err: storage.ErrNoPosition, wantPool: 5 * OutcomePriceScale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mu := mapState{}
+			marketID := putResolvedMarket(t, mu, 5*OutcomePriceScale, BinaryOutcomeYes, resolvedAt)
+			holder := testTrader(1)
+			for outcome, shares := range map[uint8]uint64{BinaryOutcomeYes: tt.yes, BinaryOutcomeNo: tt.no} {
+				if shares == 0 {
+					continue
+				}
+				if _, err := storage.AddPosition(ctx, mu, marketID, outcome, holder, shares); err != nil {
+					t.Fatalf("add position: %v", err)
+				}
+			}
+
+			redeem := &RedeemWinnings{MarketID: marketID}
+			if _, err := redeem.Execute(ctx, nil, mu, resolvedAt+MarketDisputeWindowMs-1, holder, ids.Empty); !errors.Is(err, storage.ErrDisputeWindowOpen) {
+				t.Fatalf("expected dispute window to block redemption, got %v", err)
+			}
+			out, err := redeem.Execute(ctx, nil, mu, resolvedAt+MarketDisputeWindowMs, holder, ids.Empty)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: got=%v want=%v", err, tt.err)
+			}
+			if tt.err == nil {
+				resultAny, err := UnmarshalRedeemWinningsResult(out)
+				if err != nil {
+					t.Fatalf("unmarshal result: %v", err)
+				}
+				result := resultAny.(*RedeemWinningsResult)
+				if result.Payout != tt.wantPayout || result.SenderBalance != tt.wantPayout || result.Burned != tt.no {
+					t.Fatalf("unexpected result: %+v", result)
+				}
+			}
+			pool, err := storage.GetMarketPool(ctx, mu, marketID)
+			if err != nil {
+				t.Fatalf("get market pool: %v", err)
+			}
+			if pool.Collateral != tt.wantPool {
+				t.Fatalf("unexpected pool collateral: got=%d want=%d", pool.Collateral, tt.wantPool)
+			}
+		})
+	}
+}
+
+func TestSettleDisputeOverturnsResolution(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	const resolvedAt = int64(1_000)
+	marketID := putResolvedMarket(t, mu, OutcomePriceScale, BinaryOutcomeYes, resolvedAt)
+	governance, disputer, holder := testTrader(0xA0), testTrader(0xB0), testTrader(0xC0)
+	if err := storage.PutTreasuryConfig(ctx, mu, storage.TreasuryConfig{Governance: governance}); err != nil {
+		t.Fatalf("put treasury config: %v", err)
+	}
+	if err := storage.SetBalance(ctx, mu, disputer, 50); err != nil {
+		t.Fatalf("set balance: %v", err)
+	}
+	if _, err := storage.AddPosition(ctx, mu, marketID, BinaryOutcomeNo, holder, 1); err != nil {
+		t.Fatalf("add position: %v", err)
+	}
+
+	dispute := &Dispute{MarketID: marketID, Bond: 50, Evidence: []byte("wrong outcome")}
+	if _, err := dispute.Execute(ctx, nil, mu, resolvedAt+MarketDisputeWindowMs, disputer, ids.Empty); !errors.Is(err, storage.ErrDisputeWindow) {
+		t.Fatalf("expected closed dispute window, got %v", err)
+	}
+	if _, err := dispute.Execute(ctx, nil, mu, resolvedAt+1, disputer, ids.Empty); err != nil {
+		t.Fatalf("dispute: %v", err)
+	}
+	redeem := &RedeemWinnings{MarketID: marketID}
+	if _, err := redeem.Execute(ctx, nil, mu, resolvedAt+MarketDisputeWindowMs, holder, ids.Empty); !errors.Is(err, storage.ErrMarketNotResolved) {
+		t.Fatalf("expected disputed market to block redemption, got %v", err)
+	}
+
+	settle := &SettleDispute{MarketID: marketID, Outcome: BinaryOutcomeNo, Disputer: disputer}
+	if _, err := settle.Execute(ctx, nil, mu, resolvedAt+2, disputer, ids.Empty); !errors.Is(err, storage.ErrUnauthorized) {
+		t.Fatalf("expected governance-only settlement, got %v", err)
+	}
+	const settledAt = resolvedAt + 3
+	if _, err := settle.Execute(ctx, nil, mu, settledAt, governance, ids.Empty); err != nil {
+		t.Fatalf("settle dispute: %v", err)
+	}
+	balance, err := storage.GetBalance(ctx, mu, disputer)
+	if err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+	if balance != 50 {
+		t.Fatalf("overturning dispute should refund its bond, got balance %d", balance)
+	}
+	if _, err := storage.GetDispute(ctx, mu, marketID); !errors.Is(err, storage.ErrDisputeNotFound) {
+		t.Fatalf("expected settled dispute to be removed, got %v", err)
+	}
+	if _, err := settle.Execute(ctx, nil, mu, settledAt, governance, ids.Empty); !errors.Is(err, storage.ErrMarketNotDisputed) {
+		t.Fatalf("expected settled dispute not to settle twice, got %v", err)
+	}
+	if _, err := dispute.Execute(ctx, nil, mu, settledAt, disputer, ids.Empty); !errors.Is(err, storage.ErrDisputeWindow) {
+		t.Fatalf("expected settled market to be final, got %v", err)
+	}
+	out, err := redeem.Execute(ctx, nil, mu, settledAt, holder, ids.Empty)
+	if err != nil {
+		t.Fatalf("redeem after settlement: %v", err)
+	}
+	resultAny, err := UnmarshalRedeemWinningsResult(out)
+	if err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if result := resultAny.(*RedeemWinningsResult); result.Outcome != BinaryOutcomeNo || result.Payout != OutcomePriceScale {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
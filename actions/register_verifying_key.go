@@ -0,0 +1,196 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	plonkbn254 "github.com/consensys/gnark/backend/plonk/bn254"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const (
+	RegisterVerifyingKeyComputeUnits = 3
+	MaxRegisterVerifyingKeySize      = storage.MaxVerifyingKeySize + 256
+)
+
+var (
+	ErrUnmarshalEmptyRegisterVerifyingKey              = errors.New("cannot unmarshal empty bytes as register_verifying_key")
+	_                                     chain.Action = (*RegisterVerifyingKey)(nil)
+)
+
+// RegisterVerifyingKey lets governance publish the verifying key for a
+// circuit and proof type. The new version becomes the active key that every
+// validator verifies proofs against, replacing any node-local key file.
+type RegisterVerifyingKey struct {
+	CircuitID string `serialize:"true" json:"circuit_id"`
+	ProofType uint8  `serialize:"true" json:"proof_type"`
+	Version   uint32 `serialize:"true" json:"version"`
+	Key       []byte `serialize:"true" json:"key"`
+}
+
+func (*RegisterVerifyingKey) GetTypeID() uint8 {
+	return mconsts.RegisterVerifyingKeyID
+}
+
+func (t *RegisterVerifyingKey) StateKeys(_ codec.Address, _ ids.ID) state.Keys {
+	circuitID := strings.TrimSpace(t.CircuitID)
+	return state.Keys{
+		string(storage.TreasuryConfigKey()):                                       state.Read,
+		string(storage.VerifyingKeyKey(circuitID, t.ProofType)):                   state.All,
+		string(storage.VerifyingKeyVersionKey(circuitID, t.ProofType, t.Version)): state.All,
+	}
+}
+
+func (t *RegisterVerifyingKey) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxRegisterVerifyingKeySize),
+		MaxSize: MaxRegisterVerifyingKeySize,
+	}
+	p.PackByte(mconsts.RegisterVerifyingKeyID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalRegisterVerifyingKey(bytes []byte) (chain.Action, error) {
+	t := &RegisterVerifyingKey{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyRegisterVerifyingKey
+	}
+	if bytes[0] != mconsts.RegisterVerifyingKeyID {
+		return nil, fmt.Errorf("unexpected register_verifying_key typeID: %d != %d", bytes[0], mconsts.RegisterVerifyingKeyID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *RegisterVerifyingKey) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	treasuryCfg, err := storage.GetTreasuryConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	if actor != treasuryCfg.Governance {
+		return nil, storage.ErrUnauthorized
+	}
+	circuitID := strings.TrimSpace(t.CircuitID)
+	if !slices.Contains(mconsts.ProofCircuitIDs, circuitID) {
+		return nil, storage.ErrUnsupportedProofCircuit
+	}
+	if t.ProofType != mconsts.ProofTypeGroth16 && t.ProofType != mconsts.ProofTypePlonk {
+		return nil, storage.ErrProofTypeMismatch
+	}
+	if err := validateVerifyingKey(t.ProofType, t.Key); err != nil {
+		return nil, err
+	}
+
+	vk := storage.VerifyingKey{
+		Version:        t.Version,
+		KeyHash:        sha256.Sum256(t.Key),
+		RegisteredAtMs: timestamp,
+		Key:            t.Key,
+	}
+	if err := storage.PutVerifyingKey(ctx, mu, circuitID, t.ProofType, vk); err != nil {
+		return nil, err
+	}
+
+	result := &RegisterVerifyingKeyResult{
+		CircuitID: circuitID,
+		ProofType: t.ProofType,
+		Version:   vk.Version,
+		KeyHash:   vk.KeyHash,
+	}
+	return result.Bytes(), nil
+}
+
+// validateVerifyingKey checks that key decodes as a BN254 verifying key for
+// proofType with nothing left over. Decoding rejects points that are not on
+// the curve or not in its subgroup, so a proof checked against a registered
+// key fails on its pairing rather than on a broken key.
+func validateVerifyingKey(proofType uint8, key []byte) error {
+	var vk io.ReaderFrom
+	switch proofType {
+	case mconsts.ProofTypeGroth16:
+		vk = new(groth16bn254.VerifyingKey)
+	case mconsts.ProofTypePlonk:
+		vk = new(plonkbn254.VerifyingKey)
+	default:
+		return storage.ErrProofTypeMismatch
+	}
+	n, err := vk.ReadFrom(bytes.NewReader(key))
+	if err != nil {
+		return fmt.Errorf("%w: %v", storage.ErrInvalidVerifyingKey, err)
+	}
+	if n != int64(len(key)) {
+		return fmt.Errorf("%w: %d trailing bytes", storage.ErrInvalidVerifyingKey, int64(len(key))-n)
+	}
+	return nil
+}
+
+func (*RegisterVerifyingKey) ComputeUnits(chain.Rules) uint64 {
+	return RegisterVerifyingKeyComputeUnits
+}
+
+func (*RegisterVerifyingKey) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*RegisterVerifyingKeyResult)(nil)
+
+type RegisterVerifyingKeyResult struct {
+	CircuitID string `serialize:"true" json:"circuit_id"`
+	ProofType uint8  `serialize:"true" json:"proof_type"`
+	Version   uint32 `serialize:"true" json:"version"`
+	KeyHash   ids.ID `serialize:"true" json:"key_hash"`
+}
+
+func (*RegisterVerifyingKeyResult) GetTypeID() uint8 {
+	return mconsts.RegisterVerifyingKeyID
+}
+
+func (t *RegisterVerifyingKeyResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxRegisterVerifyingKeySize,
+	}
+	p.PackByte(mconsts.RegisterVerifyingKeyID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalRegisterVerifyingKeyResult(b []byte) (codec.Typed, error) {
+	t := &RegisterVerifyingKeyResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
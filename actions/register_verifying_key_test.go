@@ -0,0 +1,70 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func TestRegisterVerifyingKeyRejectsMalformedKeys(t *testing.T) {
+	ctx := context.Background()
+	governance := testTrader(0xA0)
+	key, err := os.ReadFile("../zk-fixture-new/groth16_clearhash_vk.bin")
+	if err != nil {
+		t.Fatalf("read fixture key: %v", err)
+	}
+
+	// The first compressed G1 point with an x coordinate above the field
+	// modulus.
+	badPoint := bytes.Clone(key)
+	badPoint[0] |= 0x3F
+	for i := 1; i < 32; i++ {
+		badPoint[i] = 0xFF
+	}
+
+	tests := []struct {
+		name      string
+		proofType uint8
+		key       []byte
+		err       error
+	}{
+		{name: "groth16 key", proofType: mconsts.ProofTypeGroth16, key: key},
+		{name: "arbitrary bytes", proofType: mconsts.ProofTypeGroth16, key: []byte("registered-vk"), err: storage.ErrInvalidVerifyingKey},
+		{name: "truncated", proofType: mconsts.ProofTypeGroth16, key: key[:len(key)-1], err: storage.ErrInvalidVerifyingKey},
+		{name: "trailing bytes", proofType: mconsts.ProofTypeGroth16, key: append(bytes.Clone(key), 0x00), err: storage.ErrInvalidVerifyingKey},
+		{name: "invalid curve point", proofType: mconsts.ProofTypeGroth16, key: badPoint, err: storage.ErrInvalidVerifyingKey},
+		{name: "groth16 key as plonk", proofType: mconsts.ProofTypePlonk, key: key, err: storage.ErrInvalidVerifyingKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu := mapState{}
+			if err := storage.PutTreasuryConfig(ctx, mu, storage.TreasuryConfig{Governance: governance}); err != nil {
+				t.Fatalf("put treasury config: %v", err)
+			}
+			action := &RegisterVerifyingKey{
+				CircuitID: mconsts.ProofCircuitClearHashV1,
+				ProofType: tt.proofType,
+				Version:   1,
+				Key:       tt.key,
+			}
+			_, err := action.Execute(ctx, nil, mu, 1_000, governance, ids.Empty)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: got=%v want=%v", err, tt.err)
+			}
+			_, err = storage.GetVerifyingKey(ctx, mu, mconsts.ProofCircuitClearHashV1, tt.proofType)
+			if tt.err == nil && err != nil {
+				t.Fatalf("get verifying key: %v", err)
+			}
+			if tt.err != nil && !errors.Is(err, storage.ErrVerifyingKeyNotFound) {
+				t.Fatalf("malformed key was stored: %v", err)
+			}
+		})
+	}
+}
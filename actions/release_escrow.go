@@ -0,0 +1,190 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const (
+	ReleaseEscrowComputeUnits = 2
+	MaxReleaseEscrowSize      = 256
+)
+
+var (
+	ErrUnmarshalEmptyReleaseEscrow              = errors.New("cannot unmarshal empty bytes as release_escrow")
+	_                              chain.Action = (*ReleaseEscrow)(nil)
+)
+
+// ReleaseEscrow refunds the collateral locked by CommitOrder when the
+// window was abandoned (the market stopped trading, or the window was not
+// proven by its deadline or not cleared by its clear deadline). Once the
+// window has cleared it also returns any escrow the clear left behind.
+type ReleaseEscrow struct {
+	MarketID ids.ID `serialize:"true" json:"market_id"`
+	WindowID uint64 `serialize:"true" json:"window_id"`
+}
+
+func (*ReleaseEscrow) GetTypeID() uint8 {
+	return mconsts.ReleaseEscrowID
+}
+
+func (t *ReleaseEscrow) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
+	return state.Keys{
+		string(storage.MarketKey(t.MarketID)):                    state.Read,
+		string(storage.WindowStateKey(t.MarketID, t.WindowID)):   state.Read,
+		string(storage.ProofConfigKey()):                         state.Read,
+		string(storage.EscrowKey(t.MarketID, t.WindowID, actor)): state.Read | state.Write,
+		string(storage.BalanceKey(actor)):                        state.Read | state.Write,
+		string(storage.VAIBalanceKey(actor)):                     state.Read | state.Write,
+	}
+}
+
+func (t *ReleaseEscrow) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxReleaseEscrowSize),
+		MaxSize: MaxReleaseEscrowSize,
+	}
+	p.PackByte(mconsts.ReleaseEscrowID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalReleaseEscrow(bytes []byte) (chain.Action, error) {
+	t := &ReleaseEscrow{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyReleaseEscrow
+	}
+	if bytes[0] != mconsts.ReleaseEscrowID {
+		return nil, fmt.Errorf("unexpected release_escrow typeID: %d != %d", bytes[0], mconsts.ReleaseEscrowID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *ReleaseEscrow) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	escrow, err := storage.GetEscrow(ctx, mu, t.MarketID, t.WindowID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := storage.GetProofConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	window, _, err := loadWindowState(ctx, mu, t.MarketID, t.WindowID, cfg, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	phase := windowPhaseAt(window, timestamp)
+	cleared := phase == storage.WindowPhaseCleared
+	if !cleared {
+		abandoned, err := isEscrowWindowAbandoned(ctx, mu, t.MarketID, phase)
+		if err != nil {
+			return nil, err
+		}
+		if !abandoned {
+			return nil, storage.ErrEscrowLocked
+		}
+	}
+
+	if err := storage.DeleteEscrow(ctx, mu, t.MarketID, t.WindowID, actor); err != nil {
+		return nil, err
+	}
+	balance, err := addAssetBalance(ctx, mu, actor, escrow.Asset, escrow.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReleaseEscrowResult{
+		Asset:         escrow.Asset,
+		Released:      escrow.Amount,
+		Abandoned:     !cleared,
+		SenderBalance: balance,
+	}
+	return result.Bytes(), nil
+}
+
+// isEscrowWindowAbandoned reports whether an uncleared window can no longer
+// clear: either the market stopped trading, or the window passed its
+// deadline without being proven or its clear deadline without being cleared.
+func isEscrowWindowAbandoned(
+	ctx context.Context,
+	im state.Immutable,
+	marketID ids.ID,
+	phase uint8,
+) (bool, error) {
+	status, _, _, _, _, err := storage.GetMarket(ctx, im, marketID)
+	if err != nil {
+		return false, err
+	}
+	if status != storage.MarketStatusActive {
+		return true, nil
+	}
+	return phase == storage.WindowPhaseAbandoned, nil
+}
+
+func (*ReleaseEscrow) ComputeUnits(chain.Rules) uint64 {
+	return ReleaseEscrowComputeUnits
+}
+
+func (*ReleaseEscrow) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*ReleaseEscrowResult)(nil)
+
+type ReleaseEscrowResult struct {
+	Asset         uint8  `serialize:"true" json:"asset"`
+	Released      uint64 `serialize:"true" json:"released"`
+	Abandoned     bool   `serialize:"true" json:"abandoned"`
+	SenderBalance uint64 `serialize:"true" json:"sender_balance"`
+}
+
+func (*ReleaseEscrowResult) GetTypeID() uint8 {
+	return mconsts.ReleaseEscrowID
+}
+
+func (t *ReleaseEscrowResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxReleaseEscrowSize,
+	}
+	p.PackByte(mconsts.ReleaseEscrowID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalReleaseEscrowResult(b []byte) (codec.Typed, error) {
+	t := &ReleaseEscrowResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,282 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const (
+	ReportMissedProofComputeUnits = 5
+	MaxReportMissedProofSize      = 2048
+
+	// MissedProofReporterBounty is paid to the reporter from the fee
+	// router's operations budget, or whatever is left of it.
+	MissedProofReporterBounty uint64 = 100
+)
+
+var (
+	ErrMissedProofProversMismatch                   = errors.New("listed provers do not match the window's assignment")
+	ErrUnmarshalEmptyReportMissedProof              = errors.New("cannot unmarshal empty bytes as report_missed_proof")
+	_                                  chain.Action = (*ReportMissedProof)(nil)
+)
+
+// ReportMissedProof abandons a revealed window whose proof deadline passed
+// without a batch proof, and scars and slashes the provers that owed it.
+// Anyone may report. Provers lists the window's primaries in assignment
+// order, or ProverAuthority when no prover committee is registered, so their
+// Bloodsworn and bond keys are known up front.
+type ReportMissedProof struct {
+	MarketID ids.ID          `serialize:"true" json:"market_id"`
+	WindowID uint64          `serialize:"true" json:"window_id"`
+	Provers  []codec.Address `serialize:"true" json:"provers"`
+}
+
+func (*ReportMissedProof) GetTypeID() uint8 {
+	return mconsts.ReportMissedProofID
+}
+
+func (t *ReportMissedProof) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
+	keys := state.Keys{
+		string(storage.MarketKey(t.MarketID)):                  state.Read,
+		string(storage.ProofConfigKey()):                       state.Read,
+		string(storage.BatchProofKey(t.MarketID, t.WindowID)):  state.Read,
+		string(storage.WindowStateKey(t.MarketID, t.WindowID)): state.Read | state.Write,
+		string(storage.FeeRouterStateKey()):                    state.Read | state.Write,
+		string(storage.BalanceKey(actor)):                      state.All,
+	}
+	keys = proverAssignmentStateKeys(keys, t.MarketID, t.WindowID)
+	for _, prover := range t.Provers {
+		keys[string(storage.BloodswornKey(prover))] = state.All
+	}
+	return proverPenaltyStateKeys(keys, t.Provers...)
+}
+
+func (t *ReportMissedProof) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxReportMissedProofSize),
+		MaxSize: MaxReportMissedProofSize,
+	}
+	p.PackByte(mconsts.ReportMissedProofID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalReportMissedProof(bytes []byte) (chain.Action, error) {
+	t := &ReportMissedProof{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyReportMissedProof
+	}
+	if bytes[0] != mconsts.ReportMissedProofID {
+		return nil, fmt.Errorf("unexpected report_missed_proof typeID: %d != %d", bytes[0], mconsts.ReportMissedProofID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *ReportMissedProof) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	status, _, _, _, _, err := storage.GetMarket(ctx, mu, t.MarketID)
+	if err != nil {
+		return nil, err
+	}
+	if status != storage.MarketStatusActive {
+		return nil, storage.ErrMarketNotActive
+	}
+	cfg, err := storage.GetProofConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.RequireProof {
+		return nil, storage.ErrInvalidProofConfig
+	}
+
+	// Only a window whose key was revealed owed a proof; one that never
+	// revealed is the decrypt committee's miss, not the provers'.
+	window, err := storage.GetWindowState(ctx, mu, t.MarketID, t.WindowID)
+	if errors.Is(err, storage.ErrWindowStateNotFound) {
+		return nil, storage.ErrWindowNotRevealed
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch window.Phase {
+	case storage.WindowPhaseRevealed:
+	case storage.WindowPhaseProven:
+		return nil, storage.ErrProofAlreadySubmitted
+	case storage.WindowPhaseCleared:
+		return nil, storage.ErrBatchAlreadyCleared
+	case storage.WindowPhaseAbandoned:
+		return nil, storage.ErrWindowAbandoned
+	default:
+		return nil, storage.ErrWindowNotRevealed
+	}
+	if windowPhaseAt(window, timestamp) != storage.WindowPhaseAbandoned {
+		return nil, storage.ErrProofDeadlineNotReached
+	}
+	_, err = storage.GetBatchProofRecord(ctx, mu, t.MarketID, t.WindowID)
+	if err == nil {
+		return nil, storage.ErrProofAlreadySubmitted
+	}
+	if !errors.Is(err, storage.ErrProofNotFound) {
+		return nil, err
+	}
+
+	owed, err := missedProofProvers(ctx, mu, cfg, t.MarketID, t.WindowID, window.CloseAtMs, window.DeadlineAtMs, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if len(owed) != len(t.Provers) {
+		return nil, ErrMissedProofProversMismatch
+	}
+	for i, prover := range owed {
+		if t.Provers[i] != prover {
+			return nil, ErrMissedProofProversMismatch
+		}
+	}
+
+	if err := putWindowPhase(ctx, mu, t.MarketID, t.WindowID, window, storage.WindowPhaseAbandoned, timestamp); err != nil {
+		return nil, err
+	}
+	var slashed uint64
+	for _, prover := range owed {
+		bloodsworn, err := storage.GetBloodsworn(ctx, mu, prover)
+		if err != nil {
+			return nil, err
+		}
+		bloodsworn.ScarCount++
+		bloodsworn.ActiveStreak = 0
+		if err := storage.PutBloodsworn(ctx, mu, prover, bloodsworn); err != nil {
+			return nil, err
+		}
+		amount, err := penalizeMissedProof(ctx, mu, prover, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		slashed += amount
+	}
+
+	feeState, err := storage.GetFeeRouterState(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	bounty := min(MissedProofReporterBounty, feeState.OpsBudget)
+	feeState.OpsBudget -= bounty
+	if err := storage.PutFeeRouterState(ctx, mu, feeState); err != nil {
+		return nil, err
+	}
+	balance, err := storage.AddBalance(ctx, mu, actor, bounty)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReportMissedProofResult{
+		Scarred:         uint8(len(owed)),
+		Slashed:         slashed,
+		Bounty:          bounty,
+		ReporterBalance: balance,
+	}
+	return result.Bytes(), nil
+}
+
+// missedProofProvers returns who owed a window's proof: its primaries, or
+// ProverAuthority when no prover committee is registered. Backups are only
+// a fallback and are not penalized, and nor are provers serving a jail at
+// the deadline, who could not act. Jails are judged as of the deadline so
+// the answer does not depend on when the miss is reported.
+func missedProofProvers(
+	ctx context.Context,
+	im state.Immutable,
+	cfg storage.ProofConfig,
+	marketID ids.ID,
+	windowID uint64,
+	closeAtMs int64,
+	deadlineAtMs int64,
+	timestamp int64,
+) ([]codec.Address, error) {
+	jailed, err := proversJailedAt(ctx, im, deadlineAtMs)
+	if err != nil {
+		return nil, err
+	}
+	assignment, err := LoadProverAssignment(ctx, im, cfg, marketID, windowID, closeAtMs, timestamp)
+	if errors.Is(err, storage.ErrProverCommitteeNotFound) {
+		if _, ok := jailed[cfg.ProverAuthority]; ok {
+			return nil, nil
+		}
+		return []codec.Address{cfg.ProverAuthority}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	owed := make([]codec.Address, 0, len(assignment.Primaries))
+	for _, prover := range assignment.Primaries {
+		if _, ok := jailed[prover]; !ok {
+			owed = append(owed, prover)
+		}
+	}
+	return owed, nil
+}
+
+func (*ReportMissedProof) ComputeUnits(chain.Rules) uint64 {
+	return ReportMissedProofComputeUnits
+}
+
+func (*ReportMissedProof) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*ReportMissedProofResult)(nil)
+
+type ReportMissedProofResult struct {
+	Scarred         uint8  `serialize:"true" json:"scarred"`
+	Slashed         uint64 `serialize:"true" json:"slashed"`
+	Bounty          uint64 `serialize:"true" json:"bounty"`
+	ReporterBalance uint64 `serialize:"true" json:"reporter_balance"`
+}
+
+func (*ReportMissedProofResult) GetTypeID() uint8 {
+	return mconsts.ReportMissedProofID
+}
+
+func (t *ReportMissedProofResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxReportMissedProofSize,
+	}
+	p.PackByte(mconsts.ReportMissedProofID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalReportMissedProofResult(b []byte) (codec.Typed, error) {
+	t := &ReportMissedProofResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
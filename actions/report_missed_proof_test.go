@@ -0,0 +1,98 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func TestReportMissedProofScarsPrimaries(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := ids.GenerateTestID()
+	const windowID = uint64(4)
+	var authority, reporter codec.Address
+	authority[0] = 0xAA
+	reporter[0] = 0xBB
+
+	cfg := storage.ProofConfig{
+		RequireProof:      true,
+		RequiredProofType: mconsts.ProofTypeGroth16,
+		BatchWindowMs:     5_000,
+		ProofDeadlineMs:   10_000,
+		ProverAuthority:   authority,
+	}
+	if err := storage.PutProofConfig(ctx, mu, cfg); err != nil {
+		t.Fatalf("put proof config: %v", err)
+	}
+	if err := storage.PutMarket(ctx, mu, marketID, storage.MarketStatusActive, 2, 0, 0, []byte("q")); err != nil {
+		t.Fatalf("put market: %v", err)
+	}
+	if err := storage.PutFeeRouterState(ctx, mu, storage.FeeRouterState{OpsBudget: 60}); err != nil {
+		t.Fatalf("put fee router state: %v", err)
+	}
+	window, err := newWindowState(windowID, cfg, 20_000)
+	if err != nil {
+		t.Fatalf("new window state: %v", err)
+	}
+	window.Phase = storage.WindowPhaseRevealed
+	if err := storage.PutWindowState(ctx, mu, marketID, windowID, window); err != nil {
+		t.Fatalf("put window state: %v", err)
+	}
+	committee := testProverCommittee(20)
+	if err := storage.PutProverCommittee(ctx, mu, committee); err != nil {
+		t.Fatalf("put committee: %v", err)
+	}
+	key := []byte("window-key")
+	if err := storage.PutWindowDecryption(ctx, mu, marketID, windowID, storage.WindowDecryption{WindowKey: key}); err != nil {
+		t.Fatalf("put window decryption: %v", err)
+	}
+	primaries, backups := AssignProvers(committee, marketID, windowID, key, nil)
+
+	action := &ReportMissedProof{MarketID: marketID, WindowID: windowID, Provers: primaries}
+	if _, err := action.Execute(ctx, nil, mu, window.DeadlineAtMs, reporter, ids.Empty); !errors.Is(err, storage.ErrProofDeadlineNotReached) {
+		t.Fatalf("expected deadline not reached, got %v", err)
+	}
+	wrong := &ReportMissedProof{MarketID: marketID, WindowID: windowID, Provers: backups}
+	if _, err := wrong.Execute(ctx, nil, mu, window.DeadlineAtMs+1, reporter, ids.Empty); !errors.Is(err, ErrMissedProofProversMismatch) {
+		t.Fatalf("expected provers mismatch, got %v", err)
+	}
+
+	out, err := action.Execute(ctx, nil, mu, window.DeadlineAtMs+1, reporter, ids.Empty)
+	if err != nil {
+		t.Fatalf("report missed proof: %v", err)
+	}
+	resultAny, err := UnmarshalReportMissedProofResult(out)
+	if err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	result := resultAny.(*ReportMissedProofResult)
+	if result.Scarred != 5 || result.Bounty != 60 || result.ReporterBalance != 60 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	for _, prover := range primaries {
+		bloodsworn, err := storage.GetBloodsworn(ctx, mu, prover)
+		if err != nil {
+			t.Fatalf("get bloodsworn: %v", err)
+		}
+		if bloodsworn.ScarCount != 1 || bloodsworn.ActiveStreak != 0 {
+			t.Fatalf("primary not scarred: %+v", bloodsworn)
+		}
+	}
+	stored, err := storage.GetWindowState(ctx, mu, marketID, windowID)
+	if err != nil {
+		t.Fatalf("get window state: %v", err)
+	}
+	if stored.Phase != storage.WindowPhaseAbandoned {
+		t.Fatalf("window not abandoned: phase=%d", stored.Phase)
+	}
+	if _, err := action.Execute(ctx, nil, mu, window.DeadlineAtMs+2, reporter, ids.Empty); !errors.Is(err, storage.ErrWindowAbandoned) {
+		t.Fatalf("expected second report to fail, got %v", err)
+	}
+}
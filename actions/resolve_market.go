@@ -41,7 +41,8 @@ func (*ResolveMarket) GetTypeID() uint8 {
 
 func (t *ResolveMarket) StateKeys(_ codec.Address, _ ids.ID) state.Keys {
 	return state.Keys{
-		string(storage.MarketKey(t.MarketID)): state.Read | state.Write,
+		string(storage.MarketKey(t.MarketID)):           state.Read | state.Write,
+		string(storage.MarketResolutionKey(t.MarketID)): state.All,
 	}
 }
 
@@ -78,7 +79,7 @@ func (t *ResolveMarket) Execute(
 	ctx context.Context,
 	_ chain.Rules,
 	mu state.Mutable,
-	_ int64,
+	timestamp int64,
 	_ codec.Address,
 	_ ids.ID,
 ) ([]byte, error) {
@@ -108,6 +109,13 @@ func (t *ResolveMarket) Execute(
 	if err := storage.PutMarket(ctx, mu, t.MarketID, storage.MarketStatusResolved, outcomes, resolutionTime, t.Outcome, question); err != nil {
 		return nil, err
 	}
+	// Winnings stay locked until the resolution can no longer be disputed.
+	if err := storage.PutMarketResolution(ctx, mu, t.MarketID, storage.MarketResolution{
+		ResolvedAtMs:    timestamp,
+		DisputeEndsAtMs: timestamp + MarketDisputeWindowMs,
+	}); err != nil {
+		return nil, err
+	}
 
 	result := &ResolveMarketResult{
 		Outcome: t.Outcome,
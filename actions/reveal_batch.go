@@ -2,7 +2,6 @@ package actions
 
 import (
 	"context"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"time"
@@ -16,10 +15,11 @@ import (
 
 	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
 	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/examples/veilvm/threshold"
 )
 
 const (
-	RevealBatchComputeUnits = 3
+	RevealBatchComputeUnits = 6
 	MaxDecryptionShareSize  = 512
 	MaxRevealBatchSize      = 2048
 )
@@ -27,6 +27,8 @@ const (
 var (
 	ErrDecryptionShareEmpty                   = errors.New("decryption share is empty")
 	ErrDecryptionShareTooLarge                = errors.New("decryption share is too large")
+	ErrInvalidValidatorIndex                  = errors.New("validator index is not in the decrypt committee")
+	ErrNotCommitteeMember                     = errors.New("actor is not the committee member for this validator index")
 	ErrUnmarshalEmptyRevealBatch              = errors.New("cannot unmarshal empty bytes as reveal_batch")
 	_                            chain.Action = (*RevealBatch)(nil)
 )
@@ -44,8 +46,16 @@ func (*RevealBatch) GetTypeID() uint8 {
 
 func (t *RevealBatch) StateKeys(_ codec.Address, _ ids.ID) state.Keys {
 	return state.Keys{
-		string(storage.MarketKey(t.MarketID)):                   state.Read,
-		string(storage.OracleKey(t.MarketID, t.ValidatorIndex)): state.All,
+		string(storage.MarketKey(t.MarketID)):                                    state.Read,
+		string(storage.ProofConfigKey()):                                         state.Read,
+		string(storage.WindowStateKey(t.MarketID, t.WindowID)):                   state.All,
+		string(storage.DecryptCommitteeKey()):                                    state.Read,
+		string(storage.RevealShareKey(t.MarketID, t.WindowID, t.ValidatorIndex)): state.All,
+		string(storage.WindowDecryptionKey(t.MarketID, t.WindowID)):              state.All,
+		string(storage.ProverCommitteeKey()):                                     state.Read,
+		string(storage.ProverJailsKey()):                                         state.Read,
+		string(storage.UnderbondedProversKey()):                                  state.Read,
+		string(storage.ProverAssignmentKey(t.MarketID, t.WindowID)):              state.All,
 	}
 }
 
@@ -82,8 +92,8 @@ func (t *RevealBatch) Execute(
 	ctx context.Context,
 	_ chain.Rules,
 	mu state.Mutable,
-	_ int64,
-	_ codec.Address,
+	timestamp int64,
+	actor codec.Address,
 	_ ids.ID,
 ) (_ []byte, err error) {
 	start := time.Now()
@@ -107,17 +117,114 @@ func (t *RevealBatch) Execute(
 		return nil, storage.ErrMarketNotActive
 	}
 
-	// TODO(M2): require oracle/committee authorization for reveal submissions.
-	// Store decryption share keyed by validator index
-	k := storage.OracleKey(t.MarketID, t.ValidatorIndex)
-	v := make([]byte, 0, 8+len(t.DecryptionShare))
-	v = binary.BigEndian.AppendUint64(v, t.WindowID)
-	v = append(v, t.DecryptionShare...)
-	if err := mu.Insert(ctx, k, v); err != nil {
+	// Shares are accepted only after the window stops taking commits and
+	// before it is proven, cleared or abandoned.
+	proofCfg, err := storage.GetProofConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	window, stored, err := loadWindowState(ctx, mu, t.MarketID, t.WindowID, proofCfg, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	phase := windowPhaseAt(window, timestamp)
+	switch phase {
+	case storage.WindowPhaseFrozen, storage.WindowPhaseRevealed:
+	case storage.WindowPhaseOpen:
+		return nil, storage.ErrWindowNotRevealPhase
+	default:
+		return nil, storage.ErrStaleRevealShare
+	}
+
+	// Only the registered member may reveal for an index, and the share must
+	// verify against that member's key.
+	committee, err := storage.GetDecryptCommittee(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	if t.ValidatorIndex == 0 || int(t.ValidatorIndex) > len(committee.MemberKeys) {
+		return nil, ErrInvalidValidatorIndex
+	}
+	if actor != committee.MemberAddresses[t.ValidatorIndex-1] {
+		return nil, ErrNotCommitteeMember
+	}
+	// Only the committee the window opened under can open its envelopes.
+	if err := bindCommitteeEpoch(&window, stored, committee.Epoch); err != nil {
+		return nil, err
+	}
+	memberKey := committee.MemberKeys[t.ValidatorIndex-1]
+	if err := threshold.VerifyShare(memberKey, t.MarketID, t.WindowID, t.DecryptionShare); err != nil {
+		return nil, err
+	}
+
+	// Shares revealed under a committee that has since been replaced can't
+	// combine with the new members' shares, so an unrevealed window's tally
+	// starts over. A revealed key is final.
+	tally, err := storage.GetWindowDecryption(ctx, mu, t.MarketID, t.WindowID)
+	if err != nil {
 		return nil, err
 	}
+	if tally.Epoch != committee.Epoch {
+		if len(tally.WindowKey) > 0 {
+			return nil, storage.ErrDecryptCommitteeRotated
+		}
+		tally = storage.WindowDecryption{Epoch: committee.Epoch}
+	}
+	for _, share := range tally.Shares {
+		if share.ValidatorIndex == t.ValidatorIndex {
+			return nil, storage.ErrDuplicateRevealShare
+		}
+	}
 
-	result := &RevealBatchResult{ValidatorIndex: t.ValidatorIndex}
+	// Shares are kept per (market, window, index) so past windows stay
+	// auditable after later reveals.
+	if err := storage.PutRevealShare(ctx, mu, t.MarketID, t.WindowID, t.ValidatorIndex, t.DecryptionShare); err != nil {
+		return nil, err
+	}
+	tally.Shares = append(tally.Shares, storage.RevealShare{
+		ValidatorIndex: t.ValidatorIndex,
+		Share:          t.DecryptionShare,
+	})
+	members := make([]storage.RevealShare, 0, len(tally.Shares))
+	for _, share := range tally.Shares {
+		if share.ValidatorIndex >= 1 && int(share.ValidatorIndex) <= len(committee.MemberKeys) {
+			members = append(members, share)
+		}
+	}
+	if len(tally.WindowKey) == 0 && len(members) >= int(committee.Threshold) {
+		windowKey, err := combineRevealShares(members[:committee.Threshold])
+		if err != nil {
+			return nil, err
+		}
+		// Envelopes are opened with this key, so it must be the committee's
+		// key for the window and not just an interpolation of the shares.
+		if err := threshold.VerifyWindowKey(committee.PublicKey, t.MarketID, t.WindowID, windowKey); err != nil {
+			return nil, err
+		}
+		tally.WindowKey = windowKey
+		// The window's provers are drawn once, here, so jails and bond
+		// changes before its deadline do not reshuffle them.
+		if err := pinProverAssignment(ctx, mu, proofCfg, t.MarketID, t.WindowID, window.CloseAtMs, windowKey, timestamp); err != nil {
+			return nil, err
+		}
+	}
+	if err := storage.PutWindowDecryption(ctx, mu, t.MarketID, t.WindowID, tally); err != nil {
+		return nil, err
+	}
+	if len(tally.WindowKey) > 0 {
+		phase = storage.WindowPhaseRevealed
+	}
+	if phase != window.Phase {
+		if err := putWindowPhase(ctx, mu, t.MarketID, t.WindowID, window, phase, timestamp); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &RevealBatchResult{
+		ValidatorIndex: t.ValidatorIndex,
+		Shares:         uint16(len(tally.Shares)),
+		Revealed:       len(tally.WindowKey) > 0,
+	}
 	return result.Bytes(), nil
 }
 
@@ -133,6 +240,8 @@ var _ codec.Typed = (*RevealBatchResult)(nil)
 
 type RevealBatchResult struct {
 	ValidatorIndex uint32 `serialize:"true" json:"validator_index"`
+	Shares         uint16 `serialize:"true" json:"shares"`
+	Revealed       bool   `serialize:"true" json:"revealed"`
 }
 
 func (*RevealBatchResult) GetTypeID() uint8 {
@@ -159,3 +268,13 @@ func UnmarshalRevealBatchResult(b []byte) (codec.Typed, error) {
 	}
 	return t, nil
 }
+
+func combineRevealShares(shares []storage.RevealShare) ([]byte, error) {
+	indices := make([]uint32, len(shares))
+	values := make([][]byte, len(shares))
+	for i, share := range shares {
+		indices[i] = share.ValidatorIndex
+		values[i] = share.Share
+	}
+	return threshold.CombineShares(indices, values)
+}
@@ -0,0 +1,220 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/examples/veilvm/threshold"
+)
+
+// readState serves storage's *FromState getters from the map.
+func (m mapState) readState(ctx context.Context, keys [][]byte) ([][]byte, []error) {
+	values := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		values[i], errs[i] = m.GetValue(ctx, key)
+	}
+	return values, errs
+}
+
+// putTestDecryptCommittee registers a freshly dealt 2-of-3 decrypt
+// committee whose members are testTrader(1..3).
+func putTestDecryptCommittee(t *testing.T, mu mapState) ([]byte, []threshold.KeyShare, []codec.Address) {
+	t.Helper()
+	publicKey, memberKeys, shares, err := threshold.Deal(2, 3, rand.Reader)
+	if err != nil {
+		t.Fatalf("deal: %v", err)
+	}
+	members := []codec.Address{testTrader(1), testTrader(2), testTrader(3)}
+	if err := storage.PutDecryptCommittee(context.Background(), mu, storage.DecryptCommittee{
+		Threshold:       2,
+		PublicKey:       publicKey,
+		MemberKeys:      memberKeys,
+		MemberAddresses: members,
+	}); err != nil {
+		t.Fatalf("put decrypt committee: %v", err)
+	}
+	return publicKey, shares, members
+}
+
+// testRevealBatch builds member index's reveal for a window.
+func testRevealBatch(t *testing.T, shares []threshold.KeyShare, marketID ids.ID, index int, windowID uint64) *RevealBatch {
+	t.Helper()
+	share, err := shares[index-1].DecryptionShare(marketID, windowID)
+	if err != nil {
+		t.Fatalf("decryption share: %v", err)
+	}
+	return &RevealBatch{
+		MarketID:        marketID,
+		WindowID:        windowID,
+		DecryptionShare: share,
+		ValidatorIndex:  uint32(index),
+	}
+}
+
+func TestRevealBatchKeepsSharesPerWindow(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	publicKey, shares, members := putTestDecryptCommittee(t, mu)
+
+	// Window 4 closes at 25_000 and window 5 at 30_000.
+	steps := []struct {
+		index    int
+		windowID uint64
+		now      int64
+	}{
+		{index: 1, windowID: 4, now: 26_000},
+		{index: 1, windowID: 5, now: 31_000},
+		{index: 2, windowID: 4, now: 31_001},
+	}
+	for _, step := range steps {
+		action := testRevealBatch(t, shares, marketID, step.index, step.windowID)
+		if _, err := action.Execute(ctx, nil, mu, step.now, members[step.index-1], ids.Empty); err != nil {
+			t.Fatalf("reveal %d for window %d: %v", step.index, step.windowID, err)
+		}
+	}
+
+	// Revealing window 5 did not overwrite member 1's window 4 share.
+	for windowID, want := range map[uint64][]uint32{4: {1, 2}, 5: {1}} {
+		revealed, err := storage.GetRevealSharesFromState(ctx, mu.readState, marketID, windowID)
+		if err != nil {
+			t.Fatalf("get reveal shares: %v", err)
+		}
+		if len(revealed) != len(want) {
+			t.Fatalf("window %d: unexpected shares: %+v", windowID, revealed)
+		}
+		for i, share := range revealed {
+			expected := testRevealBatch(t, shares, marketID, int(want[i]), windowID)
+			if share.ValidatorIndex != want[i] || !bytes.Equal(share.Share, expected.DecryptionShare) {
+				t.Fatalf("window %d: unexpected share %d: %+v", windowID, i, share)
+			}
+		}
+	}
+	tally, err := storage.GetWindowDecryption(ctx, mu, marketID, 4)
+	if err != nil {
+		t.Fatalf("get window decryption: %v", err)
+	}
+	if err := threshold.VerifyWindowKey(publicKey, marketID, 4, tally.WindowKey); err != nil {
+		t.Fatalf("window key: %v", err)
+	}
+	window, err := storage.GetWindowState(ctx, mu, marketID, 4)
+	if err != nil {
+		t.Fatalf("get window state: %v", err)
+	}
+	if window.Phase != storage.WindowPhaseRevealed {
+		t.Fatalf("unexpected phase: %d", window.Phase)
+	}
+}
+
+func TestRevealBatchAuthorizesCommitteeMembers(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	_, shares, members := putTestDecryptCommittee(t, mu)
+	reveal := func(index int, windowID uint64) *RevealBatch {
+		return testRevealBatch(t, shares, marketID, index, windowID)
+	}
+	foreignWindow := reveal(1, 5)
+	foreignWindow.WindowID = 4
+	unknownIndex := reveal(1, 4)
+	unknownIndex.ValidatorIndex = 4
+
+	// Window 4 closes at 25_000 and its deadline is 35_000.
+	tests := []struct {
+		name   string
+		action *RevealBatch
+		actor  codec.Address
+		now    int64
+		err    error
+	}{
+		{name: "not a member", action: reveal(1, 4), actor: testTrader(9), now: 26_000, err: ErrNotCommitteeMember},
+		{name: "other member's index", action: reveal(1, 4), actor: members[1], now: 26_000, err: ErrNotCommitteeMember},
+		{name: "unknown index", action: unknownIndex, actor: members[0], now: 26_000, err: ErrInvalidValidatorIndex},
+		{name: "window still open", action: reveal(1, 6), actor: members[0], now: 31_000, err: storage.ErrWindowNotRevealPhase},
+		{name: "share for another window", action: foreignWindow, actor: members[0], now: 26_000, err: threshold.ErrInvalidShare},
+		{name: "member share", action: reveal(1, 4), actor: members[0], now: 26_000},
+		{name: "duplicate share", action: reveal(1, 4), actor: members[0], now: 26_001, err: storage.ErrDuplicateRevealShare},
+		{name: "past deadline", action: reveal(2, 4), actor: members[1], now: 35_001, err: storage.ErrStaleRevealShare},
+	}
+	for _, tt := range tests {
+		if _, err := tt.action.Execute(ctx, nil, mu, tt.now, tt.actor, ids.Empty); !errors.Is(err, tt.err) {
+			t.Fatalf("%s: unexpected error: got=%v want=%v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestRevealBatchAfterCommitteeRotation(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	marketID := putTestMarket(t, mu, AssetVEIL)
+	_, oldShares, oldMembers := putTestDecryptCommittee(t, mu)
+	governance := testTrader(0xCC)
+	if err := storage.PutTreasuryConfig(ctx, mu, storage.TreasuryConfig{Governance: governance}); err != nil {
+		t.Fatalf("put treasury config: %v", err)
+	}
+	for _, trader := range []codec.Address{testTrader(7), testTrader(8)} {
+		if _, err := addAssetBalance(ctx, mu, trader, AssetVEIL, 1_000); err != nil {
+			t.Fatalf("fund trader: %v", err)
+		}
+	}
+
+	// Window 4 closes at 25_000 and window 5 is open until 30_000. Both take
+	// orders and window 4 a reveal share under the old committee before
+	// governance replaces it.
+	if _, err := testCommitOrder(marketID, 4, AssetVEIL, 100).Execute(ctx, nil, mu, 21_000, testTrader(7), ids.Empty); err != nil {
+		t.Fatalf("commit to window 4: %v", err)
+	}
+	if _, err := testCommitOrder(marketID, 5, AssetVEIL, 100).Execute(ctx, nil, mu, 25_100, testTrader(7), ids.Empty); err != nil {
+		t.Fatalf("commit to window 5: %v", err)
+	}
+	if _, err := testRevealBatch(t, oldShares, marketID, 1, 4).Execute(ctx, nil, mu, 26_000, oldMembers[0], ids.Empty); err != nil {
+		t.Fatalf("old member reveal: %v", err)
+	}
+	publicKey, memberKeys, shares, err := threshold.Deal(2, 3, rand.Reader)
+	if err != nil {
+		t.Fatalf("deal: %v", err)
+	}
+	members := []codec.Address{testTrader(4), testTrader(5), testTrader(6)}
+	rotate := &SetDecryptCommittee{Threshold: 2, PublicKey: publicKey, MemberKeys: memberKeys, MemberAddresses: members}
+	if _, err := rotate.Execute(ctx, nil, mu, 26_500, governance, ids.Empty); err != nil {
+		t.Fatalf("rotate committee: %v", err)
+	}
+
+	// Envelopes in windows 4 and 5 are sealed to the old key: the new
+	// committee cannot reveal them and window 5 takes no more orders. They
+	// are abandoned at their deadlines and release their escrow.
+	if _, err := testRevealBatch(t, shares, marketID, 1, 4).Execute(ctx, nil, mu, 27_000, members[0], ids.Empty); !errors.Is(err, storage.ErrDecryptCommitteeRotated) {
+		t.Fatalf("expected rotated committee for window 4, got %v", err)
+	}
+	if _, err := testCommitOrder(marketID, 5, AssetVEIL, 100).Execute(ctx, nil, mu, 27_000, testTrader(8), ids.Empty); !errors.Is(err, storage.ErrDecryptCommitteeRotated) {
+		t.Fatalf("expected rotated committee for window 5, got %v", err)
+	}
+
+	// Window 6 opens under the new committee, which reveals it.
+	if _, err := testCommitOrder(marketID, 6, AssetVEIL, 100).Execute(ctx, nil, mu, 30_000, testTrader(8), ids.Empty); err != nil {
+		t.Fatalf("commit to window 6: %v", err)
+	}
+	for index := 1; index <= 2; index++ {
+		if _, err := testRevealBatch(t, shares, marketID, index, 6).Execute(ctx, nil, mu, 36_000, members[index-1], ids.Empty); err != nil {
+			t.Fatalf("new member reveal %d: %v", index, err)
+		}
+	}
+	tally, err := storage.GetWindowDecryption(ctx, mu, marketID, 6)
+	if err != nil {
+		t.Fatalf("get window decryption: %v", err)
+	}
+	if tally.Epoch != 1 {
+		t.Fatalf("unexpected tally epoch: %d", tally.Epoch)
+	}
+	if err := threshold.VerifyWindowKey(publicKey, marketID, 6, tally.WindowKey); err != nil {
+		t.Fatalf("window key: %v", err)
+	}
+}
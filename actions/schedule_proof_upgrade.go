@@ -0,0 +1,199 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const (
+	ScheduleProofUpgradeComputeUnits = 2
+	MaxScheduleProofUpgradeSize      = 256
+
+	// MaxProofUpgradeOverlapMs bounds how long two proof systems are
+	// accepted side by side.
+	MaxProofUpgradeOverlapMs int64 = 7 * 24 * 60 * 60 * 1000
+)
+
+var (
+	ErrUnmarshalEmptyScheduleProofUpgrade              = errors.New("cannot unmarshal empty bytes as schedule_proof_upgrade")
+	_                                     chain.Action = (*ScheduleProofUpgrade)(nil)
+)
+
+// ScheduleProofUpgrade lets governance move batch proofs to a new proof
+// type, circuit or verifying key at ActivateAtMs. The rule in effect when
+// the upgrade is scheduled becomes the old rule; both are accepted for
+// OverlapMs after activation. Scheduling again before activation replaces
+// the pending upgrade.
+type ScheduleProofUpgrade struct {
+	ProofType    uint8  `serialize:"true" json:"proof_type"`
+	CircuitID    string `serialize:"true" json:"circuit_id"`
+	KeyHash      ids.ID `serialize:"true" json:"key_hash"`
+	ActivateAtMs int64  `serialize:"true" json:"activate_at_ms"`
+	OverlapMs    int64  `serialize:"true" json:"overlap_ms"`
+}
+
+func (*ScheduleProofUpgrade) GetTypeID() uint8 {
+	return mconsts.ScheduleProofUpgradeID
+}
+
+func (*ScheduleProofUpgrade) StateKeys(_ codec.Address, _ ids.ID) state.Keys {
+	return state.Keys{
+		string(storage.TreasuryConfigKey()): state.Read,
+		string(storage.ProofConfigKey()):    state.Read,
+		string(storage.ProofUpgradeKey()):   state.All,
+	}
+}
+
+func (t *ScheduleProofUpgrade) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxScheduleProofUpgradeSize),
+		MaxSize: MaxScheduleProofUpgradeSize,
+	}
+	p.PackByte(mconsts.ScheduleProofUpgradeID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalScheduleProofUpgrade(bytes []byte) (chain.Action, error) {
+	t := &ScheduleProofUpgrade{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyScheduleProofUpgrade
+	}
+	if bytes[0] != mconsts.ScheduleProofUpgradeID {
+		return nil, fmt.Errorf("unexpected schedule_proof_upgrade typeID: %d != %d", bytes[0], mconsts.ScheduleProofUpgradeID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *ScheduleProofUpgrade) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	treasuryCfg, err := storage.GetTreasuryConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	if actor != treasuryCfg.Governance {
+		return nil, storage.ErrUnauthorized
+	}
+	to := storage.ProofRule{
+		ProofType: t.ProofType,
+		CircuitID: strings.TrimSpace(t.CircuitID),
+		KeyHash:   t.KeyHash,
+	}
+	if to.ProofType != mconsts.ProofTypeGroth16 && to.ProofType != mconsts.ProofTypePlonk {
+		return nil, storage.ErrInvalidProofUpgrade
+	}
+	if to.CircuitID != "" && !slices.Contains(mconsts.ProofCircuitIDs, to.CircuitID) {
+		return nil, storage.ErrUnsupportedProofCircuit
+	}
+	if to.CircuitID == mconsts.ProofCircuitAggregateV1 {
+		// Rules name the circuit binding each window; aggregates are
+		// accepted wherever clearhash-mimc-v1 is.
+		return nil, storage.ErrUnsupportedProofCircuit
+	}
+	if t.ActivateAtMs <= timestamp || t.OverlapMs < 0 || t.OverlapMs > MaxProofUpgradeOverlapMs {
+		return nil, storage.ErrInvalidProofUpgrade
+	}
+
+	proofCfg, err := storage.GetProofConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := loadProofRules(ctx, mu, proofCfg, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) != 1 {
+		return nil, storage.ErrProofUpgradeInProgress
+	}
+	if rules[0] == to {
+		return nil, storage.ErrInvalidProofUpgrade
+	}
+	upgrade := storage.ProofUpgrade{
+		From:          rules[0],
+		To:            to,
+		ActivateAtMs:  t.ActivateAtMs,
+		OverlapMs:     t.OverlapMs,
+		ScheduledAtMs: timestamp,
+	}
+	if err := storage.PutProofUpgrade(ctx, mu, upgrade); err != nil {
+		return nil, err
+	}
+
+	result := &ScheduleProofUpgradeResult{
+		FromProofType: upgrade.From.ProofType,
+		FromCircuitID: upgrade.From.CircuitID,
+		FromKeyHash:   upgrade.From.KeyHash,
+		ActivateAtMs:  upgrade.ActivateAtMs,
+		OverlapEndsMs: upgrade.ActivateAtMs + upgrade.OverlapMs,
+	}
+	return result.Bytes(), nil
+}
+
+func (*ScheduleProofUpgrade) ComputeUnits(chain.Rules) uint64 {
+	return ScheduleProofUpgradeComputeUnits
+}
+
+func (*ScheduleProofUpgrade) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*ScheduleProofUpgradeResult)(nil)
+
+type ScheduleProofUpgradeResult struct {
+	FromProofType uint8  `serialize:"true" json:"from_proof_type"`
+	FromCircuitID string `serialize:"true" json:"from_circuit_id"`
+	FromKeyHash   ids.ID `serialize:"true" json:"from_key_hash"`
+	ActivateAtMs  int64  `serialize:"true" json:"activate_at_ms"`
+	OverlapEndsMs int64  `serialize:"true" json:"overlap_ends_ms"`
+}
+
+func (*ScheduleProofUpgradeResult) GetTypeID() uint8 {
+	return mconsts.ScheduleProofUpgradeID
+}
+
+func (t *ScheduleProofUpgradeResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxScheduleProofUpgradeSize),
+		MaxSize: MaxScheduleProofUpgradeSize,
+	}
+	p.PackByte(mconsts.ScheduleProofUpgradeID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalScheduleProofUpgradeResult(b []byte) (codec.Typed, error) {
+	t := &ScheduleProofUpgradeResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,225 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/examples/veilvm/threshold"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const (
+	SetDecryptCommitteeComputeUnits = 10
+	MaxSetDecryptCommitteeSize      = 4096
+)
+
+var (
+	ErrCommitteeKeyMismatch                           = errors.New("member keys do not interpolate to the committee public key")
+	ErrInvalidCommitteeMember                         = errors.New("committee member address is empty or repeated")
+	ErrUnmarshalEmptySetDecryptCommittee              = errors.New("cannot unmarshal empty bytes as set_decrypt_committee")
+	_                                    chain.Action = (*SetDecryptCommittee)(nil)
+)
+
+// SetDecryptCommittee installs the threshold committee that decrypts order
+// envelopes. Validator index i+1 is registered to MemberAddresses[i] with
+// verification key MemberKeys[i]; only that address may reveal for it.
+// Replacing a committee bumps its epoch.
+type SetDecryptCommittee struct {
+	Threshold       uint16          `serialize:"true" json:"threshold"`
+	PublicKey       []byte          `serialize:"true" json:"public_key"`
+	MemberKeys      [][]byte        `serialize:"true" json:"member_keys"`
+	MemberAddresses []codec.Address `serialize:"true" json:"member_addresses"`
+}
+
+func (*SetDecryptCommittee) GetTypeID() uint8 {
+	return mconsts.SetDecryptCommitteeID
+}
+
+func (*SetDecryptCommittee) StateKeys(_ codec.Address, _ ids.ID) state.Keys {
+	return state.Keys{
+		string(storage.TreasuryConfigKey()):   state.Read,
+		string(storage.DecryptCommitteeKey()): state.All,
+	}
+}
+
+func (t *SetDecryptCommittee) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxSetDecryptCommitteeSize),
+		MaxSize: MaxSetDecryptCommitteeSize,
+	}
+	p.PackByte(mconsts.SetDecryptCommitteeID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalSetDecryptCommittee(bytes []byte) (chain.Action, error) {
+	t := &SetDecryptCommittee{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptySetDecryptCommittee
+	}
+	if bytes[0] != mconsts.SetDecryptCommitteeID {
+		return nil, fmt.Errorf("unexpected set_decrypt_committee typeID: %d != %d", bytes[0], mconsts.SetDecryptCommitteeID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *SetDecryptCommittee) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	_ int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	treasuryCfg, err := storage.GetTreasuryConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	if actor != treasuryCfg.Governance {
+		return nil, storage.ErrUnauthorized
+	}
+	if err := validateDecryptCommittee(t.Threshold, t.PublicKey, t.MemberKeys); err != nil {
+		return nil, err
+	}
+	if err := validateCommitteeMembers(t.MemberAddresses, len(t.MemberKeys)); err != nil {
+		return nil, err
+	}
+
+	// Each replacement starts a new epoch, so shares the previous committee
+	// revealed are never combined with the new committee's.
+	var epoch uint64
+	previous, err := storage.GetDecryptCommittee(ctx, mu)
+	switch {
+	case err == nil:
+		epoch = previous.Epoch + 1
+	case !errors.Is(err, storage.ErrDecryptCommitteeNotFound):
+		return nil, err
+	}
+	committee := storage.DecryptCommittee{
+		Threshold:       t.Threshold,
+		PublicKey:       t.PublicKey,
+		MemberKeys:      t.MemberKeys,
+		MemberAddresses: t.MemberAddresses,
+		Epoch:           epoch,
+	}
+	if err := storage.PutDecryptCommittee(ctx, mu, committee); err != nil {
+		return nil, err
+	}
+
+	result := &SetDecryptCommitteeResult{
+		Threshold: t.Threshold,
+		Members:   uint16(len(t.MemberKeys)),
+	}
+	return result.Bytes(), nil
+}
+
+// validateDecryptCommittee checks every key decodes and that all members lie
+// on one degree-(threshold-1) polynomial through the committee public key.
+// Every run of threshold consecutive members must interpolate to it, which
+// pins each later member to the polynomial fixed by the first run, so any
+// quorum of revealers combines the same window key.
+func validateDecryptCommittee(thresholdSize uint16, publicKey []byte, memberKeys [][]byte) error {
+	n := len(memberKeys)
+	if n == 0 || n > storage.MaxDecryptCommitteeMembers || thresholdSize == 0 || int(thresholdSize) > n {
+		return storage.ErrInvalidDecryptCommittee
+	}
+	if _, err := threshold.ParsePublicKey(publicKey); err != nil {
+		return err
+	}
+	for _, key := range memberKeys {
+		if _, err := threshold.ParsePublicKey(key); err != nil {
+			return err
+		}
+	}
+	t := int(thresholdSize)
+	indices := make([]uint32, t)
+	for start := 0; start+t <= n; start++ {
+		for i := range indices {
+			indices[i] = uint32(start + i + 1)
+		}
+		combined, err := threshold.CombinePublicKeys(indices, memberKeys[start:start+t])
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(combined, publicKey) {
+			return ErrCommitteeKeyMismatch
+		}
+	}
+	return nil
+}
+
+// validateCommitteeMembers requires one distinct, non-empty address per
+// member key so every validator index has exactly one authorized submitter.
+func validateCommitteeMembers(addresses []codec.Address, members int) error {
+	if len(addresses) != members {
+		return storage.ErrInvalidDecryptCommittee
+	}
+	seen := make(map[codec.Address]struct{}, len(addresses))
+	for _, addr := range addresses {
+		if addr == codec.EmptyAddress {
+			return ErrInvalidCommitteeMember
+		}
+		if _, ok := seen[addr]; ok {
+			return ErrInvalidCommitteeMember
+		}
+		seen[addr] = struct{}{}
+	}
+	return nil
+}
+
+func (*SetDecryptCommittee) ComputeUnits(chain.Rules) uint64 {
+	return SetDecryptCommitteeComputeUnits
+}
+
+func (*SetDecryptCommittee) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*SetDecryptCommitteeResult)(nil)
+
+type SetDecryptCommitteeResult struct {
+	Threshold uint16 `serialize:"true" json:"threshold"`
+	Members   uint16 `serialize:"true" json:"members"`
+}
+
+func (*SetDecryptCommitteeResult) GetTypeID() uint8 {
+	return mconsts.SetDecryptCommitteeID
+}
+
+func (t *SetDecryptCommitteeResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxSetDecryptCommitteeSize,
+	}
+	p.PackByte(mconsts.SetDecryptCommitteeID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalSetDecryptCommitteeResult(b []byte) (codec.Typed, error) {
+	t := &SetDecryptCommitteeResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
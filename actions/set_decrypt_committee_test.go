@@ -0,0 +1,31 @@
+package actions
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/threshold"
+)
+
+func TestValidateDecryptCommitteeChecksEveryMember(t *testing.T) {
+	publicKey, memberKeys, _, err := threshold.Deal(3, 5, rand.Reader)
+	if err != nil {
+		t.Fatalf("deal: %v", err)
+	}
+	if err := validateDecryptCommittee(3, publicKey, memberKeys); err != nil {
+		t.Fatalf("valid committee rejected: %v", err)
+	}
+
+	_, foreignKeys, _, err := threshold.Deal(3, 5, rand.Reader)
+	if err != nil {
+		t.Fatalf("deal: %v", err)
+	}
+	// The first threshold members still interpolate to the public key, but
+	// a quorum including the last member would not.
+	tampered := append([][]byte{}, memberKeys...)
+	tampered[4] = foreignKeys[4]
+	if err := validateDecryptCommittee(3, publicKey, tampered); !errors.Is(err, ErrCommitteeKeyMismatch) {
+		t.Fatalf("expected ErrCommitteeKeyMismatch, got %v", err)
+	}
+}
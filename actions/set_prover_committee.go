@@ -0,0 +1,175 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const (
+	SetProverCommitteeComputeUnits = 5
+	MaxSetProverCommitteeSize      = 2048
+)
+
+var (
+	ErrUnmarshalEmptySetProverCommittee              = errors.New("cannot unmarshal empty bytes as set_prover_committee")
+	_                                   chain.Action = (*SetProverCommittee)(nil)
+)
+
+// SetProverCommittee installs the prover set. Once registered, each window's
+// proof and proof-gated clear must come from the provers AssignProvers draws
+// for it rather than from ProofConfig.ProverAuthority. Members bonded below
+// MinProverBond are left out of the draw.
+type SetProverCommittee struct {
+	Primaries       uint8           `serialize:"true" json:"primaries"`
+	Backups         uint8           `serialize:"true" json:"backups"`
+	BackupDelayBips uint16          `serialize:"true" json:"backup_delay_bips"`
+	Members         []codec.Address `serialize:"true" json:"members"`
+}
+
+func (*SetProverCommittee) GetTypeID() uint8 {
+	return mconsts.SetProverCommitteeID
+}
+
+func (t *SetProverCommittee) StateKeys(_ codec.Address, _ ids.ID) state.Keys {
+	keys := state.Keys{
+		string(storage.TreasuryConfigKey()):     state.Read,
+		string(storage.ProverCommitteeKey()):    state.All,
+		string(storage.UnderbondedProversKey()): state.All,
+	}
+	for _, member := range t.Members {
+		keys[string(storage.ProverBondKey(member))] = state.Read
+	}
+	return keys
+}
+
+func (t *SetProverCommittee) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxSetProverCommitteeSize),
+		MaxSize: MaxSetProverCommitteeSize,
+	}
+	p.PackByte(mconsts.SetProverCommitteeID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalSetProverCommittee(bytes []byte) (chain.Action, error) {
+	t := &SetProverCommittee{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptySetProverCommittee
+	}
+	if bytes[0] != mconsts.SetProverCommitteeID {
+		return nil, fmt.Errorf("unexpected set_prover_committee typeID: %d != %d", bytes[0], mconsts.SetProverCommitteeID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *SetProverCommittee) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	_ int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	treasuryCfg, err := storage.GetTreasuryConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	if actor != treasuryCfg.Governance {
+		return nil, storage.ErrUnauthorized
+	}
+	if err := validateCommitteeMembers(t.Members, len(t.Members)); err != nil {
+		return nil, err
+	}
+
+	committee := storage.ProverCommittee{
+		Primaries:       t.Primaries,
+		Backups:         t.Backups,
+		BackupDelayBips: t.BackupDelayBips,
+		Members:         t.Members,
+	}
+	if err := storage.PutProverCommittee(ctx, mu, committee); err != nil {
+		return nil, err
+	}
+	// Members bonded below MinProverBond are skipped by new assignments
+	// until they top up.
+	var underbonded []codec.Address
+	for _, member := range t.Members {
+		bond, err := storage.GetProverBond(ctx, mu, member)
+		if err != nil {
+			return nil, err
+		}
+		if bond.Bonded < MinProverBond {
+			underbonded = append(underbonded, member)
+		}
+	}
+	if err := storage.PutUnderbondedProvers(ctx, mu, underbonded); err != nil {
+		return nil, err
+	}
+
+	result := &SetProverCommitteeResult{
+		Primaries: t.Primaries,
+		Backups:   t.Backups,
+		Members:   uint16(len(t.Members)),
+	}
+	return result.Bytes(), nil
+}
+
+func (*SetProverCommittee) ComputeUnits(chain.Rules) uint64 {
+	return SetProverCommitteeComputeUnits
+}
+
+func (*SetProverCommittee) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*SetProverCommitteeResult)(nil)
+
+type SetProverCommitteeResult struct {
+	Primaries uint8  `serialize:"true" json:"primaries"`
+	Backups   uint8  `serialize:"true" json:"backups"`
+	Members   uint16 `serialize:"true" json:"members"`
+}
+
+func (*SetProverCommitteeResult) GetTypeID() uint8 {
+	return mconsts.SetProverCommitteeID
+}
+
+func (t *SetProverCommitteeResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxSetProverCommitteeSize,
+	}
+	p.PackByte(mconsts.SetProverCommitteeID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalSetProverCommitteeResult(b []byte) (codec.Typed, error) {
+	t := &SetProverCommitteeResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
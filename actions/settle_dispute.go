@@ -0,0 +1,204 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+
+	smath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+const (
+	SettleDisputeComputeUnits = 3
+	MaxSettleDisputeSize      = 256
+)
+
+var (
+	ErrUnmarshalEmptySettleDispute              = errors.New("cannot unmarshal empty bytes as settle_dispute")
+	_                              chain.Action = (*SettleDispute)(nil)
+)
+
+// SettleDispute is governance's ruling on a disputed market. Outcome becomes
+// the final resolution and winnings are redeemable at once. If it overturns
+// the disputed outcome the bond is returned to the disputer; otherwise it is
+// forfeited to the treasury's locked pool.
+type SettleDispute struct {
+	MarketID ids.ID `serialize:"true" json:"market_id"`
+	Outcome  uint8  `serialize:"true" json:"outcome"`
+
+	// Disputer must be the dispute's sender, so its balance key is known up
+	// front. It is ignored for disputes recorded without a sender.
+	Disputer codec.Address `serialize:"true" json:"disputer"`
+}
+
+func (*SettleDispute) GetTypeID() uint8 {
+	return mconsts.SettleDisputeID
+}
+
+func (t *SettleDispute) StateKeys(_ codec.Address, _ ids.ID) state.Keys {
+	return state.Keys{
+		string(storage.TreasuryConfigKey()):             state.Read,
+		string(storage.TreasuryStateKey()):              state.Read | state.Write,
+		string(storage.MarketKey(t.MarketID)):           state.Read | state.Write,
+		string(storage.MarketResolutionKey(t.MarketID)): state.All,
+		string(storage.DisputeKey(t.MarketID)):          state.Read | state.Write,
+		string(storage.BalanceKey(t.Disputer)):          state.Read | state.Write,
+	}
+}
+
+func (t *SettleDispute) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxSettleDisputeSize),
+		MaxSize: MaxSettleDisputeSize,
+	}
+	p.PackByte(mconsts.SettleDisputeID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalSettleDispute(bytes []byte) (chain.Action, error) {
+	t := &SettleDispute{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptySettleDispute
+	}
+	if bytes[0] != mconsts.SettleDisputeID {
+		return nil, fmt.Errorf("unexpected settle_dispute typeID: %d != %d", bytes[0], mconsts.SettleDisputeID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *SettleDispute) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	treasuryCfg, err := storage.GetTreasuryConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	if actor != treasuryCfg.Governance {
+		return nil, storage.ErrUnauthorized
+	}
+
+	status, outcomes, resolutionTime, disputedOutcome, question, err := storage.GetMarket(ctx, mu, t.MarketID)
+	if err != nil {
+		return nil, err
+	}
+	if status != storage.MarketStatusDisputed {
+		return nil, storage.ErrMarketNotDisputed
+	}
+	if t.Outcome >= outcomes {
+		return nil, storage.ErrInvalidOutcome
+	}
+	dispute, err := storage.GetDispute(ctx, mu, t.MarketID)
+	if err != nil {
+		return nil, err
+	}
+	var zero codec.Address
+	if dispute.Disputer != zero && dispute.Disputer != t.Disputer {
+		return nil, storage.ErrUnauthorized
+	}
+
+	overturned := t.Outcome != disputedOutcome
+	if overturned && dispute.Disputer != zero {
+		if _, err := storage.AddBalance(ctx, mu, dispute.Disputer, dispute.Bond); err != nil {
+			return nil, err
+		}
+	} else {
+		treasury, err := storage.GetTreasuryState(ctx, mu)
+		if err != nil {
+			return nil, err
+		}
+		treasury.Locked, err = smath.Add(treasury.Locked, dispute.Bond)
+		if err != nil {
+			return nil, err
+		}
+		if err := storage.PutTreasuryState(ctx, mu, treasury); err != nil {
+			return nil, err
+		}
+	}
+
+	// The bond is paid out, so the dispute is closed for good.
+	if err := storage.DeleteDispute(ctx, mu, t.MarketID); err != nil {
+		return nil, err
+	}
+	if err := storage.PutMarket(ctx, mu, t.MarketID, storage.MarketStatusResolved, outcomes, resolutionTime, t.Outcome, question); err != nil {
+		return nil, err
+	}
+	// A settled market is final: it can no longer be disputed and its
+	// winnings are redeemable from now on.
+	if err := storage.PutMarketResolution(ctx, mu, t.MarketID, storage.MarketResolution{
+		ResolvedAtMs:    timestamp,
+		DisputeEndsAtMs: timestamp,
+	}); err != nil {
+		return nil, err
+	}
+
+	result := &SettleDisputeResult{
+		Outcome:    t.Outcome,
+		Overturned: overturned,
+		Bond:       dispute.Bond,
+	}
+	return result.Bytes(), nil
+}
+
+func (*SettleDispute) ComputeUnits(chain.Rules) uint64 {
+	return SettleDisputeComputeUnits
+}
+
+func (*SettleDispute) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*SettleDisputeResult)(nil)
+
+type SettleDisputeResult struct {
+	Outcome    uint8  `serialize:"true" json:"outcome"`
+	Overturned bool   `serialize:"true" json:"overturned"`
+	Bond       uint64 `serialize:"true" json:"bond"`
+}
+
+func (*SettleDisputeResult) GetTypeID() uint8 {
+	return mconsts.SettleDisputeID
+}
+
+func (t *SettleDisputeResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxSettleDisputeSize,
+	}
+	p.PackByte(mconsts.SettleDisputeID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalSettleDisputeResult(b []byte) (codec.Typed, error) {
+	t := &SettleDisputeResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
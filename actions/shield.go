@@ -0,0 +1,175 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/shielded"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const (
+	ShieldComputeUnits = 4
+	MaxShieldSize      = 256
+)
+
+var (
+	ErrInvalidShieldRecipient              = errors.New("shield recipient is not a canonical field element")
+	ErrUnmarshalEmptyShield                = errors.New("cannot unmarshal empty bytes as shield")
+	_                         chain.Action = (*Shield)(nil)
+)
+
+// Shield moves Amount of Asset from the actor's transparent balance into a
+// new shielded note. Recipient is the note's recipient hash; the commitment
+// is derived on-chain so the note provably holds exactly Amount.
+type Shield struct {
+	Asset     uint8  `serialize:"true" json:"asset"`
+	Amount    uint64 `serialize:"true" json:"amount"`
+	Recipient ids.ID `serialize:"true" json:"recipient"`
+}
+
+func (*Shield) GetTypeID() uint8 {
+	return mconsts.ShieldID
+}
+
+func (s *Shield) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
+	keys := state.Keys{
+		string(storage.BalanceKey(actor)):        state.Read | state.Write,
+		string(storage.VAIBalanceKey(actor)):     state.Read | state.Write,
+		string(storage.NoteTreeKey()):            state.All,
+		string(storage.NoteRootsKey()):           state.All,
+		string(storage.ShieldedPoolKey(s.Asset)): state.All,
+	}
+	if commitment, err := s.commitment(); err == nil {
+		keys[string(storage.NoteLeafKey(commitment))] = state.All
+	}
+	return keys
+}
+
+func (s *Shield) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxShieldSize),
+		MaxSize: MaxShieldSize,
+	}
+	p.PackByte(mconsts.ShieldID)
+	if err := codec.LinearCodec.MarshalInto(s, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalShield(bytes []byte) (chain.Action, error) {
+	s := &Shield{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyShield
+	}
+	if bytes[0] != mconsts.ShieldID {
+		return nil, fmt.Errorf("unexpected shield typeID: %d != %d", bytes[0], mconsts.ShieldID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		s,
+	); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Shield) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	_ int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	if !isSupportedAsset(s.Asset) {
+		return nil, storage.ErrUnsupportedAsset
+	}
+	if s.Amount == 0 {
+		return nil, ErrOutputValueZero
+	}
+	commitment, err := s.commitment()
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := subAssetBalance(ctx, mu, actor, s.Asset, s.Amount)
+	if err != nil {
+		return nil, err
+	}
+	index, root, err := storage.AppendNoteCommitments(ctx, mu, []ids.ID{commitment})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := storage.AddShieldedPool(ctx, mu, s.Asset, s.Amount); err != nil {
+		return nil, err
+	}
+
+	result := &ShieldResult{
+		Commitment:    commitment,
+		LeafIndex:     index,
+		NoteRoot:      root,
+		SenderBalance: balance,
+	}
+	return result.Bytes(), nil
+}
+
+func (s *Shield) commitment() (ids.ID, error) {
+	recipient, err := shielded.ElementFromBytes(s.Recipient[:])
+	if err != nil {
+		return ids.Empty, ErrInvalidShieldRecipient
+	}
+	commitment := shielded.Commitment(s.Asset, s.Amount, recipient)
+	return ids.ID(commitment.Bytes()), nil
+}
+
+func (*Shield) ComputeUnits(chain.Rules) uint64 {
+	return ShieldComputeUnits + NoteAppendComputeUnits
+}
+
+func (*Shield) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*ShieldResult)(nil)
+
+type ShieldResult struct {
+	Commitment    ids.ID `serialize:"true" json:"commitment"`
+	LeafIndex     uint64 `serialize:"true" json:"leaf_index"`
+	NoteRoot      ids.ID `serialize:"true" json:"note_root"`
+	SenderBalance uint64 `serialize:"true" json:"sender_balance"`
+}
+
+func (*ShieldResult) GetTypeID() uint8 {
+	return mconsts.ShieldID
+}
+
+func (r *ShieldResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxShieldSize,
+	}
+	p.PackByte(mconsts.ShieldID)
+	_ = codec.LinearCodec.MarshalInto(r, p)
+	return p.Bytes
+}
+
+func UnmarshalShieldResult(b []byte) (codec.Typed, error) {
+	r := &ShieldResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		r,
+	); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,73 @@
+package actions
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+func TestNoteRootHistorySurvivesManyAppends(t *testing.T) {
+	ctx := context.Background()
+	mu := mapState{}
+	leaf := func(i int) ids.ID {
+		var commitment ids.ID
+		binary.BigEndian.PutUint32(commitment[ids.IDLen-4:], uint32(i+1))
+		return commitment
+	}
+
+	_, first, err := storage.AppendNoteCommitments(ctx, mu, []ids.ID{leaf(0)})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	for i := 1; i < storage.MaxRecentNoteRoots; i++ {
+		if _, _, err := storage.AppendNoteCommitments(ctx, mu, []ids.ID{leaf(i)}); err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+	}
+	if known, err := storage.IsKnownNoteRoot(ctx, mu, first); err != nil || !known {
+		t.Fatalf("root should still be known after %d appends: known=%v err=%v", storage.MaxRecentNoteRoots-1, known, err)
+	}
+	if _, _, err := storage.AppendNoteCommitments(ctx, mu, []ids.ID{leaf(storage.MaxRecentNoteRoots)}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if known, err := storage.IsKnownNoteRoot(ctx, mu, first); err != nil || known {
+		t.Fatalf("root should age out: known=%v err=%v", known, err)
+	}
+	if _, _, err := storage.AppendNoteCommitments(ctx, mu, []ids.ID{leaf(0)}); !errors.Is(err, storage.ErrDuplicateNoteCommitment) {
+		t.Fatalf("expected ErrDuplicateNoteCommitment, got %v", err)
+	}
+}
+
+func TestUnshieldRequiresRegisteredKey(t *testing.T) {
+	prevVerifier, prevStrict := getBatchProofVerifier()
+	defer ConfigureBatchProofVerifier(prevVerifier, prevStrict)
+
+	envelope, err := BuildProofEnvelopeWithCircuit(
+		mconsts.ProofTypeGroth16,
+		mconsts.ProofCircuitShieldedLedgerV2,
+		[]byte{0x01},
+		[]byte{0x02},
+	)
+	if err != nil {
+		t.Fatalf("build envelope: %v", err)
+	}
+	// A verifier that would accept the proof against its local key must not
+	// be consulted while no shielded-ledger-v2 key is registered.
+	ConfigureBatchProofVerifier(failVerifier{}, true)
+	action := &Unshield{
+		Asset:      AssetVEIL,
+		Amount:     100,
+		To:         testTrader(1),
+		Nullifiers: []ids.ID{{0x01}},
+		Proof:      envelope,
+	}
+	if _, err := action.Execute(context.Background(), nil, mapState{}, 0, testTrader(1), ids.Empty); !errors.Is(err, storage.ErrVerifyingKeyNotFound) {
+		t.Fatalf("expected verifying key not found, got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package actions
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/state"
+
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/shielded"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+)
+
+// Public witness layout of shielded-ledger-v2: batch digest, note root,
+// asset, fee, then the nullifiers and output commitments in order.
+const (
+	ShieldedLedgerV2SpendCount              = 4
+	ShieldedLedgerV2OutputCount             = 4
+	shieldedLedgerV2NoteRootOffset          = 1
+	shieldedLedgerV2AssetOffset             = 2
+	shieldedLedgerV2FeeOffset               = 3
+	ShieldedLedgerV2NullifiersOffset        = 4
+	ShieldedLedgerV2OutputCommitmentsOffset = ShieldedLedgerV2NullifiersOffset + ShieldedLedgerV2SpendCount
+	ShieldedLedgerV2PublicInputCount        = ShieldedLedgerV2OutputCommitmentsOffset + ShieldedLedgerV2OutputCount
+
+	// NoteAppendComputeUnits is charged by every action that appends to the
+	// note tree. Each append hashes a new root and pushes it into the
+	// recent-root history, which spend proofs depend on.
+	NoteAppendComputeUnits = 12
+
+	// MaxBatchNullifiers and MaxBatchOutputCommitments bound the notes a
+	// single clear can spend and create.
+	MaxBatchNullifiers        = ShieldedLedgerV2SpendCount
+	MaxBatchOutputCommitments = ShieldedLedgerV2OutputCount
+
+	publicWitnessHeaderLen = 12 // nb_public(4) + nb_secret(4) + vector_len(4)
+)
+
+var (
+	ErrNullifierMismatch        = errors.New("nullifiers do not match proof public inputs")
+	ErrOutputCommitmentMismatch = errors.New("output commitments do not match proof public inputs")
+	ErrTooManyNullifiers        = errors.New("too many nullifiers")
+	ErrTooManyOutputCommitments = errors.New("too many output commitments")
+	ErrInvalidPublicWitness     = errors.New("invalid public witness")
+	ErrShieldedFeeOnClear       = errors.New("a clear's shielded transfer cannot carry a fee")
+)
+
+// shieldedTransfer is the part of a proof's public inputs that changes the
+// shielded pool: the root its spends were proven against, the asset and fee
+// that leave the pool, the nullifiers it reveals and the note commitments it
+// creates.
+type shieldedTransfer struct {
+	NoteRoot          ids.ID
+	Asset             uint8
+	Fee               uint64
+	Nullifiers        []ids.ID
+	OutputCommitments []ids.ID
+}
+
+// proofShieldedTransfer extracts the shielded transfer a batch proof makes.
+// Only shielded-ledger-v2 proofs move notes; other circuits return nil.
+func proofShieldedTransfer(circuitID string, publicWitness []byte) (*shieldedTransfer, error) {
+	if strings.TrimSpace(circuitID) != mconsts.ProofCircuitShieldedLedgerV2 {
+		return nil, nil
+	}
+	elems, err := publicWitnessElements(publicWitness, ShieldedLedgerV2PublicInputCount)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := publicWitnessUint(elems[shieldedLedgerV2AssetOffset], 1)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := publicWitnessUint(elems[shieldedLedgerV2FeeOffset], 8)
+	if err != nil {
+		return nil, err
+	}
+	return &shieldedTransfer{
+		NoteRoot:          elems[shieldedLedgerV2NoteRootOffset],
+		Asset:             uint8(asset),
+		Fee:               fee,
+		Nullifiers:        elems[ShieldedLedgerV2NullifiersOffset:ShieldedLedgerV2OutputCommitmentsOffset],
+		OutputCommitments: elems[ShieldedLedgerV2OutputCommitmentsOffset:ShieldedLedgerV2PublicInputCount],
+	}, nil
+}
+
+// ShieldedLedgerV2Nullifiers decodes the nullifiers from a shielded-ledger-v2
+// public witness.
+func ShieldedLedgerV2Nullifiers(publicWitness []byte) ([]ids.ID, error) {
+	transfer, err := proofShieldedTransfer(mconsts.ProofCircuitShieldedLedgerV2, publicWitness)
+	if err != nil {
+		return nil, err
+	}
+	return transfer.Nullifiers, nil
+}
+
+// publicWitnessElements decodes a gnark BN254 public witness with exactly
+// count public inputs into canonical big-endian field elements.
+func publicWitnessElements(witness []byte, count int) ([]ids.ID, error) {
+	if len(witness) < publicWitnessHeaderLen {
+		return nil, ErrInvalidPublicWitness
+	}
+	nbPublic := binary.BigEndian.Uint32(witness[0:4])
+	vectorLen := binary.BigEndian.Uint32(witness[8:12])
+	if nbPublic != uint32(count) || vectorLen < nbPublic {
+		return nil, ErrInvalidPublicWitness
+	}
+	body := witness[publicWitnessHeaderLen:]
+	if uint64(len(body)) != uint64(vectorLen)*shielded.ElementSize {
+		return nil, ErrInvalidPublicWitness
+	}
+	elems := make([]ids.ID, count)
+	for i := range elems {
+		b := body[i*shielded.ElementSize : (i+1)*shielded.ElementSize]
+		if _, err := shielded.ElementFromBytes(b); err != nil {
+			return nil, ErrInvalidPublicWitness
+		}
+		copy(elems[i][:], b)
+	}
+	return elems, nil
+}
+
+// publicWitnessUint reads a public input that the circuit range-checks to
+// size bytes.
+func publicWitnessUint(elem ids.ID, size int) (uint64, error) {
+	for _, b := range elem[:ids.IDLen-size] {
+		if b != 0 {
+			return 0, ErrInvalidPublicWitness
+		}
+	}
+	var out uint64
+	for _, b := range elem[ids.IDLen-size:] {
+		out = out<<8 | uint64(b)
+	}
+	return out, nil
+}
+
+// applyShieldedTransfer checks that the listed nullifiers and output
+// commitments equal those revealed by the window's proof, that the proof's
+// note root is a recent root, then records each nullifier as spent and
+// appends the outputs to the note tree. The fee leaves the shielded pool of
+// the proof's asset, so the caller must credit it: Unshield pays it to its
+// recipient, and ClearBatch only accepts transfers without a fee. A
+// nullifier that was spent before fails the action, which reverts every
+// write made by it.
+func applyShieldedTransfer(
+	ctx context.Context,
+	mu state.Mutable,
+	nullifiers []ids.ID,
+	outputCommitments []ids.ID,
+	revealed *shieldedTransfer,
+	txID ids.ID,
+	timestamp int64,
+) error {
+	if revealed == nil {
+		revealed = &shieldedTransfer{}
+	}
+	if !equalIDs(nullifiers, revealed.Nullifiers) {
+		return ErrNullifierMismatch
+	}
+	if !equalIDs(outputCommitments, revealed.OutputCommitments) {
+		return ErrOutputCommitmentMismatch
+	}
+	if len(nullifiers) == 0 && len(outputCommitments) == 0 {
+		return nil
+	}
+
+	known, err := storage.IsKnownNoteRoot(ctx, mu, revealed.NoteRoot)
+	if err != nil {
+		return err
+	}
+	if !known {
+		return storage.ErrUnknownNoteRoot
+	}
+	for _, nullifier := range nullifiers {
+		if err := storage.SpendNullifier(ctx, mu, nullifier, storage.SpentNullifier{
+			TxID:      txID,
+			SpentAtMs: timestamp,
+		}); err != nil {
+			return err
+		}
+	}
+	if len(outputCommitments) > 0 {
+		if _, _, err := storage.AppendNoteCommitments(ctx, mu, outputCommitments); err != nil {
+			return err
+		}
+	}
+	if revealed.Fee > 0 {
+		if !isSupportedAsset(revealed.Asset) {
+			return storage.ErrUnsupportedAsset
+		}
+		if _, err := storage.SubShieldedPool(ctx, mu, revealed.Asset, revealed.Fee); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shieldedTransferStateKeys adds the keys a clear touches when it applies a
+// shielded transfer.
+func shieldedTransferStateKeys(keys state.Keys, nullifiers []ids.ID, outputCommitments []ids.ID) state.Keys {
+	if len(nullifiers) == 0 && len(outputCommitments) == 0 {
+		return keys
+	}
+	keys[string(storage.NoteTreeKey())] = state.All
+	keys[string(storage.NoteRootsKey())] = state.All
+	keys[string(storage.ShieldedPoolKey(AssetVEIL))] = state.All
+	keys[string(storage.ShieldedPoolKey(AssetVAI))] = state.All
+	for _, nullifier := range nullifiers {
+		keys[string(storage.NullifierKey(nullifier))] = state.All
+	}
+	for _, commitment := range outputCommitments {
+		keys[string(storage.NoteLeafKey(commitment))] = state.All
+	}
+	return keys
+}
+
+func equalIDs(a []ids.ID, b []ids.ID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
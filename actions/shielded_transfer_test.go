@@ -0,0 +1,75 @@
+package actions
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+)
+
+func TestProofShieldedTransfer(t *testing.T) {
+	witness := make([]byte, publicWitnessHeaderLen, publicWitnessHeaderLen+ShieldedLedgerV2PublicInputCount*32)
+	binary.BigEndian.PutUint32(witness[0:4], ShieldedLedgerV2PublicInputCount)
+	binary.BigEndian.PutUint32(witness[8:12], ShieldedLedgerV2PublicInputCount)
+	for i := 0; i < ShieldedLedgerV2PublicInputCount; i++ {
+		var elem [32]byte
+		elem[31] = byte(i + 1)
+		witness = append(witness, elem[:]...)
+	}
+
+	transfer, err := proofShieldedTransfer(mconsts.ProofCircuitShieldedLedgerV2, witness)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	elem := func(slot int) ids.ID {
+		var want ids.ID
+		want[31] = byte(slot + 1)
+		return want
+	}
+	if transfer.NoteRoot != elem(shieldedLedgerV2NoteRootOffset) {
+		t.Fatalf("unexpected note root: %x", transfer.NoteRoot)
+	}
+	if transfer.Asset != shieldedLedgerV2AssetOffset+1 || transfer.Fee != shieldedLedgerV2FeeOffset+1 {
+		t.Fatalf("unexpected asset/fee: %d/%d", transfer.Asset, transfer.Fee)
+	}
+	if len(transfer.Nullifiers) != ShieldedLedgerV2SpendCount || len(transfer.OutputCommitments) != ShieldedLedgerV2OutputCount {
+		t.Fatalf("unexpected counts: nullifiers=%d outputs=%d", len(transfer.Nullifiers), len(transfer.OutputCommitments))
+	}
+	for i, nullifier := range transfer.Nullifiers {
+		if nullifier != elem(ShieldedLedgerV2NullifiersOffset+i) {
+			t.Fatalf("nullifier %d: got=%x", i, nullifier)
+		}
+	}
+	for i, commitment := range transfer.OutputCommitments {
+		if commitment != elem(ShieldedLedgerV2OutputCommitmentsOffset+i) {
+			t.Fatalf("output commitment %d: got=%x", i, commitment)
+		}
+	}
+
+	// Circuits that do not move notes reveal no transfer.
+	if transfer, err := proofShieldedTransfer(mconsts.ProofCircuitClearHashV1, witness); err != nil || transfer != nil {
+		t.Fatalf("expected no transfer for clearhash-v1, got %v %v", transfer, err)
+	}
+
+	// Field elements must be canonical so one nullifier has one encoding.
+	nonCanonical := append([]byte(nil), witness...)
+	offset := publicWitnessHeaderLen + ShieldedLedgerV2NullifiersOffset*32
+	for i := 0; i < 32; i++ {
+		nonCanonical[offset+i] = 0xff
+	}
+	if _, err := proofShieldedTransfer(mconsts.ProofCircuitShieldedLedgerV2, nonCanonical); !errors.Is(err, ErrInvalidPublicWitness) {
+		t.Fatalf("expected ErrInvalidPublicWitness, got %v", err)
+	}
+	// The asset must fit in a byte.
+	wideAsset := append([]byte(nil), witness...)
+	wideAsset[publicWitnessHeaderLen+shieldedLedgerV2AssetOffset*32+30] = 1
+	if _, err := proofShieldedTransfer(mconsts.ProofCircuitShieldedLedgerV2, wideAsset); !errors.Is(err, ErrInvalidPublicWitness) {
+		t.Fatalf("expected ErrInvalidPublicWitness for wide asset, got %v", err)
+	}
+	if _, err := proofShieldedTransfer(mconsts.ProofCircuitShieldedLedgerV2, witness[:len(witness)-1]); !errors.Is(err, ErrInvalidPublicWitness) {
+		t.Fatalf("expected ErrInvalidPublicWitness for truncated witness, got %v", err)
+	}
+}
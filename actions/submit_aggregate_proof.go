@@ -0,0 +1,346 @@
+package actions
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const (
+	SubmitAggregateProofBaseComputeUnits   = 8
+	SubmitAggregateProofWindowComputeUnits = 2
+	MaxSubmitAggregateProofSize            = MaxProofBytesSize + 4096 + AggregateV1MaxWindows*128
+)
+
+var (
+	ErrUnmarshalEmptySubmitAggregateProof              = errors.New("cannot unmarshal empty bytes as submit_aggregate_proof")
+	_                                     chain.Action = (*SubmitAggregateProof)(nil)
+)
+
+// AggregateWindow is one window covered by an aggregate proof.
+// PublicInputsHash is the window's clearhash-mimc-v1 public inputs hash.
+type AggregateWindow struct {
+	MarketID         ids.ID `serialize:"true" json:"market_id"`
+	WindowID         uint64 `serialize:"true" json:"window_id"`
+	WindowCloseAtMs  int64  `serialize:"true" json:"window_close_at_ms"`
+	PublicInputsHash []byte `serialize:"true" json:"public_inputs_hash"`
+	FillsHash        []byte `serialize:"true" json:"fills_hash"`
+}
+
+// SubmitAggregateProof proves up to AggregateV1MaxWindows windows, across
+// any markets, with one aggregate-v1 proof. Each window is checked as
+// SubmitBatchProof would check it, the proof is verified once, and every
+// window becomes proven with a record bound by clearhash-mimc-v1. The proof
+// blob is stored once, keyed by its commitment.
+type SubmitAggregateProof struct {
+	ProofType uint8             `serialize:"true" json:"proof_type"`
+	Windows   []AggregateWindow `serialize:"true" json:"windows"`
+	Proof     []byte            `serialize:"true" json:"proof"`
+}
+
+func (*SubmitAggregateProof) GetTypeID() uint8 {
+	return mconsts.SubmitAggregateProofID
+}
+
+func (a *SubmitAggregateProof) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
+	keys := state.Keys{
+		string(storage.ProofConfigKey()):                                              state.Read,
+		string(storage.ProofUpgradeKey()):                                             state.Read,
+		string(storage.BloodswornKey(actor)):                                          state.All,
+		string(storage.AggregateProofKey(sha256.Sum256(a.Proof))):                     state.All,
+		string(storage.VerifyingKeyKey(mconsts.ProofCircuitAggregateV1, a.ProofType)): state.Read,
+	}
+	for _, w := range a.Windows {
+		keys[string(storage.MarketKey(w.MarketID))] = state.Read
+		keys[string(storage.BatchProofKey(w.MarketID, w.WindowID))] = state.All
+		keys[string(storage.GlyphKey(w.MarketID, w.WindowID))] = state.All
+		keys[string(storage.WindowStateKey(w.MarketID, w.WindowID))] = state.All
+		keys = proverAssignmentStateKeys(keys, w.MarketID, w.WindowID)
+	}
+	return proverPenaltyStateKeys(keys, actor)
+}
+
+func (a *SubmitAggregateProof) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxSubmitAggregateProofSize),
+		MaxSize: MaxSubmitAggregateProofSize,
+	}
+	p.PackByte(mconsts.SubmitAggregateProofID)
+	if err := codec.LinearCodec.MarshalInto(a, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalSubmitAggregateProof(bytes []byte) (chain.Action, error) {
+	a := &SubmitAggregateProof{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptySubmitAggregateProof
+	}
+	if bytes[0] != mconsts.SubmitAggregateProofID {
+		return nil, fmt.Errorf("unexpected submit_aggregate_proof typeID: %d != %d", bytes[0], mconsts.SubmitAggregateProofID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		a,
+	); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *SubmitAggregateProof) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	actor codec.Address,
+	txID ids.ID,
+) (_ []byte, err error) {
+	start := time.Now()
+	missedDeadline := false
+	var invalidProof error
+	defer func() {
+		metricErr := err
+		if metricErr == nil {
+			metricErr = invalidProof
+		}
+		for _, w := range a.Windows {
+			RecordProofSubmitMetric(
+				w.MarketID,
+				w.WindowID,
+				w.WindowCloseAtMs,
+				timestamp,
+				time.Since(start),
+				missedDeadline,
+				metricErr,
+			)
+		}
+	}()
+
+	if a.ProofType == 0 {
+		return nil, storage.ErrInvalidProofEnvelope
+	}
+	if len(a.Windows) == 0 || len(a.Windows) > AggregateV1MaxWindows {
+		return nil, storage.ErrInvalidAggregateProof
+	}
+	if len(a.Proof) == 0 || len(a.Proof) > MaxProofBytesSize {
+		return nil, storage.ErrInvalidProofEnvelope
+	}
+
+	cfg, err := storage.GetProofConfig(ctx, mu)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.RequireProof {
+		return nil, storage.ErrInvalidProofConfig
+	}
+
+	type windowKey struct {
+		marketID ids.ID
+		windowID uint64
+	}
+	seen := make(map[windowKey]struct{}, len(a.Windows))
+	windows := make([]storage.WindowState, len(a.Windows))
+	windowHashes := make([][]byte, len(a.Windows))
+	for i, w := range a.Windows {
+		if w.WindowCloseAtMs <= 0 {
+			return nil, storage.ErrInvalidProofEnvelope
+		}
+		if len(w.PublicInputsHash) != ExpectedProofHashSize {
+			return nil, storage.ErrInvalidProofEnvelope
+		}
+		if len(w.FillsHash) != ExpectedFillsHashSize {
+			return nil, storage.ErrInvalidProofEnvelope
+		}
+		key := windowKey{marketID: w.MarketID, windowID: w.WindowID}
+		if _, ok := seen[key]; ok {
+			return nil, storage.ErrDuplicateAggregateWindow
+		}
+		seen[key] = struct{}{}
+
+		status, _, _, _, _, err := storage.GetMarket(ctx, mu, w.MarketID)
+		if err != nil {
+			return nil, err
+		}
+		if status != storage.MarketStatusActive {
+			return nil, storage.ErrMarketNotActive
+		}
+		window, _, err := loadWindowState(ctx, mu, w.MarketID, w.WindowID, cfg, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		if w.WindowCloseAtMs != window.CloseAtMs {
+			return nil, storage.ErrWindowCloseMismatch
+		}
+		if timestamp < w.WindowCloseAtMs || timestamp > w.WindowCloseAtMs+cfg.ProofDeadlineMs {
+			missedDeadline = true
+			return nil, storage.ErrProofDeadlineMissed
+		}
+		_, err = storage.GetBatchProofRecord(ctx, mu, w.MarketID, w.WindowID)
+		if err == nil {
+			return nil, storage.ErrProofAlreadySubmitted
+		}
+		if !errors.Is(err, storage.ErrProofNotFound) {
+			return nil, err
+		}
+		switch windowPhaseAt(window, timestamp) {
+		case storage.WindowPhaseRevealed:
+		case storage.WindowPhaseCleared:
+			return nil, storage.ErrBatchAlreadyCleared
+		case storage.WindowPhaseAbandoned:
+			return nil, storage.ErrWindowAbandoned
+		default:
+			return nil, storage.ErrWindowNotRevealed
+		}
+		if err := authorizeProver(ctx, mu, cfg, w.MarketID, w.WindowID, window.CloseAtMs, actor, timestamp); err != nil {
+			return nil, err
+		}
+		windows[i] = window
+		windowHashes[i] = w.PublicInputsHash
+	}
+
+	aggregateHash, err := ComputeAggregatePublicInputsHash(windowHashes)
+	if err != nil {
+		return nil, storage.ErrInvalidProofEnvelope
+	}
+	rules, err := loadProofRules(ctx, mu, cfg, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	commitment := sha256.Sum256(a.Proof)
+	verified, err := verifyAggregateProofInConsensus(ctx, mu, rules, a.ProofType, a.Proof, aggregateHash[:])
+	if errors.Is(err, storage.ErrProofVerificationFailed) {
+		// As in SubmitBatchProof, a failed proof slashes and jails its
+		// prover once and leaves every window open.
+		invalidProof = err
+		slashed, err := penalizeInvalidProof(ctx, mu, actor, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		result := &SubmitAggregateProofResult{
+			SubmittedAtMs:   timestamp,
+			ProofCommitment: commitment[:],
+			AggregateHash:   aggregateHash[:],
+			Rejected:        true,
+			Slashed:         slashed,
+		}
+		return result.Bytes(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storage.PutAggregateProof(ctx, mu, commitment, a.Proof); err != nil {
+		return nil, err
+	}
+	// The aggregate batches the windows' clearhash-mimc-v1 statements; it
+	// does not verify a proof of each window. Records name aggregate-v1 and
+	// are never Verified, so ClearBatch checks them as attestations.
+	for i, w := range a.Windows {
+		record := storage.BatchProofRecord{
+			ProofType:        a.ProofType,
+			SubmittedAtMs:    timestamp,
+			WindowCloseAtMs:  w.WindowCloseAtMs,
+			Prover:           actor,
+			ProofCommitment:  commitment,
+			PublicInputsHash: append([]byte(nil), w.PublicInputsHash...),
+			FillsHash:        append([]byte(nil), w.FillsHash...),
+			CircuitID:        verified.CircuitID,
+			KeyHash:          verified.KeyHash,
+		}
+		if err := storage.PutBatchProofRecord(ctx, mu, w.MarketID, w.WindowID, record); err != nil {
+			return nil, err
+		}
+		if err := putWindowPhase(ctx, mu, w.MarketID, w.WindowID, windows[i], storage.WindowPhaseProven, timestamp); err != nil {
+			return nil, err
+		}
+		glyph := deriveGlyph(
+			txID,
+			w.MarketID,
+			w.WindowID,
+			actor,
+			commitment,
+			w.PublicInputsHash,
+			timestamp,
+		)
+		if err := storage.PutGlyph(ctx, mu, w.MarketID, w.WindowID, glyph); err != nil {
+			return nil, err
+		}
+	}
+	bloodsworn, err := storage.GetBloodsworn(ctx, mu, actor)
+	if err != nil {
+		return nil, err
+	}
+	bloodsworn.TotalAcceptedProofs += uint64(len(a.Windows))
+	bloodsworn.ActiveStreak += uint64(len(a.Windows))
+	bloodsworn.LastProofAtMs = timestamp
+	if err := storage.PutBloodsworn(ctx, mu, actor, bloodsworn); err != nil {
+		return nil, err
+	}
+
+	result := &SubmitAggregateProofResult{
+		SubmittedAtMs:    timestamp,
+		ProofCommitment:  commitment[:],
+		AggregateHash:    aggregateHash[:],
+		StoredProofBytes: uint32(len(a.Proof)),
+		Windows:          uint8(len(a.Windows)),
+	}
+	return result.Bytes(), nil
+}
+
+func (a *SubmitAggregateProof) ComputeUnits(chain.Rules) uint64 {
+	return SubmitAggregateProofBaseComputeUnits + uint64(len(a.Windows))*SubmitAggregateProofWindowComputeUnits
+}
+
+func (*SubmitAggregateProof) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*SubmitAggregateProofResult)(nil)
+
+type SubmitAggregateProofResult struct {
+	SubmittedAtMs    int64  `serialize:"true" json:"submitted_at_ms"`
+	ProofCommitment  []byte `serialize:"true" json:"proof_commitment"`
+	AggregateHash    []byte `serialize:"true" json:"aggregate_hash"`
+	StoredProofBytes uint32 `serialize:"true" json:"stored_proof_bytes"`
+	Windows          uint8  `serialize:"true" json:"windows"`
+	Rejected         bool   `serialize:"true" json:"rejected"`
+	Slashed          uint64 `serialize:"true" json:"slashed"`
+}
+
+func (*SubmitAggregateProofResult) GetTypeID() uint8 {
+	return mconsts.SubmitAggregateProofID
+}
+
+func (r *SubmitAggregateProofResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxSubmitAggregateProofSize,
+	}
+	p.PackByte(mconsts.SubmitAggregateProofID)
+	_ = codec.LinearCodec.MarshalInto(r, p)
+	return p.Bytes
+}
+
+func UnmarshalSubmitAggregateProofResult(b []byte) (codec.Typed, error) {
+	r := &SubmitAggregateProofResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		r,
+	); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
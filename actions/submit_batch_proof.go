@@ -44,14 +44,19 @@ func (*SubmitBatchProof) GetTypeID() uint8 {
 }
 
 func (a *SubmitBatchProof) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
-	return state.Keys{
+	keys := state.Keys{
 		string(storage.MarketKey(a.MarketID)):                  state.Read,
 		string(storage.ProofConfigKey()):                       state.Read,
+		string(storage.ProofUpgradeKey()):                      state.Read,
 		string(storage.BatchProofKey(a.MarketID, a.WindowID)):  state.All,
 		string(storage.VellumProofKey(a.MarketID, a.WindowID)): state.All,
 		string(storage.BloodswornKey(actor)):                   state.All,
 		string(storage.GlyphKey(a.MarketID, a.WindowID)):       state.All,
+		string(storage.WindowStateKey(a.MarketID, a.WindowID)): state.All,
 	}
+	keys = proverAssignmentStateKeys(keys, a.MarketID, a.WindowID)
+	keys = proverPenaltyStateKeys(keys, actor)
+	return proofVerifyingKeyStateKeys(keys, a.ProofType, a.Proof)
 }
 
 func (a *SubmitBatchProof) Bytes() []byte {
@@ -93,7 +98,12 @@ func (a *SubmitBatchProof) Execute(
 ) (_ []byte, err error) {
 	start := time.Now()
 	missedDeadline := false
+	var invalidProof error
 	defer func() {
+		metricErr := err
+		if metricErr == nil {
+			metricErr = invalidProof
+		}
 		RecordProofSubmitMetric(
 			a.MarketID,
 			a.WindowID,
@@ -101,7 +111,7 @@ func (a *SubmitBatchProof) Execute(
 			timestamp,
 			time.Since(start),
 			missedDeadline,
-			err,
+			metricErr,
 		)
 	}()
 
@@ -136,14 +146,12 @@ func (a *SubmitBatchProof) Execute(
 	if !cfg.RequireProof {
 		return nil, storage.ErrInvalidProofConfig
 	}
-	if actor != cfg.ProverAuthority {
-		return nil, storage.ErrUnauthorized
-	}
-	if a.ProofType != cfg.RequiredProofType {
-		return nil, storage.ErrProofTypeMismatch
+	window, _, err := loadWindowState(ctx, mu, a.MarketID, a.WindowID, cfg, timestamp)
+	if err != nil {
+		return nil, err
 	}
-	if a.WindowCloseAtMs%cfg.BatchWindowMs != 0 {
-		return nil, storage.ErrInvalidProofEnvelope
+	if a.WindowCloseAtMs != window.CloseAtMs {
+		return nil, storage.ErrWindowCloseMismatch
 	}
 	if timestamp < a.WindowCloseAtMs {
 		missedDeadline = true
@@ -161,13 +169,49 @@ func (a *SubmitBatchProof) Execute(
 	if !errors.Is(err, storage.ErrProofNotFound) {
 		return nil, err
 	}
-
-	if err := verifyBatchProofInConsensus(cfg.RequiredProofType, a.Proof, a.PublicInputsHash); err != nil {
+	switch windowPhaseAt(window, timestamp) {
+	case storage.WindowPhaseRevealed:
+	case storage.WindowPhaseCleared:
+		return nil, storage.ErrBatchAlreadyCleared
+	case storage.WindowPhaseAbandoned:
+		return nil, storage.ErrWindowAbandoned
+	default:
+		return nil, storage.ErrWindowNotRevealed
+	}
+	if err := authorizeProver(ctx, mu, cfg, a.MarketID, a.WindowID, window.CloseAtMs, actor, timestamp); err != nil {
 		return nil, err
 	}
 
-	// canonical public-input binding against clear inputs is enforced at clear time.
+	rules, err := loadProofRules(ctx, mu, cfg, timestamp)
+	if err != nil {
+		return nil, err
+	}
 	commitment := sha256.Sum256(a.Proof)
+	verified, checked, err := verifyBatchProofInConsensus(ctx, mu, rules, a.ProofType, a.Proof, a.PublicInputsHash)
+	if errors.Is(err, storage.ErrProofVerificationFailed) {
+		// A proof that fails its pairing check against the registered key is
+		// objective evidence against its prover. The transaction succeeds so
+		// the slash and jail persist; the window stays open to other provers.
+		// Every other verification error fails the transaction.
+		invalidProof = err
+		slashed, err := penalizeInvalidProof(ctx, mu, actor, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		result := &SubmitBatchProofResult{
+			SubmittedAtMs:   timestamp,
+			ProofCommitment: commitment[:],
+			Rejected:        true,
+			Slashed:         slashed,
+		}
+		return result.Bytes(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The proof is checked once, here. ClearBatch relies on the stored rule
+	// and only binds PublicInputsHash to the clear inputs.
 	record := storage.BatchProofRecord{
 		ProofType:        a.ProofType,
 		SubmittedAtMs:    timestamp,
@@ -176,6 +220,9 @@ func (a *SubmitBatchProof) Execute(
 		ProofCommitment:  commitment,
 		PublicInputsHash: append([]byte(nil), a.PublicInputsHash...),
 		FillsHash:        append([]byte(nil), a.FillsHash...),
+		Verified:         checked,
+		CircuitID:        verified.CircuitID,
+		KeyHash:          verified.KeyHash,
 	}
 	if err := storage.PutBatchProofRecord(ctx, mu, a.MarketID, a.WindowID, record); err != nil {
 		return nil, err
@@ -183,6 +230,9 @@ func (a *SubmitBatchProof) Execute(
 	if err := storage.PutVellumProof(ctx, mu, a.MarketID, a.WindowID, a.Proof); err != nil {
 		return nil, err
 	}
+	if err := putWindowPhase(ctx, mu, a.MarketID, a.WindowID, window, storage.WindowPhaseProven, timestamp); err != nil {
+		return nil, err
+	}
 	bloodsworn, err := storage.GetBloodsworn(ctx, mu, actor)
 	if err != nil {
 		return nil, err
@@ -232,6 +282,10 @@ type SubmitBatchProofResult struct {
 	StoredProofBytes uint32 `serialize:"true" json:"stored_proof_bytes"`
 	GlyphClass       uint8  `serialize:"true" json:"glyph_class"`
 	GlyphRarity      uint8  `serialize:"true" json:"glyph_rarity"`
+	// Rejected is set when the proof failed verification and the prover
+	// was slashed by Slashed instead.
+	Rejected bool   `serialize:"true" json:"rejected"`
+	Slashed  uint64 `serialize:"true" json:"slashed"`
 }
 
 func (*SubmitBatchProofResult) GetTypeID() uint8 {
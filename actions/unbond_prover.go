@@ -0,0 +1,172 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+
+	smath "github.com/ava-labs/avalanchego/utils/math"
+)
+
+const (
+	UnbondProverComputeUnits = 1
+	MaxUnbondProverSize      = 256
+)
+
+var (
+	ErrUnmarshalEmptyUnbondProver              = errors.New("cannot unmarshal empty bytes as unbond_prover")
+	_                             chain.Action = (*UnbondProver)(nil)
+)
+
+// UnbondProver first withdraws any unbonding stake whose cooldown has ended,
+// then starts unbonding Amount of the bonded stake. Unbonding stake stays
+// slashable for ProverUnbondCooldownMs, and adding to it restarts the
+// cooldown. An Amount of zero only withdraws.
+type UnbondProver struct {
+	Amount uint64 `serialize:"true" json:"amount"`
+}
+
+func (*UnbondProver) GetTypeID() uint8 {
+	return mconsts.UnbondProverID
+}
+
+func (*UnbondProver) StateKeys(actor codec.Address, _ ids.ID) state.Keys {
+	return proverBondStateKeys(state.Keys{
+		string(storage.BalanceKey(actor)): state.All,
+	}, actor)
+}
+
+func (t *UnbondProver) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxUnbondProverSize),
+		MaxSize: MaxUnbondProverSize,
+	}
+	p.PackByte(mconsts.UnbondProverID)
+	if err := codec.LinearCodec.MarshalInto(t, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalUnbondProver(bytes []byte) (chain.Action, error) {
+	t := &UnbondProver{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyUnbondProver
+	}
+	if bytes[0] != mconsts.UnbondProverID {
+		return nil, fmt.Errorf("unexpected unbond_prover typeID: %d != %d", bytes[0], mconsts.UnbondProverID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *UnbondProver) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	actor codec.Address,
+	_ ids.ID,
+) ([]byte, error) {
+	bond, err := storage.GetProverBond(ctx, mu, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	var withdrawn uint64
+	if bond.Unbonding > 0 && timestamp >= bond.UnbondAtMs {
+		withdrawn = bond.Unbonding
+		bond.Unbonding = 0
+		bond.UnbondAtMs = 0
+	}
+	if t.Amount > 0 {
+		if t.Amount > bond.Bonded {
+			return nil, storage.ErrInsufficientProverBond
+		}
+		bond.Bonded -= t.Amount
+		bond.Unbonding, err = smath.Add(bond.Unbonding, t.Amount)
+		if err != nil {
+			return nil, err
+		}
+		bond.UnbondAtMs = timestamp + ProverUnbondCooldownMs
+	} else if withdrawn == 0 {
+		if bond.Unbonding > 0 {
+			return nil, storage.ErrProverBondUnbonding
+		}
+		return nil, storage.ErrInvalidBondAmount
+	}
+
+	if err := putProverBond(ctx, mu, actor, bond); err != nil {
+		return nil, err
+	}
+	balance, err := storage.AddBalance(ctx, mu, actor, withdrawn)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UnbondProverResult{
+		Bonded:        bond.Bonded,
+		Unbonding:     bond.Unbonding,
+		UnbondAtMs:    bond.UnbondAtMs,
+		Withdrawn:     withdrawn,
+		SenderBalance: balance,
+	}
+	return result.Bytes(), nil
+}
+
+func (*UnbondProver) ComputeUnits(chain.Rules) uint64 {
+	return UnbondProverComputeUnits
+}
+
+func (*UnbondProver) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*UnbondProverResult)(nil)
+
+type UnbondProverResult struct {
+	Bonded        uint64 `serialize:"true" json:"bonded"`
+	Unbonding     uint64 `serialize:"true" json:"unbonding"`
+	UnbondAtMs    int64  `serialize:"true" json:"unbond_at_ms"`
+	Withdrawn     uint64 `serialize:"true" json:"withdrawn"`
+	SenderBalance uint64 `serialize:"true" json:"sender_balance"`
+}
+
+func (*UnbondProverResult) GetTypeID() uint8 {
+	return mconsts.UnbondProverID
+}
+
+func (t *UnbondProverResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 256),
+		MaxSize: MaxUnbondProverSize,
+	}
+	p.PackByte(mconsts.UnbondProverID)
+	_ = codec.LinearCodec.MarshalInto(t, p)
+	return p.Bytes
+}
+
+func UnmarshalUnbondProverResult(b []byte) (codec.Typed, error) {
+	t := &UnbondProverResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		t,
+	); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
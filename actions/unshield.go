@@ -0,0 +1,168 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/state"
+)
+
+const (
+	UnshieldComputeUnits = 8
+	MaxUnshieldSize      = MaxProofBytesSize + 4096
+)
+
+var (
+	ErrUnshieldAmountMismatch              = errors.New("unshield asset/amount does not match proof fee")
+	ErrUnmarshalEmptyUnshield              = errors.New("cannot unmarshal empty bytes as unshield")
+	_                         chain.Action = (*Unshield)(nil)
+)
+
+// Unshield spends shielded notes with a shielded-ledger-v2 proof and credits
+// the proof's fee to a transparent address. The proof's batch digest is
+// ComputeUnshieldPublicInputsHash(Asset, Amount, To), and its fee must equal
+// Amount; any change goes back into the pool as OutputCommitments.
+type Unshield struct {
+	Asset             uint8         `serialize:"true" json:"asset"`
+	Amount            uint64        `serialize:"true" json:"amount"`
+	To                codec.Address `serialize:"true" json:"to"`
+	Nullifiers        []ids.ID      `serialize:"true" json:"nullifiers"`
+	OutputCommitments []ids.ID      `serialize:"true" json:"output_commitments"`
+	Proof             []byte        `serialize:"true" json:"proof"`
+}
+
+func (*Unshield) GetTypeID() uint8 {
+	return mconsts.UnshieldID
+}
+
+func (u *Unshield) StateKeys(_ codec.Address, _ ids.ID) state.Keys {
+	keys := state.Keys{
+		string(storage.BalanceKey(u.To)):    state.All,
+		string(storage.VAIBalanceKey(u.To)): state.All,
+		string(storage.VerifyingKeyKey(mconsts.ProofCircuitShieldedLedgerV2, mconsts.ProofTypeGroth16)): state.Read,
+	}
+	return shieldedTransferStateKeys(keys, u.Nullifiers, u.OutputCommitments)
+}
+
+func (u *Unshield) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, MaxUnshieldSize),
+		MaxSize: MaxUnshieldSize,
+	}
+	p.PackByte(mconsts.UnshieldID)
+	if err := codec.LinearCodec.MarshalInto(u, p); err != nil {
+		panic(err)
+	}
+	return p.Bytes
+}
+
+func UnmarshalUnshield(bytes []byte) (chain.Action, error) {
+	u := &Unshield{}
+	if len(bytes) == 0 {
+		return nil, ErrUnmarshalEmptyUnshield
+	}
+	if bytes[0] != mconsts.UnshieldID {
+		return nil, fmt.Errorf("unexpected unshield typeID: %d != %d", bytes[0], mconsts.UnshieldID)
+	}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: bytes[1:]},
+		u,
+	); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *Unshield) Execute(
+	ctx context.Context,
+	_ chain.Rules,
+	mu state.Mutable,
+	timestamp int64,
+	_ codec.Address,
+	txID ids.ID,
+) ([]byte, error) {
+	if !isSupportedAsset(u.Asset) {
+		return nil, storage.ErrUnsupportedAsset
+	}
+	if u.Amount == 0 {
+		return nil, ErrOutputValueZero
+	}
+	if len(u.Nullifiers) > MaxBatchNullifiers {
+		return nil, ErrTooManyNullifiers
+	}
+	if len(u.OutputCommitments) > MaxBatchOutputCommitments {
+		return nil, ErrTooManyOutputCommitments
+	}
+	if len(u.Proof) == 0 || len(u.Proof) > MaxProofBytesSize {
+		return nil, storage.ErrInvalidProofEnvelope
+	}
+
+	publicInputsHash := ComputeUnshieldPublicInputsHash(u.Asset, u.Amount, u.To)
+	transfer, err := verifyShieldedProofInConsensus(ctx, mu, u.Proof, publicInputsHash[:])
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Asset != u.Asset || transfer.Fee != u.Amount {
+		return nil, ErrUnshieldAmountMismatch
+	}
+	if err := applyShieldedTransfer(ctx, mu, u.Nullifiers, u.OutputCommitments, transfer, txID, timestamp); err != nil {
+		return nil, err
+	}
+	balance, err := addAssetBalance(ctx, mu, u.To, u.Asset, u.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UnshieldResult{ReceiverBalance: balance}
+	return result.Bytes(), nil
+}
+
+func (u *Unshield) ComputeUnits(chain.Rules) uint64 {
+	if len(u.OutputCommitments) > 0 {
+		return UnshieldComputeUnits + NoteAppendComputeUnits
+	}
+	return UnshieldComputeUnits
+}
+
+func (*Unshield) ValidRange(chain.Rules) (int64, int64) {
+	return -1, -1
+}
+
+var _ codec.Typed = (*UnshieldResult)(nil)
+
+type UnshieldResult struct {
+	ReceiverBalance uint64 `serialize:"true" json:"receiver_balance"`
+}
+
+func (*UnshieldResult) GetTypeID() uint8 {
+	return mconsts.UnshieldID
+}
+
+func (r *UnshieldResult) Bytes() []byte {
+	p := &wrappers.Packer{
+		Bytes:   make([]byte, 0, 64),
+		MaxSize: MaxUnshieldSize,
+	}
+	p.PackByte(mconsts.UnshieldID)
+	_ = codec.LinearCodec.MarshalInto(r, p)
+	return p.Bytes
+}
+
+func UnmarshalUnshieldResult(b []byte) (codec.Typed, error) {
+	r := &UnshieldResult{}
+	if err := codec.LinearCodec.UnmarshalFrom(
+		&wrappers.Packer{Bytes: b[1:]},
+		r,
+	); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -17,6 +17,7 @@ type ZKWindowMetrics struct {
 	BatchSizeHint uint32 `json:"batch_size_hint"`
 
 	CommitCount uint32 `json:"commit_count"`
+	CancelCount uint32 `json:"cancel_count"`
 	RevealCount uint32 `json:"reveal_count"`
 
 	FirstCommitAtMs int64 `json:"first_commit_at_ms"`
@@ -35,6 +36,7 @@ type ZKWindowMetrics struct {
 	BlockAcceptLatencyMs int64 `json:"block_accept_latency_ms"`
 
 	CommitExecUs      uint64 `json:"commit_exec_us"`
+	CancelExecUs      uint64 `json:"cancel_exec_us"`
 	RevealExecUs      uint64 `json:"reveal_exec_us"`
 	ProofSubmitExecUs uint64 `json:"proof_submit_exec_us"`
 	ClearExecUs       uint64 `json:"clear_exec_us"`
@@ -47,6 +49,7 @@ type ZKWindowMetrics struct {
 type ZKMetricsSummary struct {
 	TotalWindowsObserved       uint64 `json:"total_windows_observed"`
 	TotalCommits               uint64 `json:"total_commits"`
+	TotalCancels               uint64 `json:"total_cancels"`
 	TotalReveals               uint64 `json:"total_reveals"`
 	TotalProofSubmissions      uint64 `json:"total_proof_submissions"`
 	TotalProofSubmissionErrors uint64 `json:"total_proof_submission_errors"`
@@ -136,6 +139,28 @@ func (c *zkMetricsCollector) recordCommit(marketID ids.ID, windowID uint64, time
 	c.summary.TotalCommits++
 }
 
+// recordCancel removes a cancelled commitment from CommitCount so it keeps
+// matching the commitments a clear can list. A failed cancel leaves the
+// window's commitments untouched, so it does not mark the window rejected.
+func (c *zkMetricsCollector) recordCancel(marketID ids.ID, windowID uint64, exec time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.getOrCreateLocked(marketID, windowID)
+	if exec > 0 {
+		w.CancelExecUs += uint64(exec.Microseconds())
+	}
+	if err != nil {
+		w.LastError = err.Error()
+		return
+	}
+	if w.CommitCount > 0 {
+		w.CommitCount--
+	}
+	w.CancelCount++
+	c.summary.TotalCancels++
+}
+
 func (c *zkMetricsCollector) recordReveal(marketID ids.ID, windowID uint64, exec time.Duration, err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -295,6 +320,10 @@ func RecordCommitMetric(marketID ids.ID, windowID uint64, timestampMs int64, exe
 	zkMetrics.recordCommit(marketID, windowID, timestampMs, exec, err)
 }
 
+func RecordCancelMetric(marketID ids.ID, windowID uint64, exec time.Duration, err error) {
+	zkMetrics.recordCancel(marketID, windowID, exec, err)
+}
+
 func RecordRevealMetric(marketID ids.ID, windowID uint64, exec time.Duration, err error) {
 	zkMetrics.recordReveal(marketID, windowID, exec, err)
 }
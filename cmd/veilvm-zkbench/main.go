@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/csv"
 	"encoding/hex"
@@ -26,6 +27,7 @@ import (
 	"github.com/ava-labs/hypersdk/crypto/ed25519"
 	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
 	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/threshold"
 	vmclient "github.com/ava-labs/hypersdk/examples/veilvm/vm"
 	"github.com/ava-labs/hypersdk/examples/veilvm/zk"
 	"github.com/ava-labs/hypersdk/fees"
@@ -166,6 +168,11 @@ func (g *groth16ProofBuilder) Build(publicInputsHash []byte, preimage []byte, _
 		if err != nil {
 			return nil, 0, fmt.Errorf("build clear-hash assignment: %w", err)
 		}
+	case mconsts.ProofCircuitClearHashMiMCV1:
+		assignment, err = zk.NewClearHashMiMCAssignment(preimage, publicInputsHash)
+		if err != nil {
+			return nil, 0, fmt.Errorf("build clear-hash-mimc assignment: %w", err)
+		}
 	case mconsts.ProofCircuitShieldedLedgerV1:
 		assignment, err = zk.NewShieldedLedgerAssignment(preimage, publicInputsHash)
 		if err != nil {
@@ -215,6 +222,8 @@ func (g *groth16ProofBuilder) Description() string {
 	switch g.circuitID {
 	case mconsts.ProofCircuitShieldedLedgerV1:
 		return "groth16-shielded-ledger-v1"
+	case mconsts.ProofCircuitClearHashMiMCV1:
+		return "groth16-clearhash-mimc-v1"
 	default:
 		return "groth16-clearhash-v1"
 	}
@@ -229,6 +238,8 @@ func newProofBuilder(cfg benchConfig) (proofBuilder, error) {
 		switch cfg.ProofCircuitID {
 		case mconsts.ProofCircuitClearHashV1:
 			circuit = &zk.ClearHashCircuit{}
+		case mconsts.ProofCircuitClearHashMiMCV1:
+			circuit = &zk.ClearHashMiMCCircuit{}
 		case mconsts.ProofCircuitShieldedLedgerV1:
 			circuit = &zk.ShieldedLedgerCircuitV1{}
 		default:
@@ -342,6 +353,8 @@ func defaultGroth16CSCachePath(circuitID string) string {
 	switch circuitID {
 	case mconsts.ProofCircuitShieldedLedgerV1:
 		circuitSource = "zk/shielded_ledger_circuit.go"
+	case mconsts.ProofCircuitClearHashMiMCV1:
+		circuitSource = "zk/clearhash_mimc_circuit.go"
 	}
 	fingerprint := sourceFingerprintHex("go.mod", "go.sum", circuitSource)
 	safeCircuitID := sanitizePathComponent(circuitID)
@@ -452,12 +465,13 @@ func loadConfig() (benchConfig, error) {
 			return benchConfig{}, fmt.Errorf("GROTH16_PK_PATH is required when PROOF_MODE=groth16")
 		}
 		switch cfg.ProofCircuitID {
-		case mconsts.ProofCircuitClearHashV1, mconsts.ProofCircuitShieldedLedgerV1:
+		case mconsts.ProofCircuitClearHashV1, mconsts.ProofCircuitClearHashMiMCV1, mconsts.ProofCircuitShieldedLedgerV1:
 		default:
 			return benchConfig{}, fmt.Errorf(
-				"invalid PROOF_CIRCUIT_ID=%q (expected %s|%s)",
+				"invalid PROOF_CIRCUIT_ID=%q (expected %s|%s|%s)",
 				cfg.ProofCircuitID,
 				mconsts.ProofCircuitClearHashV1,
+				mconsts.ProofCircuitClearHashMiMCV1,
 				mconsts.ProofCircuitShieldedLedgerV1,
 			)
 		}
@@ -727,6 +741,7 @@ func runBench(ctx context.Context, cfg benchConfig) (*benchReport, error) {
 		return nil, err
 	}
 
+	governanceSigner := proofConfigSigner
 	if err := setProofConfig(ctx, submitSignedAction, proofConfigSigner, proverAuthority, cfg); err != nil {
 		if !isUnauthorizedError(err) {
 			return nil, err
@@ -750,6 +765,12 @@ func runBench(ctx context.Context, cfg benchConfig) (*benchReport, error) {
 		if retryErr := setProofConfig(ctx, submitSignedAction, fallbackSigner, proverAuthority, cfg); retryErr != nil {
 			return nil, retryErr
 		}
+		governanceSigner = fallbackSigner
+	}
+
+	committeeKey, committeeShare, err := setBenchDecryptCommittee(submitSignedAction, governanceSigner, addr)
+	if err != nil {
+		return nil, err
 	}
 
 	report := &benchReport{
@@ -772,29 +793,54 @@ func runBench(ctx context.Context, cfg benchConfig) (*benchReport, error) {
 		}
 		marketID := deriveMarketID(batchSize)
 		if _, err := submitAction("create_market", &actions.CreateMarket{
-			MarketID:       marketID,
-			Question:       []byte(fmt.Sprintf("zkbench-%d", batchSize)),
-			Outcomes:       2,
-			ResolutionTime: time.Now().Unix() + 86_400,
-			CreatorBond:    1,
+			MarketID:        marketID,
+			Question:        []byte(fmt.Sprintf("zkbench-%d", batchSize)),
+			Outcomes:        2,
+			ResolutionTime:  time.Now().Unix() + 86_400,
+			CreatorBond:     1,
+			CollateralAsset: actions.AssetVEIL,
 		}); err != nil {
 			return nil, err
 		}
 
 		for i := 1; i <= cfg.WindowsPerSize; i++ {
-			windowID := uint64(i)
-			envelope := buildEnvelope(batchSize, windowID)
-			commitment := sha256.Sum256(envelope)
+			// Commits only land in the window that contains the block
+			// timestamp, and reveals wait until that window has closed.
+			windowID, windowClose, err := nextBenchWindow(ctx, cfg.BatchWindowMs)
+			if err != nil {
+				return nil, fmt.Errorf("next window: %w", err)
+			}
+			order := actions.OrderPlaintext{
+				Side:       actions.OrderSideBuy,
+				LimitPrice: actions.OutcomePriceScale / 2,
+				Quantity:   uint64(batchSize),
+			}
+			if _, err := rand.Read(order.Salt[:]); err != nil {
+				return nil, fmt.Errorf("order salt: %w", err)
+			}
+			envelope, err := actions.SealOrder(committeeKey, marketID, windowID, order, rand.Reader)
+			if err != nil {
+				return nil, fmt.Errorf("seal order: %w", err)
+			}
+			commitment := actions.ComputeOrderCommitment(marketID, windowID, addr, order)
 			if _, err := submitAction("commit_order", &actions.CommitOrder{
-				MarketID:   marketID,
-				WindowID:   windowID,
-				Envelope:   envelope,
-				Commitment: commitment[:],
+				MarketID:    marketID,
+				WindowID:    windowID,
+				Envelope:    envelope,
+				Commitment:  commitment[:],
+				Asset:       actions.AssetVEIL,
+				MaxNotional: 1,
 			}); err != nil {
 				return nil, err
 			}
 
-			reveal := buildRevealShare(batchSize, windowID)
+			if err := sleepUntilMs(ctx, windowClose); err != nil {
+				return nil, fmt.Errorf("wait for window close: %w", err)
+			}
+			reveal, err := committeeShare.DecryptionShare(marketID, windowID)
+			if err != nil {
+				return nil, fmt.Errorf("decryption share: %w", err)
+			}
 			if _, err := submitAction("reveal_batch", &actions.RevealBatch{
 				MarketID:        marketID,
 				WindowID:        windowID,
@@ -804,20 +850,18 @@ func runBench(ctx context.Context, cfg benchConfig) (*benchReport, error) {
 				return nil, err
 			}
 
+			// The bench submits no plaintext orders, so the window clears empty;
+			// the witness step is the same deterministic clearing the VM replays.
 			witnessStart := time.Now()
-			fillsHash := buildFillsHash(batchSize, windowID, envelope, reveal)
-			witnessMs := time.Since(witnessStart).Milliseconds()
-
-			_, _, acceptedTs, err := coreClient.Accepted(ctx)
+			clearing, err := actions.ComputeUniformClearing(marketID, windowID, nil)
 			if err != nil {
-				return nil, fmt.Errorf("accepted before proof: %w", err)
-			}
-			windowClose := acceptedTs - (acceptedTs % cfg.BatchWindowMs)
-			if windowClose <= 0 {
-				windowClose = acceptedTs
+				return nil, fmt.Errorf("compute clearing: %w", err)
 			}
-			clearPrice := uint64(1000 + (i % 97))
-			totalVolume := uint64(batchSize * 100)
+			fillsHash := clearing.FillsHash[:]
+			witnessMs := time.Since(witnessStart).Milliseconds()
+
+			clearPrice := clearing.ClearPrice
+			totalVolume := clearing.TotalVolume
 			var publicInputsHash [32]byte
 			var preimage []byte
 			switch cfg.ProofCircuitID {
@@ -836,6 +880,27 @@ func runBench(ctx context.Context, cfg benchConfig) (*benchReport, error) {
 					totalVolume,
 					fillsHash,
 				)
+			case mconsts.ProofCircuitClearHashMiMCV1:
+				publicInputsHash, err = actions.ComputeClearMiMCPublicInputsHash(
+					marketID,
+					windowID,
+					clearPrice,
+					totalVolume,
+					fillsHash,
+				)
+				if err != nil {
+					return nil, fmt.Errorf("clear-hash-mimc public inputs: %w", err)
+				}
+				preimage, err = actions.BuildClearMiMCPublicInputsPreimage(
+					marketID,
+					windowID,
+					clearPrice,
+					totalVolume,
+					fillsHash,
+				)
+				if err != nil {
+					return nil, fmt.Errorf("clear-hash-mimc preimage: %w", err)
+				}
 			default:
 				publicInputsHash = actions.ComputeClearPublicInputsHash(
 					marketID,
@@ -936,6 +1001,61 @@ func runBench(ctx context.Context, cfg benchConfig) (*benchReport, error) {
 	return report, nil
 }
 
+// nextBenchWindow returns the window currently accepting commits and its
+// close time, moving to the following window when less than half of the
+// current one remains so the commit still lands while it is open.
+func nextBenchWindow(ctx context.Context, windowMs int64) (uint64, int64, error) {
+	now := time.Now().UnixMilli()
+	windowID := actions.WindowIDAt(now, windowMs)
+	closeAt := int64(windowID+1) * windowMs
+	if closeAt-now < windowMs/2 {
+		if err := sleepUntilMs(ctx, closeAt); err != nil {
+			return 0, 0, err
+		}
+		windowID++
+		closeAt += windowMs
+	}
+	return windowID, closeAt, nil
+}
+
+func sleepUntilMs(ctx context.Context, atMs int64) error {
+	wait := time.Until(time.UnixMilli(atMs))
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// setBenchDecryptCommittee installs a single-member decrypt committee held by
+// the bench account so it can seal real envelopes and submit verifiable
+// reveal shares.
+func setBenchDecryptCommittee(
+	submitSignedAction func(string, chain.AuthFactory, chain.Action) (string, uint64, error),
+	signer chain.AuthFactory,
+	member codec.Address,
+) ([]byte, threshold.KeyShare, error) {
+	publicKey, memberKeys, shares, err := threshold.Deal(1, 1, rand.Reader)
+	if err != nil {
+		return nil, threshold.KeyShare{}, fmt.Errorf("deal decrypt committee: %w", err)
+	}
+	if _, _, err := submitSignedAction("set_decrypt_committee", signer, &actions.SetDecryptCommittee{
+		Threshold:       1,
+		PublicKey:       publicKey,
+		MemberKeys:      memberKeys,
+		MemberAddresses: []codec.Address{member},
+	}); err != nil {
+		return nil, threshold.KeyShare{}, fmt.Errorf("set decrypt committee: %w", err)
+	}
+	return publicKey, shares[0], nil
+}
+
 func setProofConfig(
 	ctx context.Context,
 	submitSignedAction func(string, chain.AuthFactory, chain.Action) (string, uint64, error),
@@ -1025,49 +1145,6 @@ func deriveMarketID(batchSize int) ids.ID {
 	return id
 }
 
-func buildEnvelope(batchSize int, windowID uint64) []byte {
-	n := batchSize * 24
-	if n < 96 {
-		n = 96
-	}
-	if n > actions.MaxEnvelopeSize {
-		n = actions.MaxEnvelopeSize
-	}
-	out := make([]byte, n)
-	for i := range out {
-		out[i] = byte((int(windowID) + i*17 + batchSize) & 0xff)
-	}
-	return out
-}
-
-func buildRevealShare(batchSize int, windowID uint64) []byte {
-	n := 96 + batchSize
-	if n > actions.MaxDecryptionShareSize {
-		n = actions.MaxDecryptionShareSize
-	}
-	out := make([]byte, n)
-	for i := range out {
-		out[i] = byte((i*11 + int(windowID) + batchSize*3) & 0xff)
-	}
-	return out
-}
-
-func buildFillsHash(batchSize int, windowID uint64, envelope []byte, reveal []byte) []byte {
-	h := sha256.New()
-	loops := 50 + batchSize*5
-	for i := 0; i < loops; i++ {
-		h.Write(envelope)
-		h.Write(reveal)
-		var b [8]byte
-		v := uint64(i) ^ windowID ^ uint64(batchSize)
-		for j := 0; j < 8; j++ {
-			b[j] = byte(v >> (8 * j))
-		}
-		h.Write(b[:])
-	}
-	return h.Sum(nil)
-}
-
 func buildProofBlob(batchSize int, windowID uint64, fillsHash []byte) []byte {
 	n := 2048 + batchSize*64
 	if n > MaxProofBytesSize {
@@ -1106,6 +1183,7 @@ func writeMetricsCSV(path string, windows []actions.ZKWindowMetrics) error {
 		"window_id",
 		"batch_size_hint",
 		"commit_count",
+		"cancel_count",
 		"reveal_count",
 		"first_commit_at_ms",
 		"window_close_at_ms",
@@ -1118,6 +1196,7 @@ func writeMetricsCSV(path string, windows []actions.ZKWindowMetrics) error {
 		"proof_submit_latency_ms",
 		"block_accept_latency_ms",
 		"commit_exec_us",
+		"cancel_exec_us",
 		"reveal_exec_us",
 		"proof_submit_exec_us",
 		"clear_exec_us",
@@ -1134,6 +1213,7 @@ func writeMetricsCSV(path string, windows []actions.ZKWindowMetrics) error {
 			strconv.FormatUint(row.WindowID, 10),
 			strconv.FormatUint(uint64(row.BatchSizeHint), 10),
 			strconv.FormatUint(uint64(row.CommitCount), 10),
+			strconv.FormatUint(uint64(row.CancelCount), 10),
 			strconv.FormatUint(uint64(row.RevealCount), 10),
 			strconv.FormatInt(row.FirstCommitAtMs, 10),
 			strconv.FormatInt(row.WindowCloseAtMs, 10),
@@ -1146,6 +1226,7 @@ func writeMetricsCSV(path string, windows []actions.ZKWindowMetrics) error {
 			strconv.FormatInt(row.ProofSubmitLatencyMs, 10),
 			strconv.FormatInt(row.BlockAcceptLatencyMs, 10),
 			strconv.FormatUint(row.CommitExecUs, 10),
+			strconv.FormatUint(row.CancelExecUs, 10),
 			strconv.FormatUint(row.RevealExecUs, 10),
 			strconv.FormatUint(row.ProofSubmitExecUs, 10),
 			strconv.FormatUint(row.ClearExecUs, 10),
@@ -1318,9 +1399,9 @@ func estimateMaxFee(unitPrices fees.Dimensions) uint64 {
 }
 
 func plannedActionCount(cfg benchConfig) int {
-	// set_proof_config + (create_market + 4 actions per window) per batch size.
+	// set_proof_config + set_decrypt_committee + (create_market + 4 actions per window) per batch size.
 	perBatch := 1 + (4 * cfg.WindowsPerSize)
-	return 1 + (len(cfg.BatchSizes) * perBatch)
+	return 2 + (len(cfg.BatchSizes) * perBatch)
 }
 
 func estimateRequiredBudget(txCount int, maxFee uint64, gasSafetyBps uint64, gasReserve uint64) uint64 {
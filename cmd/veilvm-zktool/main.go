@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"flag"
 	"fmt"
@@ -13,35 +14,62 @@ import (
 	"strings"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
 	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
 	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	kzgbn254 "github.com/consensys/gnark-crypto/ecc/bn254/kzg"
 	"github.com/consensys/gnark/backend/groth16"
 	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/plonk"
+	plonkbn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/test/unsafekzg"
 
 	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/shielded"
 	"github.com/ava-labs/hypersdk/examples/veilvm/zk"
 )
 
+const (
+	backendGroth16 = "groth16"
+	backendPlonk   = "plonk"
+)
+
 func main() {
 	var (
 		outDir       string
 		writeSample  bool
 		writeKeyPair bool
 		circuitID    string
+		backend      string
+		srsPath      string
+		windows      int
 	)
 	flag.StringVar(&outDir, "out", "./zk-fixture", "output directory")
 	flag.BoolVar(&writeSample, "sample", true, "write sample proof and envelope artifacts")
-	flag.BoolVar(&writeKeyPair, "keys", true, "write groth16 proving/verifying key artifacts")
+	flag.BoolVar(&writeKeyPair, "keys", true, "write proving/verifying key artifacts")
 	flag.StringVar(
 		&circuitID,
 		"circuit",
 		mconsts.ProofCircuitClearHashV1,
-		"proof circuit id (clearhash-v1|shielded-ledger-v1)",
+		"proof circuit id (clearhash-v1|clearhash-mimc-v1|shielded-ledger-v1|shielded-ledger-v2|clearing-v1|aggregate-v1)",
+	)
+	flag.StringVar(&backend, "backend", backendGroth16, "proving backend (groth16|plonk)")
+	flag.StringVar(
+		&srsPath,
+		"srs",
+		"",
+		"plonk only: canonical KZG SRS file; when empty an unsafe test SRS is generated and written to -out",
 	)
+	flag.IntVar(&windows, "windows", 4, "aggregate-v1 only: number of sample windows (1-8)")
 	flag.Parse()
 	circuitID = strings.TrimSpace(circuitID)
+	backend = strings.TrimSpace(backend)
 
 	if err := os.MkdirAll(outDir, 0o755); err != nil {
 		fatalf("mkdir %s: %v", outDir, err)
@@ -51,50 +79,52 @@ func main() {
 	if err != nil {
 		fatalf("select circuit: %v", err)
 	}
-	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
-	if err != nil {
-		fatalf("compile circuit: %v", err)
+	var keys keyPair
+	switch backend {
+	case backendGroth16:
+		keys, err = setupGroth16(circuit)
+	case backendPlonk:
+		keys, err = setupPlonk(circuit, srsPath, outDir)
+	default:
+		err = fmt.Errorf("unsupported backend: %q", backend)
 	}
-	pk, vk, err := groth16.Setup(ccs)
 	if err != nil {
 		fatalf("setup: %v", err)
 	}
 
-	pkBN, ok := pk.(*groth16bn254.ProvingKey)
-	if !ok {
-		fatalf("unexpected proving key type %T", pk)
-	}
-	vkBN, ok := vk.(*groth16bn254.VerifyingKey)
-	if !ok {
-		fatalf("unexpected verifying key type %T", vk)
-	}
 	pkName, vkName, samplePrefix, writeCompatArtifacts, err := artifactNames(circuitID)
 	if err != nil {
 		fatalf("artifact names: %v", err)
 	}
+	if backend == backendPlonk {
+		pkName = strings.Replace(pkName, backendGroth16, backendPlonk, 1)
+		vkName = strings.Replace(vkName, backendGroth16, backendPlonk, 1)
+		samplePrefix += "_" + backendPlonk
+		writeCompatArtifacts = false
+	}
 	pkPath := filepath.Join(outDir, pkName)
 	vkPath := filepath.Join(outDir, vkName)
 
 	if writeKeyPair {
-		if err := writeWithWriterTo(pkPath, pkBN); err != nil {
+		if err := writeWithWriterTo(pkPath, keys.pk); err != nil {
 			fatalf("write proving key: %v", err)
 		}
-		if err := writeWithWriterTo(vkPath, vkBN); err != nil {
+		if err := writeWithWriterTo(vkPath, keys.vk); err != nil {
 			fatalf("write verifying key: %v", err)
 		}
 		if writeCompatArtifacts {
 			// compatibility copies for older clear-hash scripts/envs
-			if err := writeWithWriterTo(filepath.Join(outDir, "groth16_hashbinding_pk.bin"), pkBN); err != nil {
+			if err := writeWithWriterTo(filepath.Join(outDir, "groth16_hashbinding_pk.bin"), keys.pk); err != nil {
 				fatalf("write compat proving key: %v", err)
 			}
-			if err := writeWithWriterTo(filepath.Join(outDir, "groth16_hashbinding_vk.bin"), vkBN); err != nil {
+			if err := writeWithWriterTo(filepath.Join(outDir, "groth16_hashbinding_vk.bin"), keys.vk); err != nil {
 				fatalf("write compat verifying key: %v", err)
 			}
 		}
 	}
 
 	if writeSample {
-		preimage, hashBytes, err := buildSamplePreimage(circuitID)
+		preimage, hashBytes, err := buildSamplePreimage(circuitID, windows)
 		if err != nil {
 			fatalf("build sample preimage: %v", err)
 		}
@@ -116,21 +146,12 @@ func main() {
 			fatalf("marshal public witness: %v", err)
 		}
 
-		proofAny, err := groth16.Prove(ccs, pk, fullWitness)
+		proofBytes, err := keys.prove(fullWitness)
 		if err != nil {
 			fatalf("prove: %v", err)
 		}
-		proofBN, ok := proofAny.(*groth16bn254.Proof)
-		if !ok {
-			fatalf("unexpected proof type %T", proofAny)
-		}
-		var proofBuf bytes.Buffer
-		if _, err := proofBN.WriteTo(&proofBuf); err != nil {
-			fatalf("serialize proof: %v", err)
-		}
-		proofBytes := proofBuf.Bytes()
 		envelope, err := actions.BuildProofEnvelopeWithCircuit(
-			mconsts.ProofTypeGroth16,
+			keys.proofType,
 			circuitID,
 			proofBytes,
 			publicWitnessBytes,
@@ -156,7 +177,7 @@ func main() {
 		}
 
 		// Preserve historic clear-hash sample names for downstream scripts.
-		if circuitID == mconsts.ProofCircuitClearHashV1 {
+		if circuitID == mconsts.ProofCircuitClearHashV1 && backend == backendGroth16 {
 			if err := os.WriteFile(filepath.Join(outDir, "sample_public_inputs_hash.hex"), []byte(hex.EncodeToString(hashBytes)), 0o644); err != nil {
 				fatalf("write compat sample hash: %v", err)
 			}
@@ -179,16 +200,168 @@ func main() {
 	fmt.Println("Set verifier env on VEIL node:")
 	fmt.Printf("  VEIL_ZK_VERIFIER_ENABLED=true\n")
 	fmt.Printf("  VEIL_ZK_VERIFIER_STRICT=true\n")
-	fmt.Printf("  VEIL_ZK_REQUIRED_CIRCUIT_ID=%s\n", circuitID)
+	if circuitID == mconsts.ProofCircuitAggregateV1 {
+		// Aggregates are verified only against a registered key.
+		fmt.Printf("Register %s with RegisterVerifyingKey for circuit %s.\n", vkPath, circuitID)
+		return
+	}
+	fmt.Printf("Require circuit %s on chain with ScheduleProofUpgrade.\n", circuitID)
+	if backend == backendPlonk {
+		fmt.Printf("  VEIL_ZK_PLONK_VK_PATH=%s\n", vkPath)
+		if srsPath == "" {
+			fmt.Println("PLONK keys use an unsafe test SRS; do not use them outside fixtures.")
+		}
+		return
+	}
 	fmt.Printf("  VEIL_ZK_GROTH16_VK_PATH=%s\n", vkPath)
 }
 
+// keyPair is a backend's proving and verifying key together with its prover,
+// which returns the serialized BN254 proof.
+type keyPair struct {
+	proofType uint8
+	pk        io.WriterTo
+	vk        io.WriterTo
+	prove     func(fullWitness witness.Witness) ([]byte, error)
+}
+
+func setupGroth16(circuit frontend.Circuit) (keyPair, error) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	if err != nil {
+		return keyPair{}, fmt.Errorf("compile circuit: %w", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		return keyPair{}, err
+	}
+	pkBN, ok := pk.(*groth16bn254.ProvingKey)
+	if !ok {
+		return keyPair{}, fmt.Errorf("unexpected proving key type %T", pk)
+	}
+	vkBN, ok := vk.(*groth16bn254.VerifyingKey)
+	if !ok {
+		return keyPair{}, fmt.Errorf("unexpected verifying key type %T", vk)
+	}
+	return keyPair{
+		proofType: mconsts.ProofTypeGroth16,
+		pk:        pkBN,
+		vk:        vkBN,
+		prove: func(fullWitness witness.Witness) ([]byte, error) {
+			proofAny, err := groth16.Prove(ccs, pk, fullWitness)
+			if err != nil {
+				return nil, err
+			}
+			proofBN, ok := proofAny.(*groth16bn254.Proof)
+			if !ok {
+				return nil, fmt.Errorf("unexpected proof type %T", proofAny)
+			}
+			return serialize(proofBN)
+		},
+	}, nil
+}
+
+// setupPlonk compiles the circuit to a sparse constraint system and runs the
+// PLONK setup over a KZG SRS. The SRS is read from srsPath when set;
+// otherwise an unsafe SRS with a known toxic waste is generated and written
+// to outDir so later runs can reuse it.
+func setupPlonk(circuit frontend.Circuit, srsPath string, outDir string) (keyPair, error) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, circuit)
+	if err != nil {
+		return keyPair{}, fmt.Errorf("compile circuit: %w", err)
+	}
+	canonical, lagrange, err := plonkSRS(ccs, srsPath)
+	if err != nil {
+		return keyPair{}, err
+	}
+	if srsPath == "" {
+		if err := writeWithWriterTo(filepath.Join(outDir, "plonk_unsafe_srs.bin"), canonical); err != nil {
+			return keyPair{}, fmt.Errorf("write srs: %w", err)
+		}
+	}
+	pk, vk, err := plonk.Setup(ccs, canonical, lagrange)
+	if err != nil {
+		return keyPair{}, err
+	}
+	pkBN, ok := pk.(*plonkbn254.ProvingKey)
+	if !ok {
+		return keyPair{}, fmt.Errorf("unexpected proving key type %T", pk)
+	}
+	vkBN, ok := vk.(*plonkbn254.VerifyingKey)
+	if !ok {
+		return keyPair{}, fmt.Errorf("unexpected verifying key type %T", vk)
+	}
+	return keyPair{
+		proofType: mconsts.ProofTypePlonk,
+		pk:        pkBN,
+		vk:        vkBN,
+		prove: func(fullWitness witness.Witness) ([]byte, error) {
+			proofAny, err := plonk.Prove(ccs, pk, fullWitness)
+			if err != nil {
+				return nil, err
+			}
+			proofBN, ok := proofAny.(*plonkbn254.Proof)
+			if !ok {
+				return nil, fmt.Errorf("unexpected proof type %T", proofAny)
+			}
+			return serialize(proofBN)
+		},
+	}, nil
+}
+
+// plonkSRS returns the canonical and Lagrange KZG SRS sized for ccs.
+func plonkSRS(ccs constraint.ConstraintSystem, srsPath string) (*kzgbn254.SRS, *kzgbn254.SRS, error) {
+	if srsPath == "" {
+		canonical, lagrange, err := unsafekzg.NewSRS(ccs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("generate srs: %w", err)
+		}
+		return canonical.(*kzgbn254.SRS), lagrange.(*kzgbn254.SRS), nil
+	}
+
+	raw, err := os.ReadFile(srsPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read srs: %w", err)
+	}
+	canonical := new(kzgbn254.SRS)
+	if _, err := canonical.ReadFrom(bytes.NewReader(raw)); err != nil {
+		return nil, nil, fmt.Errorf("parse srs: %w", err)
+	}
+	// Same sizing as gnark's SRS helpers: the Lagrange basis covers the
+	// domain and the canonical SRS needs three extra points for blinding.
+	sizeLagrange := ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints() + ccs.GetNbPublicVariables()))
+	if uint64(len(canonical.Pk.G1)) < sizeLagrange+3 {
+		return nil, nil, fmt.Errorf("srs too small: got=%d need=%d", len(canonical.Pk.G1), sizeLagrange+3)
+	}
+	lagrangeG1, err := kzgbn254.ToLagrangeG1(canonical.Pk.G1[:sizeLagrange])
+	if err != nil {
+		return nil, nil, fmt.Errorf("lagrange srs: %w", err)
+	}
+	lagrange := &kzgbn254.SRS{Pk: kzgbn254.ProvingKey{G1: lagrangeG1}, Vk: canonical.Vk}
+	return canonical, lagrange, nil
+}
+
+func serialize(obj io.WriterTo) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := obj.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("serialize proof: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
 func selectedCircuit(circuitID string) (frontend.Circuit, error) {
 	switch strings.TrimSpace(circuitID) {
 	case mconsts.ProofCircuitClearHashV1:
 		return &zk.ClearHashCircuit{}, nil
+	case mconsts.ProofCircuitClearHashMiMCV1:
+		return &zk.ClearHashMiMCCircuit{}, nil
+	case mconsts.ProofCircuitAggregateV1:
+		return &zk.AggregateCircuitV1{}, nil
 	case mconsts.ProofCircuitShieldedLedgerV1:
 		return &zk.ShieldedLedgerCircuitV1{}, nil
+	case mconsts.ProofCircuitShieldedLedgerV2:
+		return &zk.ShieldedLedgerCircuitV2{}, nil
+	case mconsts.ProofCircuitClearingV1:
+		return &zk.ClearingCircuitV1{}, nil
 	default:
 		return nil, fmt.Errorf("unsupported circuit id: %q", circuitID)
 	}
@@ -198,8 +371,16 @@ func artifactNames(circuitID string) (pkName string, vkName string, samplePrefix
 	switch strings.TrimSpace(circuitID) {
 	case mconsts.ProofCircuitClearHashV1:
 		return "groth16_clearhash_pk.bin", "groth16_clearhash_vk.bin", "sample_clearhash", true, nil
+	case mconsts.ProofCircuitClearHashMiMCV1:
+		return "groth16_clearhash_mimc_pk.bin", "groth16_clearhash_mimc_vk.bin", "sample_clearhash_mimc", false, nil
+	case mconsts.ProofCircuitAggregateV1:
+		return "groth16_aggregate_pk.bin", "groth16_aggregate_vk.bin", "sample_aggregate", false, nil
 	case mconsts.ProofCircuitShieldedLedgerV1:
 		return "groth16_shielded_ledger_pk.bin", "groth16_shielded_ledger_vk.bin", "sample_shielded_ledger", false, nil
+	case mconsts.ProofCircuitShieldedLedgerV2:
+		return "groth16_shielded_ledger_v2_pk.bin", "groth16_shielded_ledger_v2_vk.bin", "sample_shielded_ledger_v2", false, nil
+	case mconsts.ProofCircuitClearingV1:
+		return "groth16_clearing_pk.bin", "groth16_clearing_vk.bin", "sample_clearing", false, nil
 	default:
 		return "", "", "", false, fmt.Errorf("unsupported circuit id: %q", circuitID)
 	}
@@ -209,14 +390,25 @@ func buildAssignment(circuitID string, preimage []byte, hashBytes []byte) (front
 	switch strings.TrimSpace(circuitID) {
 	case mconsts.ProofCircuitClearHashV1:
 		return zk.NewClearHashAssignment(preimage, hashBytes)
+	case mconsts.ProofCircuitClearHashMiMCV1:
+		return zk.NewClearHashMiMCAssignment(preimage, hashBytes)
+	case mconsts.ProofCircuitAggregateV1:
+		return zk.NewAggregateV1Assignment(preimage, hashBytes)
 	case mconsts.ProofCircuitShieldedLedgerV1:
 		return zk.NewShieldedLedgerAssignment(preimage, hashBytes)
+	case mconsts.ProofCircuitShieldedLedgerV2:
+		return buildShieldedLedgerV2Assignment(hashBytes)
+	case mconsts.ProofCircuitClearingV1:
+		return buildClearingV1Assignment(preimage, hashBytes)
 	default:
 		return nil, fmt.Errorf("unsupported circuit id: %q", circuitID)
 	}
 }
 
-func buildSamplePreimage(circuitID string) ([]byte, []byte, error) {
+func buildSamplePreimage(circuitID string, windows int) ([]byte, []byte, error) {
+	if strings.TrimSpace(circuitID) == mconsts.ProofCircuitAggregateV1 {
+		return buildAggregateSamplePreimage(windows)
+	}
 	var marketID ids.ID
 	if _, err := rand.Read(marketID[:]); err != nil {
 		return nil, nil, fmt.Errorf("rand marketID: %w", err)
@@ -235,11 +427,47 @@ func buildSamplePreimage(circuitID string) ([]byte, []byte, error) {
 		if len(preimage) != zk.ClearHashPreimageLen {
 			return nil, nil, fmt.Errorf("invalid clear-hash sample preimage length: got=%d expected=%d", len(preimage), zk.ClearHashPreimageLen)
 		}
+	case mconsts.ProofCircuitClearHashMiMCV1:
+		// The MiMC digest is not a SHA-256 of the preimage.
+		preimage, err := actions.BuildClearMiMCPublicInputsPreimage(marketID, windowID, clearPrice, totalVolume, fills)
+		if err != nil {
+			return nil, nil, fmt.Errorf("clear-hash-mimc sample preimage: %w", err)
+		}
+		hash, err := actions.ComputeClearMiMCPublicInputsHash(marketID, windowID, clearPrice, totalVolume, fills)
+		if err != nil {
+			return nil, nil, fmt.Errorf("clear-hash-mimc sample hash: %w", err)
+		}
+		return preimage, hash[:], nil
 	case mconsts.ProofCircuitShieldedLedgerV1:
 		preimage = actions.BuildShieldedLedgerPublicInputsPreimage(marketID, windowID, clearPrice, totalVolume, fills)
 		if len(preimage) != zk.ShieldedLedgerPreimageLen {
 			return nil, nil, fmt.Errorf("invalid shielded-ledger sample preimage length: got=%d expected=%d", len(preimage), zk.ShieldedLedgerPreimageLen)
 		}
+	case mconsts.ProofCircuitShieldedLedgerV2:
+		preimage = actions.BuildShieldedLedgerV2PublicInputsPreimage(marketID, windowID, clearPrice, totalVolume, fills)
+	case mconsts.ProofCircuitClearingV1:
+		// Clearing-v1 proves the clearing itself, so the sample clears real
+		// orders instead of random fills.
+		orders := sampleClearingOrders()
+		clearing, err := actions.ComputeUniformClearing(marketID, windowID, orders)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sample clearing: %w", err)
+		}
+		ordersDigest, err := actions.ComputeClearingOrdersDigest(orders)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sample orders digest: %w", err)
+		}
+		preimage = actions.BuildClearingV1PublicInputsPreimage(
+			marketID,
+			windowID,
+			clearing.ClearPrice,
+			clearing.TotalVolume,
+			clearing.FillsHash[:],
+			ordersDigest,
+		)
+		if len(preimage) != zk.ClearingV1PreimageLen {
+			return nil, nil, fmt.Errorf("invalid clearing-v1 sample preimage length: got=%d expected=%d", len(preimage), zk.ClearingV1PreimageLen)
+		}
 	default:
 		return nil, nil, fmt.Errorf("unsupported circuit id: %q", circuitID)
 	}
@@ -247,6 +475,134 @@ func buildSamplePreimage(circuitID string) ([]byte, []byte, error) {
 	return preimage, hash[:], nil
 }
 
+// buildShieldedLedgerV2Assignment proves a sample transfer that spends one
+// VEIL note into a payment, change and a fee. The note is the only leaf of
+// the note tree.
+func buildShieldedLedgerV2Assignment(batchDigest []byte) (frontend.Circuit, error) {
+	const (
+		spendValue = uint64(1_000)
+		payValue   = uint64(600)
+		fee        = uint64(10)
+	)
+	spendingKey, err := shielded.RandomElement(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("rand spending key: %w", err)
+	}
+	randomNote := func(value uint64, owner fr.Element) (shielded.Note, error) {
+		rho, err := shielded.RandomElement(rand.Reader)
+		if err != nil {
+			return shielded.Note{}, err
+		}
+		noteRand, err := shielded.RandomElement(rand.Reader)
+		if err != nil {
+			return shielded.Note{}, err
+		}
+		return shielded.Note{Asset: actions.AssetVEIL, Value: value, Owner: owner, Rho: rho, Rand: noteRand}, nil
+	}
+
+	owner := shielded.OwnerKey(spendingKey)
+	spends := make([]zk.ShieldedSpend, zk.ShieldedLedgerV2Inputs)
+	for i := range spends {
+		value := uint64(0)
+		if i == 0 {
+			value = spendValue
+		}
+		note, err := randomNote(value, owner)
+		if err != nil {
+			return nil, fmt.Errorf("rand spend note: %w", err)
+		}
+		spends[i] = zk.ShieldedSpend{Note: note, SpendingKey: spendingKey}
+	}
+	path, noteRoot, err := shielded.Path([]fr.Element{spends[0].Note.Commitment()}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("note tree path: %w", err)
+	}
+	spends[0].Path = path
+
+	outputs := make([]shielded.Note, zk.ShieldedLedgerV2Outputs)
+	values := []uint64{payValue, spendValue - payValue - fee}
+	for i := range outputs {
+		value := uint64(0)
+		if i < len(values) {
+			value = values[i]
+		}
+		if outputs[i], err = randomNote(value, owner); err != nil {
+			return nil, fmt.Errorf("rand output note: %w", err)
+		}
+	}
+	return zk.NewShieldedLedgerV2Assignment(batchDigest, noteRoot, actions.AssetVEIL, fee, spends, outputs)
+}
+
+// buildAggregateSamplePreimage concatenates the clearhash-mimc-v1 preimages
+// of windows random sample windows, one per market, and returns them with
+// their aggregate-v1 digest.
+func buildAggregateSamplePreimage(windows int) ([]byte, []byte, error) {
+	if windows < 1 || windows > zk.AggregateV1MaxWindows {
+		return nil, nil, fmt.Errorf("invalid aggregate window count: got=%d max=%d", windows, zk.AggregateV1MaxWindows)
+	}
+	var preimages []byte
+	hashes := make([][]byte, windows)
+	for i := range hashes {
+		var marketID ids.ID
+		if _, err := rand.Read(marketID[:]); err != nil {
+			return nil, nil, fmt.Errorf("rand marketID: %w", err)
+		}
+		fills := make([]byte, actions.ExpectedFillsHashSize)
+		if _, err := rand.Read(fills); err != nil {
+			return nil, nil, fmt.Errorf("rand fills hash: %w", err)
+		}
+		preimage, err := actions.BuildClearMiMCPublicInputsPreimage(marketID, 1, 1025, 3200, fills)
+		if err != nil {
+			return nil, nil, fmt.Errorf("aggregate sample preimage: %w", err)
+		}
+		hash, err := actions.ComputeClearMiMCPublicInputsHash(marketID, 1, 1025, 3200, fills)
+		if err != nil {
+			return nil, nil, fmt.Errorf("aggregate sample hash: %w", err)
+		}
+		preimages = append(preimages, preimage...)
+		hashes[i] = hash[:]
+	}
+	digest, err := actions.ComputeAggregatePublicInputsHash(hashes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("aggregate sample digest: %w", err)
+	}
+	return preimages, digest[:], nil
+}
+
+// sampleClearingOrders is a small crossing book in canonical trader order.
+// The buy side is larger at the clearing price, so one buy fills partially.
+func sampleClearingOrders() []actions.BatchOrder {
+	order := func(n byte, side uint8, limitPrice uint64, quantity uint64) actions.BatchOrder {
+		var trader codec.Address
+		trader[0] = n
+		return actions.BatchOrder{Trader: trader, Side: side, LimitPrice: limitPrice, Quantity: quantity}
+	}
+	return []actions.BatchOrder{
+		order(1, actions.OrderSideBuy, 5_500, 1_200),
+		order(2, actions.OrderSideSell, 4_800, 900),
+		order(3, actions.OrderSideBuy, 6_000, 700),
+		order(4, actions.OrderSideSell, 5_200, 600),
+		order(5, actions.OrderSideBuy, 4_500, 400),
+	}
+}
+
+// buildClearingV1Assignment re-clears the sample orders for the market and
+// window encoded in the sample preimage.
+func buildClearingV1Assignment(preimage []byte, digest []byte) (frontend.Circuit, error) {
+	if len(preimage) != zk.ClearingV1PreimageLen {
+		return nil, fmt.Errorf("invalid clearing-v1 preimage length: got=%d expected=%d", len(preimage), zk.ClearingV1PreimageLen)
+	}
+	offset := len(actions.ClearingV1InputsDomainTag)
+	marketID := ids.ID(preimage[offset : offset+ids.IDLen])
+	windowID := binary.BigEndian.Uint64(preimage[offset+ids.IDLen:])
+	orders := sampleClearingOrders()
+	clearing, err := actions.ComputeUniformClearing(marketID, windowID, orders)
+	if err != nil {
+		return nil, fmt.Errorf("sample clearing: %w", err)
+	}
+	return zk.NewClearingV1Assignment(marketID, windowID, orders, clearing, digest)
+}
+
 func writeWithWriterTo(path string, obj io.WriterTo) error {
 	buf := &bytes.Buffer{}
 	if _, err := obj.WriteTo(buf); err != nil {
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/ava-labs/hypersdk/examples/veilvm/zk"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+)
+
+func TestSetupPlonkRoundTrip(t *testing.T) {
+	circuitID := mconsts.ProofCircuitClearHashMiMCV1
+	circuit, err := selectedCircuit(circuitID)
+	if err != nil {
+		t.Fatalf("select circuit: %v", err)
+	}
+	outDir := t.TempDir()
+	keys, err := setupPlonk(circuit, "", outDir)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if keys.proofType != mconsts.ProofTypePlonk {
+		t.Fatalf("unexpected proof type: %d", keys.proofType)
+	}
+
+	preimage, hashBytes, err := buildSamplePreimage(circuitID, 1)
+	if err != nil {
+		t.Fatalf("sample preimage: %v", err)
+	}
+	assignment, err := buildAssignment(circuitID, preimage, hashBytes)
+	if err != nil {
+		t.Fatalf("assignment: %v", err)
+	}
+	fullWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("new witness: %v", err)
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		t.Fatalf("public witness: %v", err)
+	}
+	publicWitnessBytes, err := publicWitness.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal public witness: %v", err)
+	}
+	proof, err := keys.prove(fullWitness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+
+	// The sample proof verifies through the consensus verifier against the
+	// key the tool writes out.
+	vk, err := serialize(keys.vk)
+	if err != nil {
+		t.Fatalf("serialize vk: %v", err)
+	}
+	verifier, err := zk.NewVerifier(zk.Config{})
+	if err != nil {
+		t.Fatalf("new verifier: %v", err)
+	}
+	if err := verifier.Verify(mconsts.ProofTypePlonk, circuitID, vk, proof, hashBytes, publicWitnessBytes); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	otherHash := append([]byte(nil), hashBytes...)
+	otherHash[len(otherHash)-1] ^= 0x01
+	if err := verifier.Verify(mconsts.ProofTypePlonk, circuitID, vk, proof, otherHash, publicWitnessBytes); !errors.Is(err, storage.ErrProofPublicInputsMismatch) {
+		t.Fatalf("expected public inputs mismatch, got %v", err)
+	}
+
+	// Loading the written SRS reproduces the same verifying key, so fixtures
+	// can be regenerated without a new setup.
+	reloaded, err := setupPlonk(circuit, filepath.Join(outDir, "plonk_unsafe_srs.bin"), t.TempDir())
+	if err != nil {
+		t.Fatalf("setup from srs file: %v", err)
+	}
+	reloadedVK, err := serialize(reloaded.vk)
+	if err != nil {
+		t.Fatalf("serialize reloaded vk: %v", err)
+	}
+	if !bytes.Equal(vk, reloadedVK) {
+		t.Fatalf("verifying key differs after reloading the srs")
+	}
+}
@@ -2,23 +2,37 @@ package consts
 
 const (
 	// Action TypeIDs
-	TransferID           uint8 = 0
-	CreateMarketID       uint8 = 1
-	CommitOrderID        uint8 = 2
-	RevealBatchID        uint8 = 3
-	ClearBatchID         uint8 = 4
-	ResolveMarketID      uint8 = 5
-	DisputeID            uint8 = 6
-	RouteFeesID          uint8 = 7
-	ReleaseCOLTrancheID  uint8 = 8
-	MintVAIID            uint8 = 9
-	BurnVAIID            uint8 = 10
-	CreatePoolID         uint8 = 11
-	AddLiquidityID       uint8 = 12
-	RemoveLiquidityID    uint8 = 13
-	SwapExactInID        uint8 = 14
-	UpdateReserveStateID uint8 = 15
-	SetRiskParamsID      uint8 = 16
-	SubmitBatchProofID   uint8 = 17
-	SetProofConfigID     uint8 = 18
+	TransferID             uint8 = 0
+	CreateMarketID         uint8 = 1
+	CommitOrderID          uint8 = 2
+	RevealBatchID          uint8 = 3
+	ClearBatchID           uint8 = 4
+	ResolveMarketID        uint8 = 5
+	DisputeID              uint8 = 6
+	RouteFeesID            uint8 = 7
+	ReleaseCOLTrancheID    uint8 = 8
+	MintVAIID              uint8 = 9
+	BurnVAIID              uint8 = 10
+	CreatePoolID           uint8 = 11
+	AddLiquidityID         uint8 = 12
+	RemoveLiquidityID      uint8 = 13
+	SwapExactInID          uint8 = 14
+	UpdateReserveStateID   uint8 = 15
+	SetRiskParamsID        uint8 = 16
+	SubmitBatchProofID     uint8 = 17
+	SetProofConfigID       uint8 = 18
+	ReleaseEscrowID        uint8 = 19
+	RedeemWinningsID       uint8 = 20
+	SettleDisputeID        uint8 = 21
+	SetDecryptCommitteeID  uint8 = 22
+	CancelCommitmentID     uint8 = 23
+	ShieldID               uint8 = 24
+	UnshieldID             uint8 = 25
+	RegisterVerifyingKeyID uint8 = 26
+	ScheduleProofUpgradeID uint8 = 27
+	SubmitAggregateProofID uint8 = 28
+	SetProverCommitteeID   uint8 = 29
+	ReportMissedProofID    uint8 = 30
+	BondProverID           uint8 = 31
+	UnbondProverID         uint8 = 32
 )
@@ -8,4 +8,18 @@ const (
 const (
 	ProofCircuitClearHashV1      = "clearhash-v1"
 	ProofCircuitShieldedLedgerV1 = "shielded-ledger-v1"
+	ProofCircuitShieldedLedgerV2 = "shielded-ledger-v2"
+	ProofCircuitClearingV1       = "clearing-v1"
+	ProofCircuitClearHashMiMCV1  = "clearhash-mimc-v1"
+	ProofCircuitAggregateV1      = "aggregate-v1"
 )
+
+// ProofCircuitIDs lists every circuit the chain accepts proofs for.
+var ProofCircuitIDs = []string{
+	ProofCircuitClearHashV1,
+	ProofCircuitShieldedLedgerV1,
+	ProofCircuitShieldedLedgerV2,
+	ProofCircuitClearingV1,
+	ProofCircuitClearHashMiMCV1,
+	ProofCircuitAggregateV1,
+}
@@ -608,7 +608,7 @@ async function resolveHealthyNodeUrl(args) {
     `last error: ${lastMessage}`,
     'troubleshooting:',
     '- start/restart VeilVM node: docker compose -f docker-compose.local.yml up -d --build node',
-    '- ensure shielded verifier gate is active: VEIL_ZK_REQUIRED_CIRCUIT_ID=shielded-ledger-v1',
+    '- ensure shielded verifier gate is active: VEIL_ZK_VERIFIER_ENABLED=true with the shielded-ledger key registered',
     '- verify readiness endpoint: http://127.0.0.1:9660/ext/health/readiness',
     '- if docker commands hang, restart Docker Desktop and retry',
   ].join('\n');
@@ -0,0 +1,160 @@
+// Package shielded implements the native note primitives of the VeilVM
+// shielded pool: note commitments, nullifiers and the append-only note tree.
+//
+// Every value is a BN254 scalar field element hashed with MiMC, so the same
+// computations are cheap to express inside gnark circuits. Package zk mirrors
+// each function here as a circuit gadget; the two must stay in lockstep.
+//
+//	owner      = MiMC(ownerTag, spendingKey)
+//	recipient  = MiMC(recipientTag, owner, rho, rand)
+//	commitment = MiMC(noteTag, asset, value, recipient)
+//	nullifier  = MiMC(nullifierTag, spendingKey, commitment)
+//	node       = MiMC(left, right)
+//
+// The recipient hash hides who can spend a note, so a commitment can be
+// derived on-chain from a public asset and value without revealing the owner.
+package shielded
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+)
+
+const (
+	// ElementSize is the canonical big-endian encoding size of a field element.
+	ElementSize = fr.Bytes
+
+	// TreeDepth is the fixed depth of the note-commitment tree.
+	TreeDepth = 20
+
+	ownerDomainTag     = "VEIL_SHIELDED_OWNER_V1"
+	recipientDomainTag = "VEIL_SHIELDED_RECIPIENT_V1"
+	noteDomainTag      = "VEIL_SHIELDED_NOTE_V1"
+	nullifierDomainTag = "VEIL_SHIELDED_NULLIFIER_V1"
+)
+
+var (
+	ErrInvalidElement = errors.New("invalid field element encoding")
+
+	ownerTag     = domainElement(ownerDomainTag)
+	recipientTag = domainElement(recipientDomainTag)
+	noteTag      = domainElement(noteDomainTag)
+	nullifierTag = domainElement(nullifierDomainTag)
+)
+
+// Note is a shielded note owned by the holder of the spending key whose
+// OwnerKey matches Owner.
+type Note struct {
+	Asset uint8
+	Value uint64
+	Owner fr.Element
+	Rho   fr.Element
+	Rand  fr.Element
+}
+
+// OwnerTag, RecipientTag, NoteTag and NullifierTag expose the domain
+// separators so circuits can embed them as constants.
+func OwnerTag() *big.Int     { return toBig(ownerTag) }
+func RecipientTag() *big.Int { return toBig(recipientTag) }
+func NoteTag() *big.Int      { return toBig(noteTag) }
+func NullifierTag() *big.Int { return toBig(nullifierTag) }
+
+// OwnerKey derives the public owner key of a spending key.
+func OwnerKey(spendingKey fr.Element) fr.Element {
+	return Hash(ownerTag, spendingKey)
+}
+
+// Recipient returns the hash that hides the note's owner and randomness.
+func (n Note) Recipient() fr.Element {
+	return Hash(recipientTag, n.Owner, n.Rho, n.Rand)
+}
+
+// Commitment returns the note commitment appended to the note tree.
+func (n Note) Commitment() fr.Element {
+	return Commitment(n.Asset, n.Value, n.Recipient())
+}
+
+// Commitment binds a public asset and value to a recipient hash.
+func Commitment(asset uint8, value uint64, recipient fr.Element) fr.Element {
+	var a, v fr.Element
+	a.SetUint64(uint64(asset))
+	v.SetUint64(value)
+	return Hash(noteTag, a, v, recipient)
+}
+
+// Nullifier derives the nullifier revealed when a note is spent. Only the
+// holder of spendingKey can compute it, and it is unique per commitment.
+func Nullifier(spendingKey fr.Element, commitment fr.Element) fr.Element {
+	return Hash(nullifierTag, spendingKey, commitment)
+}
+
+// HashNode combines two Merkle children into their parent.
+func HashNode(left fr.Element, right fr.Element) fr.Element {
+	return Hash(left, right)
+}
+
+// RootFromPath recomputes the tree root from a leaf at index and its
+// sibling path, ordered from the leaf level upwards.
+func RootFromPath(leaf fr.Element, index uint64, path []fr.Element) fr.Element {
+	node := leaf
+	for i := range path {
+		if index&(1<<uint(i)) == 0 {
+			node = HashNode(node, path[i])
+		} else {
+			node = HashNode(path[i], node)
+		}
+	}
+	return node
+}
+
+// Hash is MiMC over a sequence of field elements.
+func Hash(elems ...fr.Element) fr.Element {
+	h := mimc.NewMiMC()
+	for i := range elems {
+		b := elems[i].Bytes()
+		_, _ = h.Write(b[:])
+	}
+	var out fr.Element
+	out.SetBytes(h.Sum(nil))
+	return out
+}
+
+// RandomElement samples a uniformly random field element, for note rho,
+// rand and spending keys.
+func RandomElement(r io.Reader) (fr.Element, error) {
+	var buf [ElementSize + 16]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return fr.Element{}, err
+	}
+	var out fr.Element
+	out.SetBigInt(new(big.Int).SetBytes(buf[:]))
+	return out, nil
+}
+
+// ElementFromBytes parses a canonical big-endian field element.
+func ElementFromBytes(b []byte) (fr.Element, error) {
+	if len(b) != ElementSize {
+		return fr.Element{}, ErrInvalidElement
+	}
+	var out fr.Element
+	if err := out.SetBytesCanonical(b); err != nil {
+		return fr.Element{}, ErrInvalidElement
+	}
+	return out, nil
+}
+
+func domainElement(tag string) fr.Element {
+	sum := sha256.Sum256([]byte(tag))
+	var out fr.Element
+	out.SetBytes(sum[:])
+	return out
+}
+
+func toBig(e fr.Element) *big.Int {
+	return e.BigInt(new(big.Int))
+}
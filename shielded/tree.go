@@ -0,0 +1,96 @@
+package shielded
+
+import (
+	"errors"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+var (
+	ErrTreeFull       = errors.New("note tree is full")
+	ErrLeafOutOfRange = errors.New("leaf index is out of range")
+)
+
+// zeroHashes[i] is the root of an empty subtree of height i. Unused leaves
+// are the zero element.
+var zeroHashes = func() [TreeDepth + 1]fr.Element {
+	var out [TreeDepth + 1]fr.Element
+	for i := 0; i < TreeDepth; i++ {
+		out[i+1] = HashNode(out[i], out[i])
+	}
+	return out
+}()
+
+// EmptyRoot is the root of a tree with no leaves.
+func EmptyRoot() fr.Element {
+	return zeroHashes[TreeDepth]
+}
+
+// Tree is an append-only Merkle tree of fixed depth TreeDepth. It keeps only
+// the frontier: Frontier[i] is the last left child appended at height i,
+// which is all that is needed to append further leaves and update Root.
+type Tree struct {
+	Size     uint64
+	Root     fr.Element
+	Frontier [TreeDepth]fr.Element
+}
+
+func NewTree() Tree {
+	return Tree{Root: EmptyRoot()}
+}
+
+// Append adds leaf at index Size and returns that index.
+func (t *Tree) Append(leaf fr.Element) (uint64, error) {
+	index := t.Size
+	if index >= 1<<TreeDepth {
+		return 0, ErrTreeFull
+	}
+	node := leaf
+	pos := index
+	for i := 0; i < TreeDepth; i++ {
+		if pos&1 == 0 {
+			t.Frontier[i] = node
+			node = HashNode(node, zeroHashes[i])
+		} else {
+			node = HashNode(t.Frontier[i], node)
+		}
+		pos >>= 1
+	}
+	t.Root = node
+	t.Size++
+	return index, nil
+}
+
+// Path returns the sibling path of the leaf at index, ordered from the leaf
+// level upwards, and the root it authenticates against. leaves must hold
+// every appended leaf in order.
+func Path(leaves []fr.Element, index uint64) ([TreeDepth]fr.Element, fr.Element, error) {
+	var path [TreeDepth]fr.Element
+	if uint64(len(leaves)) > 1<<TreeDepth {
+		return path, fr.Element{}, ErrTreeFull
+	}
+	if index >= uint64(len(leaves)) {
+		return path, fr.Element{}, ErrLeafOutOfRange
+	}
+
+	level := append([]fr.Element(nil), leaves...)
+	pos := index
+	for i := 0; i < TreeDepth; i++ {
+		if sibling := pos ^ 1; sibling < uint64(len(level)) {
+			path[i] = level[sibling]
+		} else {
+			path[i] = zeroHashes[i]
+		}
+		next := make([]fr.Element, (len(level)+1)/2)
+		for j := range next {
+			right := zeroHashes[i]
+			if 2*j+1 < len(level) {
+				right = level[2*j+1]
+			}
+			next[j] = HashNode(level[2*j], right)
+		}
+		level = next
+		pos >>= 1
+	}
+	return path, level[0], nil
+}
@@ -0,0 +1,64 @@
+package shielded
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+func TestTreeAppendMatchesPaths(t *testing.T) {
+	tree := NewTree()
+	if root := EmptyRoot(); !tree.Root.Equal(&root) {
+		t.Fatalf("new tree must have the empty root")
+	}
+
+	var leaves []fr.Element
+	for i := uint64(0); i < 7; i++ {
+		var leaf fr.Element
+		leaf.SetUint64(1_000 + i)
+		index, err := tree.Append(leaf)
+		if err != nil {
+			t.Fatalf("append %d failed: %v", i, err)
+		}
+		if index != i {
+			t.Fatalf("unexpected index: got=%d want=%d", index, i)
+		}
+		leaves = append(leaves, leaf)
+
+		// Every earlier leaf still authenticates to the updated root.
+		for j := range leaves {
+			path, root, err := Path(leaves, uint64(j))
+			if err != nil {
+				t.Fatalf("path %d failed: %v", j, err)
+			}
+			if !root.Equal(&tree.Root) {
+				t.Fatalf("path root mismatch after %d appends", i+1)
+			}
+			if got := RootFromPath(leaves[j], uint64(j), path[:]); !got.Equal(&tree.Root) {
+				t.Fatalf("leaf %d does not authenticate after %d appends", j, i+1)
+			}
+		}
+	}
+
+	if _, _, err := Path(leaves, uint64(len(leaves))); !errors.Is(err, ErrLeafOutOfRange) {
+		t.Fatalf("expected ErrLeafOutOfRange, got %v", err)
+	}
+}
+
+func TestNullifierBindsSpendingKey(t *testing.T) {
+	var sk, other fr.Element
+	sk.SetUint64(7)
+	other.SetUint64(8)
+	note := Note{Asset: 1, Value: 50, Owner: OwnerKey(sk)}
+	commitment := note.Commitment()
+
+	a, b := Nullifier(sk, commitment), Nullifier(other, commitment)
+	if a.Equal(&b) {
+		t.Fatalf("nullifier must depend on the spending key")
+	}
+	note.Value++
+	if moved := note.Commitment(); moved.Equal(&commitment) {
+		t.Fatalf("commitment must bind the value")
+	}
+}
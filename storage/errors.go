@@ -12,6 +12,80 @@ var (
 	ErrMarketResolved    = errors.New("market already resolved")
 	ErrInvalidOutcome    = errors.New("invalid outcome")
 	ErrDisputeWindow     = errors.New("not in dispute window")
+	ErrDisputeWindowOpen = errors.New("market is still in its dispute window")
+	ErrMarketNotDisputed = errors.New("market is not disputed")
+	ErrDisputeNotFound   = errors.New("dispute not found")
+	ErrInvalidDispute    = errors.New("invalid dispute")
+
+	ErrInvalidMarketResolution = errors.New("invalid market resolution")
+
+	ErrCommitmentExists     = errors.New("commitment already exists")
+	ErrCommitmentNotFound   = errors.New("commitment not found")
+	ErrEscrowNotFound       = errors.New("escrow not found")
+	ErrInvalidEscrow        = errors.New("invalid escrow")
+	ErrEscrowLocked         = errors.New("escrow is locked until the window clears or is abandoned")
+	ErrInsufficientEscrow   = errors.New("insufficient escrow")
+	ErrBatchAlreadyCleared  = errors.New("batch window already cleared")
+	ErrBatchResultNotFound  = errors.New("batch result not found")
+	ErrInvalidBatchResult   = errors.New("invalid batch result")
+	ErrWindowStateNotFound  = errors.New("window state not found")
+	ErrInvalidWindowState   = errors.New("invalid window state")
+	ErrWindowNotOpen        = errors.New("window is not open for commits")
+	ErrWindowNotRevealPhase = errors.New("window is not in its reveal phase")
+	ErrWindowNotClearable   = errors.New("window is not ready to clear")
+	ErrWindowAbandoned      = errors.New("window was abandoned")
+	ErrWindowCloseMismatch  = errors.New("window close time does not match window state")
+
+	ErrInvalidCommitment        = errors.New("invalid commitment record")
+	ErrOrderFaultNotFound       = errors.New("order fault not found")
+	ErrInvalidOrderFault        = errors.New("invalid order fault")
+	ErrDecryptCommitteeNotFound = errors.New("decrypt committee not found")
+	ErrInvalidDecryptCommittee  = errors.New("invalid decrypt committee")
+	ErrInvalidWindowDecryption  = errors.New("invalid window decryption")
+	ErrProverCommitteeNotFound  = errors.New("prover committee not found")
+	ErrInvalidProverCommittee   = errors.New("invalid prover committee")
+	ErrProverNotAssigned        = errors.New("prover is not assigned to window")
+	ErrBackupProverNotEligible  = errors.New("backup prover is not yet eligible")
+	ErrProverJailed             = errors.New("prover is jailed")
+	ErrInvalidProverBond        = errors.New("invalid prover bond")
+	ErrInvalidProverJails       = errors.New("invalid prover jails")
+	ErrProverAssignmentNotFound = errors.New("prover assignment not found")
+	ErrInvalidProverAssignment  = errors.New("invalid prover assignment")
+	ErrInvalidUnderbondedList   = errors.New("invalid underbonded prover list")
+	ErrInsufficientProverBond   = errors.New("insufficient prover bond")
+	ErrInvalidBondAmount        = errors.New("invalid bond amount")
+	ErrProverBondUnbonding      = errors.New("prover bond is still unbonding")
+	ErrWindowNotRevealed        = errors.New("window key has not been revealed")
+	ErrDuplicateRevealShare     = errors.New("reveal share already submitted")
+	ErrRevealShareNotFound      = errors.New("reveal share not found")
+	ErrStaleRevealShare         = errors.New("reveal share is for a window past its reveal phase")
+	ErrInvalidRevealShare       = errors.New("invalid reveal share")
+	ErrDecryptCommitteeRotated  = errors.New("decrypt committee was replaced for this window")
+
+	ErrMarketPoolNotFound      = errors.New("market pool not found")
+	ErrInvalidMarketPool       = errors.New("invalid market pool")
+	ErrCollateralAssetMismatch = errors.New("collateral asset does not match market")
+	ErrInsufficientCollateral  = errors.New("insufficient market collateral")
+	ErrNoPosition              = errors.New("no outcome position")
+	ErrSettlementNotFound      = errors.New("settlement not found")
+	ErrInvalidSettlement       = errors.New("invalid settlement")
+	ErrNullifierSpent          = errors.New("nullifier already spent")
+	ErrNullifierNotFound       = errors.New("nullifier not found")
+	ErrInvalidNullifier        = errors.New("invalid nullifier record")
+	ErrInvalidNoteTree         = errors.New("invalid note tree")
+	ErrInvalidNoteCommitment   = errors.New("invalid note commitment")
+	ErrDuplicateNoteCommitment = errors.New("note commitment already in tree")
+	ErrNoteLeafNotFound        = errors.New("note commitment not found")
+	ErrUnknownNoteRoot         = errors.New("note root is not a recent root")
+	ErrShieldedPoolOverflow    = errors.New("shielded pool overflow")
+	ErrShieldedPoolUnderflow   = errors.New("shielded pool holds less than the amount leaving it")
+	ErrInvalidShieldedPool     = errors.New("invalid shielded pool")
+	ErrVerifyingKeyNotFound    = errors.New("verifying key not registered")
+	ErrInvalidVerifyingKey     = errors.New("invalid verifying key")
+	ErrVerifyingKeyVersion     = errors.New("verifying key version must increase")
+	ErrVerifyingKeyMismatch    = errors.New("registered verifying key does not match the proof rule")
+	ErrInvalidProofUpgrade     = errors.New("invalid proof upgrade")
+	ErrProofUpgradeInProgress  = errors.New("proof upgrade overlap in progress")
 
 	ErrUnauthorized              = errors.New("unauthorized")
 	ErrInvalidTokenomicsConfig   = errors.New("invalid tokenomics config")
@@ -49,11 +123,16 @@ var (
 	ErrProofCircuitMismatch      = errors.New("proof circuit mismatch")
 	ErrUnsupportedProofCircuit   = errors.New("unsupported proof circuit")
 	ErrProofDeadlineMissed       = errors.New("proof deadline missed")
+	ErrProofDeadlineNotReached   = errors.New("proof deadline has not passed")
 	ErrProofFillsMismatch        = errors.New("proof/batch fills hash mismatch")
 	ErrProofCommitmentMismatch   = errors.New("proof commitment mismatch")
 	ErrProofPublicInputsMismatch = errors.New("proof public inputs mismatch")
 	ErrProofVerifierUnavailable  = errors.New("proof verifier unavailable")
 	ErrProofVerificationFailed   = errors.New("proof verification failed")
+	ErrProofPairingFailed        = errors.New("proof failed its pairing check")
+	ErrProofNotVerified          = errors.New("batch proof record predates stored verification")
+	ErrInvalidAggregateProof     = errors.New("aggregate proof covers no windows or too many")
+	ErrDuplicateAggregateWindow  = errors.New("aggregate proof lists a window twice")
 	ErrVellumProofNotFound       = errors.New("vellum proof not found")
 	ErrInvalidVellumProof        = errors.New("invalid vellum proof")
 	ErrInvalidBloodsworn         = errors.New("invalid bloodsworn")
@@ -2,16 +2,19 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
 
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 
 	"github.com/ava-labs/hypersdk/codec"
 	"github.com/ava-labs/hypersdk/consts"
 	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/shielded"
 	"github.com/ava-labs/hypersdk/state"
 	"github.com/ava-labs/hypersdk/state/metadata"
 
@@ -21,51 +24,100 @@ import (
 type ReadState func(context.Context, [][]byte) ([][]byte, []error)
 
 const (
-	balancePrefix         byte = metadata.DefaultMinimumPrefix
-	marketPrefix          byte = metadata.DefaultMinimumPrefix + 1
-	commitmentPrefix      byte = metadata.DefaultMinimumPrefix + 2
-	batchPrefix           byte = metadata.DefaultMinimumPrefix + 3
-	oraclePrefix          byte = metadata.DefaultMinimumPrefix + 4
-	treasuryConfigPrefix  byte = metadata.DefaultMinimumPrefix + 5
-	treasuryStatePrefix   byte = metadata.DefaultMinimumPrefix + 6
-	feeRouterConfigPrefix byte = metadata.DefaultMinimumPrefix + 7
-	feeRouterStatePrefix  byte = metadata.DefaultMinimumPrefix + 8
-	vaiConfigPrefix       byte = metadata.DefaultMinimumPrefix + 9
-	vaiStatePrefix        byte = metadata.DefaultMinimumPrefix + 10
-	vaiBalancePrefix      byte = metadata.DefaultMinimumPrefix + 11
-	poolPrefix            byte = metadata.DefaultMinimumPrefix + 12
-	lpBalancePrefix       byte = metadata.DefaultMinimumPrefix + 13
-	riskConfigPrefix      byte = metadata.DefaultMinimumPrefix + 14
-	reserveStatePrefix    byte = metadata.DefaultMinimumPrefix + 15
-	proofConfigPrefix     byte = metadata.DefaultMinimumPrefix + 16
-	batchProofPrefix      byte = metadata.DefaultMinimumPrefix + 17
-	vellumProofPrefix     byte = metadata.DefaultMinimumPrefix + 18
-	bloodswornPrefix      byte = metadata.DefaultMinimumPrefix + 19
-	glyphPrefix           byte = metadata.DefaultMinimumPrefix + 20
+	balancePrefix          byte = metadata.DefaultMinimumPrefix
+	marketPrefix           byte = metadata.DefaultMinimumPrefix + 1
+	commitmentPrefix       byte = metadata.DefaultMinimumPrefix + 2
+	batchPrefix            byte = metadata.DefaultMinimumPrefix + 3
+	oraclePrefix           byte = metadata.DefaultMinimumPrefix + 4
+	treasuryConfigPrefix   byte = metadata.DefaultMinimumPrefix + 5
+	treasuryStatePrefix    byte = metadata.DefaultMinimumPrefix + 6
+	feeRouterConfigPrefix  byte = metadata.DefaultMinimumPrefix + 7
+	feeRouterStatePrefix   byte = metadata.DefaultMinimumPrefix + 8
+	vaiConfigPrefix        byte = metadata.DefaultMinimumPrefix + 9
+	vaiStatePrefix         byte = metadata.DefaultMinimumPrefix + 10
+	vaiBalancePrefix       byte = metadata.DefaultMinimumPrefix + 11
+	poolPrefix             byte = metadata.DefaultMinimumPrefix + 12
+	lpBalancePrefix        byte = metadata.DefaultMinimumPrefix + 13
+	riskConfigPrefix       byte = metadata.DefaultMinimumPrefix + 14
+	reserveStatePrefix     byte = metadata.DefaultMinimumPrefix + 15
+	proofConfigPrefix      byte = metadata.DefaultMinimumPrefix + 16
+	batchProofPrefix       byte = metadata.DefaultMinimumPrefix + 17
+	vellumProofPrefix      byte = metadata.DefaultMinimumPrefix + 18
+	bloodswornPrefix       byte = metadata.DefaultMinimumPrefix + 19
+	glyphPrefix            byte = metadata.DefaultMinimumPrefix + 20
+	escrowPrefix           byte = metadata.DefaultMinimumPrefix + 21
+	positionPrefix         byte = metadata.DefaultMinimumPrefix + 22
+	marketPoolPrefix       byte = metadata.DefaultMinimumPrefix + 23
+	marketResolutionPrefix byte = metadata.DefaultMinimumPrefix + 24
+	decryptCommitteePrefix byte = metadata.DefaultMinimumPrefix + 25
+	windowDecryptionPrefix byte = metadata.DefaultMinimumPrefix + 26
+	revealSharePrefix      byte = metadata.DefaultMinimumPrefix + 27
+	windowStatePrefix      byte = metadata.DefaultMinimumPrefix + 28
+	orderFaultPrefix       byte = metadata.DefaultMinimumPrefix + 29
+	settlementPrefix       byte = metadata.DefaultMinimumPrefix + 30
+	clearedWindowsPrefix   byte = metadata.DefaultMinimumPrefix + 31
+	nullifierPrefix        byte = metadata.DefaultMinimumPrefix + 32
+	noteTreePrefix         byte = metadata.DefaultMinimumPrefix + 33
+	noteRootsPrefix        byte = metadata.DefaultMinimumPrefix + 34
+	noteLeafPrefix         byte = metadata.DefaultMinimumPrefix + 35
+	shieldedPoolPrefix     byte = metadata.DefaultMinimumPrefix + 36
+	verifyingKeyPrefix     byte = metadata.DefaultMinimumPrefix + 37
+	vkVersionPrefix        byte = metadata.DefaultMinimumPrefix + 38
+	proofUpgradePrefix     byte = metadata.DefaultMinimumPrefix + 39
+	aggregateProofPrefix   byte = metadata.DefaultMinimumPrefix + 40
+	proverCommitteePrefix  byte = metadata.DefaultMinimumPrefix + 41
+	proverBondPrefix       byte = metadata.DefaultMinimumPrefix + 42
+	proverJailsPrefix      byte = metadata.DefaultMinimumPrefix + 43
+	proverAssignmentPrefix byte = metadata.DefaultMinimumPrefix + 44
+	underbondedPrefix      byte = metadata.DefaultMinimumPrefix + 45
 )
 
 const (
-	BalanceChunks         uint16 = 1
-	MarketChunks          uint16 = 8
-	CommitmentChunks      uint16 = 16
-	BatchChunks           uint16 = 4
-	OracleChunks          uint16 = 8
-	TreasuryConfigChunks  uint16 = 4
-	TreasuryStateChunks   uint16 = 4
-	FeeRouterConfigChunks uint16 = 2
-	FeeRouterStateChunks  uint16 = 4
-	VAIConfigChunks       uint16 = 4
-	VAIStateChunks        uint16 = 4
-	VAIBalanceChunks      uint16 = 1
-	PoolChunks            uint16 = 4
-	LPBalanceChunks       uint16 = 1
-	RiskConfigChunks      uint16 = 4
-	ReserveStateChunks    uint16 = 4
-	ProofConfigChunks     uint16 = 4
-	BatchProofChunks      uint16 = 8
-	VellumProofChunks     uint16 = 128
-	BloodswornChunks      uint16 = 4
-	GlyphChunks           uint16 = 16
+	BalanceChunks          uint16 = 1
+	MarketChunks           uint16 = 8
+	CommitmentChunks       uint16 = 66
+	BatchChunks            uint16 = 4
+	OracleChunks           uint16 = 8
+	TreasuryConfigChunks   uint16 = 4
+	TreasuryStateChunks    uint16 = 4
+	FeeRouterConfigChunks  uint16 = 2
+	FeeRouterStateChunks   uint16 = 4
+	VAIConfigChunks        uint16 = 4
+	VAIStateChunks         uint16 = 4
+	VAIBalanceChunks       uint16 = 1
+	PoolChunks             uint16 = 4
+	LPBalanceChunks        uint16 = 1
+	RiskConfigChunks       uint16 = 4
+	ReserveStateChunks     uint16 = 4
+	ProofConfigChunks      uint16 = 4
+	BatchProofChunks       uint16 = 8
+	VellumProofChunks      uint16 = 128
+	BloodswornChunks       uint16 = 4
+	GlyphChunks            uint16 = 16
+	EscrowChunks           uint16 = 1
+	PositionChunks         uint16 = 1
+	MarketPoolChunks       uint16 = 1
+	MarketResolutionChunks uint16 = 1
+	DecryptCommitteeChunks uint16 = 56
+	WindowDecryptionChunks uint16 = 24
+	RevealShareChunks      uint16 = 1
+	WindowStateChunks      uint16 = 1
+	OrderFaultChunks       uint16 = 1
+	SettlementChunks       uint16 = 1
+	ClearedWindowsChunks   uint16 = 17
+	NullifierChunks        uint16 = 1
+	NoteTreeChunks         uint16 = 12
+	NoteRootsChunks        uint16 = 256
+	NoteLeafChunks         uint16 = 1
+	ShieldedPoolChunks     uint16 = 1
+	VerifyingKeyChunks     uint16 = 64
+	ProofUpgradeChunks     uint16 = 4
+	AggregateProofChunks   uint16 = 128
+	ProverCommitteeChunks  uint16 = 17
+	ProverBondChunks       uint16 = 1
+	ProverJailsChunks      uint16 = 42
+	ProverAssignmentChunks uint16 = 17
+	UnderbondedChunks      uint16 = 17
 )
 
 const (
@@ -73,12 +125,64 @@ const (
 	maxVellumProofBytes        = 131_072
 )
 
+// MaxRecentClearedWindows is how many of a market's most recent cleared
+// window IDs are indexed for range queries.
+const MaxRecentClearedWindows = 128
+
+// MaxDecryptCommitteeMembers bounds the decrypt committee so its config and
+// per-window share tallies fit their chunk budgets.
+const MaxDecryptCommitteeMembers = 32
+
+// MaxProverCommitteeMembers bounds the prover set so it fits
+// ProverCommitteeChunks.
+const MaxProverCommitteeMembers = 32
+
+// MaxJailedProvers bounds the jail list so it fits ProverJailsChunks.
+const MaxJailedProvers = 64
+
+// MaxRecentNoteRoots is how many recent note-tree roots spend proofs may be
+// built against. Every append records a root, so the history is long enough
+// that flushing it takes hundreds of paid appends.
+const MaxRecentNoteRoots = 512
+
+// MaxVerifyingKeySize bounds a registered verifying key so its record fits
+// VerifyingKeyChunks.
+const MaxVerifyingKeySize = 3_968
+
 const (
 	MarketStatusActive   uint8 = 0
 	MarketStatusResolved uint8 = 1
 	MarketStatusDisputed uint8 = 2
 )
 
+// Batch window phases. A window is open for commits until CloseAtMs, then
+// frozen until its key is revealed, optionally proven, and finally cleared.
+// Windows that miss DeadlineAtMs before being proven are abandoned.
+const (
+	WindowPhaseOpen      uint8 = 0
+	WindowPhaseFrozen    uint8 = 1
+	WindowPhaseRevealed  uint8 = 2
+	WindowPhaseProven    uint8 = 3
+	WindowPhaseCleared   uint8 = 4
+	WindowPhaseAbandoned uint8 = 5
+)
+
+// MarketResolution records when a market resolved and until when its
+// resolution may be disputed. Winnings are redeemable from DisputeEndsAtMs.
+type MarketResolution struct {
+	ResolvedAtMs    int64
+	DisputeEndsAtMs int64
+}
+
+// MarketDispute is a challenge to a market's resolution. Disputer is zero
+// for records written before disputes stored their sender.
+type MarketDispute struct {
+	Disputer     codec.Address
+	Bond         uint64
+	DisputedAtMs int64
+	Evidence     []byte
+}
+
 type TreasuryConfig struct {
 	Governance          codec.Address
 	Operations          codec.Address
@@ -143,6 +247,25 @@ type ProofConfig struct {
 	ProverAuthority   codec.Address
 }
 
+// ProofRule is a proof system accepted for batch proofs. An empty CircuitID
+// accepts any circuit, and an empty KeyHash accepts whichever verifying key
+// is registered for the circuit.
+type ProofRule struct {
+	ProofType uint8
+	CircuitID string
+	KeyHash   ids.ID
+}
+
+// ProofUpgrade schedules a switch from one proof rule to another. Both are
+// accepted from ActivateAtMs for OverlapMs; after that only To is.
+type ProofUpgrade struct {
+	From          ProofRule
+	To            ProofRule
+	ActivateAtMs  int64
+	OverlapMs     int64
+	ScheduledAtMs int64
+}
+
 type BatchProofRecord struct {
 	ProofType        uint8
 	SubmittedAtMs    int64
@@ -151,6 +274,19 @@ type BatchProofRecord struct {
 	ProofCommitment  [32]byte
 	PublicInputsHash []byte
 	FillsHash        []byte
+
+	// CircuitID and KeyHash name the proof rule the proof was accepted under,
+	// KeyHash being empty when no key was registered. Verified records that a
+	// verifier actually checked the proof against PublicInputsHash; a
+	// non-strict node accepts proofs for unregistered circuits unchecked.
+	// Windows covered by an aggregate name aggregate-v1 and are never
+	// Verified, since no proof of the window itself was checked. Legacy
+	// records were written before any of this was persisted and carry
+	// none of it.
+	Legacy    bool
+	Verified  bool
+	CircuitID string
+	KeyHash   ids.ID
 }
 
 type Bloodsworn struct {
@@ -170,186 +306,1935 @@ type Glyph struct {
 	Entropy          [32]byte
 }
 
-type Pool struct {
-	Asset0   uint8
-	Asset1   uint8
-	FeeBips  uint16
-	Reserve0 uint64
-	Reserve1 uint64
-	TotalLP  uint64
+// DecryptCommittee is the threshold committee that opens order envelopes.
+// Validator index i+1 is held by MemberAddresses[i], whose shares verify
+// against MemberKeys[i].
+type DecryptCommittee struct {
+	Threshold       uint16
+	PublicKey       []byte
+	MemberKeys      [][]byte
+	MemberAddresses []codec.Address
+
+	// Epoch counts committee replacements, so shares revealed under an
+	// earlier committee can be told apart from the current one's.
+	Epoch uint64
+}
+
+// ProverCommittee is the on-chain prover set. Each revealed window draws
+// Primaries members and then Backups more from it; backups may act once
+// BackupDelayBips of ProofDeadlineMs has passed since the window closed.
+type ProverCommittee struct {
+	Primaries       uint8
+	Backups         uint8
+	BackupDelayBips uint16
+	Members         []codec.Address
+}
+
+// ProverBond is a prover's slashable VEIL stake. Unbonding stake stays
+// slashable until UnbondAtMs, when it can be withdrawn. Misses counts missed
+// proof deadlines since the prover was last jailed.
+type ProverBond struct {
+	Bonded     uint64
+	Unbonding  uint64
+	UnbondAtMs int64
+	Misses     uint32
+}
+
+// ProverAssignment is the set of provers drawn for one window. It is pinned
+// when the window key is revealed, so later jails and bond changes do not
+// reshuffle it.
+type ProverAssignment struct {
+	Primaries          []codec.Address
+	Backups            []codec.Address
+	BackupEligibleAtMs int64
+}
+
+// ProverJail keeps Prover out of window assignments until UntilMs.
+type ProverJail struct {
+	Prover  codec.Address
+	UntilMs int64
+}
+
+type RevealShare struct {
+	ValidatorIndex uint32
+	Share          []byte
+}
+
+// WindowDecryption accumulates verified reveal shares for one window until
+// they combine into WindowKey. Epoch is the decrypt committee epoch the
+// shares were revealed under.
+type WindowDecryption struct {
+	Shares    []RevealShare
+	WindowKey []byte
+	Epoch     uint64
+}
+
+// Reasons a committed order is excluded from its window's clearing.
+const (
+	OrderFaultUndecryptable       uint8 = 1
+	OrderFaultInvalidOrder        uint8 = 2
+	OrderFaultCommitmentMismatch  uint8 = 3
+	OrderFaultUnderCollateralized uint8 = 4
+)
+
+type OrderFault struct {
+	Reason       uint8
+	RecordedAtMs int64
+}
+
+// BatchResult is the outcome ClearBatch stores for a window.
+type BatchResult struct {
+	ClearPrice  uint64
+	TotalVolume uint64
+	Fills       uint32
+	ClearedAtMs int64
+	FillsHash   []byte
+}
+
+// Settlement records how a cleared window applied to one trader: the fill
+// taken (Quantity is zero when the order did not fill), its collateral cost
+// and the unused escrow refunded to the trader's balance.
+type Settlement struct {
+	Side        uint8
+	Quantity    uint64
+	Cost        uint64
+	Asset       uint8
+	Refunded    uint64
+	SettledAtMs int64
+}
+
+// SpentNullifier records the transaction that revealed a nullifier. A
+// nullifier is written once and never removed, so its note cannot be spent
+// again.
+type SpentNullifier struct {
+	TxID      ids.ID
+	SpentAtMs int64
+}
+
+// NoteTree is the persisted frontier of the note-commitment tree. Each
+// NoteLeaf links to the leaf appended before it, starting from LastLeaf, so
+// the full leaf sequence can be rebuilt to serve Merkle paths.
+type NoteTree struct {
+	Tree     shielded.Tree
+	LastLeaf ids.ID
+}
+
+// NoteLeaf records where a note commitment sits in the note tree.
+type NoteLeaf struct {
+	Index uint64
+	Prev  ids.ID
+}
+
+// VerifyingKey is a governance-registered verifying key for one circuit and
+// proof type. KeyHash is the sha256 of Key, so nodes can compare keys
+// without shipping them.
+type VerifyingKey struct {
+	Version        uint32
+	KeyHash        ids.ID
+	RegisteredAtMs int64
+	Key            []byte
+}
+
+// WindowState pins a window's timeline when it is first used, so a later
+// ProofConfig change cannot move the boundaries of a window in flight.
+type WindowState struct {
+	Phase        uint8
+	OpenAtMs     int64
+	CloseAtMs    int64
+	DeadlineAtMs int64
+	UpdatedAtMs  int64
+
+	// Commitments counts the window's live commitments. ClearBatch must
+	// settle exactly this many traders, so no committed order can be dropped.
+	Commitments uint32
+
+	// CommitteeEpoch is the decrypt committee epoch the window opened under.
+	// Its envelopes are sealed to that committee's key, so only it may
+	// reveal the window.
+	CommitteeEpoch uint64
+}
+
+type Escrow struct {
+	Asset      uint8
+	Amount     uint64
+	LockedAtMs int64
+}
+
+// MarketPool holds the collateral backing a market's outstanding outcome
+// positions. Every matched share pair adds one full payout to Collateral.
+type MarketPool struct {
+	Asset      uint8
+	Collateral uint64
+}
+
+type Pool struct {
+	Asset0   uint8
+	Asset1   uint8
+	FeeBips  uint16
+	Reserve0 uint64
+	Reserve1 uint64
+	TotalLP  uint64
+}
+
+// ========== Balance ==========
+
+func BalanceKey(addr codec.Address) (k []byte) {
+	k = make([]byte, 1+codec.AddressLen+consts.Uint16Len)
+	k[0] = balancePrefix
+	copy(k[1:], addr[:])
+	binary.BigEndian.PutUint16(k[1+codec.AddressLen:], BalanceChunks)
+	return
+}
+
+func GetBalance(ctx context.Context, im state.Immutable, addr codec.Address) (uint64, error) {
+	_, bal, _, err := getBalance(ctx, im, addr)
+	return bal, err
+}
+
+func getBalance(ctx context.Context, im state.Immutable, addr codec.Address) ([]byte, uint64, bool, error) {
+	k := BalanceKey(addr)
+	bal, exists, err := innerGetBalance(im.GetValue(ctx, k))
+	return k, bal, exists, err
+}
+
+func GetBalanceFromState(ctx context.Context, f ReadState, addr codec.Address) (uint64, error) {
+	k := BalanceKey(addr)
+	values, errs := f(ctx, [][]byte{k})
+	bal, _, err := innerGetBalance(values[0], errs[0])
+	return bal, err
+}
+
+func innerGetBalance(v []byte, err error) (uint64, bool, error) {
+	if errors.Is(err, database.ErrNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	val, err := database.ParseUInt64(v)
+	if err != nil {
+		return 0, false, err
+	}
+	return val, true, nil
+}
+
+func SetBalance(ctx context.Context, mu state.Mutable, addr codec.Address, balance uint64) error {
+	k := BalanceKey(addr)
+	return setBalance(ctx, mu, k, balance)
+}
+
+func setBalance(ctx context.Context, mu state.Mutable, key []byte, balance uint64) error {
+	return mu.Insert(ctx, key, binary.BigEndian.AppendUint64(nil, balance))
+}
+
+func AddBalance(ctx context.Context, mu state.Mutable, addr codec.Address, amount uint64) (uint64, error) {
+	key, bal, _, err := getBalance(ctx, mu, addr)
+	if err != nil {
+		return 0, err
+	}
+	nbal, err := smath.Add(bal, amount)
+	if err != nil {
+		return 0, fmt.Errorf("%w: could not add balance (bal=%d, addr=%v, amount=%d)", ErrInvalidBalance, bal, addr, amount)
+	}
+	return nbal, setBalance(ctx, mu, key, nbal)
+}
+
+func SubBalance(ctx context.Context, mu state.Mutable, addr codec.Address, amount uint64) (uint64, error) {
+	key, bal, ok, err := getBalance(ctx, mu, addr)
+	if !ok {
+		return 0, fmt.Errorf("%w: could not subtract (bal=%d, addr=%v, amount=%d)", ErrInvalidBalance, 0, addr, amount)
+	}
+	if err != nil {
+		return 0, err
+	}
+	nbal, err := smath.Sub(bal, amount)
+	if err != nil {
+		return 0, fmt.Errorf("%w: could not subtract balance (bal=%d < amount=%d, gap=%d, addr=%v)", ErrInvalidBalance, bal, amount, amount-bal, addr)
+	}
+	if nbal == 0 {
+		return 0, mu.Remove(ctx, key)
+	}
+	return nbal, setBalance(ctx, mu, key, nbal)
+}
+
+// ========== Market ==========
+
+func MarketKey(marketID ids.ID) (k []byte) {
+	k = make([]byte, 1+ids.IDLen+consts.Uint16Len)
+	k[0] = marketPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen:], MarketChunks)
+	return
+}
+
+func PutMarket(ctx context.Context, mu state.Mutable, marketID ids.ID, status uint8, outcomes uint8, resolutionTime int64, resolvedOutcome uint8, question []byte) error {
+	k := MarketKey(marketID)
+	v := make([]byte, 0, 11+len(question))
+	v = append(v, status)
+	v = append(v, outcomes)
+	v = binary.BigEndian.AppendUint64(v, uint64(resolutionTime))
+	v = append(v, resolvedOutcome)
+	v = append(v, question...)
+	return mu.Insert(ctx, k, v)
+}
+
+func GetMarket(ctx context.Context, im state.Immutable, marketID ids.ID) (uint8, uint8, int64, uint8, []byte, error) {
+	k := MarketKey(marketID)
+	v, err := im.GetValue(ctx, k)
+	if errors.Is(err, database.ErrNotFound) {
+		return 0, 0, 0, 0, nil, ErrMarketNotFound
+	}
+	if err != nil {
+		return 0, 0, 0, 0, nil, err
+	}
+	if len(v) < 11 {
+		return 0, 0, 0, 0, nil, fmt.Errorf("invalid market data length: %d", len(v))
+	}
+	return v[0], v[1], int64(binary.BigEndian.Uint64(v[2:10])), v[10], v[11:], nil
+}
+
+func GetMarketFromState(ctx context.Context, f ReadState, marketID ids.ID) (uint8, uint8, int64, uint8, []byte, error) {
+	k := MarketKey(marketID)
+	values, errs := f(ctx, [][]byte{k})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return 0, 0, 0, 0, nil, ErrMarketNotFound
+	}
+	if errs[0] != nil {
+		return 0, 0, 0, 0, nil, errs[0]
+	}
+	v := values[0]
+	if len(v) < 11 {
+		return 0, 0, 0, 0, nil, fmt.Errorf("invalid market data length: %d", len(v))
+	}
+	return v[0], v[1], int64(binary.BigEndian.Uint64(v[2:10])), v[10], v[11:], nil
+}
+
+// ========== Market Resolution ==========
+
+func MarketResolutionKey(marketID ids.ID) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint16Len)
+	k[0] = marketResolutionPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen:], MarketResolutionChunks)
+	return k
+}
+
+func PutMarketResolution(ctx context.Context, mu state.Mutable, marketID ids.ID, resolution MarketResolution) error {
+	if resolution.DisputeEndsAtMs < resolution.ResolvedAtMs {
+		return ErrInvalidMarketResolution
+	}
+	v := make([]byte, 0, consts.Uint64Len*2)
+	v = binary.BigEndian.AppendUint64(v, uint64(resolution.ResolvedAtMs))
+	v = binary.BigEndian.AppendUint64(v, uint64(resolution.DisputeEndsAtMs))
+	return mu.Insert(ctx, MarketResolutionKey(marketID), v)
+}
+
+// GetMarketResolution returns ErrMarketNotResolved for markets that have no
+// resolution record, including those resolved before it was kept.
+func GetMarketResolution(ctx context.Context, im state.Immutable, marketID ids.ID) (MarketResolution, error) {
+	v, err := im.GetValue(ctx, MarketResolutionKey(marketID))
+	if errors.Is(err, database.ErrNotFound) {
+		return MarketResolution{}, ErrMarketNotResolved
+	}
+	if err != nil {
+		return MarketResolution{}, err
+	}
+	return parseMarketResolution(v)
+}
+
+func GetMarketResolutionFromState(ctx context.Context, f ReadState, marketID ids.ID) (MarketResolution, error) {
+	values, errs := f(ctx, [][]byte{MarketResolutionKey(marketID)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return MarketResolution{}, ErrMarketNotResolved
+	}
+	if errs[0] != nil {
+		return MarketResolution{}, errs[0]
+	}
+	return parseMarketResolution(values[0])
+}
+
+func parseMarketResolution(v []byte) (MarketResolution, error) {
+	if len(v) != consts.Uint64Len*2 {
+		return MarketResolution{}, ErrInvalidMarketResolution
+	}
+	return MarketResolution{
+		ResolvedAtMs:    int64(binary.BigEndian.Uint64(v[:consts.Uint64Len])),
+		DisputeEndsAtMs: int64(binary.BigEndian.Uint64(v[consts.Uint64Len:])),
+	}, nil
+}
+
+// ========== Commitment ==========
+
+func CommitmentKey(marketID ids.ID, windowID uint64, actor codec.Address) (k []byte) {
+	k = make([]byte, 1+ids.IDLen+8+codec.AddressLen+consts.Uint16Len)
+	k[0] = commitmentPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
+	copy(k[1+ids.IDLen+8:], actor[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+8+codec.AddressLen:], CommitmentChunks)
+	return
+}
+
+// CommitmentRecordVersion tags the stored commitment encoding:
+// version ‖ uint16 len ‖ commitment ‖ uint16 len ‖ envelope.
+const CommitmentRecordVersion byte = 1
+
+func PutCommitment(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, actor codec.Address, envelope []byte, commitment []byte) error {
+	if len(commitment) == 0 || len(commitment) > int(consts.MaxUint16) || len(envelope) == 0 || len(envelope) > int(consts.MaxUint16) {
+		return ErrInvalidCommitment
+	}
+	k := CommitmentKey(marketID, windowID, actor)
+	v := make([]byte, 0, 1+consts.Uint16Len*2+len(commitment)+len(envelope))
+	v = append(v, CommitmentRecordVersion)
+	v = binary.BigEndian.AppendUint16(v, uint16(len(commitment)))
+	v = append(v, commitment...)
+	v = binary.BigEndian.AppendUint16(v, uint16(len(envelope)))
+	v = append(v, envelope...)
+	return mu.Insert(ctx, k, v)
+}
+
+// GetCommitment returns the stored commitment and envelope.
+func GetCommitment(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64, actor codec.Address) ([]byte, []byte, error) {
+	v, err := im.GetValue(ctx, CommitmentKey(marketID, windowID, actor))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil, ErrCommitmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseCommitment(v)
+}
+
+func parseCommitment(v []byte) ([]byte, []byte, error) {
+	if len(v) < 1 || v[0] != CommitmentRecordVersion {
+		return nil, nil, ErrInvalidCommitment
+	}
+	commitment, offset, ok := readLengthPrefixed(v, 1)
+	if !ok {
+		return nil, nil, ErrInvalidCommitment
+	}
+	envelope, offset, ok := readLengthPrefixed(v, offset)
+	if !ok || offset != len(v) {
+		return nil, nil, ErrInvalidCommitment
+	}
+	return commitment, envelope, nil
+}
+
+func DeleteCommitment(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, actor codec.Address) error {
+	return mu.Remove(ctx, CommitmentKey(marketID, windowID, actor))
+}
+
+func HasCommitment(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64, actor codec.Address) (bool, error) {
+	_, err := im.GetValue(ctx, CommitmentKey(marketID, windowID, actor))
+	if errors.Is(err, database.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ========== Order Faults ==========
+
+func OrderFaultKey(marketID ids.ID, windowID uint64, trader codec.Address) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint64Len+codec.AddressLen+consts.Uint16Len)
+	k[0] = orderFaultPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
+	copy(k[1+ids.IDLen+consts.Uint64Len:], trader[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+consts.Uint64Len+codec.AddressLen:], OrderFaultChunks)
+	return k
+}
+
+func PutOrderFault(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, trader codec.Address, fault OrderFault) error {
+	if fault.Reason < OrderFaultUndecryptable || fault.Reason > OrderFaultUnderCollateralized {
+		return ErrInvalidOrderFault
+	}
+	v := make([]byte, 0, 1+consts.Uint64Len)
+	v = append(v, fault.Reason)
+	v = binary.BigEndian.AppendUint64(v, uint64(fault.RecordedAtMs))
+	return mu.Insert(ctx, OrderFaultKey(marketID, windowID, trader), v)
+}
+
+func GetOrderFault(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64, trader codec.Address) (OrderFault, error) {
+	v, err := im.GetValue(ctx, OrderFaultKey(marketID, windowID, trader))
+	if errors.Is(err, database.ErrNotFound) {
+		return OrderFault{}, ErrOrderFaultNotFound
+	}
+	if err != nil {
+		return OrderFault{}, err
+	}
+	return parseOrderFault(v)
+}
+
+func GetOrderFaultFromState(ctx context.Context, f ReadState, marketID ids.ID, windowID uint64, trader codec.Address) (OrderFault, error) {
+	values, errs := f(ctx, [][]byte{OrderFaultKey(marketID, windowID, trader)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return OrderFault{}, ErrOrderFaultNotFound
+	}
+	if errs[0] != nil {
+		return OrderFault{}, errs[0]
+	}
+	return parseOrderFault(values[0])
+}
+
+func parseOrderFault(v []byte) (OrderFault, error) {
+	if len(v) != 1+consts.Uint64Len {
+		return OrderFault{}, ErrInvalidOrderFault
+	}
+	return OrderFault{
+		Reason:       v[0],
+		RecordedAtMs: int64(binary.BigEndian.Uint64(v[1:])),
+	}, nil
+}
+
+// ========== Escrow ==========
+
+func EscrowKey(marketID ids.ID, windowID uint64, actor codec.Address) (k []byte) {
+	k = make([]byte, 1+ids.IDLen+8+codec.AddressLen+consts.Uint16Len)
+	k[0] = escrowPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
+	copy(k[1+ids.IDLen+8:], actor[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+8+codec.AddressLen:], EscrowChunks)
+	return
+}
+
+func PutEscrow(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, actor codec.Address, escrow Escrow) error {
+	if escrow.Amount == 0 {
+		return ErrInvalidEscrow
+	}
+	v := make([]byte, 0, 1+consts.Uint64Len+consts.Uint64Len)
+	v = append(v, escrow.Asset)
+	v = binary.BigEndian.AppendUint64(v, escrow.Amount)
+	v = binary.BigEndian.AppendUint64(v, uint64(escrow.LockedAtMs))
+	return mu.Insert(ctx, EscrowKey(marketID, windowID, actor), v)
+}
+
+func GetEscrow(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64, actor codec.Address) (Escrow, error) {
+	v, err := im.GetValue(ctx, EscrowKey(marketID, windowID, actor))
+	if errors.Is(err, database.ErrNotFound) {
+		return Escrow{}, ErrEscrowNotFound
+	}
+	if err != nil {
+		return Escrow{}, err
+	}
+	return parseEscrow(v)
+}
+
+func GetEscrowFromState(ctx context.Context, f ReadState, marketID ids.ID, windowID uint64, actor codec.Address) (Escrow, error) {
+	values, errs := f(ctx, [][]byte{EscrowKey(marketID, windowID, actor)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return Escrow{}, ErrEscrowNotFound
+	}
+	if errs[0] != nil {
+		return Escrow{}, errs[0]
+	}
+	return parseEscrow(values[0])
+}
+
+func parseEscrow(v []byte) (Escrow, error) {
+	const minLen = 1 + consts.Uint64Len + consts.Uint64Len
+	if len(v) < minLen {
+		return Escrow{}, ErrInvalidEscrow
+	}
+	return Escrow{
+		Asset:      v[0],
+		Amount:     binary.BigEndian.Uint64(v[1 : 1+consts.Uint64Len]),
+		LockedAtMs: int64(binary.BigEndian.Uint64(v[1+consts.Uint64Len : 1+consts.Uint64Len*2])),
+	}, nil
+}
+
+func DeleteEscrow(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, actor codec.Address) error {
+	return mu.Remove(ctx, EscrowKey(marketID, windowID, actor))
+}
+
+// ========== Settlements ==========
+
+func SettlementKey(marketID ids.ID, windowID uint64, trader codec.Address) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint64Len+codec.AddressLen+consts.Uint16Len)
+	k[0] = settlementPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
+	copy(k[1+ids.IDLen+consts.Uint64Len:], trader[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+consts.Uint64Len+codec.AddressLen:], SettlementChunks)
+	return k
+}
+
+func PutSettlement(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, trader codec.Address, settlement Settlement) error {
+	v := make([]byte, 0, 1+consts.Uint64Len*2+1+consts.Uint64Len*2)
+	v = append(v, settlement.Side)
+	v = binary.BigEndian.AppendUint64(v, settlement.Quantity)
+	v = binary.BigEndian.AppendUint64(v, settlement.Cost)
+	v = append(v, settlement.Asset)
+	v = binary.BigEndian.AppendUint64(v, settlement.Refunded)
+	v = binary.BigEndian.AppendUint64(v, uint64(settlement.SettledAtMs))
+	return mu.Insert(ctx, SettlementKey(marketID, windowID, trader), v)
+}
+
+func GetSettlement(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64, trader codec.Address) (Settlement, error) {
+	v, err := im.GetValue(ctx, SettlementKey(marketID, windowID, trader))
+	if errors.Is(err, database.ErrNotFound) {
+		return Settlement{}, ErrSettlementNotFound
+	}
+	if err != nil {
+		return Settlement{}, err
+	}
+	return parseSettlement(v)
+}
+
+func GetSettlementFromState(ctx context.Context, f ReadState, marketID ids.ID, windowID uint64, trader codec.Address) (Settlement, error) {
+	values, errs := f(ctx, [][]byte{SettlementKey(marketID, windowID, trader)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return Settlement{}, ErrSettlementNotFound
+	}
+	if errs[0] != nil {
+		return Settlement{}, errs[0]
+	}
+	return parseSettlement(values[0])
+}
+
+func parseSettlement(v []byte) (Settlement, error) {
+	if len(v) != 1+consts.Uint64Len*2+1+consts.Uint64Len*2 {
+		return Settlement{}, ErrInvalidSettlement
+	}
+	return Settlement{
+		Side:        v[0],
+		Quantity:    binary.BigEndian.Uint64(v[1:9]),
+		Cost:        binary.BigEndian.Uint64(v[9:17]),
+		Asset:       v[17],
+		Refunded:    binary.BigEndian.Uint64(v[18:26]),
+		SettledAtMs: int64(binary.BigEndian.Uint64(v[26:34])),
+	}, nil
+}
+
+// ========== Nullifiers ==========
+
+func NullifierKey(nullifier ids.ID) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint16Len)
+	k[0] = nullifierPrefix
+	copy(k[1:], nullifier[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen:], NullifierChunks)
+	return k
+}
+
+// SpendNullifier marks nullifier as spent. It fails with ErrNullifierSpent
+// if the nullifier was already recorded.
+func SpendNullifier(ctx context.Context, mu state.Mutable, nullifier ids.ID, spent SpentNullifier) error {
+	k := NullifierKey(nullifier)
+	_, err := mu.GetValue(ctx, k)
+	if err == nil {
+		return ErrNullifierSpent
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return err
+	}
+	v := make([]byte, 0, ids.IDLen+consts.Uint64Len)
+	v = append(v, spent.TxID[:]...)
+	v = binary.BigEndian.AppendUint64(v, uint64(spent.SpentAtMs))
+	return mu.Insert(ctx, k, v)
+}
+
+func GetSpentNullifier(ctx context.Context, im state.Immutable, nullifier ids.ID) (SpentNullifier, error) {
+	v, err := im.GetValue(ctx, NullifierKey(nullifier))
+	if errors.Is(err, database.ErrNotFound) {
+		return SpentNullifier{}, ErrNullifierNotFound
+	}
+	if err != nil {
+		return SpentNullifier{}, err
+	}
+	return parseSpentNullifier(v)
+}
+
+func GetSpentNullifierFromState(ctx context.Context, f ReadState, nullifier ids.ID) (SpentNullifier, error) {
+	values, errs := f(ctx, [][]byte{NullifierKey(nullifier)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return SpentNullifier{}, ErrNullifierNotFound
+	}
+	if errs[0] != nil {
+		return SpentNullifier{}, errs[0]
+	}
+	return parseSpentNullifier(values[0])
+}
+
+func parseSpentNullifier(v []byte) (SpentNullifier, error) {
+	if len(v) != ids.IDLen+consts.Uint64Len {
+		return SpentNullifier{}, ErrInvalidNullifier
+	}
+	var spent SpentNullifier
+	copy(spent.TxID[:], v[:ids.IDLen])
+	spent.SpentAtMs = int64(binary.BigEndian.Uint64(v[ids.IDLen:]))
+	return spent, nil
+}
+
+// ========== Note Tree ==========
+
+func NoteTreeKey() []byte {
+	return singletonKey(noteTreePrefix, NoteTreeChunks)
+}
+
+// NoteRootsKey holds the most recent note-tree roots, oldest first. The
+// current root is always the last entry.
+func NoteRootsKey() []byte {
+	return singletonKey(noteRootsPrefix, NoteRootsChunks)
+}
+
+func NoteLeafKey(commitment ids.ID) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint16Len)
+	k[0] = noteLeafPrefix
+	copy(k[1:], commitment[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen:], NoteLeafChunks)
+	return k
+}
+
+// AppendNoteCommitments appends commitments to the note tree in order and
+// records the resulting root as the latest known root. It returns the index
+// of the first appended leaf. A commitment can be appended only once.
+func AppendNoteCommitments(ctx context.Context, mu state.Mutable, commitments []ids.ID) (uint64, ids.ID, error) {
+	tree, err := GetNoteTree(ctx, mu)
+	if err != nil {
+		return 0, ids.Empty, err
+	}
+	first := tree.Tree.Size
+	for _, commitment := range commitments {
+		leaf, err := shielded.ElementFromBytes(commitment[:])
+		if err != nil {
+			return 0, ids.Empty, ErrInvalidNoteCommitment
+		}
+		if _, err := mu.GetValue(ctx, NoteLeafKey(commitment)); err == nil {
+			return 0, ids.Empty, ErrDuplicateNoteCommitment
+		} else if !errors.Is(err, database.ErrNotFound) {
+			return 0, ids.Empty, err
+		}
+		index, err := tree.Tree.Append(leaf)
+		if err != nil {
+			return 0, ids.Empty, err
+		}
+		v := make([]byte, 0, consts.Uint64Len+ids.IDLen)
+		v = binary.BigEndian.AppendUint64(v, index)
+		v = append(v, tree.LastLeaf[:]...)
+		if err := mu.Insert(ctx, NoteLeafKey(commitment), v); err != nil {
+			return 0, ids.Empty, err
+		}
+		tree.LastLeaf = commitment
+	}
+	if err := putNoteTree(ctx, mu, tree); err != nil {
+		return 0, ids.Empty, err
+	}
+
+	root := ids.ID(tree.Tree.Root.Bytes())
+	roots, err := GetNoteRoots(ctx, mu)
+	if err != nil {
+		return 0, ids.Empty, err
+	}
+	roots = append(roots, root)
+	if len(roots) > MaxRecentNoteRoots {
+		roots = roots[len(roots)-MaxRecentNoteRoots:]
+	}
+	v := make([]byte, 0, len(roots)*ids.IDLen)
+	for _, r := range roots {
+		v = append(v, r[:]...)
+	}
+	if err := mu.Insert(ctx, NoteRootsKey(), v); err != nil {
+		return 0, ids.Empty, err
+	}
+	return first, root, nil
+}
+
+// IsKnownNoteRoot reports whether root is one of the recent note-tree roots.
+// Before the first append only the empty-tree root is known.
+func IsKnownNoteRoot(ctx context.Context, im state.Immutable, root ids.ID) (bool, error) {
+	roots, err := GetNoteRoots(ctx, im)
+	if err != nil {
+		return false, err
+	}
+	if len(roots) == 0 {
+		empty := shielded.EmptyRoot()
+		return root == ids.ID(empty.Bytes()), nil
+	}
+	for _, r := range roots {
+		if r == root {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func putNoteTree(ctx context.Context, mu state.Mutable, tree NoteTree) error {
+	v := make([]byte, 0, consts.Uint64Len+ids.IDLen*(2+shielded.TreeDepth))
+	v = binary.BigEndian.AppendUint64(v, tree.Tree.Size)
+	root := tree.Tree.Root.Bytes()
+	v = append(v, root[:]...)
+	v = append(v, tree.LastLeaf[:]...)
+	for i := range tree.Tree.Frontier {
+		node := tree.Tree.Frontier[i].Bytes()
+		v = append(v, node[:]...)
+	}
+	return mu.Insert(ctx, NoteTreeKey(), v)
+}
+
+// GetNoteTree returns the note tree, which is empty until the first append.
+func GetNoteTree(ctx context.Context, im state.Immutable) (NoteTree, error) {
+	v, err := im.GetValue(ctx, NoteTreeKey())
+	if errors.Is(err, database.ErrNotFound) {
+		return NoteTree{Tree: shielded.NewTree()}, nil
+	}
+	if err != nil {
+		return NoteTree{}, err
+	}
+	return parseNoteTree(v)
+}
+
+func GetNoteTreeFromState(ctx context.Context, f ReadState) (NoteTree, error) {
+	values, errs := f(ctx, [][]byte{NoteTreeKey()})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return NoteTree{Tree: shielded.NewTree()}, nil
+	}
+	if errs[0] != nil {
+		return NoteTree{}, errs[0]
+	}
+	return parseNoteTree(values[0])
+}
+
+func parseNoteTree(v []byte) (NoteTree, error) {
+	if len(v) != consts.Uint64Len+ids.IDLen*(2+shielded.TreeDepth) {
+		return NoteTree{}, ErrInvalidNoteTree
+	}
+	var (
+		tree NoteTree
+		err  error
+	)
+	tree.Tree.Size = binary.BigEndian.Uint64(v[:consts.Uint64Len])
+	offset := consts.Uint64Len
+	if tree.Tree.Root, err = shielded.ElementFromBytes(v[offset : offset+ids.IDLen]); err != nil {
+		return NoteTree{}, ErrInvalidNoteTree
+	}
+	offset += ids.IDLen
+	copy(tree.LastLeaf[:], v[offset:offset+ids.IDLen])
+	offset += ids.IDLen
+	for i := range tree.Tree.Frontier {
+		if tree.Tree.Frontier[i], err = shielded.ElementFromBytes(v[offset : offset+ids.IDLen]); err != nil {
+			return NoteTree{}, ErrInvalidNoteTree
+		}
+		offset += ids.IDLen
+	}
+	return tree, nil
+}
+
+// GetNoteRoots returns the recent note-tree roots, oldest first.
+func GetNoteRoots(ctx context.Context, im state.Immutable) ([]ids.ID, error) {
+	v, err := im.GetValue(ctx, NoteRootsKey())
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseNoteRoots(v)
+}
+
+func GetNoteRootsFromState(ctx context.Context, f ReadState) ([]ids.ID, error) {
+	values, errs := f(ctx, [][]byte{NoteRootsKey()})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return nil, nil
+	}
+	if errs[0] != nil {
+		return nil, errs[0]
+	}
+	return parseNoteRoots(values[0])
+}
+
+func parseNoteRoots(v []byte) ([]ids.ID, error) {
+	if len(v)%ids.IDLen != 0 || len(v)/ids.IDLen > MaxRecentNoteRoots {
+		return nil, ErrInvalidNoteTree
+	}
+	roots := make([]ids.ID, len(v)/ids.IDLen)
+	for i := range roots {
+		copy(roots[i][:], v[i*ids.IDLen:(i+1)*ids.IDLen])
+	}
+	return roots, nil
+}
+
+func GetNoteLeaf(ctx context.Context, im state.Immutable, commitment ids.ID) (NoteLeaf, error) {
+	v, err := im.GetValue(ctx, NoteLeafKey(commitment))
+	if errors.Is(err, database.ErrNotFound) {
+		return NoteLeaf{}, ErrNoteLeafNotFound
+	}
+	if err != nil {
+		return NoteLeaf{}, err
+	}
+	return parseNoteLeaf(v)
+}
+
+func GetNoteLeafFromState(ctx context.Context, f ReadState, commitment ids.ID) (NoteLeaf, error) {
+	values, errs := f(ctx, [][]byte{NoteLeafKey(commitment)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return NoteLeaf{}, ErrNoteLeafNotFound
+	}
+	if errs[0] != nil {
+		return NoteLeaf{}, errs[0]
+	}
+	return parseNoteLeaf(values[0])
+}
+
+func parseNoteLeaf(v []byte) (NoteLeaf, error) {
+	if len(v) != consts.Uint64Len+ids.IDLen {
+		return NoteLeaf{}, ErrInvalidNoteTree
+	}
+	var leaf NoteLeaf
+	leaf.Index = binary.BigEndian.Uint64(v[:consts.Uint64Len])
+	copy(leaf.Prev[:], v[consts.Uint64Len:])
+	return leaf, nil
+}
+
+// GetNoteTreePathFromState rebuilds the leaf sequence by walking back from
+// the last appended leaf and returns the Merkle path of the leaf at index,
+// together with that leaf and the current root. Its cost grows with the
+// size of the tree, so it is meant for RPC use only.
+func GetNoteTreePathFromState(ctx context.Context, f ReadState, index uint64) (ids.ID, [shielded.TreeDepth]ids.ID, ids.ID, error) {
+	var path [shielded.TreeDepth]ids.ID
+	tree, err := GetNoteTreeFromState(ctx, f)
+	if err != nil {
+		return ids.Empty, path, ids.Empty, err
+	}
+	if index >= tree.Tree.Size {
+		return ids.Empty, path, ids.Empty, shielded.ErrLeafOutOfRange
+	}
+
+	leaves := make([]fr.Element, tree.Tree.Size)
+	commitment := tree.LastLeaf
+	for i := tree.Tree.Size; i > 0; i-- {
+		leaf, err := GetNoteLeafFromState(ctx, f, commitment)
+		if err != nil {
+			return ids.Empty, path, ids.Empty, err
+		}
+		if leaf.Index != i-1 {
+			return ids.Empty, path, ids.Empty, ErrInvalidNoteTree
+		}
+		if leaves[i-1], err = shielded.ElementFromBytes(commitment[:]); err != nil {
+			return ids.Empty, path, ids.Empty, ErrInvalidNoteTree
+		}
+		commitment = leaf.Prev
+	}
+	nodes, root, err := shielded.Path(leaves, index)
+	if err != nil {
+		return ids.Empty, path, ids.Empty, err
+	}
+	if !root.Equal(&tree.Tree.Root) {
+		return ids.Empty, path, ids.Empty, ErrInvalidNoteTree
+	}
+	for i := range nodes {
+		path[i] = nodes[i].Bytes()
+	}
+	return ids.ID(leaves[index].Bytes()), path, ids.ID(root.Bytes()), nil
+}
+
+// ========== Shielded Pool ==========
+
+// ShieldedPoolKey holds the total value of asset held in shielded notes.
+// Value enters through Shield and leaves through Unshield and proof fees, so
+// the pool can never pay out more than was shielded.
+func ShieldedPoolKey(asset uint8) []byte {
+	k := make([]byte, 1+1+consts.Uint16Len)
+	k[0] = shieldedPoolPrefix
+	k[1] = asset
+	binary.BigEndian.PutUint16(k[2:], ShieldedPoolChunks)
+	return k
+}
+
+func GetShieldedPool(ctx context.Context, im state.Immutable, asset uint8) (uint64, error) {
+	v, err := im.GetValue(ctx, ShieldedPoolKey(asset))
+	if errors.Is(err, database.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return parseShieldedPool(v)
+}
+
+func GetShieldedPoolFromState(ctx context.Context, f ReadState, asset uint8) (uint64, error) {
+	values, errs := f(ctx, [][]byte{ShieldedPoolKey(asset)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return 0, nil
+	}
+	if errs[0] != nil {
+		return 0, errs[0]
+	}
+	return parseShieldedPool(values[0])
+}
+
+func AddShieldedPool(ctx context.Context, mu state.Mutable, asset uint8, amount uint64) (uint64, error) {
+	total, err := GetShieldedPool(ctx, mu, asset)
+	if err != nil {
+		return 0, err
+	}
+	total, err = smath.Add(total, amount)
+	if err != nil {
+		return 0, ErrShieldedPoolOverflow
+	}
+	return total, mu.Insert(ctx, ShieldedPoolKey(asset), binary.BigEndian.AppendUint64(nil, total))
+}
+
+func SubShieldedPool(ctx context.Context, mu state.Mutable, asset uint8, amount uint64) (uint64, error) {
+	total, err := GetShieldedPool(ctx, mu, asset)
+	if err != nil {
+		return 0, err
+	}
+	total, err = smath.Sub(total, amount)
+	if err != nil {
+		return 0, ErrShieldedPoolUnderflow
+	}
+	return total, mu.Insert(ctx, ShieldedPoolKey(asset), binary.BigEndian.AppendUint64(nil, total))
+}
+
+func parseShieldedPool(v []byte) (uint64, error) {
+	if len(v) != consts.Uint64Len {
+		return 0, ErrInvalidShieldedPool
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// ========== Verifying Keys ==========
+
+// VerifyingKeyKey holds the active verifying key for a circuit and proof
+// type. Every version ever registered is also kept under
+// VerifyingKeyVersionKey.
+func VerifyingKeyKey(circuitID string, proofType uint8) []byte {
+	return verifyingKeyKey(verifyingKeyPrefix, circuitID, proofType, nil)
+}
+
+func VerifyingKeyVersionKey(circuitID string, proofType uint8, version uint32) []byte {
+	return verifyingKeyKey(vkVersionPrefix, circuitID, proofType, binary.BigEndian.AppendUint32(nil, version))
+}
+
+func verifyingKeyKey(prefix byte, circuitID string, proofType uint8, suffix []byte) []byte {
+	k := make([]byte, 0, 1+1+1+len(circuitID)+len(suffix)+consts.Uint16Len)
+	k = append(k, prefix, proofType, byte(len(circuitID)))
+	k = append(k, circuitID...)
+	k = append(k, suffix...)
+	return binary.BigEndian.AppendUint16(k, VerifyingKeyChunks)
+}
+
+// PutVerifyingKey makes vk the active key for circuitID and proofType and
+// archives it under its version. Versions must strictly increase.
+func PutVerifyingKey(ctx context.Context, mu state.Mutable, circuitID string, proofType uint8, vk VerifyingKey) error {
+	if vk.Version == 0 || len(vk.Key) == 0 || len(vk.Key) > MaxVerifyingKeySize {
+		return ErrInvalidVerifyingKey
+	}
+	if ids.ID(sha256.Sum256(vk.Key)) != vk.KeyHash {
+		return ErrInvalidVerifyingKey
+	}
+	current, err := GetVerifyingKey(ctx, mu, circuitID, proofType)
+	switch {
+	case errors.Is(err, ErrVerifyingKeyNotFound):
+	case err != nil:
+		return err
+	case vk.Version <= current.Version:
+		return ErrVerifyingKeyVersion
+	}
+
+	v := make([]byte, 0, consts.Uint32Len+ids.IDLen+consts.Uint64Len+consts.Uint16Len+len(vk.Key))
+	v = binary.BigEndian.AppendUint32(v, vk.Version)
+	v = append(v, vk.KeyHash[:]...)
+	v = binary.BigEndian.AppendUint64(v, uint64(vk.RegisteredAtMs))
+	v = binary.BigEndian.AppendUint16(v, uint16(len(vk.Key)))
+	v = append(v, vk.Key...)
+	if err := mu.Insert(ctx, VerifyingKeyKey(circuitID, proofType), v); err != nil {
+		return err
+	}
+	return mu.Insert(ctx, VerifyingKeyVersionKey(circuitID, proofType, vk.Version), v)
+}
+
+func GetVerifyingKey(ctx context.Context, im state.Immutable, circuitID string, proofType uint8) (VerifyingKey, error) {
+	v, err := im.GetValue(ctx, VerifyingKeyKey(circuitID, proofType))
+	if errors.Is(err, database.ErrNotFound) {
+		return VerifyingKey{}, ErrVerifyingKeyNotFound
+	}
+	if err != nil {
+		return VerifyingKey{}, err
+	}
+	return parseVerifyingKey(v)
+}
+
+// GetVerifyingKeyFromState reads the key registered as version, or the
+// active key when version is zero.
+func GetVerifyingKeyFromState(ctx context.Context, f ReadState, circuitID string, proofType uint8, version uint32) (VerifyingKey, error) {
+	k := VerifyingKeyKey(circuitID, proofType)
+	if version != 0 {
+		k = VerifyingKeyVersionKey(circuitID, proofType, version)
+	}
+	values, errs := f(ctx, [][]byte{k})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return VerifyingKey{}, ErrVerifyingKeyNotFound
+	}
+	if errs[0] != nil {
+		return VerifyingKey{}, errs[0]
+	}
+	return parseVerifyingKey(values[0])
+}
+
+func parseVerifyingKey(v []byte) (VerifyingKey, error) {
+	const headerLen = consts.Uint32Len + ids.IDLen + consts.Uint64Len
+	if len(v) < headerLen {
+		return VerifyingKey{}, ErrInvalidVerifyingKey
+	}
+	vk := VerifyingKey{
+		Version:        binary.BigEndian.Uint32(v[:consts.Uint32Len]),
+		RegisteredAtMs: int64(binary.BigEndian.Uint64(v[consts.Uint32Len+ids.IDLen : headerLen])),
+	}
+	copy(vk.KeyHash[:], v[consts.Uint32Len:consts.Uint32Len+ids.IDLen])
+	key, offset, ok := readLengthPrefixed(v, headerLen)
+	if !ok || offset != len(v) || len(key) == 0 {
+		return VerifyingKey{}, ErrInvalidVerifyingKey
+	}
+	vk.Key = key
+	return vk, nil
+}
+
+// ========== Positions ==========
+
+func PositionKey(marketID ids.ID, outcome uint8, addr codec.Address) (k []byte) {
+	k = make([]byte, 1+ids.IDLen+1+codec.AddressLen+consts.Uint16Len)
+	k[0] = positionPrefix
+	copy(k[1:], marketID[:])
+	k[1+ids.IDLen] = outcome
+	copy(k[1+ids.IDLen+1:], addr[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+1+codec.AddressLen:], PositionChunks)
+	return
+}
+
+func GetPosition(ctx context.Context, im state.Immutable, marketID ids.ID, outcome uint8, addr codec.Address) (uint64, error) {
+	_, shares, _, err := getPosition(ctx, im, marketID, outcome, addr)
+	return shares, err
+}
+
+func GetPositionFromState(ctx context.Context, f ReadState, marketID ids.ID, outcome uint8, addr codec.Address) (uint64, error) {
+	k := PositionKey(marketID, outcome, addr)
+	values, errs := f(ctx, [][]byte{k})
+	shares, _, err := innerGetBalance(values[0], errs[0])
+	return shares, err
+}
+
+func getPosition(ctx context.Context, im state.Immutable, marketID ids.ID, outcome uint8, addr codec.Address) ([]byte, uint64, bool, error) {
+	k := PositionKey(marketID, outcome, addr)
+	shares, exists, err := innerGetBalance(im.GetValue(ctx, k))
+	return k, shares, exists, err
+}
+
+func AddPosition(ctx context.Context, mu state.Mutable, marketID ids.ID, outcome uint8, addr codec.Address, amount uint64) (uint64, error) {
+	key, shares, _, err := getPosition(ctx, mu, marketID, outcome, addr)
+	if err != nil {
+		return 0, err
+	}
+	nshares, err := smath.Add(shares, amount)
+	if err != nil {
+		return 0, fmt.Errorf("%w: could not add position (shares=%d, addr=%v, amount=%d)", ErrInvalidBalance, shares, addr, amount)
+	}
+	return nshares, setBalance(ctx, mu, key, nshares)
+}
+
+func DeletePosition(ctx context.Context, mu state.Mutable, marketID ids.ID, outcome uint8, addr codec.Address) error {
+	return mu.Remove(ctx, PositionKey(marketID, outcome, addr))
+}
+
+func MarketPoolKey(marketID ids.ID) (k []byte) {
+	k = make([]byte, 1+ids.IDLen+consts.Uint16Len)
+	k[0] = marketPoolPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen:], MarketPoolChunks)
+	return
+}
+
+func PutMarketPool(ctx context.Context, mu state.Mutable, marketID ids.ID, pool MarketPool) error {
+	v := make([]byte, 0, 1+consts.Uint64Len)
+	v = append(v, pool.Asset)
+	v = binary.BigEndian.AppendUint64(v, pool.Collateral)
+	return mu.Insert(ctx, MarketPoolKey(marketID), v)
+}
+
+func GetMarketPool(ctx context.Context, im state.Immutable, marketID ids.ID) (MarketPool, error) {
+	v, err := im.GetValue(ctx, MarketPoolKey(marketID))
+	if errors.Is(err, database.ErrNotFound) {
+		return MarketPool{}, ErrMarketPoolNotFound
+	}
+	if err != nil {
+		return MarketPool{}, err
+	}
+	return parseMarketPool(v)
+}
+
+func GetMarketPoolFromState(ctx context.Context, f ReadState, marketID ids.ID) (MarketPool, error) {
+	values, errs := f(ctx, [][]byte{MarketPoolKey(marketID)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return MarketPool{}, ErrMarketPoolNotFound
+	}
+	if errs[0] != nil {
+		return MarketPool{}, errs[0]
+	}
+	return parseMarketPool(values[0])
+}
+
+func parseMarketPool(v []byte) (MarketPool, error) {
+	if len(v) < 1+consts.Uint64Len {
+		return MarketPool{}, ErrInvalidMarketPool
+	}
+	return MarketPool{
+		Asset:      v[0],
+		Collateral: binary.BigEndian.Uint64(v[1 : 1+consts.Uint64Len]),
+	}, nil
+}
+
+// ========== Batch ==========
+
+func BatchKey(marketID ids.ID, windowID uint64) (k []byte) {
+	k = make([]byte, 1+ids.IDLen+8+consts.Uint16Len)
+	k[0] = batchPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+8:], BatchChunks)
+	return
+}
+
+// BatchResultVersion tags the stored batch result encoding:
+// version ‖ clear price ‖ volume ‖ fills ‖ cleared at ‖ fills hash.
+// Legacy records are clear price ‖ volume ‖ fills hash; their first byte is
+// the top byte of a clear price below OutcomePriceScale, so always zero.
+const BatchResultVersion byte = 1
+
+func PutBatchResult(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, result BatchResult) error {
+	k := BatchKey(marketID, windowID)
+	v := make([]byte, 0, 1+consts.Uint64Len*3+consts.Uint32Len+len(result.FillsHash))
+	v = append(v, BatchResultVersion)
+	v = binary.BigEndian.AppendUint64(v, result.ClearPrice)
+	v = binary.BigEndian.AppendUint64(v, result.TotalVolume)
+	v = binary.BigEndian.AppendUint32(v, result.Fills)
+	v = binary.BigEndian.AppendUint64(v, uint64(result.ClearedAtMs))
+	v = append(v, result.FillsHash...)
+	return mu.Insert(ctx, k, v)
+}
+
+func GetBatchResult(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64) (BatchResult, error) {
+	v, err := im.GetValue(ctx, BatchKey(marketID, windowID))
+	if errors.Is(err, database.ErrNotFound) {
+		return BatchResult{}, ErrBatchResultNotFound
+	}
+	if err != nil {
+		return BatchResult{}, err
+	}
+	return parseBatchResult(v)
+}
+
+func GetBatchResultFromState(ctx context.Context, f ReadState, marketID ids.ID, windowID uint64) (BatchResult, error) {
+	values, errs := f(ctx, [][]byte{BatchKey(marketID, windowID)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return BatchResult{}, ErrBatchResultNotFound
+	}
+	if errs[0] != nil {
+		return BatchResult{}, errs[0]
+	}
+	return parseBatchResult(values[0])
+}
+
+// parseBatchResult reads both encodings. Legacy records carry no fill count
+// or clear time, so those parse as zero.
+func parseBatchResult(v []byte) (BatchResult, error) {
+	if len(v) > 0 && v[0] == BatchResultVersion {
+		const headerLen = 1 + consts.Uint64Len*3 + consts.Uint32Len
+		if len(v) < headerLen {
+			return BatchResult{}, ErrInvalidBatchResult
+		}
+		return BatchResult{
+			ClearPrice:  binary.BigEndian.Uint64(v[1:9]),
+			TotalVolume: binary.BigEndian.Uint64(v[9:17]),
+			Fills:       binary.BigEndian.Uint32(v[17:21]),
+			ClearedAtMs: int64(binary.BigEndian.Uint64(v[21:29])),
+			FillsHash:   append([]byte(nil), v[headerLen:]...),
+		}, nil
+	}
+	const legacyHeaderLen = consts.Uint64Len * 2
+	if len(v) < legacyHeaderLen || v[0] != 0 {
+		return BatchResult{}, ErrInvalidBatchResult
+	}
+	return BatchResult{
+		ClearPrice:  binary.BigEndian.Uint64(v[0:8]),
+		TotalVolume: binary.BigEndian.Uint64(v[8:16]),
+		FillsHash:   append([]byte(nil), v[legacyHeaderLen:]...),
+	}, nil
+}
+
+// ClearedWindowsKey indexes a market's most recent cleared windows, oldest
+// first, so clients can page back through history without scanning.
+func ClearedWindowsKey(marketID ids.ID) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint16Len)
+	k[0] = clearedWindowsPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint16(k[1+ids.IDLen:], ClearedWindowsChunks)
+	return k
+}
+
+// AppendClearedWindow records windowID as the market's latest cleared
+// window, dropping the oldest entry once MaxRecentClearedWindows is reached.
+func AppendClearedWindow(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64) error {
+	windows, err := GetClearedWindows(ctx, mu, marketID)
+	if err != nil {
+		return err
+	}
+	windows = append(windows, windowID)
+	if len(windows) > MaxRecentClearedWindows {
+		windows = windows[len(windows)-MaxRecentClearedWindows:]
+	}
+	v := make([]byte, 0, len(windows)*consts.Uint64Len)
+	for _, w := range windows {
+		v = binary.BigEndian.AppendUint64(v, w)
+	}
+	return mu.Insert(ctx, ClearedWindowsKey(marketID), v)
+}
+
+// GetClearedWindows returns the indexed cleared windows, oldest first. It is
+// empty for a market that has not cleared a window.
+func GetClearedWindows(ctx context.Context, im state.Immutable, marketID ids.ID) ([]uint64, error) {
+	v, err := im.GetValue(ctx, ClearedWindowsKey(marketID))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseClearedWindows(v)
+}
+
+func GetClearedWindowsFromState(ctx context.Context, f ReadState, marketID ids.ID) ([]uint64, error) {
+	values, errs := f(ctx, [][]byte{ClearedWindowsKey(marketID)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return nil, nil
+	}
+	if errs[0] != nil {
+		return nil, errs[0]
+	}
+	return parseClearedWindows(values[0])
+}
+
+func parseClearedWindows(v []byte) ([]uint64, error) {
+	if len(v)%consts.Uint64Len != 0 || len(v)/consts.Uint64Len > MaxRecentClearedWindows {
+		return nil, ErrInvalidBatchResult
+	}
+	windows := make([]uint64, 0, len(v)/consts.Uint64Len)
+	for offset := 0; offset < len(v); offset += consts.Uint64Len {
+		windows = append(windows, binary.BigEndian.Uint64(v[offset:offset+consts.Uint64Len]))
+	}
+	return windows, nil
+}
+
+// ========== Window State ==========
+
+const windowStateSize = 1 + consts.Uint64Len*4 + consts.Uint32Len + consts.Uint64Len
+
+func WindowStateKey(marketID ids.ID, windowID uint64) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint64Len+consts.Uint16Len)
+	k[0] = windowStatePrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+consts.Uint64Len:], WindowStateChunks)
+	return k
+}
+
+func PutWindowState(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, ws WindowState) error {
+	if ws.Phase > WindowPhaseAbandoned || ws.CloseAtMs <= ws.OpenAtMs || ws.DeadlineAtMs < ws.CloseAtMs {
+		return ErrInvalidWindowState
+	}
+	v := make([]byte, 0, windowStateSize)
+	v = append(v, ws.Phase)
+	v = binary.BigEndian.AppendUint64(v, uint64(ws.OpenAtMs))
+	v = binary.BigEndian.AppendUint64(v, uint64(ws.CloseAtMs))
+	v = binary.BigEndian.AppendUint64(v, uint64(ws.DeadlineAtMs))
+	v = binary.BigEndian.AppendUint64(v, uint64(ws.UpdatedAtMs))
+	v = binary.BigEndian.AppendUint32(v, ws.Commitments)
+	v = binary.BigEndian.AppendUint64(v, ws.CommitteeEpoch)
+	return mu.Insert(ctx, WindowStateKey(marketID, windowID), v)
+}
+
+func GetWindowState(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64) (WindowState, error) {
+	v, err := im.GetValue(ctx, WindowStateKey(marketID, windowID))
+	if errors.Is(err, database.ErrNotFound) {
+		return WindowState{}, ErrWindowStateNotFound
+	}
+	if err != nil {
+		return WindowState{}, err
+	}
+	return parseWindowState(v)
+}
+
+func GetWindowStateFromState(ctx context.Context, f ReadState, marketID ids.ID, windowID uint64) (WindowState, error) {
+	values, errs := f(ctx, [][]byte{WindowStateKey(marketID, windowID)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return WindowState{}, ErrWindowStateNotFound
+	}
+	if errs[0] != nil {
+		return WindowState{}, errs[0]
+	}
+	return parseWindowState(values[0])
+}
+
+func parseWindowState(v []byte) (WindowState, error) {
+	if len(v) != windowStateSize {
+		return WindowState{}, ErrInvalidWindowState
+	}
+	return WindowState{
+		Phase:          v[0],
+		OpenAtMs:       int64(binary.BigEndian.Uint64(v[1:9])),
+		CloseAtMs:      int64(binary.BigEndian.Uint64(v[9:17])),
+		DeadlineAtMs:   int64(binary.BigEndian.Uint64(v[17:25])),
+		UpdatedAtMs:    int64(binary.BigEndian.Uint64(v[25:33])),
+		Commitments:    binary.BigEndian.Uint32(v[33:37]),
+		CommitteeEpoch: binary.BigEndian.Uint64(v[37:]),
+	}, nil
+}
+
+// ========== Decryption ==========
+
+func DecryptCommitteeKey() []byte {
+	return singletonKey(decryptCommitteePrefix, DecryptCommitteeChunks)
+}
+
+func PutDecryptCommittee(ctx context.Context, mu state.Mutable, committee DecryptCommittee) error {
+	n := len(committee.MemberKeys)
+	if n == 0 || n > MaxDecryptCommitteeMembers || committee.Threshold == 0 || int(committee.Threshold) > n {
+		return ErrInvalidDecryptCommittee
+	}
+	if len(committee.MemberAddresses) != n {
+		return ErrInvalidDecryptCommittee
+	}
+	v := make([]byte, 0, consts.Uint16Len+1+consts.Uint16Len+len(committee.PublicKey)+n*(consts.Uint16Len+len(committee.PublicKey)+codec.AddressLen))
+	v = binary.BigEndian.AppendUint16(v, committee.Threshold)
+	v = append(v, byte(n))
+	v = binary.BigEndian.AppendUint16(v, uint16(len(committee.PublicKey)))
+	v = append(v, committee.PublicKey...)
+	for i, key := range committee.MemberKeys {
+		v = binary.BigEndian.AppendUint16(v, uint16(len(key)))
+		v = append(v, key...)
+		v = append(v, committee.MemberAddresses[i][:]...)
+	}
+	v = binary.BigEndian.AppendUint64(v, committee.Epoch)
+	return mu.Insert(ctx, DecryptCommitteeKey(), v)
+}
+
+func GetDecryptCommittee(ctx context.Context, im state.Immutable) (DecryptCommittee, error) {
+	v, err := im.GetValue(ctx, DecryptCommitteeKey())
+	if errors.Is(err, database.ErrNotFound) {
+		return DecryptCommittee{}, ErrDecryptCommitteeNotFound
+	}
+	if err != nil {
+		return DecryptCommittee{}, err
+	}
+	return parseDecryptCommittee(v)
+}
+
+func GetDecryptCommitteeFromState(ctx context.Context, f ReadState) (DecryptCommittee, error) {
+	values, errs := f(ctx, [][]byte{DecryptCommitteeKey()})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return DecryptCommittee{}, ErrDecryptCommitteeNotFound
+	}
+	if errs[0] != nil {
+		return DecryptCommittee{}, errs[0]
+	}
+	return parseDecryptCommittee(values[0])
+}
+
+func parseDecryptCommittee(v []byte) (DecryptCommittee, error) {
+	if len(v) < consts.Uint16Len+1 {
+		return DecryptCommittee{}, ErrInvalidDecryptCommittee
+	}
+	committee := DecryptCommittee{Threshold: binary.BigEndian.Uint16(v[:consts.Uint16Len])}
+	n := int(v[consts.Uint16Len])
+	offset := consts.Uint16Len + 1
+	var ok bool
+	if committee.PublicKey, offset, ok = readLengthPrefixed(v, offset); !ok {
+		return DecryptCommittee{}, ErrInvalidDecryptCommittee
+	}
+	committee.MemberKeys = make([][]byte, 0, n)
+	committee.MemberAddresses = make([]codec.Address, 0, n)
+	for i := 0; i < n; i++ {
+		var key []byte
+		if key, offset, ok = readLengthPrefixed(v, offset); !ok {
+			return DecryptCommittee{}, ErrInvalidDecryptCommittee
+		}
+		if len(v[offset:]) < codec.AddressLen {
+			return DecryptCommittee{}, ErrInvalidDecryptCommittee
+		}
+		var addr codec.Address
+		copy(addr[:], v[offset:offset+codec.AddressLen])
+		offset += codec.AddressLen
+		committee.MemberKeys = append(committee.MemberKeys, key)
+		committee.MemberAddresses = append(committee.MemberAddresses, addr)
+	}
+	// Committees stored before epochs were tracked end here and are epoch 0.
+	switch len(v[offset:]) {
+	case 0:
+	case consts.Uint64Len:
+		committee.Epoch = binary.BigEndian.Uint64(v[offset:])
+	default:
+		return DecryptCommittee{}, ErrInvalidDecryptCommittee
+	}
+	return committee, nil
+}
+
+func ProverCommitteeKey() []byte {
+	return singletonKey(proverCommitteePrefix, ProverCommitteeChunks)
+}
+
+func PutProverCommittee(ctx context.Context, mu state.Mutable, committee ProverCommittee) error {
+	n := len(committee.Members)
+	if n == 0 || n > MaxProverCommitteeMembers || committee.Primaries == 0 ||
+		int(committee.Primaries)+int(committee.Backups) > n ||
+		uint64(committee.BackupDelayBips) > bipsDenominator {
+		return ErrInvalidProverCommittee
+	}
+	v := make([]byte, 0, 2+consts.Uint16Len+1+n*codec.AddressLen)
+	v = append(v, committee.Primaries, committee.Backups)
+	v = binary.BigEndian.AppendUint16(v, committee.BackupDelayBips)
+	v = append(v, byte(n))
+	for _, member := range committee.Members {
+		v = append(v, member[:]...)
+	}
+	return mu.Insert(ctx, ProverCommitteeKey(), v)
+}
+
+func GetProverCommittee(ctx context.Context, im state.Immutable) (ProverCommittee, error) {
+	v, err := im.GetValue(ctx, ProverCommitteeKey())
+	if errors.Is(err, database.ErrNotFound) {
+		return ProverCommittee{}, ErrProverCommitteeNotFound
+	}
+	if err != nil {
+		return ProverCommittee{}, err
+	}
+	return parseProverCommittee(v)
+}
+
+func GetProverCommitteeFromState(ctx context.Context, f ReadState) (ProverCommittee, error) {
+	values, errs := f(ctx, [][]byte{ProverCommitteeKey()})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return ProverCommittee{}, ErrProverCommitteeNotFound
+	}
+	if errs[0] != nil {
+		return ProverCommittee{}, errs[0]
+	}
+	return parseProverCommittee(values[0])
+}
+
+func parseProverCommittee(v []byte) (ProverCommittee, error) {
+	if len(v) < 2+consts.Uint16Len+1 {
+		return ProverCommittee{}, ErrInvalidProverCommittee
+	}
+	committee := ProverCommittee{
+		Primaries:       v[0],
+		Backups:         v[1],
+		BackupDelayBips: binary.BigEndian.Uint16(v[2:4]),
+	}
+	n := int(v[4])
+	if len(v) != 5+n*codec.AddressLen {
+		return ProverCommittee{}, ErrInvalidProverCommittee
+	}
+	committee.Members = make([]codec.Address, n)
+	for i := range committee.Members {
+		copy(committee.Members[i][:], v[5+i*codec.AddressLen:])
+	}
+	return committee, nil
+}
+
+func WindowDecryptionKey(marketID ids.ID, windowID uint64) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint64Len+consts.Uint16Len)
+	k[0] = windowDecryptionPrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+consts.Uint64Len:], WindowDecryptionChunks)
+	return k
+}
+
+func PutWindowDecryption(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, wd WindowDecryption) error {
+	if len(wd.Shares) > MaxDecryptCommitteeMembers {
+		return ErrInvalidWindowDecryption
+	}
+	v := make([]byte, 0, 1+len(wd.Shares)*(4+consts.Uint16Len+32)+consts.Uint16Len+len(wd.WindowKey)+consts.Uint64Len)
+	v = append(v, byte(len(wd.Shares)))
+	for _, share := range wd.Shares {
+		v = binary.BigEndian.AppendUint32(v, share.ValidatorIndex)
+		v = binary.BigEndian.AppendUint16(v, uint16(len(share.Share)))
+		v = append(v, share.Share...)
+	}
+	v = binary.BigEndian.AppendUint16(v, uint16(len(wd.WindowKey)))
+	v = append(v, wd.WindowKey...)
+	v = binary.BigEndian.AppendUint64(v, wd.Epoch)
+	return mu.Insert(ctx, WindowDecryptionKey(marketID, windowID), v)
+}
+
+// GetWindowDecryption returns an empty tally when no share has been
+// revealed for the window yet.
+func GetWindowDecryption(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64) (WindowDecryption, error) {
+	v, err := im.GetValue(ctx, WindowDecryptionKey(marketID, windowID))
+	if errors.Is(err, database.ErrNotFound) {
+		return WindowDecryption{}, nil
+	}
+	if err != nil {
+		return WindowDecryption{}, err
+	}
+	return parseWindowDecryption(v)
+}
+
+func GetWindowDecryptionFromState(ctx context.Context, f ReadState, marketID ids.ID, windowID uint64) (WindowDecryption, error) {
+	values, errs := f(ctx, [][]byte{WindowDecryptionKey(marketID, windowID)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return WindowDecryption{}, nil
+	}
+	if errs[0] != nil {
+		return WindowDecryption{}, errs[0]
+	}
+	return parseWindowDecryption(values[0])
+}
+
+func parseWindowDecryption(v []byte) (WindowDecryption, error) {
+	if len(v) < 1 {
+		return WindowDecryption{}, ErrInvalidWindowDecryption
+	}
+	n := int(v[0])
+	offset := 1
+	wd := WindowDecryption{Shares: make([]RevealShare, 0, n)}
+	for i := 0; i < n; i++ {
+		if len(v[offset:]) < 4 {
+			return WindowDecryption{}, ErrInvalidWindowDecryption
+		}
+		share := RevealShare{ValidatorIndex: binary.BigEndian.Uint32(v[offset : offset+4])}
+		offset += 4
+		var ok bool
+		if share.Share, offset, ok = readLengthPrefixed(v, offset); !ok {
+			return WindowDecryption{}, ErrInvalidWindowDecryption
+		}
+		wd.Shares = append(wd.Shares, share)
+	}
+	key, offset, ok := readLengthPrefixed(v, offset)
+	if !ok {
+		return WindowDecryption{}, ErrInvalidWindowDecryption
+	}
+	if len(key) > 0 {
+		wd.WindowKey = key
+	}
+	// Tallies stored before epochs were tracked end here and are epoch 0.
+	switch len(v[offset:]) {
+	case 0:
+	case consts.Uint64Len:
+		wd.Epoch = binary.BigEndian.Uint64(v[offset:])
+	default:
+		return WindowDecryption{}, ErrInvalidWindowDecryption
+	}
+	return wd, nil
+}
+
+// RevealShareKey stores one committee member's verified share for a single
+// window, so every historical window's reveal can be replayed.
+func RevealShareKey(marketID ids.ID, windowID uint64, validatorIndex uint32) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint64Len+4+consts.Uint16Len)
+	k[0] = revealSharePrefix
+	copy(k[1:], marketID[:])
+	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
+	binary.BigEndian.PutUint32(k[1+ids.IDLen+consts.Uint64Len:], validatorIndex)
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+consts.Uint64Len+4:], RevealShareChunks)
+	return k
 }
 
-// ========== Balance ==========
+func PutRevealShare(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, validatorIndex uint32, share []byte) error {
+	if len(share) == 0 {
+		return ErrInvalidRevealShare
+	}
+	return mu.Insert(ctx, RevealShareKey(marketID, windowID, validatorIndex), share)
+}
 
-func BalanceKey(addr codec.Address) (k []byte) {
-	k = make([]byte, 1+codec.AddressLen+consts.Uint16Len)
-	k[0] = balancePrefix
-	copy(k[1:], addr[:])
-	binary.BigEndian.PutUint16(k[1+codec.AddressLen:], BalanceChunks)
-	return
+func GetRevealShare(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64, validatorIndex uint32) ([]byte, error) {
+	v, err := im.GetValue(ctx, RevealShareKey(marketID, windowID, validatorIndex))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrRevealShareNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
 }
 
-func GetBalance(ctx context.Context, im state.Immutable, addr codec.Address) (uint64, error) {
-	_, bal, _, err := getBalance(ctx, im, addr)
-	return bal, err
+func GetRevealShareFromState(ctx context.Context, f ReadState, marketID ids.ID, windowID uint64, validatorIndex uint32) ([]byte, error) {
+	values, errs := f(ctx, [][]byte{RevealShareKey(marketID, windowID, validatorIndex)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return nil, ErrRevealShareNotFound
+	}
+	if errs[0] != nil {
+		return nil, errs[0]
+	}
+	return values[0], nil
+}
+
+// GetRevealSharesFromState returns every share revealed for a window in
+// validator index order. All possible indices are scanned so the result
+// does not depend on the committee that is registered today.
+func GetRevealSharesFromState(ctx context.Context, f ReadState, marketID ids.ID, windowID uint64) ([]RevealShare, error) {
+	keys := make([][]byte, MaxDecryptCommitteeMembers)
+	for i := range keys {
+		keys[i] = RevealShareKey(marketID, windowID, uint32(i+1))
+	}
+	values, errs := f(ctx, keys)
+	shares := make([]RevealShare, 0, len(keys))
+	for i := range keys {
+		if errors.Is(errs[i], database.ErrNotFound) {
+			continue
+		}
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		shares = append(shares, RevealShare{
+			ValidatorIndex: uint32(i + 1),
+			Share:          values[i],
+		})
+	}
+	return shares, nil
+}
+
+// readLengthPrefixed reads a uint16 length-prefixed field at offset and
+// returns a copy of it along with the next offset.
+func readLengthPrefixed(v []byte, offset int) ([]byte, int, bool) {
+	if len(v[offset:]) < consts.Uint16Len {
+		return nil, offset, false
+	}
+	n := int(binary.BigEndian.Uint16(v[offset : offset+consts.Uint16Len]))
+	offset += consts.Uint16Len
+	if len(v[offset:]) < n {
+		return nil, offset, false
+	}
+	return append([]byte(nil), v[offset:offset+n]...), offset + n, true
 }
 
-func getBalance(ctx context.Context, im state.Immutable, addr codec.Address) ([]byte, uint64, bool, error) {
-	k := BalanceKey(addr)
-	bal, exists, err := innerGetBalance(im.GetValue(ctx, k))
-	return k, bal, exists, err
+// ========== Prover Bonds ==========
+
+func ProverBondKey(addr codec.Address) []byte {
+	k := make([]byte, 1+codec.AddressLen+consts.Uint16Len)
+	k[0] = proverBondPrefix
+	copy(k[1:], addr[:])
+	binary.BigEndian.PutUint16(k[1+codec.AddressLen:], ProverBondChunks)
+	return k
 }
 
-func GetBalanceFromState(ctx context.Context, f ReadState, addr codec.Address) (uint64, error) {
-	k := BalanceKey(addr)
-	values, errs := f(ctx, [][]byte{k})
-	bal, _, err := innerGetBalance(values[0], errs[0])
-	return bal, err
+func PutProverBond(ctx context.Context, mu state.Mutable, addr codec.Address, bond ProverBond) error {
+	if bond.Bonded == 0 && bond.Unbonding == 0 && bond.Misses == 0 {
+		return mu.Remove(ctx, ProverBondKey(addr))
+	}
+	v := make([]byte, 0, consts.Uint64Len*3+consts.Uint32Len)
+	v = binary.BigEndian.AppendUint64(v, bond.Bonded)
+	v = binary.BigEndian.AppendUint64(v, bond.Unbonding)
+	v = binary.BigEndian.AppendUint64(v, uint64(bond.UnbondAtMs))
+	v = binary.BigEndian.AppendUint32(v, bond.Misses)
+	return mu.Insert(ctx, ProverBondKey(addr), v)
 }
 
-func innerGetBalance(v []byte, err error) (uint64, bool, error) {
+// GetProverBond returns an empty bond for an address that never bonded.
+func GetProverBond(ctx context.Context, im state.Immutable, addr codec.Address) (ProverBond, error) {
+	v, err := im.GetValue(ctx, ProverBondKey(addr))
 	if errors.Is(err, database.ErrNotFound) {
-		return 0, false, nil
+		return ProverBond{}, nil
 	}
 	if err != nil {
-		return 0, false, err
+		return ProverBond{}, err
 	}
-	val, err := database.ParseUInt64(v)
-	if err != nil {
-		return 0, false, err
+	return parseProverBond(v)
+}
+
+func GetProverBondFromState(ctx context.Context, f ReadState, addr codec.Address) (ProverBond, error) {
+	values, errs := f(ctx, [][]byte{ProverBondKey(addr)})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return ProverBond{}, nil
 	}
-	return val, true, nil
+	if errs[0] != nil {
+		return ProverBond{}, errs[0]
+	}
+	return parseProverBond(values[0])
 }
 
-func SetBalance(ctx context.Context, mu state.Mutable, addr codec.Address, balance uint64) error {
-	k := BalanceKey(addr)
-	return setBalance(ctx, mu, k, balance)
+func parseProverBond(v []byte) (ProverBond, error) {
+	if len(v) != consts.Uint64Len*3+consts.Uint32Len {
+		return ProverBond{}, ErrInvalidProverBond
+	}
+	return ProverBond{
+		Bonded:     binary.BigEndian.Uint64(v[:8]),
+		Unbonding:  binary.BigEndian.Uint64(v[8:16]),
+		UnbondAtMs: int64(binary.BigEndian.Uint64(v[16:24])),
+		Misses:     binary.BigEndian.Uint32(v[24:28]),
+	}, nil
 }
 
-func setBalance(ctx context.Context, mu state.Mutable, key []byte, balance uint64) error {
-	return mu.Insert(ctx, key, binary.BigEndian.AppendUint64(nil, balance))
+func ProverJailsKey() []byte {
+	return singletonKey(proverJailsPrefix, ProverJailsChunks)
 }
 
-func AddBalance(ctx context.Context, mu state.Mutable, addr codec.Address, amount uint64) (uint64, error) {
-	key, bal, _, err := getBalance(ctx, mu, addr)
-	if err != nil {
-		return 0, err
+func PutProverJails(ctx context.Context, mu state.Mutable, jails []ProverJail) error {
+	if len(jails) > MaxJailedProvers {
+		return ErrInvalidProverJails
 	}
-	nbal, err := smath.Add(bal, amount)
-	if err != nil {
-		return 0, fmt.Errorf("%w: could not add balance (bal=%d, addr=%v, amount=%d)", ErrInvalidBalance, bal, addr, amount)
+	if len(jails) == 0 {
+		return mu.Remove(ctx, ProverJailsKey())
 	}
-	return nbal, setBalance(ctx, mu, key, nbal)
+	v := make([]byte, 0, 1+len(jails)*(codec.AddressLen+consts.Uint64Len))
+	v = append(v, byte(len(jails)))
+	for _, jail := range jails {
+		v = append(v, jail.Prover[:]...)
+		v = binary.BigEndian.AppendUint64(v, uint64(jail.UntilMs))
+	}
+	return mu.Insert(ctx, ProverJailsKey(), v)
 }
 
-func SubBalance(ctx context.Context, mu state.Mutable, addr codec.Address, amount uint64) (uint64, error) {
-	key, bal, ok, err := getBalance(ctx, mu, addr)
-	if !ok {
-		return 0, fmt.Errorf("%w: could not subtract (bal=%d, addr=%v, amount=%d)", ErrInvalidBalance, 0, addr, amount)
+// GetProverJails returns every stored jail, including expired ones that have
+// not been pruned yet.
+func GetProverJails(ctx context.Context, im state.Immutable) ([]ProverJail, error) {
+	v, err := im.GetValue(ctx, ProverJailsKey())
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
 	}
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	nbal, err := smath.Sub(bal, amount)
-	if err != nil {
-		return 0, fmt.Errorf("%w: could not subtract balance (bal=%d < amount=%d, gap=%d, addr=%v)", ErrInvalidBalance, bal, amount, amount-bal, addr)
+	return parseProverJails(v)
+}
+
+func GetProverJailsFromState(ctx context.Context, f ReadState) ([]ProverJail, error) {
+	values, errs := f(ctx, [][]byte{ProverJailsKey()})
+	if errors.Is(errs[0], database.ErrNotFound) {
+		return nil, nil
 	}
-	if nbal == 0 {
-		return 0, mu.Remove(ctx, key)
+	if errs[0] != nil {
+		return nil, errs[0]
 	}
-	return nbal, setBalance(ctx, mu, key, nbal)
+	return parseProverJails(values[0])
 }
 
-// ========== Market ==========
+func parseProverJails(v []byte) ([]ProverJail, error) {
+	const entryLen = codec.AddressLen + consts.Uint64Len
+	if len(v) < 1 || len(v) != 1+int(v[0])*entryLen {
+		return nil, ErrInvalidProverJails
+	}
+	jails := make([]ProverJail, int(v[0]))
+	for i := range jails {
+		offset := 1 + i*entryLen
+		copy(jails[i].Prover[:], v[offset:offset+codec.AddressLen])
+		jails[i].UntilMs = int64(binary.BigEndian.Uint64(v[offset+codec.AddressLen : offset+entryLen]))
+	}
+	return jails, nil
+}
 
-func MarketKey(marketID ids.ID) (k []byte) {
-	k = make([]byte, 1+ids.IDLen+consts.Uint16Len)
-	k[0] = marketPrefix
+func ProverAssignmentKey(marketID ids.ID, windowID uint64) []byte {
+	k := make([]byte, 1+ids.IDLen+consts.Uint64Len+consts.Uint16Len)
+	k[0] = proverAssignmentPrefix
 	copy(k[1:], marketID[:])
-	binary.BigEndian.PutUint16(k[1+ids.IDLen:], MarketChunks)
-	return
+	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
+	binary.BigEndian.PutUint16(k[1+ids.IDLen+consts.Uint64Len:], ProverAssignmentChunks)
+	return k
 }
 
-func PutMarket(ctx context.Context, mu state.Mutable, marketID ids.ID, status uint8, outcomes uint8, resolutionTime int64, resolvedOutcome uint8, question []byte) error {
-	k := MarketKey(marketID)
-	v := make([]byte, 0, 11+len(question))
-	v = append(v, status)
-	v = append(v, outcomes)
-	v = binary.BigEndian.AppendUint64(v, uint64(resolutionTime))
-	v = append(v, resolvedOutcome)
-	v = append(v, question...)
-	return mu.Insert(ctx, k, v)
+func PutProverAssignment(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, assignment ProverAssignment) error {
+	if len(assignment.Primaries)+len(assignment.Backups) > MaxProverCommitteeMembers {
+		return ErrInvalidProverAssignment
+	}
+	v := make([]byte, 0, 2+(len(assignment.Primaries)+len(assignment.Backups))*codec.AddressLen+consts.Uint64Len)
+	v = appendAddressList(v, assignment.Primaries)
+	v = appendAddressList(v, assignment.Backups)
+	v = binary.BigEndian.AppendUint64(v, uint64(assignment.BackupEligibleAtMs))
+	return mu.Insert(ctx, ProverAssignmentKey(marketID, windowID), v)
 }
 
-func GetMarket(ctx context.Context, im state.Immutable, marketID ids.ID) (uint8, uint8, int64, uint8, []byte, error) {
-	k := MarketKey(marketID)
-	v, err := im.GetValue(ctx, k)
+// GetProverAssignment returns ErrProverAssignmentNotFound for windows
+// revealed without a prover committee, or before assignments were pinned.
+func GetProverAssignment(ctx context.Context, im state.Immutable, marketID ids.ID, windowID uint64) (ProverAssignment, error) {
+	v, err := im.GetValue(ctx, ProverAssignmentKey(marketID, windowID))
 	if errors.Is(err, database.ErrNotFound) {
-		return 0, 0, 0, 0, nil, ErrMarketNotFound
+		return ProverAssignment{}, ErrProverAssignmentNotFound
 	}
 	if err != nil {
-		return 0, 0, 0, 0, nil, err
-	}
-	if len(v) < 11 {
-		return 0, 0, 0, 0, nil, fmt.Errorf("invalid market data length: %d", len(v))
+		return ProverAssignment{}, err
 	}
-	return v[0], v[1], int64(binary.BigEndian.Uint64(v[2:10])), v[10], v[11:], nil
+	return parseProverAssignment(v)
 }
 
-func GetMarketFromState(ctx context.Context, f ReadState, marketID ids.ID) (uint8, uint8, int64, uint8, []byte, error) {
-	k := MarketKey(marketID)
-	values, errs := f(ctx, [][]byte{k})
-	if errors.Is(errs[0], database.ErrNotFound) {
-		return 0, 0, 0, 0, nil, ErrMarketNotFound
+func parseProverAssignment(v []byte) (ProverAssignment, error) {
+	var (
+		assignment ProverAssignment
+		offset     int
+		ok         bool
+	)
+	if assignment.Primaries, offset, ok = readAddressList(v, 0); !ok {
+		return ProverAssignment{}, ErrInvalidProverAssignment
 	}
-	if errs[0] != nil {
-		return 0, 0, 0, 0, nil, errs[0]
+	if assignment.Backups, offset, ok = readAddressList(v, offset); !ok {
+		return ProverAssignment{}, ErrInvalidProverAssignment
 	}
-	v := values[0]
-	if len(v) < 11 {
-		return 0, 0, 0, 0, nil, fmt.Errorf("invalid market data length: %d", len(v))
+	if len(v) != offset+consts.Uint64Len {
+		return ProverAssignment{}, ErrInvalidProverAssignment
 	}
-	return v[0], v[1], int64(binary.BigEndian.Uint64(v[2:10])), v[10], v[11:], nil
+	assignment.BackupEligibleAtMs = int64(binary.BigEndian.Uint64(v[offset:]))
+	return assignment, nil
 }
 
-// ========== Commitment ==========
-
-func CommitmentKey(marketID ids.ID, windowID uint64, actor codec.Address) (k []byte) {
-	k = make([]byte, 1+ids.IDLen+8+codec.AddressLen+consts.Uint16Len)
-	k[0] = commitmentPrefix
-	copy(k[1:], marketID[:])
-	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
-	copy(k[1+ids.IDLen+8:], actor[:])
-	binary.BigEndian.PutUint16(k[1+ids.IDLen+8+codec.AddressLen:], CommitmentChunks)
-	return
+// UnderbondedProversKey holds the committee members whose bond is below the
+// minimum, so assignments can skip them without reading every bond.
+func UnderbondedProversKey() []byte {
+	return singletonKey(underbondedPrefix, UnderbondedChunks)
 }
 
-func PutCommitment(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, actor codec.Address, envelope []byte, commitment []byte) error {
-	k := CommitmentKey(marketID, windowID, actor)
-	v := make([]byte, 0, len(commitment)+len(envelope))
-	v = append(v, commitment...)
-	v = append(v, envelope...)
-	return mu.Insert(ctx, k, v)
+func PutUnderbondedProvers(ctx context.Context, mu state.Mutable, provers []codec.Address) error {
+	if len(provers) > MaxProverCommitteeMembers {
+		return ErrInvalidUnderbondedList
+	}
+	if len(provers) == 0 {
+		return mu.Remove(ctx, UnderbondedProversKey())
+	}
+	v := appendAddressList(make([]byte, 0, 1+len(provers)*codec.AddressLen), provers)
+	return mu.Insert(ctx, UnderbondedProversKey(), v)
 }
 
-// ========== Batch ==========
+func GetUnderbondedProvers(ctx context.Context, im state.Immutable) ([]codec.Address, error) {
+	v, err := im.GetValue(ctx, UnderbondedProversKey())
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	provers, offset, ok := readAddressList(v, 0)
+	if !ok || offset != len(v) {
+		return nil, ErrInvalidUnderbondedList
+	}
+	return provers, nil
+}
 
-func BatchKey(marketID ids.ID, windowID uint64) (k []byte) {
-	k = make([]byte, 1+ids.IDLen+8+consts.Uint16Len)
-	k[0] = batchPrefix
-	copy(k[1:], marketID[:])
-	binary.BigEndian.PutUint64(k[1+ids.IDLen:], windowID)
-	binary.BigEndian.PutUint16(k[1+ids.IDLen+8:], BatchChunks)
-	return
+// appendAddressList appends a one-byte count followed by the addresses.
+func appendAddressList(v []byte, addrs []codec.Address) []byte {
+	v = append(v, byte(len(addrs)))
+	for _, addr := range addrs {
+		v = append(v, addr[:]...)
+	}
+	return v
 }
 
-func PutBatchResult(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, clearPrice uint64, totalVolume uint64, fillsHash []byte) error {
-	k := BatchKey(marketID, windowID)
-	v := make([]byte, 0, 16+len(fillsHash))
-	v = binary.BigEndian.AppendUint64(v, clearPrice)
-	v = binary.BigEndian.AppendUint64(v, totalVolume)
-	v = append(v, fillsHash...)
-	return mu.Insert(ctx, k, v)
+// readAddressList reads a list written by appendAddressList at offset and
+// returns it along with the next offset.
+func readAddressList(v []byte, offset int) ([]codec.Address, int, bool) {
+	if len(v[offset:]) < 1 {
+		return nil, offset, false
+	}
+	n := int(v[offset])
+	offset++
+	if len(v[offset:]) < n*codec.AddressLen {
+		return nil, offset, false
+	}
+	addrs := make([]codec.Address, n)
+	for i := range addrs {
+		copy(addrs[i][:], v[offset:offset+codec.AddressLen])
+		offset += codec.AddressLen
+	}
+	return addrs, offset, true
 }
 
 // ========== Oracle ==========
@@ -367,14 +2252,62 @@ func DisputeKey(marketID ids.ID) (k []byte) {
 	return OracleKey(marketID, 0xFFFFFFFF)
 }
 
-func PutDispute(ctx context.Context, mu state.Mutable, marketID ids.ID, bond uint64, evidence []byte) error {
+// DisputeRecordVersion tags the stored dispute encoding:
+// version ‖ disputer ‖ bond ‖ disputed at ‖ evidence. Records written before
+// it are bond ‖ evidence, and start with the bond's high byte, which is zero
+// for any bond below 2^56.
+const DisputeRecordVersion byte = 1
+
+func PutDispute(ctx context.Context, mu state.Mutable, marketID ids.ID, dispute MarketDispute) error {
 	k := DisputeKey(marketID)
-	v := make([]byte, 0, 8+len(evidence))
-	v = binary.BigEndian.AppendUint64(v, bond)
-	v = append(v, evidence...)
+	v := make([]byte, 0, 1+codec.AddressLen+consts.Uint64Len*2+len(dispute.Evidence))
+	v = append(v, DisputeRecordVersion)
+	v = append(v, dispute.Disputer[:]...)
+	v = binary.BigEndian.AppendUint64(v, dispute.Bond)
+	v = binary.BigEndian.AppendUint64(v, uint64(dispute.DisputedAtMs))
+	v = append(v, dispute.Evidence...)
 	return mu.Insert(ctx, k, v)
 }
 
+func GetDispute(ctx context.Context, im state.Immutable, marketID ids.ID) (MarketDispute, error) {
+	v, err := im.GetValue(ctx, DisputeKey(marketID))
+	if errors.Is(err, database.ErrNotFound) {
+		return MarketDispute{}, ErrDisputeNotFound
+	}
+	if err != nil {
+		return MarketDispute{}, err
+	}
+	return parseDispute(v)
+}
+
+func parseDispute(v []byte) (MarketDispute, error) {
+	if len(v) > 0 && v[0] != DisputeRecordVersion {
+		if len(v) < consts.Uint64Len {
+			return MarketDispute{}, ErrInvalidDispute
+		}
+		return MarketDispute{
+			Bond:     binary.BigEndian.Uint64(v[:consts.Uint64Len]),
+			Evidence: v[consts.Uint64Len:],
+		}, nil
+	}
+	const headerLen = 1 + codec.AddressLen + consts.Uint64Len*2
+	if len(v) < headerLen {
+		return MarketDispute{}, ErrInvalidDispute
+	}
+	var dispute MarketDispute
+	copy(dispute.Disputer[:], v[1:1+codec.AddressLen])
+	offset := 1 + codec.AddressLen
+	dispute.Bond = binary.BigEndian.Uint64(v[offset : offset+consts.Uint64Len])
+	offset += consts.Uint64Len
+	dispute.DisputedAtMs = int64(binary.BigEndian.Uint64(v[offset : offset+consts.Uint64Len]))
+	dispute.Evidence = v[headerLen:]
+	return dispute, nil
+}
+
+func DeleteDispute(ctx context.Context, mu state.Mutable, marketID ids.ID) error {
+	return mu.Remove(ctx, DisputeKey(marketID))
+}
+
 // ========== AMM (UniV2-style pools) ==========
 
 func sortedAssetPair(asset0 uint8, asset1 uint8) (uint8, uint8) {
@@ -573,6 +2506,16 @@ func VellumProofKey(marketID ids.ID, windowID uint64) []byte {
 	return k
 }
 
+// AggregateProofKey stores an aggregate proof blob once, under its sha256
+// commitment, for every window it covers.
+func AggregateProofKey(commitment [32]byte) []byte {
+	k := make([]byte, 1+len(commitment)+consts.Uint16Len)
+	k[0] = aggregateProofPrefix
+	copy(k[1:], commitment[:])
+	binary.BigEndian.PutUint16(k[1+len(commitment):], AggregateProofChunks)
+	return k
+}
+
 func BloodswornKey(addr codec.Address) []byte {
 	k := make([]byte, 1+codec.AddressLen+consts.Uint16Len)
 	k[0] = bloodswornPrefix
@@ -919,10 +2862,84 @@ func GetProofConfig(ctx context.Context, im state.Immutable) (ProofConfig, error
 	return cfg, nil
 }
 
+func ProofUpgradeKey() []byte {
+	return singletonKey(proofUpgradePrefix, ProofUpgradeChunks)
+}
+
+func PutProofUpgrade(ctx context.Context, mu state.Mutable, upgrade ProofUpgrade) error {
+	if upgrade.ActivateAtMs <= 0 || upgrade.OverlapMs < 0 {
+		return ErrInvalidProofUpgrade
+	}
+	v := make([]byte, 0, ProofUpgradeChunks*64)
+	v = appendProofRule(v, upgrade.From)
+	v = appendProofRule(v, upgrade.To)
+	v = binary.BigEndian.AppendUint64(v, uint64(upgrade.ActivateAtMs))
+	v = binary.BigEndian.AppendUint64(v, uint64(upgrade.OverlapMs))
+	v = binary.BigEndian.AppendUint64(v, uint64(upgrade.ScheduledAtMs))
+	return mu.Insert(ctx, ProofUpgradeKey(), v)
+}
+
+// GetProofUpgrade returns the scheduled upgrade, if any.
+func GetProofUpgrade(ctx context.Context, im state.Immutable) (ProofUpgrade, bool, error) {
+	v, err := im.GetValue(ctx, ProofUpgradeKey())
+	if errors.Is(err, database.ErrNotFound) {
+		return ProofUpgrade{}, false, nil
+	}
+	if err != nil {
+		return ProofUpgrade{}, false, err
+	}
+	upgrade, err := parseProofUpgrade(v)
+	return upgrade, err == nil, err
+}
+
+func appendProofRule(v []byte, rule ProofRule) []byte {
+	v = append(v, rule.ProofType, byte(len(rule.CircuitID)))
+	v = append(v, rule.CircuitID...)
+	return append(v, rule.KeyHash[:]...)
+}
+
+func readProofRule(v []byte, offset int) (ProofRule, int, bool) {
+	if len(v[offset:]) < 2 {
+		return ProofRule{}, offset, false
+	}
+	rule := ProofRule{ProofType: v[offset]}
+	n := int(v[offset+1])
+	offset += 2
+	if len(v[offset:]) < n+ids.IDLen {
+		return ProofRule{}, offset, false
+	}
+	rule.CircuitID = string(v[offset : offset+n])
+	offset += n
+	copy(rule.KeyHash[:], v[offset:offset+ids.IDLen])
+	return rule, offset + ids.IDLen, true
+}
+
+func parseProofUpgrade(v []byte) (ProofUpgrade, error) {
+	var (
+		upgrade ProofUpgrade
+		offset  int
+		ok      bool
+	)
+	if upgrade.From, offset, ok = readProofRule(v, offset); !ok {
+		return ProofUpgrade{}, ErrInvalidProofUpgrade
+	}
+	if upgrade.To, offset, ok = readProofRule(v, offset); !ok {
+		return ProofUpgrade{}, ErrInvalidProofUpgrade
+	}
+	if len(v[offset:]) != 3*consts.Uint64Len {
+		return ProofUpgrade{}, ErrInvalidProofUpgrade
+	}
+	upgrade.ActivateAtMs = int64(binary.BigEndian.Uint64(v[offset:]))
+	upgrade.OverlapMs = int64(binary.BigEndian.Uint64(v[offset+consts.Uint64Len:]))
+	upgrade.ScheduledAtMs = int64(binary.BigEndian.Uint64(v[offset+2*consts.Uint64Len:]))
+	return upgrade, nil
+}
+
 func PutBatchProofRecord(ctx context.Context, mu state.Mutable, marketID ids.ID, windowID uint64, record BatchProofRecord) error {
 	const (
 		maxPublicInputsHashLen = 32
 		maxFillsHashLen        = 64
+		maxCircuitIDLen        = 64
 	)
 	if len(record.PublicInputsHash) == 0 || len(record.PublicInputsHash) > maxPublicInputsHashLen {
 		return ErrInvalidProofEnvelope
@@ -930,8 +2947,11 @@ func PutBatchProofRecord(ctx context.Context, mu state.Mutable, marketID ids.ID,
 	if len(record.FillsHash) == 0 || len(record.FillsHash) > maxFillsHashLen {
 		return ErrInvalidProofEnvelope
 	}
+	if len(record.CircuitID) > maxCircuitIDLen {
+		return ErrInvalidProofEnvelope
+	}
 	k := BatchProofKey(marketID, windowID)
-	v := make([]byte, 0, 1+consts.Uint64Len+consts.Uint64Len+codec.AddressLen+32+consts.Uint16Len+len(record.PublicInputsHash)+consts.Uint16Len+len(record.FillsHash))
+	v := make([]byte, 0, 1+consts.Uint64Len+consts.Uint64Len+codec.AddressLen+32+consts.Uint16Len+len(record.PublicInputsHash)+consts.Uint16Len+len(record.FillsHash)+2+len(record.CircuitID)+ids.IDLen)
 	v = append(v, record.ProofType)
 	v = binary.BigEndian.AppendUint64(v, uint64(record.SubmittedAtMs))
 	v = binary.BigEndian.AppendUint64(v, uint64(record.WindowCloseAtMs))
@@ -941,6 +2961,15 @@ func PutBatchProofRecord(ctx context.Context, mu state.Mutable, marketID ids.ID,
 	v = append(v, record.PublicInputsHash...)
 	v = binary.BigEndian.AppendUint16(v, uint16(len(record.FillsHash)))
 	v = append(v, record.FillsHash...)
+	if !record.Legacy {
+		verified := byte(0)
+		if record.Verified {
+			verified = 1
+		}
+		v = append(v, verified, byte(len(record.CircuitID)))
+		v = append(v, record.CircuitID...)
+		v = append(v, record.KeyHash[:]...)
+	}
 	return mu.Insert(ctx, k, v)
 }
 
@@ -989,6 +3018,23 @@ func GetBatchProofRecord(ctx context.Context, im state.Immutable, marketID ids.I
 		return BatchProofRecord{}, ErrInvalidProofEnvelope
 	}
 	rec.FillsHash = append([]byte(nil), v[offset:offset+fillsLen]...)
+	offset += fillsLen
+
+	if len(v[offset:]) == 0 {
+		rec.Legacy = true
+		return rec, nil
+	}
+	if len(v[offset:]) < 2 || v[offset] > 1 {
+		return BatchProofRecord{}, ErrInvalidProofEnvelope
+	}
+	rec.Verified = v[offset] == 1
+	circuitLen := int(v[offset+1])
+	offset += 2
+	if len(v[offset:]) != circuitLen+ids.IDLen {
+		return BatchProofRecord{}, ErrInvalidProofEnvelope
+	}
+	rec.CircuitID = string(v[offset : offset+circuitLen])
+	copy(rec.KeyHash[:], v[offset+circuitLen:])
 	return rec, nil
 }
 
@@ -1042,6 +3088,34 @@ func GetVellumProofFromState(ctx context.Context, f ReadState, marketID ids.ID,
 	return append([]byte(nil), v[consts.Uint32Len:]...), nil
 }
 
+func PutAggregateProof(ctx context.Context, mu state.Mutable, commitment [32]byte, proof []byte) error {
+	if len(proof) == 0 || len(proof) > maxVellumProofBytes {
+		return ErrInvalidVellumProof
+	}
+	v := make([]byte, 0, consts.Uint32Len+len(proof))
+	v = binary.BigEndian.AppendUint32(v, uint32(len(proof)))
+	v = append(v, proof...)
+	return mu.Insert(ctx, AggregateProofKey(commitment), v)
+}
+
+func GetAggregateProof(ctx context.Context, im state.Immutable, commitment [32]byte) ([]byte, error) {
+	v, err := im.GetValue(ctx, AggregateProofKey(commitment))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrVellumProofNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(v) < consts.Uint32Len {
+		return nil, ErrInvalidVellumProof
+	}
+	proofLen := int(binary.BigEndian.Uint32(v[:consts.Uint32Len]))
+	if proofLen <= 0 || proofLen > maxVellumProofBytes || len(v) != consts.Uint32Len+proofLen {
+		return nil, ErrInvalidVellumProof
+	}
+	return append([]byte(nil), v[consts.Uint32Len:]...), nil
+}
+
 func PutBloodsworn(ctx context.Context, mu state.Mutable, addr codec.Address, bloodsworn Bloodsworn) error {
 	v := make([]byte, 0, consts.Uint64Len+consts.Uint64Len+consts.Uint64Len+consts.Uint32Len)
 	v = binary.BigEndian.AppendUint64(v, bloodsworn.TotalAcceptedProofs)
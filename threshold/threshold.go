@@ -0,0 +1,389 @@
+// Package threshold implements the threshold identity-based encryption that
+// seals VeilVM order envelopes to the decrypt committee.
+//
+// The committee holds Shamir shares s_i of a master secret s over the BN254
+// scalar field. The committee public key is s·G2 and member i publishes the
+// verification key s_i·G2. Each (market, window) pair is an identity Q in G1.
+// A member's decryption share for a window is s_i·Q, checked on-chain with
+// e(s_i·Q, G2) == e(Q, s_i·G2). Any threshold of shares interpolates to the
+// window key s·Q, which opens every envelope sealed to that window.
+package threshold
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+const (
+	// Reference committee parameters: any 13 of 20 members open a window.
+	DefaultThreshold     = 13
+	DefaultCommitteeSize = 20
+	MaxCommitteeSize     = 32
+
+	PublicKeySize = bn254.SizeOfG2AffineCompressed
+	ShareSize     = bn254.SizeOfG1AffineCompressed
+	WindowKeySize = bn254.SizeOfG1AffineCompressed
+
+	EnvelopeVersion  byte = 1
+	EnvelopeOverhead      = 1 + bn254.SizeOfG2AffineCompressed + 16
+
+	identityDomainTag = "VEIL_TIBE_V1_IDENTITY"
+	keyDomainTag      = "VEIL_TIBE_V1_KEY"
+)
+
+var (
+	ErrInvalidThreshold   = errors.New("invalid threshold parameters")
+	ErrInvalidPublicKey   = errors.New("invalid committee public key")
+	ErrInvalidShare       = errors.New("invalid decryption share")
+	ErrInvalidIndex       = errors.New("invalid member index")
+	ErrDuplicateIndex     = errors.New("duplicate member index")
+	ErrInsufficientShares = errors.New("insufficient decryption shares")
+	ErrInvalidEnvelope    = errors.New("invalid envelope")
+	ErrDecryptionFailed   = errors.New("envelope decryption failed")
+)
+
+// KeyShare is a committee member's secret share. Index is the member's
+// 1-based position in the committee and the Shamir evaluation point.
+type KeyShare struct {
+	Index  uint32
+	Secret fr.Element
+}
+
+// Deal samples a fresh master secret and splits it into n shares, any
+// threshold of which reconstruct it. It returns the committee public key and
+// the member verification keys in index order. Production committees should
+// run a DKG instead; Deal exists for devnets, tests and tooling.
+func Deal(threshold int, n int, rng io.Reader) ([]byte, [][]byte, []KeyShare, error) {
+	if threshold < 1 || n < threshold || n > MaxCommitteeSize {
+		return nil, nil, nil, ErrInvalidThreshold
+	}
+	coeffs := make([]fr.Element, threshold)
+	for i := range coeffs {
+		c, err := randomScalar(rng)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		coeffs[i] = c
+	}
+
+	_, _, _, g2 := bn254.Generators()
+	publicKey := scalarMulG2(&g2, &coeffs[0])
+	memberKeys := make([][]byte, n)
+	shares := make([]KeyShare, n)
+	for i := 0; i < n; i++ {
+		var x, y fr.Element
+		x.SetUint64(uint64(i + 1))
+		// Horner evaluation of the sharing polynomial at x.
+		for j := threshold - 1; j >= 0; j-- {
+			y.Mul(&y, &x)
+			y.Add(&y, &coeffs[j])
+		}
+		shares[i] = KeyShare{Index: uint32(i + 1), Secret: y}
+		memberKey := scalarMulG2(&g2, &y)
+		memberKeys[i] = compressG2(&memberKey)
+	}
+	return compressG2(&publicKey), memberKeys, shares, nil
+}
+
+// WindowIdentity maps a (market, window) pair to its identity point in G1.
+func WindowIdentity(marketID ids.ID, windowID uint64) (bn254.G1Affine, error) {
+	msg := make([]byte, 0, ids.IDLen+8)
+	msg = append(msg, marketID[:]...)
+	msg = binary.BigEndian.AppendUint64(msg, windowID)
+	return bn254.HashToG1(msg, []byte(identityDomainTag))
+}
+
+// DecryptionShare returns the member's share of the window key.
+func (k KeyShare) DecryptionShare(marketID ids.ID, windowID uint64) ([]byte, error) {
+	q, err := WindowIdentity(marketID, windowID)
+	if err != nil {
+		return nil, err
+	}
+	share := scalarMulG1(&q, &k.Secret)
+	return compressG1(&share), nil
+}
+
+// VerifyShare checks a member's decryption share against its verification
+// key.
+func VerifyShare(memberKey []byte, marketID ids.ID, windowID uint64, share []byte) error {
+	vk, err := ParsePublicKey(memberKey)
+	if err != nil {
+		return err
+	}
+	d, err := parseG1(share, ErrInvalidShare)
+	if err != nil {
+		return err
+	}
+	q, err := WindowIdentity(marketID, windowID)
+	if err != nil {
+		return err
+	}
+	if !pairingEqual(&d, &q, &vk) {
+		return ErrInvalidShare
+	}
+	return nil
+}
+
+// CombineShares interpolates verified decryption shares into the window key.
+// indices[i] is the member index that produced shares[i].
+func CombineShares(indices []uint32, shares [][]byte) ([]byte, error) {
+	if len(indices) == 0 || len(indices) != len(shares) {
+		return nil, ErrInsufficientShares
+	}
+	lambdas, err := lagrangeAtZero(indices)
+	if err != nil {
+		return nil, err
+	}
+	var acc bn254.G1Jac
+	for i, raw := range shares {
+		d, err := parseG1(raw, ErrInvalidShare)
+		if err != nil {
+			return nil, err
+		}
+		term := scalarMulG1(&d, &lambdas[i])
+		acc.AddMixed(&term)
+	}
+	var key bn254.G1Affine
+	key.FromJacobian(&acc)
+	return compressG1(&key), nil
+}
+
+// CombinePublicKeys interpolates member verification keys into the
+// committee public key, letting callers check a committee is consistent.
+func CombinePublicKeys(indices []uint32, memberKeys [][]byte) ([]byte, error) {
+	if len(indices) == 0 || len(indices) != len(memberKeys) {
+		return nil, ErrInsufficientShares
+	}
+	lambdas, err := lagrangeAtZero(indices)
+	if err != nil {
+		return nil, err
+	}
+	var acc bn254.G2Jac
+	for i, raw := range memberKeys {
+		vk, err := ParsePublicKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		term := scalarMulG2(&vk, &lambdas[i])
+		acc.AddMixed(&term)
+	}
+	var key bn254.G2Affine
+	key.FromJacobian(&acc)
+	return compressG2(&key), nil
+}
+
+// VerifyWindowKey checks a combined window key against the committee public
+// key.
+func VerifyWindowKey(publicKey []byte, marketID ids.ID, windowID uint64, windowKey []byte) error {
+	return VerifyShare(publicKey, marketID, windowID, windowKey)
+}
+
+// Encrypt seals plaintext to the committee for one (market, window). The
+// envelope is version || U || AES-256-GCM(ciphertext), where U = r·G2 and
+// the AES key is derived from e(Q, publicKey)^r.
+func Encrypt(publicKey []byte, marketID ids.ID, windowID uint64, plaintext []byte, rng io.Reader) ([]byte, error) {
+	if rng == nil {
+		rng = rand.Reader
+	}
+	mpk, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	q, err := WindowIdentity(marketID, windowID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := randomScalar(rng)
+	if err != nil {
+		return nil, err
+	}
+	_, _, _, g2 := bn254.Generators()
+	u := scalarMulG2(&g2, &r)
+	rq := scalarMulG1(&q, &r)
+	gt, err := bn254.Pair([]bn254.G1Affine{rq}, []bn254.G2Affine{mpk})
+	if err != nil {
+		return nil, err
+	}
+	uBytes := compressG2(&u)
+	aead, err := envelopeAEAD(&gt, uBytes)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, EnvelopeOverhead+len(plaintext))
+	out = append(out, EnvelopeVersion)
+	out = append(out, uBytes...)
+	// Each envelope derives a fresh key from r, so a fixed nonce is safe.
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(out, nonce, plaintext, envelopeAAD(marketID, windowID)), nil
+}
+
+// Decrypt opens an envelope with the window key for its (market, window).
+func Decrypt(windowKey []byte, marketID ids.ID, windowID uint64, envelope []byte) ([]byte, error) {
+	if len(envelope) < EnvelopeOverhead || envelope[0] != EnvelopeVersion {
+		return nil, ErrInvalidEnvelope
+	}
+	key, err := parseG1(windowKey, ErrInvalidShare)
+	if err != nil {
+		return nil, err
+	}
+	uBytes := envelope[1 : 1+PublicKeySize]
+	u, err := ParsePublicKey(uBytes)
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	gt, err := bn254.Pair([]bn254.G1Affine{key}, []bn254.G2Affine{u})
+	if err != nil {
+		return nil, err
+	}
+	aead, err := envelopeAEAD(&gt, uBytes)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	plaintext, err := aead.Open(nil, nonce, envelope[1+PublicKeySize:], envelopeAAD(marketID, windowID))
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// ParsePublicKey decodes a compressed G2 point, rejecting the identity.
+func ParsePublicKey(b []byte) (bn254.G2Affine, error) {
+	var p bn254.G2Affine
+	if len(b) != PublicKeySize {
+		return p, ErrInvalidPublicKey
+	}
+	if _, err := p.SetBytes(b); err != nil {
+		return p, ErrInvalidPublicKey
+	}
+	if p.IsInfinity() {
+		return p, ErrInvalidPublicKey
+	}
+	return p, nil
+}
+
+func parseG1(b []byte, invalid error) (bn254.G1Affine, error) {
+	var p bn254.G1Affine
+	if len(b) != bn254.SizeOfG1AffineCompressed {
+		return p, invalid
+	}
+	if _, err := p.SetBytes(b); err != nil {
+		return p, invalid
+	}
+	if p.IsInfinity() {
+		return p, invalid
+	}
+	return p, nil
+}
+
+// pairingEqual reports whether e(a, G2) == e(q, vk).
+func pairingEqual(a *bn254.G1Affine, q *bn254.G1Affine, vk *bn254.G2Affine) bool {
+	_, _, _, g2 := bn254.Generators()
+	var negQ bn254.G1Affine
+	negQ.Neg(q)
+	ok, err := bn254.PairingCheck(
+		[]bn254.G1Affine{*a, negQ},
+		[]bn254.G2Affine{g2, *vk},
+	)
+	return err == nil && ok
+}
+
+// lagrangeAtZero returns the Lagrange coefficients that interpolate the
+// values at indices to x = 0.
+func lagrangeAtZero(indices []uint32) ([]fr.Element, error) {
+	xs := make([]fr.Element, len(indices))
+	seen := make(map[uint32]struct{}, len(indices))
+	for i, idx := range indices {
+		if idx == 0 || idx > MaxCommitteeSize {
+			return nil, ErrInvalidIndex
+		}
+		if _, ok := seen[idx]; ok {
+			return nil, ErrDuplicateIndex
+		}
+		seen[idx] = struct{}{}
+		xs[i].SetUint64(uint64(idx))
+	}
+	lambdas := make([]fr.Element, len(indices))
+	for i := range xs {
+		var num, den fr.Element
+		num.SetOne()
+		den.SetOne()
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			var diff fr.Element
+			diff.Sub(&xs[j], &xs[i])
+			num.Mul(&num, &xs[j])
+			den.Mul(&den, &diff)
+		}
+		den.Inverse(&den)
+		lambdas[i].Mul(&num, &den)
+	}
+	return lambdas, nil
+}
+
+func envelopeAEAD(gt *bn254.GT, u []byte) (cipher.AEAD, error) {
+	gtBytes := gt.Bytes()
+	h := sha256.New()
+	h.Write([]byte(keyDomainTag))
+	h.Write(gtBytes[:])
+	h.Write(u)
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func envelopeAAD(marketID ids.ID, windowID uint64) []byte {
+	aad := make([]byte, 0, ids.IDLen+8)
+	aad = append(aad, marketID[:]...)
+	return binary.BigEndian.AppendUint64(aad, windowID)
+}
+
+func randomScalar(rng io.Reader) (fr.Element, error) {
+	var s fr.Element
+	for {
+		k, err := rand.Int(rng, fr.Modulus())
+		if err != nil {
+			return s, err
+		}
+		if k.Sign() != 0 {
+			s.SetBigInt(k)
+			return s, nil
+		}
+	}
+}
+
+func scalarMulG1(p *bn254.G1Affine, s *fr.Element) bn254.G1Affine {
+	var out bn254.G1Affine
+	out.ScalarMultiplication(p, s.BigInt(new(big.Int)))
+	return out
+}
+
+func scalarMulG2(p *bn254.G2Affine, s *fr.Element) bn254.G2Affine {
+	var out bn254.G2Affine
+	out.ScalarMultiplication(p, s.BigInt(new(big.Int)))
+	return out
+}
+
+func compressG1(p *bn254.G1Affine) []byte {
+	b := p.Bytes()
+	return b[:]
+}
+
+func compressG2(p *bn254.G2Affine) []byte {
+	b := p.Bytes()
+	return b[:]
+}
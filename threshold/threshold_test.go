@@ -0,0 +1,123 @@
+package threshold
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+func TestThresholdEncryptDecryptRoundTrip(t *testing.T) {
+	publicKey, memberKeys, shares, err := Deal(DefaultThreshold, DefaultCommitteeSize, rand.Reader)
+	if err != nil {
+		t.Fatalf("deal failed: %v", err)
+	}
+	marketID := ids.ID{7}
+	const windowID = uint64(42)
+	plaintext := []byte("buy 10 @ 5500")
+
+	envelope, err := Encrypt(publicKey, marketID, windowID, plaintext, rand.Reader)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	// Use the last threshold members to make sure interpolation does not
+	// depend on starting from index 1.
+	indices := make([]uint32, 0, DefaultThreshold)
+	decShares := make([][]byte, 0, DefaultThreshold)
+	for _, share := range shares[DefaultCommitteeSize-DefaultThreshold:] {
+		d, err := share.DecryptionShare(marketID, windowID)
+		if err != nil {
+			t.Fatalf("decryption share failed: %v", err)
+		}
+		if err := VerifyShare(memberKeys[share.Index-1], marketID, windowID, d); err != nil {
+			t.Fatalf("share %d did not verify: %v", share.Index, err)
+		}
+		indices = append(indices, share.Index)
+		decShares = append(decShares, d)
+	}
+
+	windowKey, err := CombineShares(indices, decShares)
+	if err != nil {
+		t.Fatalf("combine failed: %v", err)
+	}
+	if err := VerifyWindowKey(publicKey, marketID, windowID, windowKey); err != nil {
+		t.Fatalf("window key did not verify: %v", err)
+	}
+	got, err := Decrypt(windowKey, marketID, windowID, envelope)
+	if err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("plaintext mismatch: got=%q want=%q", got, plaintext)
+	}
+
+	// A window key is bound to its window.
+	if _, err := Decrypt(windowKey, marketID, windowID+1, envelope); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("expected ErrDecryptionFailed for wrong window, got %v", err)
+	}
+}
+
+func TestThresholdBelowThresholdCannotDecrypt(t *testing.T) {
+	publicKey, _, shares, err := Deal(3, 5, rand.Reader)
+	if err != nil {
+		t.Fatalf("deal failed: %v", err)
+	}
+	marketID := ids.ID{1}
+	envelope, err := Encrypt(publicKey, marketID, 1, []byte("order"), rand.Reader)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	indices := []uint32{shares[0].Index, shares[1].Index}
+	decShares := make([][]byte, 0, 2)
+	for _, share := range shares[:2] {
+		d, err := share.DecryptionShare(marketID, 1)
+		if err != nil {
+			t.Fatalf("decryption share failed: %v", err)
+		}
+		decShares = append(decShares, d)
+	}
+	windowKey, err := CombineShares(indices, decShares)
+	if err != nil {
+		t.Fatalf("combine failed: %v", err)
+	}
+	if err := VerifyWindowKey(publicKey, marketID, 1, windowKey); !errors.Is(err, ErrInvalidShare) {
+		t.Fatalf("expected sub-threshold key to fail verification, got %v", err)
+	}
+	if _, err := Decrypt(windowKey, marketID, 1, envelope); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("expected ErrDecryptionFailed, got %v", err)
+	}
+}
+
+func TestThresholdRejectsForeignShare(t *testing.T) {
+	_, memberKeys, shares, err := Deal(2, 3, rand.Reader)
+	if err != nil {
+		t.Fatalf("deal failed: %v", err)
+	}
+	d, err := shares[0].DecryptionShare(ids.Empty, 9)
+	if err != nil {
+		t.Fatalf("decryption share failed: %v", err)
+	}
+	if err := VerifyShare(memberKeys[1], ids.Empty, 9, d); !errors.Is(err, ErrInvalidShare) {
+		t.Fatalf("expected ErrInvalidShare for wrong member key, got %v", err)
+	}
+	if err := VerifyShare(memberKeys[0], ids.Empty, 10, d); !errors.Is(err, ErrInvalidShare) {
+		t.Fatalf("expected ErrInvalidShare for wrong window, got %v", err)
+	}
+}
+
+func TestCombinePublicKeysMatchesCommitteeKey(t *testing.T) {
+	publicKey, memberKeys, _, err := Deal(3, 4, rand.Reader)
+	if err != nil {
+		t.Fatalf("deal failed: %v", err)
+	}
+	combined, err := CombinePublicKeys([]uint32{2, 3, 4}, memberKeys[1:])
+	if err != nil {
+		t.Fatalf("combine public keys failed: %v", err)
+	}
+	if !bytes.Equal(combined, publicKey) {
+		t.Fatalf("interpolated public key does not match committee key")
+	}
+}
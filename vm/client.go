@@ -242,6 +242,165 @@ func (cli *JSONRPCClient) Glyph(ctx context.Context, marketID ids.ID, windowID u
 	return resp, err
 }
 
+func (cli *JSONRPCClient) DecryptCommittee(ctx context.Context) (*DecryptCommitteeReply, error) {
+	resp := new(DecryptCommitteeReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"decryptcommittee",
+		nil,
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) ProverCommittee(ctx context.Context) (*ProverCommitteeReply, error) {
+	resp := new(ProverCommitteeReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"provercommittee",
+		nil,
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) ProverAssignment(ctx context.Context, marketID ids.ID, windowID uint64, atMs int64) (*ProverAssignmentReply, error) {
+	resp := new(ProverAssignmentReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"proverassignment",
+		&ProverAssignmentArgs{
+			MarketID: marketID,
+			WindowID: windowID,
+			AtMs:     atMs,
+		},
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) ProverBond(ctx context.Context, addr codec.Address) (*ProverBondReply, error) {
+	resp := new(ProverBondReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"proverbond",
+		&ProverBondArgs{Address: addr},
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) BatchResult(ctx context.Context, marketID ids.ID, windowID uint64) (*BatchResultReply, error) {
+	resp := new(BatchResultReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"batchresult",
+		&BatchResultArgs{
+			MarketID: marketID,
+			WindowID: windowID,
+		},
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) ClearedWindows(ctx context.Context, marketID ids.ID, limit int) (*ClearedWindowsReply, error) {
+	resp := new(ClearedWindowsReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"clearedwindows",
+		&ClearedWindowsArgs{
+			MarketID: marketID,
+			Limit:    limit,
+		},
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) RevealShares(ctx context.Context, marketID ids.ID, windowID uint64) (*RevealSharesReply, error) {
+	resp := new(RevealSharesReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"revealshares",
+		&RevealSharesArgs{
+			MarketID: marketID,
+			WindowID: windowID,
+		},
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) Settlement(
+	ctx context.Context,
+	marketID ids.ID,
+	windowID uint64,
+	trader codec.Address,
+) (*SettlementReply, error) {
+	resp := new(SettlementReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"settlement",
+		&SettlementArgs{
+			MarketID: marketID,
+			WindowID: windowID,
+			Trader:   trader,
+		},
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) Nullifier(ctx context.Context, nullifier ids.ID) (*NullifierReply, error) {
+	resp := new(NullifierReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"nullifier",
+		&NullifierArgs{Nullifier: nullifier},
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) NoteTreeRoot(ctx context.Context) (*NoteTreeRootReply, error) {
+	resp := new(NoteTreeRootReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"notetreeroot",
+		nil,
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) NoteTreePath(ctx context.Context, index uint64) (*NoteTreePathReply, error) {
+	resp := new(NoteTreePathReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"notetreepath",
+		&NoteTreePathArgs{Index: index},
+		resp,
+	)
+	return resp, err
+}
+
+func (cli *JSONRPCClient) VerifyingKey(
+	ctx context.Context,
+	circuitID string,
+	proofType uint8,
+	version uint32,
+) (*VerifyingKeyReply, error) {
+	resp := new(VerifyingKeyReply)
+	err := cli.requester.SendRequest(
+		ctx,
+		"verifyingkey",
+		&VerifyingKeyArgs{CircuitID: circuitID, ProofType: proofType, Version: version},
+		resp,
+	)
+	return resp, err
+}
+
 func (cli *JSONRPCClient) ClearInputsHash(
 	ctx context.Context,
 	marketID ids.ID,
@@ -1,19 +1,21 @@
 package vm
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/ava-labs/hypersdk/api"
 	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
 	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
 	"github.com/ava-labs/hypersdk/examples/veilvm/zk"
 	"github.com/ava-labs/hypersdk/vm"
 )
 
 const Namespace = "controller"
-const defaultLocalGroth16VKPath = "/root/.avalanchego/zk/groth16_clearhash_vk.bin"
 
 type Config struct {
 	Enabled bool             `json:"enabled"`
@@ -26,7 +28,11 @@ type ZKVerifierConfig struct {
 
 	Groth16VerifyingKeyPath string `json:"groth16VerifyingKeyPath"`
 	PlonkVerifyingKeyPath   string `json:"plonkVerifyingKeyPath"`
-	RequiredCircuitID       string `json:"requiredCircuitID"`
+
+	// RequiredCircuitID is advisory. On-chain proof rules decide which
+	// circuits are accepted; the node only checks that it names a known
+	// circuit and logs it.
+	RequiredCircuitID string `json:"requiredCircuitID"`
 }
 
 func NewDefaultConfig() Config {
@@ -81,37 +87,37 @@ func resolveZKConfig(cfg ZKVerifierConfig) ZKVerifierConfig {
 	if v, ok := getEnv("VEIL_ZK_REQUIRED_CIRCUIT_ID"); ok {
 		cfg.RequiredCircuitID = v
 	}
-	// Local/dev fallback: if env did not flow into the VM subprocess but the
-	// standard fixture VK exists in-container, fail closed by default.
-	if !cfg.Enabled &&
-		strings.TrimSpace(cfg.Groth16VerifyingKeyPath) == "" &&
-		strings.TrimSpace(cfg.PlonkVerifyingKeyPath) == "" {
-		if _, err := os.Stat(defaultLocalGroth16VKPath); err == nil {
-			cfg.Enabled = true
-			cfg.Strict = true
-			cfg.Groth16VerifyingKeyPath = defaultLocalGroth16VKPath
-			if strings.TrimSpace(cfg.RequiredCircuitID) == "" {
-				cfg.RequiredCircuitID = mconsts.ProofCircuitClearHashV1
-			}
-		}
-	}
 	return cfg
 }
 
 func installBatchProofVerifier(cfg ZKVerifierConfig) error {
-	if !cfg.Enabled {
-		actions.ConfigureBatchProofVerifier(nil, cfg.Strict)
-		return nil
+	if id := strings.TrimSpace(cfg.RequiredCircuitID); id != "" {
+		if !slices.Contains(mconsts.ProofCircuitIDs, id) {
+			return fmt.Errorf("%w: %s", storage.ErrUnsupportedProofCircuit, id)
+		}
+		log.Printf("veilvm zk required circuit %q is advisory; on-chain proof rules select accepted circuits", id)
 	}
-
-	verifier, err := zk.NewVerifier(zk.Config{
-		Groth16VerifyingKeyPath: cfg.Groth16VerifyingKeyPath,
-		PlonkVerifyingKeyPath:   cfg.PlonkVerifyingKeyPath,
-		RequiredCircuitID:       cfg.RequiredCircuitID,
-	})
+	// Every node verifies proofs against registered keys, so the verifier is
+	// always installed. Enabled only loads the node-local key files, which
+	// serve circuits without a registered key.
+	var zkCfg zk.Config
+	if cfg.Enabled {
+		zkCfg = zk.Config{
+			Groth16VerifyingKeyPath: cfg.Groth16VerifyingKeyPath,
+			PlonkVerifyingKeyPath:   cfg.PlonkVerifyingKeyPath,
+		}
+	}
+	verifier, err := zk.NewVerifier(zkCfg)
 	if err != nil {
 		return err
 	}
+	// Registered keys take precedence; log local key hashes so operators can
+	// compare them with the verifyingkey RPC.
+	for _, proofType := range []uint8{mconsts.ProofTypeGroth16, mconsts.ProofTypePlonk} {
+		if hash, ok := verifier.LocalKeyHash(proofType); ok {
+			log.Printf("veilvm zk local verifying key: proof_type=%d sha256=%x", proofType, hash)
+		}
+	}
 	actions.ConfigureBatchProofVerifier(verifier, cfg.Strict)
 	return nil
 }
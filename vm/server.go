@@ -1,8 +1,11 @@
 package vm
 
 import (
+	"errors"
 	"math/big"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
 
@@ -75,6 +78,8 @@ type MarketReply struct {
 	ResolutionTime  int64  `json:"resolution_time"`
 	ResolvedOutcome uint8  `json:"resolved_outcome"`
 	Question        []byte `json:"question"`
+	ResolvedAtMs    int64  `json:"resolved_at_ms"`
+	DisputeEndsAtMs int64  `json:"dispute_ends_at_ms"`
 }
 
 func (j *JSONRPCServer) Market(req *http.Request, args *MarketArgs, reply *MarketReply) error {
@@ -90,6 +95,15 @@ func (j *JSONRPCServer) Market(req *http.Request, args *MarketArgs, reply *Marke
 	reply.ResolutionTime = resolutionTime
 	reply.ResolvedOutcome = resolvedOutcome
 	reply.Question = question
+	resolution, err := storage.GetMarketResolutionFromState(ctx, j.vm.ReadState, args.MarketID)
+	if errors.Is(err, storage.ErrMarketNotResolved) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	reply.ResolvedAtMs = resolution.ResolvedAtMs
+	reply.DisputeEndsAtMs = resolution.DisputeEndsAtMs
 	return nil
 }
 
@@ -369,6 +383,24 @@ type ProofConfigReply struct {
 	BatchWindowMs     int64         `json:"batch_window_ms"`
 	ProofDeadlineMs   int64         `json:"proof_deadline_ms"`
 	ProverAuthority   codec.Address `json:"prover_authority"`
+
+	// Upgrade is the scheduled proof-system upgrade, if any. Once scheduled
+	// it supersedes RequiredProofType.
+	Upgrade *ProofUpgradeReply `json:"upgrade,omitempty"`
+}
+
+type ProofRuleReply struct {
+	ProofType uint8  `json:"proof_type"`
+	CircuitID string `json:"circuit_id"`
+	KeyHash   ids.ID `json:"key_hash"`
+}
+
+type ProofUpgradeReply struct {
+	From          ProofRuleReply `json:"from"`
+	To            ProofRuleReply `json:"to"`
+	ActivateAtMs  int64          `json:"activate_at_ms"`
+	OverlapMs     int64          `json:"overlap_ms"`
+	ScheduledAtMs int64          `json:"scheduled_at_ms"`
 }
 
 func (j *JSONRPCServer) ProofConfig(req *http.Request, _ *struct{}, reply *ProofConfigReply) error {
@@ -389,6 +421,20 @@ func (j *JSONRPCServer) ProofConfig(req *http.Request, _ *struct{}, reply *Proof
 	reply.BatchWindowMs = cfg.BatchWindowMs
 	reply.ProofDeadlineMs = cfg.ProofDeadlineMs
 	reply.ProverAuthority = cfg.ProverAuthority
+
+	upgrade, scheduled, err := storage.GetProofUpgrade(ctx, im)
+	if err != nil {
+		return err
+	}
+	if scheduled {
+		reply.Upgrade = &ProofUpgradeReply{
+			From:          ProofRuleReply(upgrade.From),
+			To:            ProofRuleReply(upgrade.To),
+			ActivateAtMs:  upgrade.ActivateAtMs,
+			OverlapMs:     upgrade.OverlapMs,
+			ScheduledAtMs: upgrade.ScheduledAtMs,
+		}
+	}
 	return nil
 }
 
@@ -405,6 +451,10 @@ type BatchProofReply struct {
 	ProofCommitment  []byte        `json:"proof_commitment"`
 	PublicInputsHash []byte        `json:"public_inputs_hash"`
 	FillsHash        []byte        `json:"fills_hash"`
+	Legacy           bool          `json:"legacy"`
+	Verified         bool          `json:"verified"`
+	CircuitID        string        `json:"circuit_id"`
+	KeyHash          ids.ID        `json:"key_hash"`
 }
 
 func (j *JSONRPCServer) BatchProof(req *http.Request, args *BatchProofArgs, reply *BatchProofReply) error {
@@ -427,6 +477,10 @@ func (j *JSONRPCServer) BatchProof(req *http.Request, args *BatchProofArgs, repl
 	reply.ProofCommitment = rec.ProofCommitment[:]
 	reply.PublicInputsHash = append([]byte(nil), rec.PublicInputsHash...)
 	reply.FillsHash = append([]byte(nil), rec.FillsHash...)
+	reply.Legacy = rec.Legacy
+	reply.Verified = rec.Verified
+	reply.CircuitID = rec.CircuitID
+	reply.KeyHash = rec.KeyHash
 	return nil
 }
 
@@ -445,6 +499,19 @@ func (j *JSONRPCServer) VellumProof(req *http.Request, args *VellumProofArgs, re
 	defer span.End()
 
 	proof, err := storage.GetVellumProofFromState(ctx, j.vm.ReadState, args.MarketID, args.WindowID)
+	if errors.Is(err, storage.ErrVellumProofNotFound) {
+		// Windows proven by an aggregate share one blob, stored under the
+		// record's proof commitment.
+		im, imErr := j.vm.ImmutableState(ctx)
+		if imErr != nil {
+			return imErr
+		}
+		rec, recErr := storage.GetBatchProofRecord(ctx, im, args.MarketID, args.WindowID)
+		if recErr != nil {
+			return err
+		}
+		proof, err = storage.GetAggregateProof(ctx, im, rec.ProofCommitment)
+	}
 	if err != nil {
 		return err
 	}
@@ -515,6 +582,388 @@ func (j *JSONRPCServer) Glyph(req *http.Request, args *GlyphArgs, reply *GlyphRe
 	return nil
 }
 
+type DecryptCommitteeReply struct {
+	Threshold       uint16          `json:"threshold"`
+	PublicKey       []byte          `json:"public_key"`
+	MemberKeys      [][]byte        `json:"member_keys"`
+	MemberAddresses []codec.Address `json:"member_addresses"`
+	Epoch           uint64          `json:"epoch"`
+}
+
+func (j *JSONRPCServer) DecryptCommittee(req *http.Request, _ *struct{}, reply *DecryptCommitteeReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.DecryptCommittee")
+	defer span.End()
+
+	committee, err := storage.GetDecryptCommitteeFromState(ctx, j.vm.ReadState)
+	if err != nil {
+		return err
+	}
+	reply.Threshold = committee.Threshold
+	reply.PublicKey = committee.PublicKey
+	reply.MemberKeys = committee.MemberKeys
+	reply.MemberAddresses = committee.MemberAddresses
+	reply.Epoch = committee.Epoch
+	return nil
+}
+
+type ProverCommitteeReply struct {
+	Primaries       uint8           `json:"primaries"`
+	Backups         uint8           `json:"backups"`
+	BackupDelayBips uint16          `json:"backup_delay_bips"`
+	Members         []codec.Address `json:"members"`
+}
+
+func (j *JSONRPCServer) ProverCommittee(req *http.Request, _ *struct{}, reply *ProverCommitteeReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.ProverCommittee")
+	defer span.End()
+
+	committee, err := storage.GetProverCommitteeFromState(ctx, j.vm.ReadState)
+	if err != nil {
+		return err
+	}
+	reply.Primaries = committee.Primaries
+	reply.Backups = committee.Backups
+	reply.BackupDelayBips = committee.BackupDelayBips
+	reply.Members = committee.Members
+	return nil
+}
+
+type ProverAssignmentArgs struct {
+	MarketID ids.ID `json:"market_id"`
+	WindowID uint64 `json:"window_id"`
+	// AtMs is the time jails are checked at for windows revealed without a
+	// pinned assignment; zero means now.
+	AtMs int64 `json:"at_ms"`
+}
+
+type ProverAssignmentReply struct {
+	Primaries          []codec.Address `json:"primaries"`
+	Backups            []codec.Address `json:"backups"`
+	BackupEligibleAtMs int64           `json:"backup_eligible_at_ms"`
+}
+
+// ProverAssignment returns the provers pinned for a revealed window. It fails
+// until the window key is revealed and while no prover committee is
+// registered.
+func (j *JSONRPCServer) ProverAssignment(req *http.Request, args *ProverAssignmentArgs, reply *ProverAssignmentReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.ProverAssignment")
+	defer span.End()
+
+	im, err := j.vm.ImmutableState(ctx)
+	if err != nil {
+		return err
+	}
+	cfg, err := storage.GetProofConfig(ctx, im)
+	if err != nil {
+		return err
+	}
+	window, err := storage.GetWindowState(ctx, im, args.MarketID, args.WindowID)
+	if err != nil {
+		return err
+	}
+	atMs := args.AtMs
+	if atMs == 0 {
+		atMs = time.Now().UnixMilli()
+	}
+	assignment, err := actions.LoadProverAssignment(ctx, im, cfg, args.MarketID, args.WindowID, window.CloseAtMs, atMs)
+	if err != nil {
+		return err
+	}
+	reply.Primaries = assignment.Primaries
+	reply.Backups = assignment.Backups
+	reply.BackupEligibleAtMs = assignment.BackupEligibleAtMs
+	return nil
+}
+
+type ProverBondArgs struct {
+	Address codec.Address `json:"address"`
+}
+
+type ProverBondReply struct {
+	Bonded        uint64 `json:"bonded"`
+	Unbonding     uint64 `json:"unbonding"`
+	UnbondAtMs    int64  `json:"unbond_at_ms"`
+	Misses        uint32 `json:"misses"`
+	JailedUntilMs int64  `json:"jailed_until_ms"`
+}
+
+func (j *JSONRPCServer) ProverBond(req *http.Request, args *ProverBondArgs, reply *ProverBondReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.ProverBond")
+	defer span.End()
+
+	bond, err := storage.GetProverBondFromState(ctx, j.vm.ReadState, args.Address)
+	if err != nil {
+		return err
+	}
+	jails, err := storage.GetProverJailsFromState(ctx, j.vm.ReadState)
+	if err != nil {
+		return err
+	}
+	reply.Bonded = bond.Bonded
+	reply.Unbonding = bond.Unbonding
+	reply.UnbondAtMs = bond.UnbondAtMs
+	reply.Misses = bond.Misses
+	for _, jail := range jails {
+		if jail.Prover == args.Address {
+			reply.JailedUntilMs = jail.UntilMs
+		}
+	}
+	return nil
+}
+
+type BatchResultArgs struct {
+	MarketID ids.ID `json:"market_id"`
+	WindowID uint64 `json:"window_id"`
+}
+
+type BatchResultReply struct {
+	WindowID    uint64 `json:"window_id"`
+	ClearPrice  uint64 `json:"clear_price"`
+	TotalVolume uint64 `json:"total_volume"`
+	Fills       uint32 `json:"fills"`
+	ClearedAtMs int64  `json:"cleared_at_ms"`
+	FillsHash   []byte `json:"fills_hash"`
+}
+
+func newBatchResultReply(windowID uint64, result storage.BatchResult) BatchResultReply {
+	return BatchResultReply{
+		WindowID:    windowID,
+		ClearPrice:  result.ClearPrice,
+		TotalVolume: result.TotalVolume,
+		Fills:       result.Fills,
+		ClearedAtMs: result.ClearedAtMs,
+		FillsHash:   result.FillsHash,
+	}
+}
+
+func (j *JSONRPCServer) BatchResult(req *http.Request, args *BatchResultArgs, reply *BatchResultReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.BatchResult")
+	defer span.End()
+
+	result, err := storage.GetBatchResultFromState(ctx, j.vm.ReadState, args.MarketID, args.WindowID)
+	if err != nil {
+		return err
+	}
+	*reply = newBatchResultReply(args.WindowID, result)
+	return nil
+}
+
+type ClearedWindowsArgs struct {
+	MarketID ids.ID `json:"market_id"`
+	Limit    int    `json:"limit"`
+}
+
+type ClearedWindowsReply struct {
+	Windows []BatchResultReply `json:"windows"`
+}
+
+// ClearedWindows returns up to Limit of the market's most recent cleared
+// windows, newest first. A non-positive Limit returns every indexed window.
+func (j *JSONRPCServer) ClearedWindows(req *http.Request, args *ClearedWindowsArgs, reply *ClearedWindowsReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.ClearedWindows")
+	defer span.End()
+
+	windows, err := storage.GetClearedWindowsFromState(ctx, j.vm.ReadState, args.MarketID)
+	if err != nil {
+		return err
+	}
+	limit := len(windows)
+	if args.Limit > 0 && args.Limit < limit {
+		limit = args.Limit
+	}
+	reply.Windows = make([]BatchResultReply, 0, limit)
+	for i := len(windows) - 1; i >= len(windows)-limit; i-- {
+		result, err := storage.GetBatchResultFromState(ctx, j.vm.ReadState, args.MarketID, windows[i])
+		if err != nil {
+			return err
+		}
+		reply.Windows = append(reply.Windows, newBatchResultReply(windows[i], result))
+	}
+	return nil
+}
+
+type RevealSharesArgs struct {
+	MarketID ids.ID `json:"market_id"`
+	WindowID uint64 `json:"window_id"`
+}
+
+type RevealShareEntry struct {
+	ValidatorIndex uint32 `json:"validator_index"`
+	Share          []byte `json:"share"`
+}
+
+type RevealSharesReply struct {
+	Shares    []RevealShareEntry `json:"shares"`
+	WindowKey []byte             `json:"window_key"`
+}
+
+func (j *JSONRPCServer) RevealShares(req *http.Request, args *RevealSharesArgs, reply *RevealSharesReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.RevealShares")
+	defer span.End()
+
+	shares, err := storage.GetRevealSharesFromState(ctx, j.vm.ReadState, args.MarketID, args.WindowID)
+	if err != nil {
+		return err
+	}
+	tally, err := storage.GetWindowDecryptionFromState(ctx, j.vm.ReadState, args.MarketID, args.WindowID)
+	if err != nil {
+		return err
+	}
+	reply.Shares = make([]RevealShareEntry, 0, len(shares))
+	for _, share := range shares {
+		reply.Shares = append(reply.Shares, RevealShareEntry{
+			ValidatorIndex: share.ValidatorIndex,
+			Share:          share.Share,
+		})
+	}
+	reply.WindowKey = tally.WindowKey
+	return nil
+}
+
+type SettlementArgs struct {
+	MarketID ids.ID        `json:"market_id"`
+	WindowID uint64        `json:"window_id"`
+	Trader   codec.Address `json:"trader"`
+}
+
+type SettlementReply struct {
+	Side        uint8  `json:"side"`
+	Quantity    uint64 `json:"quantity"`
+	Cost        uint64 `json:"cost"`
+	Asset       uint8  `json:"asset"`
+	Refunded    uint64 `json:"refunded"`
+	SettledAtMs int64  `json:"settled_at_ms"`
+}
+
+func (j *JSONRPCServer) Settlement(req *http.Request, args *SettlementArgs, reply *SettlementReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.Settlement")
+	defer span.End()
+
+	settlement, err := storage.GetSettlementFromState(ctx, j.vm.ReadState, args.MarketID, args.WindowID, args.Trader)
+	if err != nil {
+		return err
+	}
+	reply.Side = settlement.Side
+	reply.Quantity = settlement.Quantity
+	reply.Cost = settlement.Cost
+	reply.Asset = settlement.Asset
+	reply.Refunded = settlement.Refunded
+	reply.SettledAtMs = settlement.SettledAtMs
+	return nil
+}
+
+type NullifierArgs struct {
+	Nullifier ids.ID `json:"nullifier"`
+}
+
+type NullifierReply struct {
+	Spent     bool   `json:"spent"`
+	TxID      ids.ID `json:"tx_id"`
+	SpentAtMs int64  `json:"spent_at_ms"`
+}
+
+// Nullifier reports whether a nullifier has been spent, so wallets can detect
+// when their notes are consumed.
+func (j *JSONRPCServer) Nullifier(req *http.Request, args *NullifierArgs, reply *NullifierReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.Nullifier")
+	defer span.End()
+
+	spent, err := storage.GetSpentNullifierFromState(ctx, j.vm.ReadState, args.Nullifier)
+	if errors.Is(err, storage.ErrNullifierNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	reply.Spent = true
+	reply.TxID = spent.TxID
+	reply.SpentAtMs = spent.SpentAtMs
+	return nil
+}
+
+type NoteTreeRootReply struct {
+	Root        ids.ID   `json:"root"`
+	Size        uint64   `json:"size"`
+	RecentRoots []ids.ID `json:"recent_roots"`
+}
+
+// NoteTreeRoot returns the current note-tree root and the recent roots that
+// spend proofs may still be built against.
+func (j *JSONRPCServer) NoteTreeRoot(req *http.Request, _ *struct{}, reply *NoteTreeRootReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.NoteTreeRoot")
+	defer span.End()
+
+	tree, err := storage.GetNoteTreeFromState(ctx, j.vm.ReadState)
+	if err != nil {
+		return err
+	}
+	roots, err := storage.GetNoteRootsFromState(ctx, j.vm.ReadState)
+	if err != nil {
+		return err
+	}
+	reply.Root = tree.Tree.Root.Bytes()
+	reply.Size = tree.Tree.Size
+	reply.RecentRoots = roots
+	return nil
+}
+
+type NoteTreePathArgs struct {
+	Index uint64 `json:"index"`
+}
+
+type NoteTreePathReply struct {
+	Leaf ids.ID   `json:"leaf"`
+	Path []ids.ID `json:"path"`
+	Root ids.ID   `json:"root"`
+}
+
+// NoteTreePath returns the Merkle path of the leaf at Index against the
+// current root, ordered from the leaf level upwards.
+func (j *JSONRPCServer) NoteTreePath(req *http.Request, args *NoteTreePathArgs, reply *NoteTreePathReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.NoteTreePath")
+	defer span.End()
+
+	leaf, path, root, err := storage.GetNoteTreePathFromState(ctx, j.vm.ReadState, args.Index)
+	if err != nil {
+		return err
+	}
+	reply.Leaf = leaf
+	reply.Path = path[:]
+	reply.Root = root
+	return nil
+}
+
+type VerifyingKeyArgs struct {
+	CircuitID string `json:"circuit_id"`
+	ProofType uint8  `json:"proof_type"`
+	Version   uint32 `json:"version"`
+}
+
+type VerifyingKeyReply struct {
+	Version        uint32 `json:"version"`
+	KeyHash        ids.ID `json:"key_hash"`
+	RegisteredAtMs int64  `json:"registered_at_ms"`
+	Key            []byte `json:"key"`
+}
+
+// VerifyingKey returns a governance-registered verifying key. Version zero
+// selects the active key. Operators compare KeyHash with the sha256 of their
+// local key file.
+func (j *JSONRPCServer) VerifyingKey(req *http.Request, args *VerifyingKeyArgs, reply *VerifyingKeyReply) error {
+	ctx, span := j.vm.Tracer().Start(req.Context(), "Server.VerifyingKey")
+	defer span.End()
+
+	vk, err := storage.GetVerifyingKeyFromState(ctx, j.vm.ReadState, strings.TrimSpace(args.CircuitID), args.ProofType, args.Version)
+	if err != nil {
+		return err
+	}
+	reply.Version = vk.Version
+	reply.KeyHash = vk.KeyHash
+	reply.RegisteredAtMs = vk.RegisteredAtMs
+	reply.Key = vk.Key
+	return nil
+}
+
 type ClearInputsHashArgs struct {
 	MarketID    ids.ID `json:"market_id"`
 	WindowID    uint64 `json:"window_id"`
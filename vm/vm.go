@@ -54,6 +54,20 @@ func init() {
 		ActionParser.Register(&actions.SetRiskParams{}, actions.UnmarshalSetRiskParams),
 		ActionParser.Register(&actions.SubmitBatchProof{}, actions.UnmarshalSubmitBatchProof),
 		ActionParser.Register(&actions.SetProofConfig{}, actions.UnmarshalSetProofConfig),
+		ActionParser.Register(&actions.ReleaseEscrow{}, actions.UnmarshalReleaseEscrow),
+		ActionParser.Register(&actions.RedeemWinnings{}, actions.UnmarshalRedeemWinnings),
+		ActionParser.Register(&actions.SettleDispute{}, actions.UnmarshalSettleDispute),
+		ActionParser.Register(&actions.SetDecryptCommittee{}, actions.UnmarshalSetDecryptCommittee),
+		ActionParser.Register(&actions.CancelCommitment{}, actions.UnmarshalCancelCommitment),
+		ActionParser.Register(&actions.Shield{}, actions.UnmarshalShield),
+		ActionParser.Register(&actions.Unshield{}, actions.UnmarshalUnshield),
+		ActionParser.Register(&actions.RegisterVerifyingKey{}, actions.UnmarshalRegisterVerifyingKey),
+		ActionParser.Register(&actions.ScheduleProofUpgrade{}, actions.UnmarshalScheduleProofUpgrade),
+		ActionParser.Register(&actions.SubmitAggregateProof{}, actions.UnmarshalSubmitAggregateProof),
+		ActionParser.Register(&actions.SetProverCommittee{}, actions.UnmarshalSetProverCommittee),
+		ActionParser.Register(&actions.ReportMissedProof{}, actions.UnmarshalReportMissedProof),
+		ActionParser.Register(&actions.BondProver{}, actions.UnmarshalBondProver),
+		ActionParser.Register(&actions.UnbondProver{}, actions.UnmarshalUnbondProver),
 
 		AuthParser.Register(&auth.ED25519{}, auth.UnmarshalED25519),
 		AuthParser.Register(&auth.SECP256R1{}, auth.UnmarshalSECP256R1),
@@ -78,6 +92,20 @@ func init() {
 		OutputParser.Register(&actions.SetRiskParamsResult{}, actions.UnmarshalSetRiskParamsResult),
 		OutputParser.Register(&actions.SubmitBatchProofResult{}, actions.UnmarshalSubmitBatchProofResult),
 		OutputParser.Register(&actions.SetProofConfigResult{}, actions.UnmarshalSetProofConfigResult),
+		OutputParser.Register(&actions.ReleaseEscrowResult{}, actions.UnmarshalReleaseEscrowResult),
+		OutputParser.Register(&actions.RedeemWinningsResult{}, actions.UnmarshalRedeemWinningsResult),
+		OutputParser.Register(&actions.SettleDisputeResult{}, actions.UnmarshalSettleDisputeResult),
+		OutputParser.Register(&actions.SetDecryptCommitteeResult{}, actions.UnmarshalSetDecryptCommitteeResult),
+		OutputParser.Register(&actions.CancelCommitmentResult{}, actions.UnmarshalCancelCommitmentResult),
+		OutputParser.Register(&actions.ShieldResult{}, actions.UnmarshalShieldResult),
+		OutputParser.Register(&actions.UnshieldResult{}, actions.UnmarshalUnshieldResult),
+		OutputParser.Register(&actions.RegisterVerifyingKeyResult{}, actions.UnmarshalRegisterVerifyingKeyResult),
+		OutputParser.Register(&actions.ScheduleProofUpgradeResult{}, actions.UnmarshalScheduleProofUpgradeResult),
+		OutputParser.Register(&actions.SubmitAggregateProofResult{}, actions.UnmarshalSubmitAggregateProofResult),
+		OutputParser.Register(&actions.SetProverCommitteeResult{}, actions.UnmarshalSetProverCommitteeResult),
+		OutputParser.Register(&actions.ReportMissedProofResult{}, actions.UnmarshalReportMissedProofResult),
+		OutputParser.Register(&actions.BondProverResult{}, actions.UnmarshalBondProverResult),
+		OutputParser.Register(&actions.UnbondProverResult{}, actions.UnmarshalUnbondProverResult),
 	); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,95 @@
+package zk
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+const AggregateV1MaxWindows = actions.AggregateV1MaxWindows
+
+// AggregateCircuitV1 proves the clearhash-mimc-v1 statement for up to
+// AggregateV1MaxWindows windows at once: for each of the first Count slots
+// the prover knows clear inputs whose MiMC digest is that window's public
+// inputs hash. Digest is actions.ComputeAggregatePublicInputsHash over those
+// hashes and is the only public input, so one pairing check covers every
+// window.
+//
+// This batches statements into one circuit; it does not verify existing
+// per-window proofs recursively.
+type AggregateCircuitV1 struct {
+	Digest  frontend.Variable `gnark:",public"`
+	Count   frontend.Variable
+	Windows [AggregateV1MaxWindows][actions.ClearMiMCPreimageElements - 1]frontend.Variable
+}
+
+func (c *AggregateCircuitV1) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	// active[i] is 1 for i < Count. Count must be one of 1..AggregateV1MaxWindows.
+	api.AssertIsDifferent(c.Count, 0)
+	var countMatches frontend.Variable = 0
+	active := frontend.Variable(1)
+	hashes := make([]frontend.Variable, AggregateV1MaxWindows)
+	for i := 0; i < AggregateV1MaxWindows; i++ {
+		if i > 0 {
+			atCount := api.IsZero(api.Sub(c.Count, i))
+			countMatches = api.Add(countMatches, atCount)
+			active = api.Mul(active, api.Sub(1, atCount))
+		}
+		h.Reset()
+		h.Write(actions.ClearMiMCTag())
+		h.Write(c.Windows[i][:]...)
+		hashes[i] = api.Mul(active, h.Sum())
+	}
+	atMax := api.IsZero(api.Sub(c.Count, AggregateV1MaxWindows))
+	api.AssertIsEqual(api.Add(countMatches, atMax), 1)
+
+	h.Reset()
+	h.Write(actions.AggregateTag(), c.Count)
+	h.Write(hashes...)
+	api.AssertIsEqual(c.Digest, h.Sum())
+	return nil
+}
+
+// NewAggregateV1Assignment builds an assignment from the concatenated
+// clearhash-mimc-v1 preimages of the covered windows, in order.
+func NewAggregateV1Assignment(preimages []byte, digest []byte) (*AggregateCircuitV1, error) {
+	if len(preimages) == 0 || len(preimages)%ClearHashMiMCPreimageLen != 0 {
+		return nil, fmt.Errorf("invalid aggregate preimage size: %d", len(preimages))
+	}
+	count := len(preimages) / ClearHashMiMCPreimageLen
+	if count > AggregateV1MaxWindows {
+		return nil, fmt.Errorf("too many aggregate windows: got=%d max=%d", count, AggregateV1MaxWindows)
+	}
+	var sum fr.Element
+	if err := sum.SetBytesCanonical(digest); err != nil {
+		return nil, fmt.Errorf("aggregate digest: %w", err)
+	}
+
+	out := &AggregateCircuitV1{
+		Digest: sum.BigInt(new(big.Int)),
+		Count:  count,
+	}
+	for i := range out.Windows {
+		for j := range out.Windows[i] {
+			out.Windows[i][j] = 0
+		}
+		if i >= count {
+			continue
+		}
+		inputs, err := clearHashMiMCInputs(preimages[i*ClearHashMiMCPreimageLen : (i+1)*ClearHashMiMCPreimageLen])
+		if err != nil {
+			return nil, fmt.Errorf("aggregate window %d: %w", i, err)
+		}
+		out.Windows[i] = inputs
+	}
+	return out, nil
+}
@@ -0,0 +1,126 @@
+package zk
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/test"
+)
+
+func TestVerifyGroth16AggregateRoundTrip(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &AggregateCircuitV1{})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	preimages, hashes := aggregateWindows(t, 3)
+	digest, err := actions.ComputeAggregatePublicInputsHash(hashes)
+	if err != nil {
+		t.Fatalf("aggregate digest: %v", err)
+	}
+	assignment, err := NewAggregateV1Assignment(preimages, digest[:])
+	if err != nil {
+		t.Fatalf("assignment: %v", err)
+	}
+	fullWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("new witness: %v", err)
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		t.Fatalf("public witness: %v", err)
+	}
+	publicWitnessBytes, err := publicWitness.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal public witness: %v", err)
+	}
+	proofAny, err := groth16.Prove(ccs, pk, fullWitness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+	proof, ok := proofAny.(*groth16bn254.Proof)
+	if !ok {
+		t.Fatalf("unexpected proof type %T", proofAny)
+	}
+	var proofBuf bytes.Buffer
+	if _, err := proof.WriteTo(&proofBuf); err != nil {
+		t.Fatalf("serialize proof: %v", err)
+	}
+	vkBN, ok := vk.(*groth16bn254.VerifyingKey)
+	if !ok {
+		t.Fatalf("unexpected verifying key type %T", vk)
+	}
+	if err := verifyGroth16(vkBN, mconsts.ProofCircuitAggregateV1, proofBuf.Bytes(), digest[:], publicWitnessBytes); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// Dropping a window changes the aggregate digest.
+	fewer, err := actions.ComputeAggregatePublicInputsHash(hashes[:2])
+	if err != nil {
+		t.Fatalf("aggregate digest: %v", err)
+	}
+	if _, err := buildPublicWitnessVector(mconsts.ProofCircuitAggregateV1, fewer[:], publicWitnessBytes); !errors.Is(err, storage.ErrProofPublicInputsMismatch) {
+		t.Fatalf("expected public inputs mismatch, got %v", err)
+	}
+}
+
+func TestAggregateCircuitV1Constraints(t *testing.T) {
+	field := ecc.BN254.ScalarField()
+
+	preimages, hashes := aggregateWindows(t, AggregateV1MaxWindows)
+	digest, err := actions.ComputeAggregatePublicInputsHash(hashes)
+	if err != nil {
+		t.Fatalf("aggregate digest: %v", err)
+	}
+	full, err := NewAggregateV1Assignment(preimages, digest[:])
+	if err != nil {
+		t.Fatalf("assignment: %v", err)
+	}
+	if err := test.IsSolved(&AggregateCircuitV1{}, full, field); err != nil {
+		t.Fatalf("full aggregate not solved: %v", err)
+	}
+
+	// A count past the capacity cannot be satisfied.
+	full.Count = AggregateV1MaxWindows + 1
+	if err := test.IsSolved(&AggregateCircuitV1{}, full, field); err == nil {
+		t.Fatalf("expected count range failure")
+	}
+}
+
+// aggregateWindows returns the concatenated clearhash-mimc-v1 preimages and
+// public inputs hashes of n sample windows.
+func aggregateWindows(t *testing.T, n int) ([]byte, [][]byte) {
+	t.Helper()
+	var preimages []byte
+	hashes := make([][]byte, n)
+	fillsHash := bytes.Repeat([]byte{0xAB}, actions.ExpectedFillsHashSize)
+	for i := 0; i < n; i++ {
+		var marketID ids.ID
+		marketID[0] = byte(i + 1)
+		preimage, err := actions.BuildClearMiMCPublicInputsPreimage(marketID, uint64(10+i), 4_000, uint64(100*i), fillsHash)
+		if err != nil {
+			t.Fatalf("preimage: %v", err)
+		}
+		hash, err := actions.ComputeClearMiMCPublicInputsHash(marketID, uint64(10+i), 4_000, uint64(100*i), fillsHash)
+		if err != nil {
+			t.Fatalf("hash: %v", err)
+		}
+		preimages = append(preimages, preimage...)
+		hashes[i] = hash[:]
+	}
+	return preimages, hashes
+}
@@ -0,0 +1,80 @@
+package zk
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+const (
+	ClearHashMiMCPreimageLen = actions.ClearMiMCPreimageElements * fr.Bytes
+	ClearHashMiMCDigestLen   = fr.Bytes
+)
+
+// ClearHashMiMCCircuit proves that Digest == MiMC(tag, Inputs...), the
+// clearhash-mimc-v1 binding of actions.ComputeClearMiMCPublicInputsHash.
+// It binds the same clear inputs as ClearHashCircuit for a few thousand
+// constraints instead of an in-circuit SHA-256.
+//
+// Inputs are the preimage elements after the domain tag and are private.
+// Digest is the only public input.
+type ClearHashMiMCCircuit struct {
+	Inputs [actions.ClearMiMCPreimageElements - 1]frontend.Variable
+	Digest frontend.Variable `gnark:",public"`
+}
+
+func (c *ClearHashMiMCCircuit) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	h.Write(actions.ClearMiMCTag())
+	h.Write(c.Inputs[:]...)
+	api.AssertIsEqual(c.Digest, h.Sum())
+	return nil
+}
+
+func NewClearHashMiMCAssignment(preimage []byte, digest []byte) (*ClearHashMiMCCircuit, error) {
+	if len(digest) != ClearHashMiMCDigestLen {
+		return nil, fmt.Errorf("invalid clear-hash-mimc digest size: got=%d expected=%d", len(digest), ClearHashMiMCDigestLen)
+	}
+	inputs, err := clearHashMiMCInputs(preimage)
+	if err != nil {
+		return nil, err
+	}
+	var sum fr.Element
+	if err := sum.SetBytesCanonical(digest); err != nil {
+		return nil, fmt.Errorf("clear-hash-mimc digest: %w", err)
+	}
+	return &ClearHashMiMCCircuit{Inputs: inputs, Digest: sum.BigInt(new(big.Int))}, nil
+}
+
+// clearHashMiMCInputs checks the domain tag of a clearhash-mimc-v1 preimage
+// and returns the elements after it.
+func clearHashMiMCInputs(preimage []byte) ([actions.ClearMiMCPreimageElements - 1]frontend.Variable, error) {
+	var inputs [actions.ClearMiMCPreimageElements - 1]frontend.Variable
+	if len(preimage) != ClearHashMiMCPreimageLen {
+		return inputs, fmt.Errorf("invalid clear-hash-mimc preimage size: got=%d expected=%d", len(preimage), ClearHashMiMCPreimageLen)
+	}
+	var tag, want fr.Element
+	if err := tag.SetBytesCanonical(preimage[:fr.Bytes]); err != nil {
+		return inputs, fmt.Errorf("clear-hash-mimc domain tag: %w", err)
+	}
+	want.SetBigInt(actions.ClearMiMCTag())
+	if !tag.Equal(&want) {
+		return inputs, fmt.Errorf("clear-hash-mimc domain tag mismatch")
+	}
+	for i := range inputs {
+		var elem fr.Element
+		offset := (i + 1) * fr.Bytes
+		if err := elem.SetBytesCanonical(preimage[offset : offset+fr.Bytes]); err != nil {
+			return inputs, fmt.Errorf("clear-hash-mimc input %d: %w", i, err)
+		}
+		inputs[i] = elem.BigInt(new(big.Int))
+	}
+	return inputs, nil
+}
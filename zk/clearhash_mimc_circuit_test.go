@@ -0,0 +1,111 @@
+package zk
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+func TestVerifyGroth16ClearHashMiMCRoundTrip(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &ClearHashMiMCCircuit{})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if n := ccs.GetNbConstraints(); n > 5_000 {
+		t.Fatalf("clear-hash-mimc circuit has %d constraints", n)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	var marketID ids.ID
+	for i := range marketID {
+		marketID[i] = byte(0xFF - i)
+	}
+	fillsHash := bytes.Repeat([]byte{0xEE}, actions.ExpectedFillsHashSize)
+	preimage, err := actions.BuildClearMiMCPublicInputsPreimage(marketID, 9, 5_000, 1_200, fillsHash)
+	if err != nil {
+		t.Fatalf("preimage: %v", err)
+	}
+	digest, err := actions.ComputeClearMiMCPublicInputsHash(marketID, 9, 5_000, 1_200, fillsHash)
+	if err != nil {
+		t.Fatalf("digest: %v", err)
+	}
+	assignment, err := NewClearHashMiMCAssignment(preimage, digest[:])
+	if err != nil {
+		t.Fatalf("assignment: %v", err)
+	}
+
+	fullWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("new witness: %v", err)
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		t.Fatalf("public witness: %v", err)
+	}
+	publicWitnessBytes, err := publicWitness.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal public witness: %v", err)
+	}
+	proofAny, err := groth16.Prove(ccs, pk, fullWitness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+	proof, ok := proofAny.(*groth16bn254.Proof)
+	if !ok {
+		t.Fatalf("unexpected proof type %T", proofAny)
+	}
+	var proofBuf bytes.Buffer
+	if _, err := proof.WriteTo(&proofBuf); err != nil {
+		t.Fatalf("serialize proof: %v", err)
+	}
+	vkBN, ok := vk.(*groth16bn254.VerifyingKey)
+	if !ok {
+		t.Fatalf("unexpected verifying key type %T", vk)
+	}
+	if err := verifyGroth16(vkBN, mconsts.ProofCircuitClearHashMiMCV1, proofBuf.Bytes(), digest[:], publicWitnessBytes); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// A digest over different clear inputs does not match the proof.
+	other, err := actions.ComputeClearMiMCPublicInputsHash(marketID, 9, 5_001, 1_200, fillsHash)
+	if err != nil {
+		t.Fatalf("digest: %v", err)
+	}
+	if _, err := buildPublicWitnessVector(mconsts.ProofCircuitClearHashMiMCV1, other[:], publicWitnessBytes); !errors.Is(err, storage.ErrProofPublicInputsMismatch) {
+		t.Fatalf("expected public inputs mismatch, got %v", err)
+	}
+
+	// The proof does not hold for a consistent witness of other inputs.
+	otherPreimage, err := actions.BuildClearMiMCPublicInputsPreimage(marketID, 9, 5_001, 1_200, fillsHash)
+	if err != nil {
+		t.Fatalf("preimage: %v", err)
+	}
+	otherAssignment, err := NewClearHashMiMCAssignment(otherPreimage, other[:])
+	if err != nil {
+		t.Fatalf("assignment: %v", err)
+	}
+	otherWitness, err := frontend.NewWitness(otherAssignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatalf("new witness: %v", err)
+	}
+	otherWitnessBytes, err := otherWitness.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal public witness: %v", err)
+	}
+	if err := verifyGroth16(vkBN, mconsts.ProofCircuitClearHashMiMCV1, proofBuf.Bytes(), other[:], otherWitnessBytes); !errors.Is(err, storage.ErrProofPairingFailed) {
+		t.Fatalf("expected pairing failure, got %v", err)
+	}
+}
@@ -0,0 +1,422 @@
+package zk
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+	gnarksha2 "github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/math/uints"
+	sha2perm "github.com/consensys/gnark/std/permutation/sha2"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
+)
+
+const (
+	ClearingV1MaxOrders   = actions.ClearingV1MaxOrders
+	ClearingV1PreimageLen = len(actions.ClearingV1InputsDomainTag) + ids.IDLen + 8 + 8 + 8 + 2 + actions.ExpectedFillsHashSize + ids.IDLen
+
+	// clearingPriceBits covers every limit price below OutcomePriceScale;
+	// clearingVolumeBits covers a sum of ClearingV1MaxOrders quantities.
+	clearingPriceBits  = 14
+	clearingVolumeBits = 72
+
+	clearingFillLen        = codec.AddressLen + 1 + 8
+	clearingFillsHeaderLen = len(actions.FillsDomainTag) + ids.IDLen + 8 + 8 + 4
+)
+
+var sha256IV = [8]uint32{
+	0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A, 0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19,
+}
+
+// ClearingOrderWitness is one revealed order. Unused slots are all zero.
+type ClearingOrderWitness struct {
+	Trader     [codec.AddressLen]frontend.Variable
+	Side       frontend.Variable
+	LimitPrice frontend.Variable
+	Quantity   frontend.Variable
+}
+
+// ClearingCircuitV1 proves a window's uniform-price clearing from its
+// revealed orders, following actions.ComputeUniformClearing:
+//
+//   - ClearPrice is an order's limit price, and no limit price matches more
+//     volume, or the same volume with a smaller imbalance, or the same
+//     imbalance at a lower price; with no matched volume the price is zero;
+//   - TotalVolume is the volume matched at ClearPrice;
+//   - each fill is allocated by price priority then trader order, so no
+//     order fills beyond its limit or quantity;
+//   - the fills hash is actions.ComputeBatchFillsHash over those fills.
+//
+// Orders are private and must be the window's eligible orders in canonical
+// trader order. Digest is the only public input:
+// actions.ComputeClearingV1PublicInputsHash, which also commits to the
+// orders through actions.ComputeClearingOrdersDigest.
+type ClearingCircuitV1 struct {
+	Digest [ClearHashDigestLen]uints.U8 `gnark:",public"`
+
+	MarketID    [ids.IDLen]frontend.Variable
+	WindowID    frontend.Variable
+	ClearPrice  frontend.Variable
+	TotalVolume frontend.Variable
+	Orders      [ClearingV1MaxOrders]ClearingOrderWitness
+}
+
+func (c *ClearingCircuitV1) Define(api frontend.API) error {
+	uapi, err := uints.New[uints.U32](api)
+	if err != nil {
+		return err
+	}
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	marketID := make([]uints.U8, ids.IDLen)
+	for i := range c.MarketID {
+		marketID[i] = uapi.ByteValueOf(c.MarketID[i])
+	}
+	windowID := bigEndianBytes(api, uapi, c.WindowID, 8)
+	clearPrice := bigEndianBytes(api, uapi, c.ClearPrice, 8)
+	totalVolume := bigEndianBytes(api, uapi, c.TotalVolume, 8)
+
+	traders := make([][]uints.U8, ClearingV1MaxOrders)
+	elems := []frontend.Variable{actions.ClearingOrdersTag()}
+	for i := range c.Orders {
+		order := &c.Orders[i]
+		api.AssertIsBoolean(order.Side)
+		api.ToBinary(order.LimitPrice, clearingPriceBits)
+		api.ToBinary(order.Quantity, 64)
+
+		traders[i] = make([]uints.U8, codec.AddressLen)
+		for j := range order.Trader {
+			traders[i][j] = uapi.ByteValueOf(order.Trader[j])
+		}
+		elems = append(elems,
+			packBytes(api, order.Trader[:actions.ClearingTraderHighBytes]),
+			packBytes(api, order.Trader[actions.ClearingTraderHighBytes:]),
+			order.Side,
+			order.LimitPrice,
+			order.Quantity,
+		)
+	}
+	ordersDigest := ShieldedHash(&h, elems...)
+
+	fills := c.clearingFills(api)
+	fillsHash := c.fillsHash(api, uapi, marketID, windowID, clearPrice, traders, fills)
+
+	preimage := make([]uints.U8, 0, ClearingV1PreimageLen)
+	preimage = append(preimage, uints.NewU8Array([]byte(actions.ClearingV1InputsDomainTag))...)
+	preimage = append(preimage, marketID...)
+	preimage = append(preimage, windowID...)
+	preimage = append(preimage, clearPrice...)
+	preimage = append(preimage, totalVolume...)
+	preimage = append(preimage, uints.NewU8Array([]byte{0, actions.ExpectedFillsHashSize})...)
+	preimage = append(preimage, fillsHash...)
+	preimage = append(preimage, bigEndianBytes(api, uapi, ordersDigest, ids.IDLen)...)
+
+	sha, err := gnarksha2.New(api)
+	if err != nil {
+		return err
+	}
+	sha.Write(preimage)
+	sum := sha.Sum()
+	for i := range c.Digest {
+		uapi.ByteAssertEq(c.Digest[i], sum[i])
+	}
+	return nil
+}
+
+// clearingFills checks ClearPrice and TotalVolume against the clearing rules
+// and returns each order's fill at ClearPrice.
+func (c *ClearingCircuitV1) clearingFills(api frontend.API) [ClearingV1MaxOrders]frontend.Variable {
+	price, volume := c.ClearPrice, c.TotalVolume
+
+	matchedAt, imbalanceAt := c.crossing(api, price)
+	api.AssertIsEqual(matchedAt, volume)
+	hasVolume := api.Sub(1, api.IsZero(volume))
+	api.AssertIsEqual(api.Mul(api.Sub(1, hasVolume), price), 0)
+
+	atCandidate := frontend.Variable(0)
+	for i := range c.Orders {
+		order := &c.Orders[i]
+		active := api.Sub(1, api.IsZero(order.Quantity))
+		atCandidate = api.Add(atCandidate, api.Mul(active, api.IsZero(api.Sub(price, order.LimitPrice))))
+
+		matched, imbalance := c.crossing(api, order.LimitPrice)
+		api.AssertIsEqual(api.Mul(active, api.Sub(1, lessOrEqual(api, matched, volume, clearingVolumeBits))), 0)
+
+		smallerImbalance := api.Sub(1, lessOrEqual(api, imbalance, imbalanceAt, clearingVolumeBits))
+		sameImbalance := api.IsZero(api.Sub(imbalance, imbalanceAt))
+		notLower := lessOrEqual(api, price, order.LimitPrice, clearingPriceBits)
+		beaten := api.Add(smallerImbalance, api.Mul(sameImbalance, notLower))
+		sameVolume := api.IsZero(api.Sub(matched, volume))
+		api.AssertIsEqual(api.Mul(active, hasVolume, sameVolume, api.Sub(1, beaten)), 0)
+	}
+	api.AssertIsEqual(api.Mul(hasVolume, api.IsZero(atCandidate)), 0)
+
+	// le[i][k] is 1 when order i's limit is at most order k's.
+	var le [ClearingV1MaxOrders][ClearingV1MaxOrders]frontend.Variable
+	for i := range c.Orders {
+		for k := range c.Orders {
+			if i != k {
+				le[i][k] = lessOrEqual(api, c.Orders[i].LimitPrice, c.Orders[k].LimitPrice, clearingPriceBits)
+			}
+		}
+	}
+	var eligible [ClearingV1MaxOrders]frontend.Variable
+	for i := range c.Orders {
+		order := &c.Orders[i]
+		eligible[i] = api.Select(
+			order.Side,
+			lessOrEqual(api, order.LimitPrice, price, clearingPriceBits),
+			lessOrEqual(api, price, order.LimitPrice, clearingPriceBits),
+		)
+	}
+
+	var fills [ClearingV1MaxOrders]frontend.Variable
+	for i := range c.Orders {
+		order := &c.Orders[i]
+		ahead := frontend.Variable(0)
+		for k := range c.Orders {
+			if k == i {
+				continue
+			}
+			other := &c.Orders[k]
+			sameSide := api.IsZero(api.Sub(order.Side, other.Side))
+			// A buy ahead has a higher limit, a sell ahead a lower one.
+			better := api.Select(order.Side, api.Sub(1, le[i][k]), api.Sub(1, le[k][i]))
+			if k < i {
+				samePrice := api.Sub(api.Add(le[i][k], le[k][i]), 1)
+				better = api.Add(better, samePrice)
+			}
+			ahead = api.Add(ahead, api.Mul(sameSide, eligible[k], better, other.Quantity))
+		}
+		exhausted := lessOrEqual(api, volume, ahead, clearingVolumeBits)
+		remaining := api.Select(exhausted, 0, api.Sub(volume, ahead))
+		fill := api.Select(lessOrEqual(api, order.Quantity, remaining, clearingVolumeBits), order.Quantity, remaining)
+		fills[i] = api.Mul(eligible[i], fill)
+	}
+	return fills
+}
+
+// crossing returns the volume matched at price and the imbalance between
+// demand and supply there.
+func (c *ClearingCircuitV1) crossing(api frontend.API, price frontend.Variable) (frontend.Variable, frontend.Variable) {
+	demand, supply := frontend.Variable(0), frontend.Variable(0)
+	for i := range c.Orders {
+		order := &c.Orders[i]
+		buys := api.Mul(api.Sub(1, order.Side), lessOrEqual(api, price, order.LimitPrice, clearingPriceBits))
+		sells := api.Mul(order.Side, lessOrEqual(api, order.LimitPrice, price, clearingPriceBits))
+		demand = api.Add(demand, api.Mul(buys, order.Quantity))
+		supply = api.Add(supply, api.Mul(sells, order.Quantity))
+	}
+	short := lessOrEqual(api, demand, supply, clearingVolumeBits)
+	matched := api.Select(short, demand, supply)
+	imbalance := api.Select(short, api.Sub(supply, demand), api.Sub(demand, supply))
+	return matched, imbalance
+}
+
+// fillsHash computes actions.ComputeBatchFillsHash. Non-zero fills are
+// compacted in trader order, and the SHA-256 padding is placed after the
+// last one, so the digest is taken after a fill-count dependent block.
+func (c *ClearingCircuitV1) fillsHash(
+	api frontend.API,
+	uapi *uints.BinaryField[uints.U32],
+	marketID []uints.U8,
+	windowID []uints.U8,
+	clearPrice []uints.U8,
+	traders [][]uints.U8,
+	fills [ClearingV1MaxOrders]frontend.Variable,
+) []uints.U8 {
+	var slots [ClearingV1MaxOrders][clearingFillLen]frontend.Variable
+	for s := range slots {
+		for b := range slots[s] {
+			slots[s][b] = 0
+		}
+	}
+	count := frontend.Variable(0)
+	for i := range c.Orders {
+		record := make([]frontend.Variable, 0, clearingFillLen)
+		for _, b := range traders[i] {
+			record = append(record, b.Val)
+		}
+		record = append(record, c.Orders[i].Side)
+		for _, b := range bigEndianBytes(api, uapi, fills[i], 8) {
+			record = append(record, b.Val)
+		}
+
+		filled := api.Sub(1, api.IsZero(fills[i]))
+		// Order i can only land in one of the first i+1 slots.
+		for s := 0; s <= i; s++ {
+			sel := api.Mul(filled, api.IsZero(api.Sub(count, s)))
+			for b := range record {
+				slots[s][b] = api.Add(slots[s][b], api.Mul(sel, record[b]))
+			}
+		}
+		count = api.Add(count, filled)
+	}
+
+	data := make([]frontend.Variable, 0, clearingFillsHeaderLen+ClearingV1MaxOrders*clearingFillLen)
+	for _, b := range []byte(actions.FillsDomainTag) {
+		data = append(data, b)
+	}
+	for _, part := range [][]uints.U8{marketID, windowID, clearPrice, bigEndianBytes(api, uapi, count, 4)} {
+		for _, b := range part {
+			data = append(data, b.Val)
+		}
+	}
+	for s := range slots {
+		data = append(data, slots[s][:]...)
+	}
+
+	isCount := make([]frontend.Variable, ClearingV1MaxOrders+1)
+	blocks := make([]int, ClearingV1MaxOrders+1)
+	for k := range isCount {
+		isCount[k] = api.IsZero(api.Sub(count, k))
+		blocks[k] = sha256Blocks(clearingFillsHeaderLen + k*clearingFillLen)
+	}
+	maxBlocks := blocks[ClearingV1MaxOrders]
+
+	// Bytes past the last fill are zero, so each candidate length only adds
+	// its own padding on top of data.
+	padded := make([]uints.U8, 64*maxBlocks)
+	for j := range padded {
+		v := frontend.Variable(0)
+		if j < len(data) {
+			v = data[j]
+		}
+		for k := range isCount {
+			if p := sha256PadByte(clearingFillsHeaderLen+k*clearingFillLen, j); p != 0 {
+				v = api.Add(v, api.Mul(isCount[k], int(p)))
+			}
+		}
+		padded[j] = uapi.ByteValueOf(v)
+	}
+
+	var state [8]uints.U32
+	for i := range state {
+		state[i] = uints.NewU32(sha256IV[i])
+	}
+	digest := make([]frontend.Variable, 32)
+	for i := range digest {
+		digest[i] = 0
+	}
+	for b := 0; b < maxBlocks; b++ {
+		var block [64]uints.U8
+		copy(block[:], padded[64*b:64*(b+1)])
+		state = sha2perm.Permute(uapi, state, block)
+		for k := range blocks {
+			if blocks[k] != b+1 {
+				continue
+			}
+			for w := range state {
+				for j, byt := range uapi.UnpackMSB(state[w]) {
+					digest[4*w+j] = api.Add(digest[4*w+j], api.Mul(isCount[k], byt.Val))
+				}
+			}
+		}
+	}
+	out := make([]uints.U8, len(digest))
+	for i := range digest {
+		out[i] = uapi.ByteValueOf(digest[i])
+	}
+	return out
+}
+
+// NewClearingV1Assignment builds a full witness. orders must be the window's
+// eligible orders in canonical trader order and clearing their
+// actions.ComputeUniformClearing result; digest is the matching
+// actions.ComputeClearingV1PublicInputsHash.
+func NewClearingV1Assignment(
+	marketID ids.ID,
+	windowID uint64,
+	orders []actions.BatchOrder,
+	clearing actions.ClearingResult,
+	digest []byte,
+) (*ClearingCircuitV1, error) {
+	if len(orders) > ClearingV1MaxOrders {
+		return nil, fmt.Errorf("too many clearing-v1 orders: got=%d max=%d", len(orders), ClearingV1MaxOrders)
+	}
+	if len(digest) != ClearHashDigestLen {
+		return nil, fmt.Errorf("invalid clearing-v1 digest size: got=%d expected=%d", len(digest), ClearHashDigestLen)
+	}
+
+	out := &ClearingCircuitV1{
+		WindowID:    windowID,
+		ClearPrice:  clearing.ClearPrice,
+		TotalVolume: clearing.TotalVolume,
+	}
+	for i, b := range uints.NewU8Array(digest) {
+		out.Digest[i] = b
+	}
+	for i := range out.MarketID {
+		out.MarketID[i] = marketID[i]
+	}
+	for i := range out.Orders {
+		var order actions.BatchOrder
+		if i < len(orders) {
+			order = orders[i]
+		}
+		w := &out.Orders[i]
+		for j := range w.Trader {
+			w.Trader[j] = order.Trader[j]
+		}
+		w.Side = order.Side
+		w.LimitPrice = order.LimitPrice
+		w.Quantity = order.Quantity
+	}
+	return out, nil
+}
+
+// lessOrEqual returns 1 when a <= b, for a and b below 2^nbBits. Operands
+// outside that range leave the circuit unsatisfiable.
+func lessOrEqual(api frontend.API, a, b frontend.Variable, nbBits int) frontend.Variable {
+	offset := new(big.Int).Lsh(big.NewInt(1), uint(nbBits))
+	bits := api.ToBinary(api.Add(api.Sub(b, a), offset), nbBits+1)
+	return bits[nbBits]
+}
+
+// bigEndianBytes decomposes v into n big-endian bytes, which also bounds v
+// to 8n bits.
+func bigEndianBytes(api frontend.API, uapi *uints.BinaryField[uints.U32], v frontend.Variable, n int) []uints.U8 {
+	bits := api.ToBinary(v, 8*n)
+	out := make([]uints.U8, n)
+	for i := range out {
+		lsb := 8 * (n - 1 - i)
+		out[i] = uapi.ByteValueOf(api.FromBinary(bits[lsb : lsb+8]...))
+	}
+	return out
+}
+
+// packBytes reads big-endian bytes as one field element.
+func packBytes(api frontend.API, bytes []frontend.Variable) frontend.Variable {
+	out := frontend.Variable(0)
+	for _, b := range bytes {
+		out = api.Add(api.Mul(out, 256), b)
+	}
+	return out
+}
+
+// sha256Blocks is the number of 64-byte blocks SHA-256 pads n bytes to.
+func sha256Blocks(n int) int {
+	return (n + 9 + 63) / 64
+}
+
+// sha256PadByte is byte j of the SHA-256 padding of an n-byte message, or
+// zero inside the message.
+func sha256PadByte(n int, j int) byte {
+	end := 64 * sha256Blocks(n)
+	switch {
+	case j == n:
+		return 0x80
+	case j >= end-8 && j < end:
+		shift := 8 * uint(end-1-j)
+		return byte(uint64(8*n) >> shift)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,124 @@
+package zk
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+)
+
+func TestClearingCircuitV1Constraints(t *testing.T) {
+	field := ecc.BN254.ScalarField()
+
+	// Limits 4000 and 6000 both match 10 with no imbalance; the tie goes to
+	// the lower price. The buy at 3000 is not eligible there.
+	orders := []actions.BatchOrder{
+		{Trader: clearingTrader(1), Side: actions.OrderSideBuy, LimitPrice: 6_000, Quantity: 10},
+		{Trader: clearingTrader(2), Side: actions.OrderSideSell, LimitPrice: 4_000, Quantity: 10},
+		{Trader: clearingTrader(3), Side: actions.OrderSideBuy, LimitPrice: 3_000, Quantity: 5},
+	}
+	clearing := sampleClearing(t, orders)
+	if clearing.ClearPrice != 4_000 || clearing.TotalVolume != 10 {
+		t.Fatalf("unexpected sample clearing: price=%d volume=%d", clearing.ClearPrice, clearing.TotalVolume)
+	}
+	if err := test.IsSolved(&ClearingCircuitV1{}, clearingAssignment(t, orders, clearing), field); err != nil {
+		t.Fatalf("valid clearing not solved: %v", err)
+	}
+
+	// The same fills at the higher tied price break the tie-break rule.
+	higher := clearing
+	higher.ClearPrice = 6_000
+	higher.FillsHash = actions.ComputeBatchFillsHash(clearingMarketID(), 7, higher.ClearPrice, higher.Fills)
+	if err := test.IsSolved(&ClearingCircuitV1{}, clearingAssignment(t, orders, higher), field); err == nil {
+		t.Fatalf("expected tie-break failure")
+	}
+
+	// Fills that do not follow the allocation rules do not hash to the
+	// circuit's fills hash.
+	skewed := clearing
+	skewed.Fills = append([]actions.BatchFill(nil), clearing.Fills...)
+	skewed.Fills[0].Quantity--
+	skewed.FillsHash = actions.ComputeBatchFillsHash(clearingMarketID(), 7, skewed.ClearPrice, skewed.Fills)
+	if err := test.IsSolved(&ClearingCircuitV1{}, clearingAssignment(t, orders, skewed), field); err == nil {
+		t.Fatalf("expected fills hash failure")
+	}
+}
+
+func TestClearingCircuitV1PartialFills(t *testing.T) {
+	field := ecc.BN254.ScalarField()
+
+	// Buys outnumber sells at the clearing price, so the buy side fills by
+	// price priority and one buy fills partially.
+	orders := []actions.BatchOrder{
+		{Trader: clearingTrader(1), Side: actions.OrderSideBuy, LimitPrice: 5_500, Quantity: 8},
+		{Trader: clearingTrader(2), Side: actions.OrderSideSell, LimitPrice: 4_500, Quantity: 6},
+		{Trader: clearingTrader(3), Side: actions.OrderSideBuy, LimitPrice: 7_000, Quantity: 4},
+		{Trader: clearingTrader(4), Side: actions.OrderSideSell, LimitPrice: 5_000, Quantity: 5},
+		{Trader: clearingTrader(5), Side: actions.OrderSideBuy, LimitPrice: 5_500, Quantity: 3},
+		{Trader: clearingTrader(6), Side: actions.OrderSideSell, LimitPrice: 6_500, Quantity: 9},
+	}
+	clearing := sampleClearing(t, orders)
+	if clearing.TotalVolume == 0 || len(clearing.Fills) < 3 {
+		t.Fatalf("sample should match several orders: %+v", clearing)
+	}
+	if err := test.IsSolved(&ClearingCircuitV1{}, clearingAssignment(t, orders, clearing), field); err != nil {
+		t.Fatalf("valid clearing not solved: %v", err)
+	}
+
+	// No crossing orders clear at zero with no fills.
+	none := []actions.BatchOrder{
+		{Trader: clearingTrader(1), Side: actions.OrderSideBuy, LimitPrice: 3_000, Quantity: 8},
+		{Trader: clearingTrader(2), Side: actions.OrderSideSell, LimitPrice: 4_500, Quantity: 6},
+	}
+	empty := sampleClearing(t, none)
+	if err := test.IsSolved(&ClearingCircuitV1{}, clearingAssignment(t, none, empty), field); err != nil {
+		t.Fatalf("empty clearing not solved: %v", err)
+	}
+}
+
+func clearingMarketID() ids.ID {
+	var marketID ids.ID
+	for i := range marketID {
+		marketID[i] = byte(i + 1)
+	}
+	return marketID
+}
+
+func clearingTrader(n byte) codec.Address {
+	var trader codec.Address
+	trader[0] = n
+	trader[codec.AddressLen-1] = 0xF0 + n
+	return trader
+}
+
+func sampleClearing(t *testing.T, orders []actions.BatchOrder) actions.ClearingResult {
+	t.Helper()
+	clearing, err := actions.ComputeUniformClearing(clearingMarketID(), 7, orders)
+	if err != nil {
+		t.Fatalf("compute clearing: %v", err)
+	}
+	return clearing
+}
+
+func clearingAssignment(t *testing.T, orders []actions.BatchOrder, clearing actions.ClearingResult) *ClearingCircuitV1 {
+	t.Helper()
+	digest, err := actions.ComputeClearingV1PublicInputsHash(
+		clearingMarketID(),
+		7,
+		clearing.ClearPrice,
+		clearing.TotalVolume,
+		clearing.FillsHash[:],
+		orders,
+	)
+	if err != nil {
+		t.Fatalf("public inputs hash: %v", err)
+	}
+	assignment, err := NewClearingV1Assignment(clearingMarketID(), 7, orders, clearing, digest[:])
+	if err != nil {
+		t.Fatalf("assignment: %v", err)
+	}
+	return assignment
+}
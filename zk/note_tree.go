@@ -0,0 +1,47 @@
+package zk
+
+import (
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/shielded"
+)
+
+// NoteTreePath is the in-circuit form of a note-tree Merkle path: sibling
+// nodes from the leaf level upwards and the leaf index as little-endian bits.
+type NoteTreePath struct {
+	Elements [shielded.TreeDepth]frontend.Variable
+	Indices  [shielded.TreeDepth]frontend.Variable
+}
+
+// NewNoteTreePath assigns the path of the leaf at index, as returned by
+// shielded.Path or the notetreepath RPC.
+func NewNoteTreePath(index uint64, path [shielded.TreeDepth]fr.Element) NoteTreePath {
+	var out NoteTreePath
+	for i := 0; i < shielded.TreeDepth; i++ {
+		out.Elements[i] = elementVar(path[i])
+		out.Indices[i] = (index >> uint(i)) & 1
+	}
+	return out
+}
+
+// ShieldedHash mirrors shielded.Hash.
+func ShieldedHash(h *mimc.MiMC, elems ...frontend.Variable) frontend.Variable {
+	h.Reset()
+	h.Write(elems...)
+	return h.Sum()
+}
+
+// NoteTreeRoot mirrors shielded.RootFromPath and returns the root that leaf
+// authenticates to along path.
+func NoteTreeRoot(api frontend.API, h *mimc.MiMC, leaf frontend.Variable, path NoteTreePath) frontend.Variable {
+	node := leaf
+	for i := range path.Elements {
+		api.AssertIsBoolean(path.Indices[i])
+		left := api.Select(path.Indices[i], path.Elements[i], node)
+		right := api.Select(path.Indices[i], node, path.Elements[i])
+		node = ShieldedHash(h, left, right)
+	}
+	return node
+}
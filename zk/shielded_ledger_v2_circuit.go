@@ -0,0 +1,185 @@
+package zk
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+
+	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
+	"github.com/ava-labs/hypersdk/examples/veilvm/shielded"
+)
+
+const (
+	ShieldedLedgerV2Inputs       = actions.ShieldedLedgerV2SpendCount
+	ShieldedLedgerV2Outputs      = actions.ShieldedLedgerV2OutputCount
+	ShieldedLedgerV2PublicInputs = actions.ShieldedLedgerV2PublicInputCount
+
+	shieldedValueBits = 64
+	shieldedAssetBits = 8
+)
+
+// ShieldedSpendWitness is the private material for one spent note.
+type ShieldedSpendWitness struct {
+	Value       frontend.Variable
+	Rho         frontend.Variable
+	Rand        frontend.Variable
+	SpendingKey frontend.Variable
+	Path        NoteTreePath
+}
+
+// ShieldedOutputWitness is the private material for one created note. The
+// recipient hash is enough to commit to the note without knowing its owner.
+type ShieldedOutputWitness struct {
+	Value     frontend.Variable
+	Recipient frontend.Variable
+}
+
+// ShieldedLedgerCircuitV2 proves a shielded transfer for a batch:
+//
+//   - every spent note with a non-zero value is included under NoteRoot;
+//   - every nullifier is derived from its note and the note's spending key;
+//   - every output commitment opens to a note of Asset;
+//   - inputs equal outputs plus Fee, with all values in 64-bit range.
+//
+// Zero-value spends are padding and skip the inclusion check. BatchDigest
+// binds the proof to the cleared batch it is submitted for. Public fields are
+// declared in the witness order that actions.proofShieldedTransfer decodes.
+type ShieldedLedgerCircuitV2 struct {
+	BatchDigest       frontend.Variable                          `gnark:",public"`
+	NoteRoot          frontend.Variable                          `gnark:",public"`
+	Asset             frontend.Variable                          `gnark:",public"`
+	Fee               frontend.Variable                          `gnark:",public"`
+	Nullifiers        [ShieldedLedgerV2Inputs]frontend.Variable  `gnark:",public"`
+	OutputCommitments [ShieldedLedgerV2Outputs]frontend.Variable `gnark:",public"`
+
+	Spends  [ShieldedLedgerV2Inputs]ShieldedSpendWitness
+	Outputs [ShieldedLedgerV2Outputs]ShieldedOutputWitness
+}
+
+func (c *ShieldedLedgerCircuitV2) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsDifferent(c.BatchDigest, 0)
+	api.ToBinary(c.Asset, shieldedAssetBits)
+	api.ToBinary(c.Fee, shieldedValueBits)
+
+	totalIn := frontend.Variable(0)
+	for i := range c.Spends {
+		spend := &c.Spends[i]
+		api.ToBinary(spend.Value, shieldedValueBits)
+
+		owner := ShieldedHash(&h, shielded.OwnerTag(), spend.SpendingKey)
+		recipient := ShieldedHash(&h, shielded.RecipientTag(), owner, spend.Rho, spend.Rand)
+		commitment := ShieldedHash(&h, shielded.NoteTag(), c.Asset, spend.Value, recipient)
+		nullifier := ShieldedHash(&h, shielded.NullifierTag(), spend.SpendingKey, commitment)
+		api.AssertIsEqual(c.Nullifiers[i], nullifier)
+
+		root := NoteTreeRoot(api, &h, commitment, spend.Path)
+		root = api.Select(api.IsZero(spend.Value), c.NoteRoot, root)
+		api.AssertIsEqual(root, c.NoteRoot)
+
+		totalIn = api.Add(totalIn, spend.Value)
+	}
+	for i := 0; i < len(c.Nullifiers); i++ {
+		for j := i + 1; j < len(c.Nullifiers); j++ {
+			api.AssertIsDifferent(c.Nullifiers[i], c.Nullifiers[j])
+		}
+	}
+
+	totalOut := c.Fee
+	for i := range c.Outputs {
+		out := &c.Outputs[i]
+		api.ToBinary(out.Value, shieldedValueBits)
+
+		commitment := ShieldedHash(&h, shielded.NoteTag(), c.Asset, out.Value, out.Recipient)
+		api.AssertIsEqual(c.OutputCommitments[i], commitment)
+
+		totalOut = api.Add(totalOut, out.Value)
+	}
+	api.AssertIsEqual(totalIn, totalOut)
+	return nil
+}
+
+// ShieldedSpend is the native description of a note being spent.
+type ShieldedSpend struct {
+	Note        shielded.Note
+	SpendingKey fr.Element
+	Index       uint64
+	Path        [shielded.TreeDepth]fr.Element
+}
+
+// NewShieldedLedgerV2Assignment builds a full witness. spends and outputs must
+// be padded to ShieldedLedgerV2Inputs and ShieldedLedgerV2Outputs; padding
+// spends carry zero value.
+func NewShieldedLedgerV2Assignment(
+	batchDigest []byte,
+	noteRoot fr.Element,
+	asset uint8,
+	fee uint64,
+	spends []ShieldedSpend,
+	outputs []shielded.Note,
+) (*ShieldedLedgerCircuitV2, error) {
+	if len(spends) != ShieldedLedgerV2Inputs {
+		return nil, fmt.Errorf(
+			"invalid shielded-ledger-v2 spend count: got=%d expected=%d",
+			len(spends),
+			ShieldedLedgerV2Inputs,
+		)
+	}
+	if len(outputs) != ShieldedLedgerV2Outputs {
+		return nil, fmt.Errorf(
+			"invalid shielded-ledger-v2 output count: got=%d expected=%d",
+			len(outputs),
+			ShieldedLedgerV2Outputs,
+		)
+	}
+	digest, err := hashToFieldElement(batchDigest)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ShieldedLedgerCircuitV2{
+		BatchDigest: elementVar(digest),
+		NoteRoot:    elementVar(noteRoot),
+		Asset:       asset,
+		Fee:         fee,
+	}
+	for i, spend := range spends {
+		if spend.Note.Asset != asset {
+			return nil, fmt.Errorf("spend %d asset mismatch: got=%d expected=%d", i, spend.Note.Asset, asset)
+		}
+		if owner := shielded.OwnerKey(spend.SpendingKey); !owner.Equal(&spend.Note.Owner) {
+			return nil, fmt.Errorf("spend %d is not owned by its spending key", i)
+		}
+		commitment := spend.Note.Commitment()
+		out.Nullifiers[i] = elementVar(shielded.Nullifier(spend.SpendingKey, commitment))
+
+		w := &out.Spends[i]
+		w.Value = spend.Note.Value
+		w.Rho = elementVar(spend.Note.Rho)
+		w.Rand = elementVar(spend.Note.Rand)
+		w.SpendingKey = elementVar(spend.SpendingKey)
+		w.Path = NewNoteTreePath(spend.Index, spend.Path)
+	}
+	for i, note := range outputs {
+		if note.Asset != asset {
+			return nil, fmt.Errorf("output %d asset mismatch: got=%d expected=%d", i, note.Asset, asset)
+		}
+		out.OutputCommitments[i] = elementVar(note.Commitment())
+		out.Outputs[i] = ShieldedOutputWitness{
+			Value:     note.Value,
+			Recipient: elementVar(note.Recipient()),
+		}
+	}
+	return out, nil
+}
+
+func elementVar(e fr.Element) *big.Int {
+	return e.BigInt(new(big.Int))
+}
@@ -0,0 +1,175 @@
+package zk
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/examples/veilvm/actions"
+	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
+	"github.com/ava-labs/hypersdk/examples/veilvm/shielded"
+	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/test"
+)
+
+func TestVerifyGroth16ShieldedLedgerV2RoundTrip(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &ShieldedLedgerCircuitV2{})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	digest := sampleShieldedLedgerV2Digest()
+	assignment := buildSampleShieldedLedgerV2Assignment(t, digest[:], 10)
+	fullWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("new witness: %v", err)
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		t.Fatalf("public witness: %v", err)
+	}
+	publicWitnessBytes, err := publicWitness.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal public witness: %v", err)
+	}
+
+	proofAny, err := groth16.Prove(ccs, pk, fullWitness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+	proof, ok := proofAny.(*groth16bn254.Proof)
+	if !ok {
+		t.Fatalf("unexpected proof type %T", proofAny)
+	}
+	var proofBuf bytes.Buffer
+	if _, err := proof.WriteTo(&proofBuf); err != nil {
+		t.Fatalf("serialize proof: %v", err)
+	}
+	vkBN, ok := vk.(*groth16bn254.VerifyingKey)
+	if !ok {
+		t.Fatalf("unexpected verifying key type %T", vk)
+	}
+	if err := verifyGroth16(vkBN, mconsts.ProofCircuitShieldedLedgerV2, proofBuf.Bytes(), digest[:], publicWitnessBytes); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	nullifiers, err := actions.ShieldedLedgerV2Nullifiers(publicWitnessBytes)
+	if err != nil {
+		t.Fatalf("decode nullifiers: %v", err)
+	}
+	for i, nullifier := range nullifiers {
+		want, ok := assignment.Nullifiers[i].(*big.Int)
+		if !ok || new(big.Int).SetBytes(nullifier[:]).Cmp(want) != 0 {
+			t.Fatalf("nullifier %d decoded from the wrong witness slot", i)
+		}
+	}
+
+	badHash := append([]byte(nil), digest[:]...)
+	badHash[0] ^= 0x01
+	if _, err := buildPublicWitnessVector(mconsts.ProofCircuitShieldedLedgerV2, badHash, publicWitnessBytes); err != storage.ErrProofPublicInputsMismatch {
+		t.Fatalf("expected public inputs mismatch, got %v", err)
+	}
+}
+
+func TestShieldedLedgerCircuitV2Constraints(t *testing.T) {
+	digest := sampleShieldedLedgerV2Digest()
+	field := ecc.BN254.ScalarField()
+
+	valid := buildSampleShieldedLedgerV2Assignment(t, digest[:], 10)
+	if err := test.IsSolved(&ShieldedLedgerCircuitV2{}, valid, field); err != nil {
+		t.Fatalf("valid transfer not solved: %v", err)
+	}
+
+	// Claiming a smaller fee breaks value conservation.
+	inflated := buildSampleShieldedLedgerV2Assignment(t, digest[:], 10)
+	inflated.Fee = 9
+	if err := test.IsSolved(&ShieldedLedgerCircuitV2{}, inflated, field); err == nil {
+		t.Fatalf("expected value conservation failure")
+	}
+
+	// A real spend must be included under the public root.
+	wrongRoot := buildSampleShieldedLedgerV2Assignment(t, digest[:], 10)
+	wrongRoot.NoteRoot = big.NewInt(1)
+	if err := test.IsSolved(&ShieldedLedgerCircuitV2{}, wrongRoot, field); err == nil {
+		t.Fatalf("expected merkle inclusion failure")
+	}
+
+	// The nullifier must come from the note's own spending key.
+	wrongNullifier := buildSampleShieldedLedgerV2Assignment(t, digest[:], 10)
+	wrongNullifier.Nullifiers[0] = big.NewInt(7)
+	if err := test.IsSolved(&ShieldedLedgerCircuitV2{}, wrongNullifier, field); err == nil {
+		t.Fatalf("expected nullifier derivation failure")
+	}
+}
+
+func sampleShieldedLedgerV2Digest() [32]byte {
+	var marketID ids.ID
+	for i := range marketID {
+		marketID[i] = byte(i + 1)
+	}
+	fillsHash := make([]byte, actions.ExpectedFillsHashSize)
+	for i := range fillsHash {
+		fillsHash[i] = byte(0xA0 + i)
+	}
+	return actions.ComputeShieldedLedgerV2PublicInputsHash(marketID, 17, 2500, 7200, fillsHash)
+}
+
+// buildSampleShieldedLedgerV2Assignment spends a 1000-unit note at leaf 5 of a
+// tree with one populated sibling into 600 + (400 - fee) + fee.
+func buildSampleShieldedLedgerV2Assignment(t *testing.T, digest []byte, fee uint64) *ShieldedLedgerCircuitV2 {
+	t.Helper()
+
+	element := func(v uint64) fr.Element {
+		var e fr.Element
+		e.SetUint64(v)
+		return e
+	}
+	spendingKey := element(42)
+	owner := shielded.OwnerKey(spendingKey)
+
+	spends := make([]ShieldedSpend, ShieldedLedgerV2Inputs)
+	for i := range spends {
+		spends[i] = ShieldedSpend{
+			Note: shielded.Note{
+				Asset: actions.AssetVEIL,
+				Owner: owner,
+				Rho:   element(uint64(100 + i)),
+				Rand:  element(uint64(200 + i)),
+			},
+			SpendingKey: spendingKey,
+		}
+	}
+	spends[0].Note.Value = 1_000
+	spends[0].Index = 5
+	spends[0].Path[0] = element(99)
+	noteRoot := shielded.RootFromPath(spends[0].Note.Commitment(), spends[0].Index, spends[0].Path[:])
+
+	outputs := make([]shielded.Note, ShieldedLedgerV2Outputs)
+	for i := range outputs {
+		outputs[i] = shielded.Note{
+			Asset: actions.AssetVEIL,
+			Owner: element(uint64(300 + i)),
+			Rho:   element(uint64(400 + i)),
+			Rand:  element(uint64(500 + i)),
+		}
+	}
+	outputs[0].Value = 600
+	outputs[1].Value = 400 - fee
+
+	assignment, err := NewShieldedLedgerV2Assignment(digest, noteRoot, actions.AssetVEIL, fee, spends, outputs)
+	if err != nil {
+		t.Fatalf("assignment: %v", err)
+	}
+	return assignment
+}
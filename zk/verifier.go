@@ -2,10 +2,12 @@ package zk
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"math/big"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	bn254fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
@@ -17,100 +19,173 @@ import (
 	"github.com/ava-labs/hypersdk/examples/veilvm/storage"
 )
 
+// maxCachedVerifyingKeys bounds the parsed registry keys kept per proof type.
+const maxCachedVerifyingKeys = 32
+
+// Config holds node-local verifying keys. They are used only for circuits
+// governance has not registered a key for, and otherwise act as a parse
+// cache for a registered key with the same content hash.
 type Config struct {
 	Groth16VerifyingKeyPath string
 	PlonkVerifyingKeyPath   string
-	RequiredCircuitID       string
 }
 
 type Verifier struct {
-	groth16VK         *groth16bn254.VerifyingKey
-	plonkVK           *plonkbn254.VerifyingKey
-	requiredCircuitID string
+	groth16VK     *groth16bn254.VerifyingKey
+	plonkVK       *plonkbn254.VerifyingKey
+	groth16VKHash [sha256.Size]byte
+	plonkVKHash   [sha256.Size]byte
+
+	mu          sync.Mutex
+	groth16Keys map[[sha256.Size]byte]*groth16bn254.VerifyingKey
+	plonkKeys   map[[sha256.Size]byte]*plonkbn254.VerifyingKey
 }
 
 func NewVerifier(cfg Config) (*Verifier, error) {
-	v := &Verifier{}
+	v := &Verifier{
+		groth16Keys: make(map[[sha256.Size]byte]*groth16bn254.VerifyingKey),
+		plonkKeys:   make(map[[sha256.Size]byte]*plonkbn254.VerifyingKey),
+	}
 
 	if p := strings.TrimSpace(cfg.Groth16VerifyingKeyPath); p != "" {
-		vk, err := loadGroth16VK(p)
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("load groth16 vk: %w", err)
+		}
+		vk, err := parseGroth16VK(raw)
 		if err != nil {
 			return nil, fmt.Errorf("load groth16 vk: %w", err)
 		}
 		v.groth16VK = vk
+		v.groth16VKHash = sha256.Sum256(raw)
+		v.groth16Keys[v.groth16VKHash] = vk
 	}
 	if p := strings.TrimSpace(cfg.PlonkVerifyingKeyPath); p != "" {
-		vk, err := loadPlonkVK(p)
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("load plonk vk: %w", err)
+		}
+		vk, err := parsePlonkVK(raw)
 		if err != nil {
 			return nil, fmt.Errorf("load plonk vk: %w", err)
 		}
 		v.plonkVK = vk
+		v.plonkVKHash = sha256.Sum256(raw)
+		v.plonkKeys[v.plonkVKHash] = vk
 	}
-	if v.groth16VK == nil && v.plonkVK == nil {
-		return nil, storage.ErrProofVerifierUnavailable
-	}
-	v.requiredCircuitID = strings.TrimSpace(cfg.RequiredCircuitID)
-	if v.requiredCircuitID != "" && !isSupportedCircuitID(v.requiredCircuitID) {
-		return nil, fmt.Errorf("%w: %s", storage.ErrUnsupportedProofCircuit, v.requiredCircuitID)
-	}
-
 	return v, nil
 }
 
+// Verify checks a proof against verifyingKey, the key registered on-chain
+// for its circuit and proof type. When no key is registered it falls back to
+// the node-local key for proofType. A well-formed proof the key rejects fails
+// with ErrProofPairingFailed; malformed keys, proofs and witnesses fail with
+// other errors.
 func (v *Verifier) Verify(
 	proofType uint8,
 	circuitID string,
+	verifyingKey []byte,
 	proof []byte,
 	publicInputsHash []byte,
 	publicWitness []byte,
 ) error {
 	circuitID = normalizeCircuitID(circuitID)
-	if v.requiredCircuitID != "" && circuitID != v.requiredCircuitID {
-		return storage.ErrProofCircuitMismatch
-	}
 	if !isSupportedCircuitID(circuitID) {
 		return storage.ErrUnsupportedProofCircuit
 	}
 
 	switch proofType {
 	case mconsts.ProofTypeGroth16:
-		if v.groth16VK == nil {
-			return storage.ErrProofVerifierUnavailable
+		vk, err := v.groth16Key(verifyingKey)
+		if err != nil {
+			return err
 		}
-		return verifyGroth16(v.groth16VK, circuitID, proof, publicInputsHash, publicWitness)
+		return verifyGroth16(vk, circuitID, proof, publicInputsHash, publicWitness)
 	case mconsts.ProofTypePlonk:
-		if v.plonkVK == nil {
-			return storage.ErrProofVerifierUnavailable
+		vk, err := v.plonkKey(verifyingKey)
+		if err != nil {
+			return err
 		}
-		return verifyPlonk(v.plonkVK, circuitID, proof, publicInputsHash, publicWitness)
+		return verifyPlonk(vk, circuitID, proof, publicInputsHash, publicWitness)
 	default:
 		return storage.ErrProofTypeMismatch
 	}
 }
 
-func loadGroth16VK(path string) (*groth16bn254.VerifyingKey, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// LocalKeyHash returns the sha256 of the node-local key file for proofType,
+// so operators can cross-check it against the registered KeyHash.
+func (v *Verifier) LocalKeyHash(proofType uint8) ([sha256.Size]byte, bool) {
+	switch proofType {
+	case mconsts.ProofTypeGroth16:
+		return v.groth16VKHash, v.groth16VK != nil
+	case mconsts.ProofTypePlonk:
+		return v.plonkVKHash, v.plonkVK != nil
+	default:
+		return [sha256.Size]byte{}, false
 	}
-	defer f.Close()
+}
 
-	vk := new(groth16bn254.VerifyingKey)
-	if _, err := vk.ReadFrom(f); err != nil {
-		return nil, err
+func (v *Verifier) groth16Key(raw []byte) (*groth16bn254.VerifyingKey, error) {
+	if len(raw) == 0 {
+		if v.groth16VK == nil {
+			return nil, storage.ErrProofVerifierUnavailable
+		}
+		return v.groth16VK, nil
 	}
+	hash := sha256.Sum256(raw)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if vk, ok := v.groth16Keys[hash]; ok {
+		return vk, nil
+	}
+	vk, err := parseGroth16VK(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", storage.ErrInvalidVerifyingKey, err)
+	}
+	if len(v.groth16Keys) >= maxCachedVerifyingKeys {
+		clear(v.groth16Keys)
+	}
+	v.groth16Keys[hash] = vk
 	return vk, nil
 }
 
-func loadPlonkVK(path string) (*plonkbn254.VerifyingKey, error) {
-	f, err := os.Open(path)
+func (v *Verifier) plonkKey(raw []byte) (*plonkbn254.VerifyingKey, error) {
+	if len(raw) == 0 {
+		if v.plonkVK == nil {
+			return nil, storage.ErrProofVerifierUnavailable
+		}
+		return v.plonkVK, nil
+	}
+	hash := sha256.Sum256(raw)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if vk, ok := v.plonkKeys[hash]; ok {
+		return vk, nil
+	}
+	vk, err := parsePlonkVK(raw)
 	if err != nil {
+		return nil, fmt.Errorf("%w: %v", storage.ErrInvalidVerifyingKey, err)
+	}
+	if len(v.plonkKeys) >= maxCachedVerifyingKeys {
+		clear(v.plonkKeys)
+	}
+	v.plonkKeys[hash] = vk
+	return vk, nil
+}
+
+func parseGroth16VK(raw []byte) (*groth16bn254.VerifyingKey, error) {
+	vk := new(groth16bn254.VerifyingKey)
+	if _, err := vk.ReadFrom(bytes.NewReader(raw)); err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	return vk, nil
+}
 
+func parsePlonkVK(raw []byte) (*plonkbn254.VerifyingKey, error) {
 	vk := new(plonkbn254.VerifyingKey)
-	if _, err := vk.ReadFrom(f); err != nil {
+	if _, err := vk.ReadFrom(bytes.NewReader(raw)); err != nil {
 		return nil, err
 	}
 	return vk, nil
@@ -131,7 +206,15 @@ func verifyGroth16(
 	if err != nil {
 		return err
 	}
-	return groth16bn254.Verify(proof, vk, publicWitness)
+	// A well-formed proof that gnark rejects is the prover's fault, so only
+	// that is reported as ErrProofPairingFailed.
+	if len(publicWitness) != len(vk.G1.K)-len(vk.PublicAndCommitmentCommitted)-1 {
+		return storage.ErrProofPublicInputsMismatch
+	}
+	if err := groth16bn254.Verify(proof, vk, publicWitness); err != nil {
+		return fmt.Errorf("%w: %v", storage.ErrProofPairingFailed, err)
+	}
+	return nil
 }
 
 func verifyPlonk(
@@ -149,7 +232,16 @@ func verifyPlonk(
 	if err != nil {
 		return err
 	}
-	return plonkbn254.Verify(proof, vk, publicWitness)
+	if len(proof.Bsb22Commitments) != len(vk.Qcp) {
+		return storage.ErrInvalidProofEnvelope
+	}
+	if len(publicWitness) != int(vk.NbPublicVariables) {
+		return storage.ErrProofPublicInputsMismatch
+	}
+	if err := plonkbn254.Verify(proof, vk, publicWitness); err != nil {
+		return fmt.Errorf("%w: %v", storage.ErrProofPairingFailed, err)
+	}
+	return nil
 }
 
 func buildPublicWitnessVector(
@@ -191,6 +283,21 @@ func buildPublicWitnessVector(
 		if err := validateDigestVector(publicInputsHash, vec); err != nil {
 			return nil, err
 		}
+	case mconsts.ProofCircuitShieldedLedgerV2:
+		if err := validateShieldedLedgerV2Vector(publicInputsHash, vec); err != nil {
+			return nil, err
+		}
+	case mconsts.ProofCircuitClearingV1:
+		if err := validateDigestVector(publicInputsHash, vec); err != nil {
+			return nil, err
+		}
+	case mconsts.ProofCircuitClearHashMiMCV1, mconsts.ProofCircuitAggregateV1:
+		if len(vec) != 1 {
+			return nil, fmt.Errorf("unsupported %s witness length: %d", circuitID, len(vec))
+		}
+		if err := validateDigestVector(publicInputsHash, vec); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, storage.ErrUnsupportedProofCircuit
 	}
@@ -214,6 +321,14 @@ func isSupportedCircuitID(circuitID string) bool {
 		return true
 	case mconsts.ProofCircuitShieldedLedgerV1:
 		return true
+	case mconsts.ProofCircuitShieldedLedgerV2:
+		return true
+	case mconsts.ProofCircuitClearingV1:
+		return true
+	case mconsts.ProofCircuitClearHashMiMCV1:
+		return true
+	case mconsts.ProofCircuitAggregateV1:
+		return true
 	default:
 		return false
 	}
@@ -232,6 +347,9 @@ func hashToFieldElement(publicInputsHash []byte) (bn254fr.Element, error) {
 	return el, nil
 }
 
+// validateDigestVector accepts a digest exposed either as one field element,
+// as clearhash-mimc-v1 and aggregate-v1 do, or as ClearHashDigestLen public
+// bytes.
 func validateDigestVector(publicInputsHash []byte, vec bn254fr.Vector) error {
 	switch len(vec) {
 	case 1:
@@ -257,3 +375,19 @@ func validateDigestVector(publicInputsHash []byte, vec bn254fr.Vector) error {
 		return fmt.Errorf("unsupported digest witness length: %d", len(vec))
 	}
 }
+
+// validateShieldedLedgerV2Vector checks the fixed public-input layout of
+// shielded-ledger-v2 and that its batch digest matches publicInputsHash.
+func validateShieldedLedgerV2Vector(publicInputsHash []byte, vec bn254fr.Vector) error {
+	if len(vec) != ShieldedLedgerV2PublicInputs {
+		return fmt.Errorf("unsupported shielded-ledger-v2 witness length: %d", len(vec))
+	}
+	expected, err := hashToFieldElement(publicInputsHash)
+	if err != nil {
+		return err
+	}
+	if !vec[0].Equal(&expected) {
+		return storage.ErrProofPublicInputsMismatch
+	}
+	return nil
+}
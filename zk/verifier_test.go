@@ -3,6 +3,7 @@ package zk
 import (
 	"bytes"
 	"crypto/sha256"
+	"errors"
 	"testing"
 
 	mconsts "github.com/ava-labs/hypersdk/examples/veilvm/consts"
@@ -97,4 +98,23 @@ func TestVerifyGroth16ClearHashRoundTrip(t *testing.T) {
 	if err := verifyGroth16(vkBN, mconsts.ProofCircuitClearHashV1, proofBuf.Bytes(), digest[:], publicWitnessBytes); err != nil {
 		t.Fatalf("verify: %v", err)
 	}
+
+	// A verifier with no local key verifies against the registered key.
+	var vkBuf bytes.Buffer
+	if _, err := vkBN.WriteTo(&vkBuf); err != nil {
+		t.Fatalf("serialize vk: %v", err)
+	}
+	verifier, err := NewVerifier(Config{})
+	if err != nil {
+		t.Fatalf("new verifier: %v", err)
+	}
+	if err := verifier.Verify(mconsts.ProofTypeGroth16, mconsts.ProofCircuitClearHashV1, vkBuf.Bytes(), proofBuf.Bytes(), digest[:], publicWitnessBytes); err != nil {
+		t.Fatalf("verify with registered key: %v", err)
+	}
+	if err := verifier.Verify(mconsts.ProofTypeGroth16, mconsts.ProofCircuitClearHashV1, nil, proofBuf.Bytes(), digest[:], publicWitnessBytes); !errors.Is(err, storage.ErrProofVerifierUnavailable) {
+		t.Fatalf("expected verifier unavailable without a key, got %v", err)
+	}
+	if err := verifier.Verify(mconsts.ProofTypeGroth16, mconsts.ProofCircuitClearHashV1, []byte{0x01}, proofBuf.Bytes(), digest[:], publicWitnessBytes); !errors.Is(err, storage.ErrInvalidVerifyingKey) {
+		t.Fatalf("expected invalid verifying key, got %v", err)
+	}
 }